@@ -2,60 +2,323 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
+	// User time zones are checked against the IANA database; the runtime
+	// image does not ship one.
+	_ "time/tzdata"
 
+	"test_task/internal/cache"
 	"test_task/internal/config"
 	"test_task/internal/connections"
+	monthyear "test_task/internal/domain/models/month_year"
 
+	handleranalytics "test_task/internal/handlers/analytics"
+	handlerkey "test_task/internal/handlers/api_key"
+	handlerbudget "test_task/internal/handlers/budget"
+	handlermetrics "test_task/internal/handlers/metrics"
+	handlerservice "test_task/internal/handlers/service"
 	handlersub "test_task/internal/handlers/subscription"
+	handlertag "test_task/internal/handlers/tag"
+	handleruser "test_task/internal/handlers/user"
+	handlerwebhook "test_task/internal/handlers/webhook"
+	"test_task/internal/jobs/expiry"
+	jobsoutbox "test_task/internal/jobs/outbox"
+	"test_task/internal/jobs/reminder"
+	"test_task/internal/jobs/retention"
+	jobswebhook "test_task/internal/jobs/webhook"
+	authmid "test_task/internal/middleware/auth_middleware"
 	logmid "test_task/internal/middleware/loger_middleware"
+	ratelimit "test_task/internal/middleware/rate_limit_middleware"
+	"test_task/internal/notifier"
+	"test_task/internal/publisher"
+	repoanalytics "test_task/internal/repository/postgres/analytics"
+	repokey "test_task/internal/repository/postgres/api_key"
+	repobudget "test_task/internal/repository/postgres/budget"
+	reponotify "test_task/internal/repository/postgres/notification"
+	repooutbox "test_task/internal/repository/postgres/outbox"
+	reporollup "test_task/internal/repository/postgres/rollup"
+	reposervice "test_task/internal/repository/postgres/service"
 	reposub "test_task/internal/repository/postgres/subscription"
+	repotag "test_task/internal/repository/postgres/tag"
+	repouser "test_task/internal/repository/postgres/user"
+	repowebhook "test_task/internal/repository/postgres/webhook"
+	"test_task/internal/storage"
+	usecaseanalytics "test_task/internal/usecase/analytics"
+	usecasekey "test_task/internal/usecase/api_key"
+	usecasebudget "test_task/internal/usecase/budget"
+	usecaseservice "test_task/internal/usecase/service"
 	usecasesub "test_task/internal/usecase/subscription"
+	usecasetag "test_task/internal/usecase/tag"
+	usecaseuser "test_task/internal/usecase/user"
+	usecasewebhook "test_task/internal/usecase/webhook"
 )
 
 func main() {
-	cfg := config.GetConfig()
+	args := os.Args[1:]
+	if len(args) >= 2 && args[0] == "config" && args[1] == "print" {
+		os.Exit(printConfig(args[2:]))
+	}
+	if len(args) >= 2 && args[0] == "rollup" && args[1] == "rebuild" {
+		os.Exit(rebuildRollup(args[2:]))
+	}
+
+	cfg, err := config.Load(flag.NewFlagSet(os.Args[0], flag.ExitOnError), args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	os.Exit(serve(cfg))
+}
+
+// printConfig implements `config print [--redacted] [config flags]`.
+func printConfig(args []string) int {
+	fs := flag.NewFlagSet("config print", flag.ExitOnError)
+	redacted := fs.Bool("redacted", false, "mask secret values")
+
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := cfg.Print(os.Stdout, *redacted); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
 
-	log := logmid.NewLogger(os.Getenv("LOG_LEVEL"))
+// rebuildRollup refills the monthly_spend rollup, open-ended subscriptions
+// up to -horizon-months from the current month. The summary reads from it
+// for periods ending within the horizon.
+func rebuildRollup(args []string) int {
+	fs := flag.NewFlagSet("rollup rebuild", flag.ExitOnError)
+	months := fs.Int("horizon-months", 60, "months after the current one to fill for open-ended subscriptions")
+
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *months < 0 {
+		fmt.Fprintln(os.Stderr, "-horizon-months: must be >= 0")
+		return 2
+	}
+	log := logmid.NewLogger(cfg.AppConfig.LogLevel)
 
 	conn, err := connections.New(cfg)
 	if err != nil {
 		log.Error("connections init failed", slog.Any("err", err))
-		os.Exit(1)
+		return 1
 	}
+	defer conn.CloseAll()
 
-	repo := reposub.New(conn.PostgresSQL)
-	usecase := usecasesub.New(repo)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	now := time.Now().UTC()
+	horizon := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, *months, 0)
+	rows, err := reporollup.New(conn.PostgresSQLJobs).Rebuild(ctx, horizon)
+	if err != nil {
+		log.Error("rollup rebuild failed", slog.Any("err", err))
+		return 1
+	}
+	log.Info("rollup rebuilt", slog.String("horizon", monthyear.FormatMonthYear(horizon)), slog.Int64("rows", rows))
+	return 0
+}
+
+func serve(cfg *config.Config) int {
+	log := logmid.NewLogger(cfg.AppConfig.LogLevel)
+
+	conn, err := connections.New(cfg)
+	if err != nil {
+		log.Error("connections init failed", slog.Any("err", err))
+		return 1
+	}
+	defer conn.CloseAll()
+
+	logos := storage.NewLocal(cfg.AppConfig.ImgPath)
+	serviceUsecase := usecaseservice.New(reposervice.New(conn.PostgresSQL),
+		usecaseservice.WithLogos(logos, cfg.Logos.MaxBytes, cfg.Logos.ThumbnailSize))
+	serviceHandler := handlerservice.New(log, serviceUsecase,
+		handlerservice.WithLogoLimits(cfg.Logos.MaxBytes, cfg.Logos.CacheMaxAge))
+
+	var subRepoOpts []reposub.Option
+	if cfg.Users.RequireRegistered {
+		subRepoOpts = append(subRepoOpts, reposub.WithRegisteredUsers())
+	}
+	repo := reposub.New(conn.PostgresSQL, subRepoOpts...)
+	tagRepo := repotag.New(conn.PostgresSQL)
+	budgetUsecase := usecasebudget.New(log, repobudget.New(conn.PostgresSQL), repo, tagRepo)
+	userRepo := repouser.New(conn.PostgresSQL)
+	subOpts := []usecasesub.Option{
+		usecasesub.WithCatalog(serviceUsecase),
+		usecasesub.WithBudgets(budgetUsecase),
+	}
+	if cfg.Users.RequireRegistered {
+		subOpts = append(subOpts, usecasesub.WithUsers(userRepo))
+	}
+	caches := map[string]handlermetrics.StatsI{}
+	if sc := cfg.SummaryCache; sc.Enabled {
+		summaryCache := cache.NewLRU(sc.Size, sc.TTL)
+		subOpts = append(subOpts, usecasesub.WithSummaryCache(summaryCache))
+		caches["summary"] = summaryCache
+	}
+	usecase := usecasesub.New(repo, subOpts...)
+	// The jobs work across tenants on a connection that bypasses the
+	// row-level security, sharing the summary cache with the API.
+	jobsUsecase := usecasesub.New(reposub.New(conn.PostgresSQLJobs, subRepoOpts...), subOpts...)
 	handler := handlersub.New(log, usecase)
-	router := handlersub.Router(log, handler)
+	tagHandler := handlertag.New(log, usecasetag.New(tagRepo, usecase))
+	userHandler := handleruser.New(log, usecaseuser.New(userRepo, usecase))
+	budgetHandler := handlerbudget.New(log, budgetUsecase)
+	analyticsHandler := handleranalytics.New(log, usecaseanalytics.New(repoanalytics.New(conn.PostgresSQL)))
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	var jobs sync.WaitGroup
+	defer func() {
+		stopJobs()
+		jobs.Wait()
+	}()
+
+	if ret := cfg.Retention; ret.Enabled {
+		job := retention.New(log, jobsUsecase, time.Duration(ret.PurgeAfterDays)*24*time.Hour, ret.Interval)
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			job.Run(jobsCtx)
+		}()
+	}
+	if exp := cfg.Expiry; exp.Enabled {
+		job := expiry.New(log, jobsUsecase, exp.Interval)
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			job.Run(jobsCtx)
+		}()
+	}
+	if rem := cfg.Reminders; rem.Enabled {
+		job := reminder.New(log, jobsUsecase, reponotify.New(conn.PostgresSQLJobs), newNotifier(log, rem), rem.DaysBefore, rem.Interval)
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			job.Run(jobsCtx)
+		}()
+	}
 
-	addr := net.JoinHostPort(cfg.AppConfig.Host, cfg.AppConfig.Port)
-	if cfg.AppConfig.Host == "" || cfg.AppConfig.Port == "" {
-		addr = "0.0.0.0:8080"
+	webhookRepo := repowebhook.New(conn.PostgresSQL)
+	var webhookOpts []usecasewebhook.Option
+	if cfg.Webhooks.AllowPrivateNetworks {
+		webhookOpts = append(webhookOpts, usecasewebhook.WithPrivateNetworks())
 	}
+	webhookUsecase := usecasewebhook.New(webhookRepo, webhookOpts...)
+	webhookHandler := handlerwebhook.New(log, webhookUsecase)
+	webhookJobsRepo := repowebhook.New(conn.PostgresSQLJobs)
 
-	srv := &http.Server{
-		Addr:         addr,
-		Handler:      router,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	if ev := cfg.Events; ev.Enabled {
+		pub := newPublisher(log, ev)
+		if cfg.Webhooks.Enabled {
+			pub = publisher.Multi{pub, usecasewebhook.New(webhookJobsRepo, webhookOpts...)}
+		}
+		// The lease must outlive publishing a whole batch, otherwise another
+		// replica could pick the same events up and break their order.
+		dispatcher := jobsoutbox.New(log, repooutbox.New(conn.PostgresSQLJobs), pub, jobsoutbox.Options{
+			BatchSize:    ev.BatchSize,
+			PollInterval: ev.PollInterval,
+			Lease:        ev.WebhookTimeout*time.Duration(ev.BatchSize) + time.Minute,
+			MaxAttempts:  ev.MaxAttempts,
+			BackoffBase:  ev.BackoffBase,
+			BackoffMax:   ev.BackoffMax,
+		})
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			dispatcher.Run(jobsCtx)
+		}()
+	}
+	if wh := cfg.Webhooks; wh.Enabled {
+		deliverer := jobswebhook.New(log, webhookJobsRepo, jobswebhook.Options{
+			BatchSize:            wh.BatchSize,
+			PollInterval:         wh.PollInterval,
+			Timeout:              wh.Timeout,
+			MaxAttempts:          wh.MaxAttempts,
+			BackoffBase:          wh.BackoffBase,
+			BackoffMax:           wh.BackoffMax,
+			DisableAfter:         wh.DisableAfter,
+			AllowPrivateNetworks: wh.AllowPrivateNetworks,
+		})
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			deliverer.Run(jobsCtx)
+		}()
+	}
+
+	apiKeyUsecase := usecasekey.New(repokey.New(conn.PostgresSQL), cfg.Auth.BootstrapAdminKey)
+	apiKeyHandler := handlerkey.New(log, apiKeyUsecase)
+	// The key of a request is looked up before its tenant is known.
+	authenticator := authmid.Authenticate(log, map[string]authmid.Authenticator{
+		usecasekey.Scheme: usecasekey.New(repokey.New(conn.PostgresSQLJobs), cfg.Auth.BootstrapAdminKey),
+	})
+
+	routerOpts := []handlersub.Option{
+		handlersub.WithMaxBodyBytes(cfg.HTTPConfig.MaxBodyBytes),
+		handlersub.WithAuthenticator(authenticator, cfg.Auth.Required),
+		handlersub.WithMount(handlerkey.Routes(apiKeyHandler)),
+		handlersub.WithMount(handlerwebhook.Routes(webhookHandler)),
+		handlersub.WithMount(handlerservice.Routes(serviceHandler)),
+		handlersub.WithMount(handlertag.Routes(tagHandler)),
+		handlersub.WithMount(handleruser.Routes(userHandler)),
+		handlersub.WithMount(handlerbudget.Routes(budgetHandler)),
+		handlersub.WithMount(handleranalytics.Routes(analyticsHandler)),
+		handlersub.WithMount(handlermetrics.Routes(handlermetrics.New(caches))),
+	}
+	if rl := cfg.RateLimit; rl.Enabled {
+		limiter := ratelimit.New(log, ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{
+			ratelimit.GroupRead:    {Rate: rl.ReadRPS, Burst: rl.ReadBurst},
+			ratelimit.GroupWrite:   {Rate: rl.WriteRPS, Burst: rl.WriteBurst},
+			ratelimit.GroupSummary: {Rate: rl.SummaryRPS, Burst: rl.SummaryBurst},
+			ratelimit.GroupIP:      {Rate: rl.IPRPS, Burst: rl.IPBurst},
+		})
+		routerOpts = append(routerOpts, handlersub.WithRateLimiter(limiter))
+	}
+	router := handlersub.Router(log, handler, routerOpts...)
+
+	srv, err := newHTTPServer(cfg, router)
+	if err != nil {
+		log.Error("http server init failed", slog.Any("err", err))
+		return 1
 	}
 
 	serverErrors := make(chan error, 1)
 
 	go func() {
+		scheme := "http"
+		if srv.TLSConfig != nil {
+			scheme = "https"
+		}
 		log.Info("server started",
 			slog.String("addr", srv.Addr),
-			slog.String("swagger", "http://"+srv.Addr+"/swagger/"),
+			slog.Bool("tls", srv.TLSConfig != nil),
+			slog.String("swagger", scheme+"://"+srv.Addr+"/swagger/"),
 		)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Error("server error", slog.Any("err", err))
 			serverErrors <- err
 		}
@@ -73,15 +336,85 @@ func main() {
 
 	log.Info("shutting down")
 
-	ctxShutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctxShutdown, cancel := context.WithTimeout(context.Background(), cfg.AppConfig.ShutdownTimeout)
+	defer cancel()
 
 	if err := srv.Shutdown(ctxShutdown); err != nil {
 		log.Error("shutdown error", slog.Any("err", err))
-		cancel()
-		conn.CloseAll()
-		os.Exit(1)
+		return 1
 	}
-	cancel()
 
 	log.Info("bye")
+	return 0
+}
+
+// newPublisher picks the event publisher configured in the events section.
+func newPublisher(log *slog.Logger, cfg *config.EventsConfig) publisher.Publisher {
+	switch cfg.Publisher {
+	case "webhook":
+		return publisher.NewWebhook(cfg.WebhookURL, cfg.WebhookTimeout)
+	case "file":
+		return publisher.NewFile(cfg.FilePath)
+	default:
+		return publisher.NewLog(log)
+	}
+}
+
+// newNotifier picks the reminder notifier configured in the reminders section.
+func newNotifier(log *slog.Logger, cfg *config.RemindersConfig) reminder.NotifierI {
+	switch cfg.Notifier {
+	case "webhook":
+		return notifier.NewWebhook(cfg.WebhookURL, cfg.WebhookTimeout)
+	case "smtp":
+		return notifier.NewSMTP(notifier.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+			To:       cfg.SMTPTo,
+		})
+	default:
+		return notifier.NewLog(log)
+	}
+}
+
+// newHTTPServer applies the http section of the config. When TLS files are
+// configured the certificate is loaded up front so that a bad pair fails the
+// startup instead of the first handshake.
+func newHTTPServer(cfg *config.Config, handler http.Handler) (*http.Server, error) {
+	h := cfg.HTTPConfig
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(h.HTTP2)
+
+	srv := &http.Server{
+		Addr:              net.JoinHostPort(cfg.AppConfig.Host, strconv.Itoa(cfg.AppConfig.Port)),
+		Handler:           handler,
+		ReadTimeout:       h.ReadTimeout,
+		ReadHeaderTimeout: h.ReadHeaderTimeout,
+		WriteTimeout:      h.WriteTimeout,
+		IdleTimeout:       h.IdleTimeout,
+		MaxHeaderBytes:    h.MaxHeaderBytes,
+		Protocols:         protocols,
+	}
+
+	if !h.TLSEnabled() {
+		return srv, nil
+	}
+
+	minVersion, err := h.TLSVersion()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(h.TLSCertFile, h.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load tls key pair: %w", err)
+	}
+	srv.TLSConfig = &tls.Config{
+		MinVersion:   minVersion,
+		Certificates: []tls.Certificate{cert},
+	}
+	return srv, nil
 }
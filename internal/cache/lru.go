@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Stats counts the lookups of a cache since it was created.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+}
+
+type entry struct {
+	key       string
+	group     string
+	value     int64
+	expiresAt time.Time
+}
+
+// LRU is an in-process cache of int64 values with a size limit, evicting the
+// least recently used entry, and a TTL. Every entry belongs to a group so
+// the entries derived from the same data can be dropped together.
+//
+// Each group has a generation that every Invalidate of the group and every
+// Clear moves forward. A caller reads Generation before loading a value and
+// passes it to Set, which drops the value when the group was invalidated
+// meanwhile, so a value loaded before a write is not cached after it.
+type LRU struct {
+	mu     sync.Mutex
+	size   int
+	ttl    time.Duration
+	order  *list.List
+	items  map[string]*list.Element
+	groups map[string]map[string]struct{}
+	stats  Stats
+	now    func() time.Time
+	// seq numbers the invalidations, gens holds the last one of each group
+	// since cleared, the last Clear.
+	seq     uint64
+	gens    map[string]uint64
+	cleared uint64
+}
+
+func NewLRU(size int, ttl time.Duration) *LRU {
+	return &LRU{
+		size:   size,
+		ttl:    ttl,
+		order:  list.New(),
+		items:  make(map[string]*list.Element),
+		groups: make(map[string]map[string]struct{}),
+		now:    time.Now,
+		gens:   make(map[string]uint64),
+	}
+}
+
+// Generation returns the generation of group to pass to Set.
+func (c *LRU) Generation(_ context.Context, group string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation(group)
+}
+
+func (c *LRU) generation(group string) uint64 {
+	return max(c.gens[group], c.cleared)
+}
+
+// Get returns the value stored under key unless it has expired.
+func (c *LRU) Get(_ context.Context, key string) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if ok && c.now().After(el.Value.(*entry).expiresAt) {
+		c.remove(el)
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		return 0, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*entry).value, true
+}
+
+// Set stores value under key in group, evicting the least recently used
+// entry when the cache is full. It does nothing when group is no longer at
+// generation gen.
+func (c *LRU) Set(_ context.Context, group, key string, value int64, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation(group) != gen {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	for c.order.Len() >= c.size {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+
+	el := c.order.PushFront(&entry{key: key, group: group, value: value, expiresAt: c.now().Add(c.ttl)})
+	c.items[key] = el
+	keys, ok := c.groups[group]
+	if !ok {
+		keys = make(map[string]struct{})
+		c.groups[group] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+// Invalidate drops every entry of group and moves its generation forward.
+func (c *LRU) Invalidate(_ context.Context, group string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+	c.gens[group] = c.seq
+	for key := range c.groups[group] {
+		c.remove(c.items[key])
+	}
+}
+
+// Clear drops every entry and moves every generation forward.
+func (c *LRU) Clear(context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+	c.cleared = c.seq
+	c.gens = make(map[string]uint64)
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+	c.groups = make(map[string]map[string]struct{})
+}
+
+func (c *LRU) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	st := c.stats
+	st.Entries = c.order.Len()
+	return st
+}
+
+func (c *LRU) remove(el *list.Element) {
+	e := el.Value.(*entry)
+	c.order.Remove(el)
+	delete(c.items, e.key)
+	if keys := c.groups[e.group]; keys != nil {
+		delete(keys, e.key)
+		if len(keys) == 0 {
+			delete(c.groups, e.group)
+		}
+	}
+}
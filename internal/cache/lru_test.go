@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2, time.Minute)
+
+	c.Set(ctx, "u1", "a", 1, 0)
+	c.Set(ctx, "u1", "b", 2, 0)
+	if _, ok := c.Get(ctx, "a"); !ok {
+		t.Fatalf("a must be cached")
+	}
+	c.Set(ctx, "u2", "c", 3, 0)
+
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Fatalf("b is the least recently used and must be evicted")
+	}
+	if v, ok := c.Get(ctx, "a"); !ok || v != 1 {
+		t.Fatalf("want a=1, got %d, %v", v, ok)
+	}
+	if st := c.Stats(); st.Entries != 2 || st.Evictions != 1 || st.Hits != 2 || st.Misses != 1 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestLRU_Expires(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	c := NewLRU(10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "u1", "a", 1, 0)
+	now = now.Add(time.Minute)
+	if _, ok := c.Get(ctx, "a"); !ok {
+		t.Fatalf("a must be cached until its TTL is over")
+	}
+	now = now.Add(time.Second)
+	if _, ok := c.Get(ctx, "a"); ok {
+		t.Fatalf("a must have expired")
+	}
+	if st := c.Stats(); st.Entries != 0 {
+		t.Fatalf("expired entries must be dropped: %+v", st)
+	}
+}
+
+func TestLRU_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10, time.Minute)
+
+	c.Set(ctx, "u1", "a", 1, 0)
+	c.Set(ctx, "u1", "b", 2, 0)
+	c.Set(ctx, "u2", "c", 3, 0)
+	// Storing a key again moves it to its new group.
+	c.Set(ctx, "u2", "b", 4, 0)
+
+	c.Invalidate(ctx, "u1")
+	if _, ok := c.Get(ctx, "a"); ok {
+		t.Fatalf("a must be dropped with u1")
+	}
+	for key, want := range map[string]int64{"b": 4, "c": 3} {
+		if v, ok := c.Get(ctx, key); !ok || v != want {
+			t.Fatalf("want %s=%d, got %d, %v", key, want, v, ok)
+		}
+	}
+
+	c.Clear(ctx)
+	if st := c.Stats(); st.Entries != 0 {
+		t.Fatalf("unexpected stats after Clear: %+v", st)
+	}
+}
+
+func TestLRU_SetSkipsStaleGeneration(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10, time.Minute)
+
+	gen := c.Generation(ctx, "u1")
+	other := c.Generation(ctx, "u2")
+	c.Invalidate(ctx, "u1")
+	c.Set(ctx, "u1", "a", 1, gen)
+	if _, ok := c.Get(ctx, "a"); ok {
+		t.Fatalf("a was loaded before u1 was invalidated and must not be cached")
+	}
+	c.Set(ctx, "u2", "b", 2, other)
+	if _, ok := c.Get(ctx, "b"); !ok {
+		t.Fatalf("u2 was not invalidated, b must be cached")
+	}
+
+	gen = c.Generation(ctx, "u1")
+	c.Clear(ctx)
+	c.Set(ctx, "u1", "a", 1, gen)
+	if _, ok := c.Get(ctx, "a"); ok {
+		t.Fatalf("a was loaded before Clear and must not be cached")
+	}
+	c.Set(ctx, "u1", "a", 1, c.Generation(ctx, "u1"))
+	if _, ok := c.Get(ctx, "a"); !ok {
+		t.Fatalf("a must be cached at the current generation")
+	}
+}
@@ -2,59 +2,427 @@ package config
 
 import (
 	"crypto/ecdsa"
-	"os"
-
-	"github.com/joho/godotenv"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
 )
 
+// Config is the effective application configuration.
+//
+// Every leaf field is resolved in the following order, later sources winning:
+// the `default` tag, the optional config file (YAML or TOML), environment
+// variables (the `env` tag, .env is honoured) and command line flags named
+// after the dotted file path (for example -db.host).
 type Config struct {
-	DBConfig  *PostgresConfig
-	AppConfig *AppConfig
+	DBConfig     *PostgresConfig     `yaml:"db" toml:"db"`
+	AppConfig    *AppConfig          `yaml:"app" toml:"app"`
+	HTTPConfig   *HTTPConfig         `yaml:"http" toml:"http"`
+	RateLimit    *RateLimitConfig    `yaml:"rate_limit" toml:"rate_limit"`
+	Auth         *AuthConfig         `yaml:"auth" toml:"auth"`
+	Retention    *RetentionConfig    `yaml:"retention" toml:"retention"`
+	Expiry       *ExpiryConfig       `yaml:"expiry" toml:"expiry"`
+	Events       *EventsConfig       `yaml:"events" toml:"events"`
+	Webhooks     *WebhooksConfig     `yaml:"webhooks" toml:"webhooks"`
+	Reminders    *RemindersConfig    `yaml:"reminders" toml:"reminders"`
+	Logos        *LogosConfig        `yaml:"logos" toml:"logos"`
+	SummaryCache *SummaryCacheConfig `yaml:"summary_cache" toml:"summary_cache"`
+	Users        *UsersConfig        `yaml:"users" toml:"users"`
 }
 
 type PostgresConfig struct {
-	User     string
-	Password string
-	Host     string
-	Port     string
-	DB       string
+	User            string        `yaml:"user" toml:"user" env:"POSTGRES_USER" required:"true"`
+	Password        string        `yaml:"password" toml:"password" env:"POSTGRES_PASSWORD" required:"true" secret:"true"`
+	Host            string        `yaml:"host" toml:"host" env:"POSTGRES_HOST" default:"localhost" required:"true"`
+	Port            int           `yaml:"port" toml:"port" env:"POSTGRES_PORT" default:"5432"`
+	DB              string        `yaml:"db" toml:"db" env:"POSTGRES_DB" required:"true"`
+	SSLMode         string        `yaml:"sslmode" toml:"sslmode" env:"POSTGRES_SSLMODE" default:"disable"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns" env:"POSTGRES_MAX_OPEN_CONNS" default:"25"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns" env:"POSTGRES_MAX_IDLE_CONNS" default:"5"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"POSTGRES_CONN_MAX_LIFETIME" default:"30m"`
+	ConnectRetries  int           `yaml:"connect_retries" toml:"connect_retries" env:"POSTGRES_CONNECT_RETRIES" default:"10"`
+	// JobsUser connects the background jobs, the rollup rebuild and the key
+	// lookup of authentication, which work across tenants and need a role
+	// with BYPASSRLS. Through User they would see no row at all.
+	JobsUser     string `yaml:"jobs_user" toml:"jobs_user" env:"POSTGRES_JOBS_USER" required:"true"`
+	JobsPassword string `yaml:"jobs_password" toml:"jobs_password" env:"POSTGRES_JOBS_PASSWORD" secret:"true"`
+}
+
+// Jobs is the connection config of the background jobs.
+func (c *PostgresConfig) Jobs() *PostgresConfig {
+	jobs := *c
+	jobs.User, jobs.Password = c.JobsUser, c.JobsPassword
+	return &jobs
 }
 
 type AppConfig struct {
-	Host              string
-	Port              string
-	JwtPrivateKeyPath string
-	JwtPublicKeyPath  string
-	JwtPrivateKey     *ecdsa.PrivateKey
-	JwtPublicKey      *ecdsa.PublicKey
-	ImgPath           string
+	Host              string            `yaml:"host" toml:"host" env:"APP_HOST" default:"0.0.0.0"`
+	Port              int               `yaml:"port" toml:"port" env:"APP_PORT" default:"8080"`
+	LogLevel          string            `yaml:"log_level" toml:"log_level" env:"LOG_LEVEL" default:"info"`
+	ShutdownTimeout   time.Duration     `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"APP_SHUTDOWN_TIMEOUT" default:"10s"`
+	JwtPrivateKeyPath string            `yaml:"jwt_private_key_path" toml:"jwt_private_key_path" env:"JWT_PRIVATE_KEY_PATH"`
+	JwtPublicKeyPath  string            `yaml:"jwt_public_key_path" toml:"jwt_public_key_path" env:"JWT_PUBLIC_KEY_PATH"`
+	JwtPrivateKey     *ecdsa.PrivateKey `yaml:"-" toml:"-"`
+	JwtPublicKey      *ecdsa.PublicKey  `yaml:"-" toml:"-"`
+	// ImgPath is the directory uploaded service logos are stored in.
+	ImgPath string `yaml:"img_path" toml:"img_path" env:"IMG_PATH" default:"./img"`
 }
 
-func GetConfig() *Config {
-	err := godotenv.Load()
-	if err != nil {
-		panic("Error loading .env file")
-	}
+type HTTPConfig struct {
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"HTTP_READ_TIMEOUT" default:"10s"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" default:"5s"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" default:"10s"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" default:"60s"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" toml:"max_header_bytes" env:"HTTP_MAX_HEADER_BYTES" default:"1048576"`
+	MaxBodyBytes      int64         `yaml:"max_body_bytes" toml:"max_body_bytes" env:"HTTP_MAX_BODY_BYTES" default:"1048576"`
+	TLSCertFile       string        `yaml:"tls_cert_file" toml:"tls_cert_file" env:"HTTP_TLS_CERT_FILE"`
+	TLSKeyFile        string        `yaml:"tls_key_file" toml:"tls_key_file" env:"HTTP_TLS_KEY_FILE"`
+	TLSMinVersion     string        `yaml:"tls_min_version" toml:"tls_min_version" env:"HTTP_TLS_MIN_VERSION" default:"1.2"`
+	HTTP2             bool          `yaml:"http2" toml:"http2" env:"HTTP_HTTP2" default:"true"`
+}
+
+// RateLimitConfig holds token bucket budgets per route group, in requests per
+// second with a burst on top.
+type RateLimitConfig struct {
+	Enabled      bool    `yaml:"enabled" toml:"enabled" env:"RATE_LIMIT_ENABLED" default:"true"`
+	ReadRPS      float64 `yaml:"read_rps" toml:"read_rps" env:"RATE_LIMIT_READ_RPS" default:"20"`
+	ReadBurst    int     `yaml:"read_burst" toml:"read_burst" env:"RATE_LIMIT_READ_BURST" default:"40"`
+	WriteRPS     float64 `yaml:"write_rps" toml:"write_rps" env:"RATE_LIMIT_WRITE_RPS" default:"5"`
+	WriteBurst   int     `yaml:"write_burst" toml:"write_burst" env:"RATE_LIMIT_WRITE_BURST" default:"10"`
+	SummaryRPS   float64 `yaml:"summary_rps" toml:"summary_rps" env:"RATE_LIMIT_SUMMARY_RPS" default:"1"`
+	SummaryBurst int     `yaml:"summary_burst" toml:"summary_burst" env:"RATE_LIMIT_SUMMARY_BURST" default:"5"`
+	// IPRPS and IPBurst limit every API request of a client IP before its
+	// credentials are checked.
+	IPRPS   float64 `yaml:"ip_rps" toml:"ip_rps" env:"RATE_LIMIT_IP_RPS" default:"50"`
+	IPBurst int     `yaml:"ip_burst" toml:"ip_burst" env:"RATE_LIMIT_IP_BURST" default:"100"`
+}
+
+type AuthConfig struct {
+	// Required rejects anonymous calls to the subscription API. Admin
+	// endpoints always require credentials.
+	Required bool `yaml:"required" toml:"required" env:"AUTH_REQUIRED" default:"false"`
+	// BootstrapAdminKey is accepted as an admin API key, to create real keys.
+	BootstrapAdminKey string `yaml:"bootstrap_admin_key" toml:"bootstrap_admin_key" env:"AUTH_BOOTSTRAP_ADMIN_KEY" secret:"true"`
+}
+
+// RetentionConfig controls purging of soft-deleted subscriptions.
+type RetentionConfig struct {
+	Enabled        bool          `yaml:"enabled" toml:"enabled" env:"RETENTION_ENABLED" default:"true"`
+	PurgeAfterDays int           `yaml:"purge_after_days" toml:"purge_after_days" env:"RETENTION_PURGE_AFTER_DAYS" default:"90"`
+	Interval       time.Duration `yaml:"interval" toml:"interval" env:"RETENTION_INTERVAL" default:"1h"`
+}
+
+// ExpiryConfig controls moving subscriptions past end_date to expired.
+type ExpiryConfig struct {
+	Enabled  bool          `yaml:"enabled" toml:"enabled" env:"EXPIRY_ENABLED" default:"true"`
+	Interval time.Duration `yaml:"interval" toml:"interval" env:"EXPIRY_INTERVAL" default:"1h"`
+}
+
+// EventsConfig controls delivery of subscription lifecycle events from the
+// outbox table.
+type EventsConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" env:"EVENTS_ENABLED" default:"true"`
+	// Publisher is one of log, webhook or file.
+	Publisher      string        `yaml:"publisher" toml:"publisher" env:"EVENTS_PUBLISHER" default:"log"`
+	WebhookURL     string        `yaml:"webhook_url" toml:"webhook_url" env:"EVENTS_WEBHOOK_URL"`
+	WebhookTimeout time.Duration `yaml:"webhook_timeout" toml:"webhook_timeout" env:"EVENTS_WEBHOOK_TIMEOUT" default:"5s"`
+	FilePath       string        `yaml:"file_path" toml:"file_path" env:"EVENTS_FILE_PATH"`
+	PollInterval   time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"EVENTS_POLL_INTERVAL" default:"1s"`
+	BatchSize      int           `yaml:"batch_size" toml:"batch_size" env:"EVENTS_BATCH_SIZE" default:"100"`
+	MaxAttempts    int           `yaml:"max_attempts" toml:"max_attempts" env:"EVENTS_MAX_ATTEMPTS" default:"10"`
+	BackoffBase    time.Duration `yaml:"backoff_base" toml:"backoff_base" env:"EVENTS_BACKOFF_BASE" default:"1s"`
+	BackoffMax     time.Duration `yaml:"backoff_max" toml:"backoff_max" env:"EVENTS_BACKOFF_MAX" default:"5m"`
+}
+
+// WebhooksConfig controls delivery to registered webhooks. Deliveries are
+// fed by the events dispatcher.
+type WebhooksConfig struct {
+	Enabled      bool          `yaml:"enabled" toml:"enabled" env:"WEBHOOKS_ENABLED" default:"true"`
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"WEBHOOKS_POLL_INTERVAL" default:"1s"`
+	BatchSize    int           `yaml:"batch_size" toml:"batch_size" env:"WEBHOOKS_BATCH_SIZE" default:"50"`
+	Timeout      time.Duration `yaml:"timeout" toml:"timeout" env:"WEBHOOKS_TIMEOUT" default:"10s"`
+	MaxAttempts  int           `yaml:"max_attempts" toml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS" default:"8"`
+	BackoffBase  time.Duration `yaml:"backoff_base" toml:"backoff_base" env:"WEBHOOKS_BACKOFF_BASE" default:"30s"`
+	BackoffMax   time.Duration `yaml:"backoff_max" toml:"backoff_max" env:"WEBHOOKS_BACKOFF_MAX" default:"1h"`
+	// DisableAfter consecutive failed attempts disable a webhook.
+	DisableAfter int `yaml:"disable_after" toml:"disable_after" env:"WEBHOOKS_DISABLE_AFTER" default:"20"`
+	// AllowPrivateNetworks accepts webhook URLs on loopback and private
+	// networks, which are refused by default.
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" toml:"allow_private_networks" env:"WEBHOOKS_ALLOW_PRIVATE_NETWORKS" default:"false"`
+}
 
-	return &Config{
-		DBConfig:  GetPostgresConfig(),
-		AppConfig: GetAppConfig(),
+// RemindersConfig controls "renewal due" notifications.
+type RemindersConfig struct {
+	Enabled    bool          `yaml:"enabled" toml:"enabled" env:"REMINDERS_ENABLED" default:"true"`
+	DaysBefore int           `yaml:"days_before" toml:"days_before" env:"REMINDERS_DAYS_BEFORE" default:"3"`
+	Interval   time.Duration `yaml:"interval" toml:"interval" env:"REMINDERS_INTERVAL" default:"1h"`
+	// Notifier is one of log, webhook or smtp.
+	Notifier       string        `yaml:"notifier" toml:"notifier" env:"REMINDERS_NOTIFIER" default:"log"`
+	WebhookURL     string        `yaml:"webhook_url" toml:"webhook_url" env:"REMINDERS_WEBHOOK_URL"`
+	WebhookTimeout time.Duration `yaml:"webhook_timeout" toml:"webhook_timeout" env:"REMINDERS_WEBHOOK_TIMEOUT" default:"5s"`
+	SMTPHost       string        `yaml:"smtp_host" toml:"smtp_host" env:"REMINDERS_SMTP_HOST"`
+	SMTPPort       int           `yaml:"smtp_port" toml:"smtp_port" env:"REMINDERS_SMTP_PORT" default:"587"`
+	SMTPUsername   string        `yaml:"smtp_username" toml:"smtp_username" env:"REMINDERS_SMTP_USERNAME"`
+	SMTPPassword   string        `yaml:"smtp_password" toml:"smtp_password" env:"REMINDERS_SMTP_PASSWORD" secret:"true"`
+	SMTPFrom       string        `yaml:"smtp_from" toml:"smtp_from" env:"REMINDERS_SMTP_FROM"`
+	SMTPTo         string        `yaml:"smtp_to" toml:"smtp_to" env:"REMINDERS_SMTP_TO"`
+}
+
+// LogosConfig limits service logo uploads kept under app.img_path.
+type LogosConfig struct {
+	MaxBytes int64 `yaml:"max_bytes" toml:"max_bytes" env:"LOGOS_MAX_BYTES" default:"2097152"`
+	// ThumbnailSize is the largest side of generated thumbnails, in pixels.
+	ThumbnailSize int           `yaml:"thumbnail_size" toml:"thumbnail_size" env:"LOGOS_THUMBNAIL_SIZE" default:"128"`
+	CacheMaxAge   time.Duration `yaml:"cache_max_age" toml:"cache_max_age" env:"LOGOS_CACHE_MAX_AGE" default:"24h"`
+}
+
+// SummaryCacheConfig sizes the in-process cache of summary totals. Writes
+// drop the totals of the user they touch; TTL bounds how stale the other
+// totals get.
+type SummaryCacheConfig struct {
+	Enabled bool          `yaml:"enabled" toml:"enabled" env:"SUMMARY_CACHE_ENABLED" default:"true"`
+	Size    int           `yaml:"size" toml:"size" env:"SUMMARY_CACHE_SIZE" default:"10000"`
+	TTL     time.Duration `yaml:"ttl" toml:"ttl" env:"SUMMARY_CACHE_TTL" default:"5m"`
+}
+
+// UsersConfig controls how subscriptions relate to registered users.
+type UsersConfig struct {
+	// RequireRegistered rejects subscriptions of user_ids that are not
+	// registered with POST /api/v1/users.
+	RequireRegistered bool `yaml:"require_registered" toml:"require_registered" env:"USERS_REQUIRE_REGISTERED" default:"false"`
+}
+
+// TLSEnabled reports whether the server should terminate TLS itself.
+func (c *HTTPConfig) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// TLSVersion maps TLSMinVersion to its crypto/tls constant.
+func (c *HTTPConfig) TLSVersion() (uint16, error) {
+	switch c.TLSMinVersion {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q, want 1.2 or 1.3", c.TLSMinVersion)
 	}
 }
 
-func GetPostgresConfig() *PostgresConfig {
-	return &PostgresConfig{
-		User:     os.Getenv("POSTGRES_USER"),
-		Password: os.Getenv("POSTGRES_PASSWORD"),
-		Host:     os.Getenv("POSTGRES_HOST"),
-		Port:     os.Getenv("POSTGRES_PORT"),
-		DB:       os.Getenv("POSTGRES_DB"),
+// Validate checks constraints that cannot be expressed with the `required` tag.
+func (c *Config) Validate() error {
+	var errs []error
+
+	db := c.DBConfig
+	if err := validatePort("db.port", db.Port); err != nil {
+		errs = append(errs, err)
+	}
+	switch db.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		errs = append(errs, fmt.Errorf("db.sslmode: unsupported value %q", db.SSLMode))
+	}
+	if db.MaxOpenConns < 0 {
+		errs = append(errs, errors.New("db.max_open_conns: must be >= 0"))
+	}
+	if db.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("db.max_idle_conns: must be >= 0"))
+	}
+	if db.ConnMaxLifetime < 0 {
+		errs = append(errs, errors.New("db.conn_max_lifetime: must be >= 0"))
+	}
+	if db.ConnectRetries < 1 {
+		errs = append(errs, errors.New("db.connect_retries: must be >= 1"))
 	}
+	if db.JobsUser != "" && db.JobsUser == db.User {
+		errs = append(errs, errors.New("db.jobs_user: must be a role with BYPASSRLS other than db.user"))
+	}
+
+	app := c.AppConfig
+	if err := validatePort("app.port", app.Port); err != nil {
+		errs = append(errs, err)
+	}
+	switch app.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("app.log_level: unsupported value %q", app.LogLevel))
+	}
+	if app.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("app.shutdown_timeout: must be > 0"))
+	}
+
+	h := c.HTTPConfig
+	timeouts := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"http.read_timeout", h.ReadTimeout},
+		{"http.read_header_timeout", h.ReadHeaderTimeout},
+		{"http.write_timeout", h.WriteTimeout},
+		{"http.idle_timeout", h.IdleTimeout},
+	}
+	for _, t := range timeouts {
+		if t.d < 0 {
+			errs = append(errs, fmt.Errorf("%s: must be >= 0", t.name))
+		}
+	}
+	if h.MaxHeaderBytes <= 0 {
+		errs = append(errs, errors.New("http.max_header_bytes: must be > 0"))
+	}
+	if h.MaxBodyBytes <= 0 {
+		errs = append(errs, errors.New("http.max_body_bytes: must be > 0"))
+	}
+	if (h.TLSCertFile == "") != (h.TLSKeyFile == "") {
+		errs = append(errs, errors.New("http.tls_cert_file and http.tls_key_file must be set together"))
+	}
+	if _, err := h.TLSVersion(); err != nil {
+		errs = append(errs, fmt.Errorf("http.tls_min_version: %w", err))
+	}
+
+	rl := c.RateLimit
+	if rl.Enabled {
+		budgets := []struct {
+			name  string
+			rps   float64
+			burst int
+		}{
+			{"read", rl.ReadRPS, rl.ReadBurst},
+			{"write", rl.WriteRPS, rl.WriteBurst},
+			{"summary", rl.SummaryRPS, rl.SummaryBurst},
+			{"ip", rl.IPRPS, rl.IPBurst},
+		}
+		for _, b := range budgets {
+			if b.rps <= 0 || b.burst <= 0 {
+				errs = append(errs, fmt.Errorf("rate_limit.%s_rps and rate_limit.%s_burst: must be > 0", b.name, b.name))
+			}
+		}
+	}
+
+	if ret := c.Retention; ret.Enabled {
+		if ret.PurgeAfterDays < 1 {
+			errs = append(errs, errors.New("retention.purge_after_days: must be >= 1"))
+		}
+		if ret.Interval <= 0 {
+			errs = append(errs, errors.New("retention.interval: must be > 0"))
+		}
+	}
+
+	if exp := c.Expiry; exp.Enabled && exp.Interval <= 0 {
+		errs = append(errs, errors.New("expiry.interval: must be > 0"))
+	}
+
+	if ev := c.Events; ev.Enabled {
+		switch ev.Publisher {
+		case "log":
+		case "webhook":
+			if ev.WebhookURL == "" {
+				errs = append(errs, errors.New("events.webhook_url: is required for the webhook publisher"))
+			}
+			if ev.WebhookTimeout <= 0 {
+				errs = append(errs, errors.New("events.webhook_timeout: must be > 0"))
+			}
+		case "file":
+			if ev.FilePath == "" {
+				errs = append(errs, errors.New("events.file_path: is required for the file publisher"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("events.publisher: unsupported value %q, want log, webhook or file", ev.Publisher))
+		}
+		if ev.PollInterval <= 0 {
+			errs = append(errs, errors.New("events.poll_interval: must be > 0"))
+		}
+		if ev.BatchSize < 1 {
+			errs = append(errs, errors.New("events.batch_size: must be >= 1"))
+		}
+		if ev.MaxAttempts < 1 {
+			errs = append(errs, errors.New("events.max_attempts: must be >= 1"))
+		}
+		if ev.BackoffBase <= 0 || ev.BackoffMax < ev.BackoffBase {
+			errs = append(errs, errors.New("events.backoff_base and events.backoff_max: must be > 0 with backoff_max >= backoff_base"))
+		}
+	}
+
+	if wh := c.Webhooks; wh.Enabled {
+		if !c.Events.Enabled {
+			errs = append(errs, errors.New("webhooks.enabled: requires events.enabled"))
+		}
+		if wh.PollInterval <= 0 {
+			errs = append(errs, errors.New("webhooks.poll_interval: must be > 0"))
+		}
+		if wh.BatchSize < 1 {
+			errs = append(errs, errors.New("webhooks.batch_size: must be >= 1"))
+		}
+		if wh.Timeout <= 0 {
+			errs = append(errs, errors.New("webhooks.timeout: must be > 0"))
+		}
+		if wh.MaxAttempts < 1 {
+			errs = append(errs, errors.New("webhooks.max_attempts: must be >= 1"))
+		}
+		if wh.BackoffBase <= 0 || wh.BackoffMax < wh.BackoffBase {
+			errs = append(errs, errors.New("webhooks.backoff_base and webhooks.backoff_max: must be > 0 with backoff_max >= backoff_base"))
+		}
+		if wh.DisableAfter < 1 {
+			errs = append(errs, errors.New("webhooks.disable_after: must be >= 1"))
+		}
+	}
+
+	if rem := c.Reminders; rem.Enabled {
+		if rem.DaysBefore < 1 {
+			errs = append(errs, errors.New("reminders.days_before: must be >= 1"))
+		}
+		if rem.Interval <= 0 {
+			errs = append(errs, errors.New("reminders.interval: must be > 0"))
+		}
+		switch rem.Notifier {
+		case "log":
+		case "webhook":
+			if rem.WebhookURL == "" {
+				errs = append(errs, errors.New("reminders.webhook_url: is required for the webhook notifier"))
+			}
+			if rem.WebhookTimeout <= 0 {
+				errs = append(errs, errors.New("reminders.webhook_timeout: must be > 0"))
+			}
+		case "smtp":
+			if rem.SMTPHost == "" || rem.SMTPFrom == "" || rem.SMTPTo == "" {
+				errs = append(errs, errors.New("reminders.smtp_host, reminders.smtp_from and reminders.smtp_to: are required for the smtp notifier"))
+			}
+			if err := validatePort("reminders.smtp_port", rem.SMTPPort); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			errs = append(errs, fmt.Errorf("reminders.notifier: unsupported value %q, want log, webhook or smtp", rem.Notifier))
+		}
+	}
+
+	lg := c.Logos
+	if app.ImgPath == "" {
+		errs = append(errs, errors.New("app.img_path: is required"))
+	}
+	if lg.MaxBytes <= 0 {
+		errs = append(errs, errors.New("logos.max_bytes: must be > 0"))
+	}
+	if lg.ThumbnailSize < 1 {
+		errs = append(errs, errors.New("logos.thumbnail_size: must be >= 1"))
+	}
+	if lg.CacheMaxAge < 0 {
+		errs = append(errs, errors.New("logos.cache_max_age: must be >= 0"))
+	}
+
+	if sc := c.SummaryCache; sc.Enabled {
+		if sc.Size < 1 {
+			errs = append(errs, errors.New("summary_cache.size: must be >= 1"))
+		}
+		if sc.TTL <= 0 {
+			errs = append(errs, errors.New("summary_cache.ttl: must be > 0"))
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
-func GetAppConfig() *AppConfig {
-	return &AppConfig{
-		Host: os.Getenv("APP_HOST"),
-		Port: os.Getenv("APP_PORT"),
+func validatePort(name string, port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("%s: must be in 1..65535, got %d", name, port)
 	}
+	return nil
 }
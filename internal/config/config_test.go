@@ -0,0 +1,174 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "s3cret")
+	t.Setenv("POSTGRES_DB", "subscriptions")
+	t.Setenv("POSTGRES_JOBS_USER", "subscriptions_jobs")
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.AppConfig.Host != "0.0.0.0" || cfg.AppConfig.Port != 8080 {
+		t.Fatalf("unexpected app addr %s:%d", cfg.AppConfig.Host, cfg.AppConfig.Port)
+	}
+	if cfg.AppConfig.ShutdownTimeout != 10*time.Second {
+		t.Fatalf("unexpected shutdown timeout %v", cfg.AppConfig.ShutdownTimeout)
+	}
+	if cfg.DBConfig.Port != 5432 {
+		t.Fatalf("unexpected db port %d", cfg.DBConfig.Port)
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	setRequiredEnv(t)
+	path := writeFile(t, "app.yaml", `
+app:
+  port: 9000
+  host: 127.0.0.1
+  shutdown_timeout: 30s
+db:
+  max_open_conns: 7
+`)
+	t.Setenv("APP_PORT", "9100")
+
+	cfg, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{
+		"-config", path,
+		"-app.host", "10.0.0.1",
+	})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.AppConfig.Port != 9100 {
+		t.Fatalf("env must override file, got port %d", cfg.AppConfig.Port)
+	}
+	if cfg.AppConfig.Host != "10.0.0.1" {
+		t.Fatalf("flag must override file, got host %s", cfg.AppConfig.Host)
+	}
+	if cfg.AppConfig.ShutdownTimeout != 30*time.Second {
+		t.Fatalf("file must override default, got %v", cfg.AppConfig.ShutdownTimeout)
+	}
+	if cfg.DBConfig.MaxOpenConns != 7 {
+		t.Fatalf("want max_open_conns 7, got %d", cfg.DBConfig.MaxOpenConns)
+	}
+}
+
+func TestLoad_TOML(t *testing.T) {
+	setRequiredEnv(t)
+	path := writeFile(t, "app.toml", "[app]\nport = 8181\nlog_level = \"debug\"\n")
+
+	cfg, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", path})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.AppConfig.Port != 8181 || cfg.AppConfig.LogLevel != "debug" {
+		t.Fatalf("unexpected app config %+v", cfg.AppConfig)
+	}
+}
+
+func TestLoad_UnknownFileKey(t *testing.T) {
+	setRequiredEnv(t)
+	path := writeFile(t, "app.yaml", "app:\n  prot: 9000\n")
+
+	_, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", path})
+	if err == nil {
+		t.Fatalf("expected error for unknown key")
+	}
+}
+
+func TestLoad_ValidationErrors(t *testing.T) {
+	t.Setenv("POSTGRES_USER", "")
+	t.Setenv("POSTGRES_PASSWORD", "")
+	t.Setenv("POSTGRES_DB", "")
+	t.Setenv("POSTGRES_JOBS_USER", "")
+	t.Setenv("APP_PORT", "http")
+	t.Setenv("APP_SHUTDOWN_TIMEOUT", "10")
+
+	_, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	for _, want := range []string{"POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DB", "POSTGRES_JOBS_USER", "APP_PORT", "APP_SHUTDOWN_TIMEOUT"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error must mention %s, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoad_JobsUserDiffers(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("POSTGRES_JOBS_USER", "postgres")
+
+	_, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err == nil || !strings.Contains(err.Error(), "jobs_user") {
+		t.Fatalf("expected jobs_user error, got %v", err)
+	}
+}
+
+func TestLoad_TLSPairRequired(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("HTTP_TLS_CERT_FILE", "/etc/app/tls.crt")
+
+	_, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err == nil || !strings.Contains(err.Error(), "tls_key_file") {
+		t.Fatalf("expected tls pair error, got %v", err)
+	}
+}
+
+func TestPrint_Redacted(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf, true); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "s3cret") {
+		t.Fatalf("password leaked:\n%s", out)
+	}
+	if !strings.Contains(out, "password: '***'") {
+		t.Fatalf("password not masked:\n%s", out)
+	}
+	if !strings.Contains(out, "shutdown_timeout: 10s") {
+		t.Fatalf("duration not printed:\n%s", out)
+	}
+}
+
+func TestLoad_EventsPublisher(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("EVENTS_PUBLISHER", "webhook")
+
+	_, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err == nil || !strings.Contains(err.Error(), "events.webhook_url") {
+		t.Fatalf("expected webhook url error, got %v", err)
+	}
+}
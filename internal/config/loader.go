@@ -0,0 +1,294 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configFileFlag = "config"
+	configFileEnv  = "CONFIG_FILE"
+	redactedValue  = "***"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// field is a single configuration leaf discovered through reflection.
+type field struct {
+	path     string
+	env      string
+	def      string
+	required bool
+	secret   bool
+	value    reflect.Value
+}
+
+// Load resolves the configuration from defaults, the optional config file,
+// the environment and the flags in args. Configuration flags are registered
+// on flags, so callers may add their own flags before calling Load.
+func Load(flags *flag.FlagSet, args []string) (*Config, error) {
+	cfg := &Config{}
+	fields := collect(cfg)
+
+	configFile := flags.String(configFileFlag, "", "path to a YAML or TOML config file (env "+configFileEnv+")")
+	flagValues := make(map[string]string, len(fields))
+	for _, f := range fields {
+		usage := f.path
+		if f.env != "" {
+			usage += " (env " + f.env + ")"
+		}
+		flags.Func(f.path, usage, func(s string) error {
+			flagValues[f.path] = s
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := loadDotEnv(); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if f.def == "" {
+			continue
+		}
+		if err := setValue(f.value, f.def); err != nil {
+			errs = append(errs, fmt.Errorf("%s: bad default %q: %w", f.path, f.def, err))
+		}
+	}
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv(configFileEnv)
+	}
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		v, ok := os.LookupEnv(f.env)
+		if !ok {
+			continue
+		}
+		if err := setValue(f.value, v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q: %w", f.env, v, err))
+		}
+	}
+
+	for _, f := range fields {
+		v, ok := flagValues[f.path]
+		if !ok {
+			continue
+		}
+		if err := setValue(f.value, v); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: invalid value %q: %w", f.path, v, err))
+		}
+	}
+
+	for _, f := range fields {
+		if f.required && f.value.IsZero() {
+			name := f.path
+			if f.env != "" {
+				name += " (" + f.env + ")"
+			}
+			errs = append(errs, fmt.Errorf("%s: is required", name))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// Print writes the effective configuration as YAML. Fields tagged as secret
+// are masked when redact is set.
+func (c *Config) Print(w io.Writer, redact bool) error {
+	out := map[string]any{}
+	for _, f := range collect(c) {
+		var v any
+		switch {
+		case f.secret && redact:
+			v = redactedValue
+		case f.value.Type() == durationType:
+			v = time.Duration(f.value.Int()).String()
+		default:
+			v = f.value.Interface()
+		}
+		putPath(out, strings.Split(f.path, "."), v)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func putPath(m map[string]any, path []string, v any) {
+	if len(path) == 1 {
+		m[path[0]] = v
+		return
+	}
+	child, ok := m[path[0]].(map[string]any)
+	if !ok {
+		child = map[string]any{}
+		m[path[0]] = child
+	}
+	putPath(child, path[1:], v)
+}
+
+func loadDotEnv() error {
+	err := godotenv.Load()
+	if err == nil || errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return fmt.Errorf("load .env: %w", err)
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, 0, len(undecoded))
+			for _, k := range undecoded {
+				keys = append(keys, k.String())
+			}
+			sort.Strings(keys)
+			return fmt.Errorf("parse config file %s: unknown keys: %s", path, strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("config file %s: unsupported extension, want .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// collect walks cfg and returns its leaf fields, allocating nested sections.
+func collect(cfg *Config) []field {
+	var out []field
+	walk(reflect.ValueOf(cfg).Elem(), "", &out)
+	return out
+}
+
+func walk(v reflect.Value, prefix string, out *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Pointer && fv.Type().Elem().Kind() == reflect.Struct {
+			if fv.IsNil() {
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+			walk(fv.Elem(), path, out)
+			continue
+		}
+
+		*out = append(*out, field{
+			path:     path,
+			env:      sf.Tag.Get("env"),
+			def:      sf.Tag.Get("default"),
+			required: sf.Tag.Get("required") == "true",
+			secret:   sf.Tag.Get("secret") == "true",
+			value:    fv,
+		})
+	}
+}
+
+func setValue(v reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
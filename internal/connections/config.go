@@ -8,6 +8,9 @@ import (
 
 type Config struct {
 	PostgresSQL *sql.DB
+	// PostgresSQLJobs connects as the jobs role, which bypasses the
+	// row-level security. It is PostgresSQL when no jobs role is configured.
+	PostgresSQLJobs *sql.DB
 }
 
 func New(cfg *config.Config) (*Config, error) {
@@ -15,11 +18,23 @@ func New(cfg *config.Config) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	postgresSQLJobs := postgresSQL
+	if cfg.DBConfig.JobsUser != "" {
+		postgresSQLJobs, err = postgres.ConnectDB(cfg.DBConfig.Jobs())
+		if err != nil {
+			_ = postgresSQL.Close()
+			return nil, err
+		}
+	}
 	return &Config{
-		PostgresSQL: postgresSQL,
+		PostgresSQL:     postgresSQL,
+		PostgresSQLJobs: postgresSQLJobs,
 	}, nil
 }
 
 func (c *Config) CloseAll() {
 	c.PostgresSQL.Close()
+	if c.PostgresSQLJobs != c.PostgresSQL {
+		c.PostgresSQLJobs.Close()
+	}
 }
@@ -6,24 +6,32 @@ import (
 	"test_task/internal/config"
 	"time"
 
-	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
 )
 
 func ConnectDB(config *config.PostgresConfig) (*sql.DB, error) {
-	connectString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+	connectString := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		config.Host,
 		config.Port,
 		config.User,
 		config.Password,
-		config.DB)
-	db, err := sql.Open("pgx", connectString)
+		config.DB,
+		config.SSLMode)
+	connConfig, err := pgx.ParseConfig(connectString)
 	if err != nil {
 		return nil, err
 	}
+	db := sql.OpenDB(tenantConnector{stdlib.GetConnector(*connConfig)})
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+
 	i := 0
 	for err := db.Ping(); err != nil; err = db.Ping() {
 		i++
-		if i >= 10 {
+		if i >= config.ConnectRetries {
+			_ = db.Close()
 			return nil, err
 		}
 		time.Sleep(1 * time.Second)
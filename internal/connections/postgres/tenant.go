@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+
+	modeltenant "test_task/internal/domain/models/tenant"
+
+	"github.com/jackc/pgx/v4/stdlib"
+)
+
+// sqlTextForSetSessionTenant scopes the row-level security policies of the
+// session to a tenant until the next change. An empty tenant matches no row.
+const sqlTextForSetSessionTenant = `SELECT set_config('app.tenant_id', $1, false)`
+
+// tenantConnector hands out connections that scope the row-level security
+// policies to the tenant of the context of every statement and transaction,
+// so that reads outside a transaction are restricted as well.
+type tenantConnector struct {
+	driver.Connector
+}
+
+func (c tenantConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pgConn := conn.(*stdlib.Conn)
+	return &tenantConn{Conn: pgConn, session: pgxSession{pgConn}}, nil
+}
+
+// session is the part of the server session scope works with.
+type session interface {
+	// TxStatus is the transaction status of the session, 'I' when idle.
+	TxStatus() byte
+	SetTenant(ctx context.Context, tenant string) error
+}
+
+type pgxSession struct {
+	conn *stdlib.Conn
+}
+
+func (s pgxSession) TxStatus() byte {
+	return s.conn.Conn().PgConn().TxStatus()
+}
+
+func (s pgxSession) SetTenant(ctx context.Context, tenant string) error {
+	_, err := s.conn.Conn().Exec(ctx, sqlTextForSetSessionTenant, tenant)
+	return err
+}
+
+type tenantConn struct {
+	*stdlib.Conn
+	session session
+	// tenant is the app.tenant_id of the session, valid when known is set.
+	tenant string
+	known  bool
+}
+
+// scope sets the tenant of ctx on the session when it differs. Inside a
+// transaction the tenant of its start stays, tenancy.BeginTx may narrow it.
+func (c *tenantConn) scope(ctx context.Context) error {
+	var want string
+	if id := modeltenant.Scope(ctx); id != nil {
+		want = *id
+	}
+	if c.known && c.tenant == want {
+		return nil
+	}
+	if c.session.TxStatus() != 'I' {
+		return nil
+	}
+	c.known = false
+	if err := c.session.SetTenant(ctx, want); err != nil {
+		return fmt.Errorf("set tenant: %w", err)
+	}
+	c.tenant, c.known = want, true
+	return nil
+}
+
+func (c *tenantConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.scope(ctx); err != nil {
+		return nil, err
+	}
+	return c.Conn.BeginTx(ctx, opts)
+}
+
+func (c *tenantConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.scope(ctx); err != nil {
+		return nil, err
+	}
+	return c.Conn.PrepareContext(ctx, query)
+}
+
+func (c *tenantConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.scope(ctx); err != nil {
+		return nil, err
+	}
+	return c.Conn.ExecContext(ctx, query, args)
+}
+
+func (c *tenantConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.scope(ctx); err != nil {
+		return nil, err
+	}
+	return c.Conn.QueryContext(ctx, query, args)
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"testing"
+
+	modeltenant "test_task/internal/domain/models/tenant"
+)
+
+// fakeSession records the tenants set on it.
+type fakeSession struct {
+	status byte
+	set    []string
+	err    error
+}
+
+func (s *fakeSession) TxStatus() byte { return s.status }
+
+func (s *fakeSession) SetTenant(_ context.Context, tenant string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.set = append(s.set, tenant)
+	return nil
+}
+
+func TestTenantConn_Scope(t *testing.T) {
+	acme := modeltenant.WithTenant(context.Background(), "acme")
+	globex := modeltenant.WithTenant(context.Background(), "globex")
+	all := modeltenant.WithAllTenants(context.Background())
+
+	cases := []struct {
+		name   string
+		status byte
+		calls  []context.Context
+		want   []string
+	}{
+		{"idle sets the tenant once", 'I', []context.Context{acme, acme}, []string{"acme"}},
+		{"reused conn switches tenants", 'I', []context.Context{acme, globex, acme}, []string{"acme", "globex", "acme"}},
+		{"no tenant is the default one", 'I', []context.Context{context.Background()}, []string{modeltenant.Default}},
+		{"all tenants clear the tenant", 'I', []context.Context{acme, all, all}, []string{"acme", ""}},
+		{"in a transaction the tenant stays", 'T', []context.Context{acme, globex}, nil},
+		{"in a failed transaction the tenant stays", 'E', []context.Context{acme}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &fakeSession{status: tc.status}
+			c := &tenantConn{session: s}
+			for _, ctx := range tc.calls {
+				if err := c.scope(ctx); err != nil {
+					t.Fatalf("scope: %v", err)
+				}
+			}
+			if !slices.Equal(s.set, tc.want) {
+				t.Fatalf("set %q, want %q", s.set, tc.want)
+			}
+		})
+	}
+}
+
+func TestTenantConn_ScopeAfterTx(t *testing.T) {
+	s := &fakeSession{status: 'I'}
+	c := &tenantConn{session: s}
+	acme := modeltenant.WithTenant(context.Background(), "acme")
+	globex := modeltenant.WithTenant(context.Background(), "globex")
+
+	if err := c.scope(acme); err != nil {
+		t.Fatalf("scope: %v", err)
+	}
+	s.status = 'T'
+	if err := c.scope(globex); err != nil {
+		t.Fatalf("scope in tx: %v", err)
+	}
+	// Back to idle the session still holds acme, so globex must be set.
+	s.status = 'I'
+	if err := c.scope(globex); err != nil {
+		t.Fatalf("scope: %v", err)
+	}
+	if want := []string{"acme", "globex"}; !slices.Equal(s.set, want) {
+		t.Fatalf("set %q, want %q", s.set, want)
+	}
+}
+
+func TestTenantConn_ScopeError(t *testing.T) {
+	s := &fakeSession{status: 'I', err: errors.New("conn closed")}
+	c := &tenantConn{session: s}
+	acme := modeltenant.WithTenant(context.Background(), "acme")
+
+	if err := c.scope(acme); err == nil {
+		t.Fatal("expected an error")
+	}
+	// A failed set leaves the tenant of the session unknown, the next
+	// statement tries again.
+	s.err = nil
+	if err := c.scope(acme); err != nil {
+		t.Fatalf("scope: %v", err)
+	}
+	if want := []string{"acme"}; !slices.Equal(s.set, want) {
+		t.Fatalf("set %q, want %q", s.set, want)
+	}
+}
+
+type failingConnector struct{ driver.Connector }
+
+func (failingConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("refused")
+}
+
+func TestTenantConnector_ConnectError(t *testing.T) {
+	if _, err := (tenantConnector{failingConnector{}}).Connect(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+}
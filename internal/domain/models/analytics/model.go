@@ -0,0 +1,99 @@
+package analytics
+
+import "time"
+
+// MRRMonth is the monthly recurring revenue of the platform in one month and
+// how it moved since the month before, per user: New is the revenue of users
+// that paid nothing the month before, Churned what users that pay nothing
+// now paid the month before, and Expansion and Contraction the changes of
+// the users paying in both months.
+type MRRMonth struct {
+	Month       time.Time
+	MRR         int64
+	New         int64
+	Expansion   int64
+	Contraction int64
+	Churned     int64
+}
+
+// NetChange is the difference between the MRR of the month and of the month
+// before.
+func (m MRRMonth) NetChange() int64 {
+	return m.New + m.Expansion - m.Contraction - m.Churned
+}
+
+// ServiceChurn counts the subscriptions to a service billed in at least one
+// month of a period and those of them whose last billed month is before the
+// last month of the period.
+type ServiceChurn struct {
+	ServiceName string
+	Subscribers int64
+	Churned     int64
+}
+
+// Rate is the share of the subscribers that churned, zero without any.
+func (c ServiceChurn) Rate() float64 {
+	if c.Subscribers == 0 {
+		return 0
+	}
+	return float64(c.Churned) / float64(c.Subscribers)
+}
+
+// CohortMonth is the number of subscriptions started in Cohort that are
+// still active in Month.
+type CohortMonth struct {
+	Cohort time.Time
+	Month  time.Time
+	Active int64
+}
+
+// Cohort is the subscriptions started in one month. Retained[k] is how many
+// of them are still active k months later, Retained[0] being the size of the
+// cohort.
+type Cohort struct {
+	Month    time.Time
+	Retained []int64
+}
+
+// Size is the number of subscriptions started in the month.
+func (c Cohort) Size() int64 {
+	if len(c.Retained) == 0 {
+		return 0
+	}
+	return c.Retained[0]
+}
+
+// Retention is the share of the cohort still active in every month.
+func (c Cohort) Retention() []float64 {
+	out := make([]float64, len(c.Retained))
+	if size := c.Size(); size > 0 {
+		for i, n := range c.Retained {
+			out[i] = float64(n) / float64(size)
+		}
+	}
+	return out
+}
+
+type MRRMonthResp struct {
+	Month       string `json:"month"`
+	MRR         int64  `json:"mrr"`
+	New         int64  `json:"new"`
+	Expansion   int64  `json:"expansion"`
+	Contraction int64  `json:"contraction"`
+	Churned     int64  `json:"churned"`
+	NetChange   int64  `json:"net_change"`
+}
+
+type ServiceChurnResp struct {
+	ServiceName string  `json:"service_name"`
+	Subscribers int64   `json:"subscribers"`
+	Churned     int64   `json:"churned"`
+	ChurnRate   float64 `json:"churn_rate"`
+}
+
+type CohortResp struct {
+	Month     string    `json:"month"`
+	Size      int64     `json:"size"`
+	Retained  []int64   `json:"retained"`
+	Retention []float64 `json:"retention"`
+}
@@ -0,0 +1,47 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type APIKey struct {
+	ID         uuid.UUID
+	TenantID   string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	UserID     *uuid.UUID
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+type APIKeyCreateReq struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	UserID    *string  `json:"user_id,omitempty"`
+	ExpiresAt *string  `json:"expires_at,omitempty"`
+}
+
+type APIKeyResp struct {
+	ID         string   `json:"id"`
+	TenantID   string   `json:"tenant_id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	UserID     *string  `json:"user_id,omitempty"`
+	ExpiresAt  *string  `json:"expires_at,omitempty"`
+	LastUsedAt *string  `json:"last_used_at,omitempty"`
+	RevokedAt  *string  `json:"revoked_at,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// APIKeyCreatedResp is returned once on creation; Key is never shown again.
+type APIKeyCreatedResp struct {
+	APIKeyResp
+	Key string `json:"key"`
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+const (
+	OperationCreate  = "create"
+	OperationUpdate  = "update"
+	OperationDelete  = "delete"
+	OperationRestore = "restore"
+	OperationPurge   = "purge"
+	// OperationErase is the tombstone of a user erasure. It belongs to no
+	// subscription: its SubscriptionID is uuid.Nil.
+	OperationErase = "erase"
+
+	anonymousActor = "anonymous"
+)
+
+// Entry is one row of the append-only subscription_audit table. Before and
+// After hold JSON snapshots of the subscription and are nil for create and
+// delete respectively.
+type Entry struct {
+	ID             int64
+	SubscriptionID uuid.UUID
+	UserID         uuid.UUID
+	Operation      string
+	Actor          string
+	RequestID      string
+	Before         json.RawMessage
+	After          json.RawMessage
+	CreatedAt      time.Time
+}
+
+type Filter struct {
+	SubscriptionID *uuid.UUID
+	UserID         *uuid.UUID
+	Actor          *string
+	Operation      *string
+	From           *time.Time
+	To             *time.Time
+	Limit          int
+	Offset         int
+}
+
+type EntryResp struct {
+	ID             int64           `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	UserID         string          `json:"user_id"`
+	Operation      string          `json:"operation"`
+	Actor          string          `json:"actor"`
+	RequestID      string          `json:"request_id,omitempty"`
+	Before         json.RawMessage `json:"before,omitempty"`
+	After          json.RawMessage `json:"after,omitempty"`
+	CreatedAt      string          `json:"created_at"`
+}
+
+// ActorFrom names the caller of the current request for the audit log.
+func ActorFrom(ctx context.Context) string {
+	if p, ok := modelauth.PrincipalFrom(ctx); ok && p.Subject != "" {
+		return p.Subject
+	}
+	return anonymousActor
+}
+
+// RequestIDFrom returns the ID assigned by chi's middleware.RequestID.
+func RequestIDFrom(ctx context.Context) string {
+	return middleware.GetReqID(ctx)
+}
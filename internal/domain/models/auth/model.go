@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeSubscriptionsRead  = "subscriptions:read"
+	ScopeSubscriptionsWrite = "subscriptions:write"
+	ScopeAdmin              = "admin"
+)
+
+// KnownScopes lists the scopes that can be granted to credentials.
+var KnownScopes = []string{ScopeSubscriptionsRead, ScopeSubscriptionsWrite, ScopeAdmin}
+
+// Principal is the authenticated caller of a request, whatever method was
+// used to authenticate it. TenantID is empty for credentials that are not
+// bound to a tenant.
+type Principal struct {
+	Subject  string
+	Method   string
+	UserID   *uuid.UUID
+	TenantID string
+	Scopes   []string
+}
+
+// HasScope reports whether the principal was granted scope. Admins hold every scope.
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal stored by an authentication middleware.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,94 @@
+package budget
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Budget caps the monthly spend of a user, on every subscription or only on
+// the subscriptions of one service or carrying one tag.
+type Budget struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Amount      int
+	ServiceName *string
+	TagID       *uuid.UUID
+	// Tag is the current name of the tag, set when TagID is.
+	Tag *string
+	// Enforce rejects new subscriptions that would overspend the budget
+	// instead of only alerting.
+	Enforce   bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Status is the spend against a budget in one month. Spent counts the
+// charges already made, Projected every charge of the month; both are zero
+// for months without subscriptions.
+type Status struct {
+	Budget    Budget
+	Month     time.Time
+	Spent     int64
+	Projected int64
+}
+
+// Remaining is what is left of the budget after the charges already made,
+// negative when overspent.
+func (s Status) Remaining() int64 {
+	return int64(s.Budget.Amount) - s.Spent
+}
+
+// Over reports whether the month is projected to exceed the budget.
+func (s Status) Over() bool {
+	return s.Projected > int64(s.Budget.Amount)
+}
+
+// Overspend is a month a subscription change pushes over a budget.
+type Overspend struct {
+	Budget    Budget
+	Month     time.Time
+	Projected int64
+}
+
+// Alert is the data of the budget.exceeded.v1 event.
+type Alert struct {
+	BudgetID       uuid.UUID  `json:"budget_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	SubscriptionID uuid.UUID  `json:"subscription_id"`
+	Month          string     `json:"month"`
+	Amount         int        `json:"amount"`
+	Projected      int64      `json:"projected"`
+	ServiceName    *string    `json:"service_name,omitempty"`
+	TagID          *uuid.UUID `json:"tag_id,omitempty"`
+}
+
+type BudgetReq struct {
+	UserID      string  `json:"user_id"`
+	Amount      *int    `json:"amount"`
+	ServiceName *string `json:"service_name,omitempty"`
+	TagID       *string `json:"tag_id,omitempty"`
+	Enforce     bool    `json:"enforce"`
+}
+
+type BudgetResp struct {
+	ID          string  `json:"id"`
+	UserID      string  `json:"user_id"`
+	Amount      int     `json:"amount"`
+	ServiceName *string `json:"service_name,omitempty"`
+	TagID       *string `json:"tag_id,omitempty"`
+	Tag         *string `json:"tag,omitempty"`
+	Enforce     bool    `json:"enforce"`
+	CreatedAt   string  `json:"created_at"`
+	UpdatedAt   string  `json:"updated_at"`
+}
+
+type StatusResp struct {
+	BudgetID  string `json:"budget_id"`
+	Month     string `json:"month"`
+	Amount    int    `json:"amount"`
+	Spent     int64  `json:"spent"`
+	Remaining int64  `json:"remaining"`
+	Projected int64  `json:"projected"`
+	Over      bool   `json:"over"`
+}
@@ -0,0 +1,82 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Events follow the CloudEvents 1.0 JSON format. The schema version is part
+// of the type, so a breaking change to data ships as a new .v2 type.
+const (
+	SpecVersion     = "1.0"
+	Source          = "/api/v1/subscriptions"
+	DataContentType = "application/json"
+
+	TypeSubscriptionCreated   = "subscription.created.v1"
+	TypeSubscriptionUpdated   = "subscription.updated.v1"
+	TypeSubscriptionCancelled = "subscription.cancelled.v1"
+	TypeSubscriptionDeleted   = "subscription.deleted.v1"
+	TypeSubscriptionRestored  = "subscription.restored.v1"
+	TypeSubscriptionExpired   = "subscription.expired.v1"
+	TypeSubscriptionPaused    = "subscription.paused.v1"
+	TypeSubscriptionResumed   = "subscription.resumed.v1"
+
+	// TypeBudgetExceeded is emitted when a change to a subscription pushes
+	// the projected spend of a month over a budget.
+	TypeBudgetExceeded = "budget.exceeded.v1"
+)
+
+// Types lists every event type that can be subscribed to.
+var Types = []string{
+	TypeSubscriptionCreated,
+	TypeSubscriptionUpdated,
+	TypeSubscriptionCancelled,
+	TypeSubscriptionDeleted,
+	TypeSubscriptionRestored,
+	TypeSubscriptionExpired,
+	TypeSubscriptionPaused,
+	TypeSubscriptionResumed,
+	TypeBudgetExceeded,
+}
+
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// New builds an event about the entity identified by subject.
+func New(eventType, subject string, data any) (Event, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, fmt.Errorf("marshal event data: %w", err)
+	}
+	return Event{
+		SpecVersion:     SpecVersion,
+		ID:              uuid.NewString(),
+		Source:          Source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: DataContentType,
+		Data:            b,
+	}, nil
+}
+
+// OutboxRecord is an event waiting in the outbox table. Events of the same
+// aggregate are delivered strictly in ID order, to the webhooks of TenantID.
+type OutboxRecord struct {
+	ID          int64
+	TenantID    string
+	AggregateID uuid.UUID
+	Event       Event
+	Attempts    int
+}
@@ -0,0 +1,36 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	KindRenewalDue  = "renewal_due"
+	KindTrialEnding = "trial_ending"
+)
+
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// Notification is a message sent to a user through a notifier channel. The
+// DedupKey makes sure the same reminder is sent once.
+type Notification struct {
+	ID             int64      `json:"id"`
+	TenantID       string     `json:"-"`
+	UserID         uuid.UUID  `json:"user_id"`
+	SubscriptionID *uuid.UUID `json:"subscription_id,omitempty"`
+	Kind           string     `json:"kind"`
+	Channel        string     `json:"channel"`
+	Subject        string     `json:"subject"`
+	Body           string     `json:"body"`
+	DedupKey       string     `json:"-"`
+	Status         string     `json:"-"`
+	Error          *string    `json:"-"`
+	CreatedAt      time.Time  `json:"created_at"`
+	SentAt         *time.Time `json:"-"`
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	PeriodMonth = "month"
+	PeriodYear  = "year"
+)
+
+// Periods lists the supported billing periods of a default price.
+var Periods = []string{PeriodMonth, PeriodYear}
+
+// Service is a catalog entry subscriptions link to. Name is the canonical
+// display name; Name and Aliases are unique across the catalog after
+// Normalize.
+type Service struct {
+	ID           uuid.UUID
+	TenantID     string
+	Name         string
+	Aliases      []string
+	Category     *string
+	DefaultPrice *int
+	// BillingPeriod is the period DefaultPrice is charged for.
+	BillingPeriod string
+	LogoURL       *string
+	// Logo is set once a logo has been uploaded.
+	Logo      *Logo
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Logo describes the uploaded logo of a service. The image and its PNG
+// thumbnail live in the logo storage under keys derived from ETag, so a new
+// upload never changes the bytes behind an old ETag.
+type Logo struct {
+	ContentType string
+	ETag        string
+	UpdatedAt   time.Time
+}
+
+// LogoFile is a stored logo image ready to be served.
+type LogoFile struct {
+	ContentType string
+	ETag        string
+	ModTime     time.Time
+	Body        io.ReadSeekCloser
+}
+
+// Normalize folds a service name for lookups: case-insensitive and with
+// runs of whitespace collapsed.
+func Normalize(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// Keys returns the distinct normalized forms of the name and the aliases.
+func (s Service) Keys() []string {
+	seen := make(map[string]bool, len(s.Aliases)+1)
+	keys := make([]string, 0, len(s.Aliases)+1)
+	for _, n := range append([]string{s.Name}, s.Aliases...) {
+		k := Normalize(n)
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// MonthlyPrice returns the default price per month, rounding yearly prices
+// to the nearest unit.
+func (s Service) MonthlyPrice() (int, bool) {
+	if s.DefaultPrice == nil {
+		return 0, false
+	}
+	if s.BillingPeriod == PeriodYear {
+		return (*s.DefaultPrice + 6) / 12, true
+	}
+	return *s.DefaultPrice, true
+}
+
+type Filter struct {
+	Category *string
+	Limit    int
+	Offset   int
+}
+
+type ServiceReq struct {
+	Name          string   `json:"name"`
+	Aliases       []string `json:"aliases"`
+	Category      *string  `json:"category,omitempty"`
+	DefaultPrice  *int     `json:"default_price,omitempty"`
+	BillingPeriod string   `json:"billing_period,omitempty"`
+	LogoURL       *string  `json:"logo_url,omitempty"`
+}
+
+type ServiceResp struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Aliases       []string  `json:"aliases"`
+	Category      *string   `json:"category,omitempty"`
+	DefaultPrice  *int      `json:"default_price,omitempty"`
+	BillingPeriod string    `json:"billing_period"`
+	LogoURL       *string   `json:"logo_url,omitempty"`
+	Logo          *LogoResp `json:"logo,omitempty"`
+	CreatedAt     string    `json:"created_at"`
+	UpdatedAt     string    `json:"updated_at"`
+}
+
+type LogoResp struct {
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"`
+	ETag         string `json:"etag"`
+	UpdatedAt    string `json:"updated_at"`
+}
@@ -1,51 +1,350 @@
 package subscription
 
 import (
+	"context"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	StatusActive    = "active"
+	StatusTrial     = "trial"
+	StatusPaused    = "paused"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+)
+
+// Policies for a new subscription overlapping an existing one of the same
+// user and service.
+const (
+	DuplicateAllow  = "allow"
+	DuplicateWarn   = "warn"
+	DuplicateReject = "reject"
+)
+
+// DuplicatePolicies lists the accepted on_duplicate values.
+var DuplicatePolicies = []string{DuplicateAllow, DuplicateWarn, DuplicateReject}
+
+// Periods a summary can be compared with.
+const (
+	ComparePreviousPeriod = "previous_period"
+	ComparePreviousYear   = "previous_year"
+)
+
+// CompareModes lists the accepted compare values.
+var CompareModes = []string{ComparePreviousPeriod, ComparePreviousYear}
+
+// Statuses lists every subscription status.
+var Statuses = []string{StatusActive, StatusTrial, StatusPaused, StatusCancelled, StatusExpired}
+
+// Subscription is also the snapshot format of the audit log, hence the json tags.
 type Subscription struct {
-	ID          uuid.UUID
-	ServiceName string
-	Price       int
-	UserID      uuid.UUID
-	StartDate   time.Time
-	EndDate     *time.Time
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID          uuid.UUID  `json:"id"`
+	TenantID    string     `json:"tenant_id"`
+	ServiceName string     `json:"service_name"`
+	ServiceID   *uuid.UUID `json:"service_id"`
+	Price       int        `json:"price"`
+	UserID      uuid.UUID  `json:"user_id"`
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     *time.Time `json:"end_date"`
+	TrialUntil  *time.Time `json:"trial_until"`
+	TrialPrice  *int       `json:"trial_price"`
+	Status      string     `json:"status"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// PriceFromCatalog asks Create and UpdateSub to take Price from the
+	// default price of the linked catalog service. It is not stored.
+	PriceFromCatalog bool `json:"-"`
+	// Tags are the names of the user's tags put on the subscription.
+	Tags []string `json:"tags"`
+}
+
+// Guard checks a subscription write inside its transaction, once the writes
+// of the user are locked and before the row is written. before is the
+// locked row, nil for a new subscription. An error rolls the write back.
+type Guard func(ctx context.Context, before *Subscription) error
+
+// Pause is a period in which the subscription is not billed. From and To
+// are the first days of the first and the last paused month; To is nil while
+// the pause is open.
+type Pause struct {
+	ID             int64
+	SubscriptionID uuid.UUID
+	From           time.Time
+	To             *time.Time
+	CreatedAt      time.Time
+}
+
+// Covers reports whether month is one of the paused months.
+func (p Pause) Covers(month time.Time) bool {
+	return !month.Before(p.From) && (p.To == nil || !month.After(*p.To))
+}
+
+// PauseReq starts a pause, From defaults to the current month.
+type PauseReq struct {
+	From *string `json:"from,omitempty"`
+}
+
+// ResumeReq ends the open pause, From is the first billed month again and
+// defaults to the current month.
+type ResumeReq struct {
+	From *string `json:"from,omitempty"`
+}
+
+type PauseResp struct {
+	ID        int64   `json:"id"`
+	From      string  `json:"from"`
+	To        *string `json:"to,omitempty"`
+	CreatedAt string  `json:"created_at"`
+}
+
+// InTrial reports whether month is a trial month. TrialUntil is the first
+// day of the last trial month.
+func (s Subscription) InTrial(month time.Time) bool {
+	return s.TrialUntil != nil && !month.After(*s.TrialUntil)
+}
+
+// PriceFor returns the amount charged for month: the trial price, free when
+// unset, during the trial and Price afterwards.
+func (s Subscription) PriceFor(month time.Time) int {
+	if !s.InTrial(month) {
+		return s.Price
+	}
+	if s.TrialPrice == nil {
+		return 0
+	}
+	return *s.TrialPrice
+}
+
+// ChargeFor returns the amount charged for month like PriceFor when s is
+// billed monthly. Billed yearly, the months after the trial are charged
+// twelve times Price once a year, from the first month after the trial or
+// StartDate without one, and nothing in between.
+func (s Subscription) ChargeFor(month time.Time, yearly bool) int {
+	if !yearly || s.InTrial(month) {
+		return s.PriceFor(month)
+	}
+	anchor := s.StartDate
+	if s.TrialUntil != nil {
+		anchor = s.TrialUntil.AddDate(0, 1, 0)
+	}
+	elapsed := (month.Year()-anchor.Year())*12 + int(month.Month()) - int(anchor.Month())
+	if elapsed%12 != 0 {
+		return 0
+	}
+	return 12 * s.Price
+}
+
+// NextCharge returns the first charge of s on or after now. A subscription is
+// charged on the first day of every month from StartDate to EndDate inclusive,
+// the same months Summary counts.
+func (s Subscription) NextCharge(now time.Time) (time.Time, bool) {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if now.After(next) {
+		next = next.AddDate(0, 1, 0)
+	}
+	if s.StartDate.After(next) {
+		next = s.StartDate
+	}
+	if s.EndDate != nil && next.After(*s.EndDate) {
+		return time.Time{}, false
+	}
+	return next, true
 }
 
 type ListFilter struct {
 	UserID      *uuid.UUID
 	ServiceName *string
-	Limit       int
-	Offset      int
+	ServiceID   *uuid.UUID
+	Status      *string
+	// TrialEndsAfter and TrialEndsBefore select subscriptions whose first
+	// charge after the trial falls in (TrialEndsAfter, TrialEndsBefore].
+	TrialEndsAfter  *time.Time
+	TrialEndsBefore *time.Time
+	// Tags selects subscriptions carrying any of the tags, or all of them
+	// with TagsAll. Names are matched ignoring case.
+	Tags           []string
+	TagsAll        bool
+	IncludeDeleted bool
+	Limit          int
+	Offset         int
 }
 
+// SummaryFilter counts soft-deleted subscriptions too unless ExcludeDeleted
+// is set: deleting a subscription must not rewrite past spend.
 type SummaryFilter struct {
+	From           time.Time
+	To             time.Time
+	UserID         *uuid.UUID
+	ServiceName    *string
+	ServiceID      *uuid.UUID
+	Tags           []string
+	TagsAll        bool
+	ExcludeDeleted bool
+}
+
+// Compared returns f moved to the period it is compared with: as many months
+// right before From for ComparePreviousPeriod, the same months a year
+// earlier for ComparePreviousYear. Other modes leave f unchanged.
+func (f SummaryFilter) Compared(mode string) SummaryFilter {
+	switch mode {
+	case ComparePreviousPeriod:
+		months := (f.To.Year()-f.From.Year())*12 + int(f.To.Month()) - int(f.From.Month()) + 1
+		f.From, f.To = f.From.AddDate(0, -months, 0), f.From.AddDate(0, -1, 0)
+	case ComparePreviousYear:
+		f.From, f.To = f.From.AddDate(-1, 0, 0), f.To.AddDate(-1, 0, 0)
+	}
+	return f
+}
+
+// Delta is the change of a total from the compared period. Percent is
+// rounded to two decimals and nil when the previous total is zero.
+type Delta struct {
+	Previous int64
+	Change   int64
+	Percent  *float64
+}
+
+func NewDelta(current, previous int64) Delta {
+	d := Delta{Previous: previous, Change: current - previous}
+	if previous != 0 {
+		pct := math.Round(float64(d.Change)*10000/float64(previous)) / 100
+		d.Percent = &pct
+	}
+	return d
+}
+
+// MonthTotal is the spend of one month; Cumulative adds up the months of a
+// forecast up to and including this one.
+type MonthTotal struct {
+	Month      time.Time
+	Total      int64
+	Cumulative int64
+}
+
+// TagTotal is the spend on subscriptions carrying a tag; Tag is nil for
+// untagged subscriptions.
+type TagTotal struct {
+	Tag   *string
+	Total int64
+}
+
+// TagsReq replaces the tags of a subscription.
+type TagsReq struct {
+	Tags []string `json:"tags"`
+}
+
+// UpcomingFilter selects subscriptions with a charge between From and To.
+type UpcomingFilter struct {
 	From        time.Time
 	To          time.Time
 	UserID      *uuid.UUID
 	ServiceName *string
+	ServiceID   *uuid.UUID
+}
+
+// Renewal is the next charge of a subscription.
+type Renewal struct {
+	Subscription Subscription
+	ChargeDate   time.Time
+	Amount       int
 }
 
 type SubscriptionCreateReq struct {
+	// ServiceName is resolved against the service catalog, ServiceID links
+	// a catalog entry directly; one of them is required.
 	ServiceName string  `json:"service_name"`
-	Price       int     `json:"price"`
-	UserID      string  `json:"user_id"`
-	StartDate   string  `json:"start_date"`
-	EndDate     *string `json:"end_date,omitempty"`
+	ServiceID   *string `json:"service_id,omitempty"`
+	// Price defaults to the catalog price of the service when omitted.
+	Price     *int    `json:"price,omitempty"`
+	UserID    string  `json:"user_id"`
+	StartDate string  `json:"start_date"`
+	EndDate   *string `json:"end_date,omitempty"`
+	// TrialUntil is the last trial month, charged TrialPrice (free when
+	// omitted) instead of Price.
+	TrialUntil *string `json:"trial_until,omitempty"`
+	TrialPrice *int    `json:"trial_price,omitempty"`
+	// Status is optional: new subscriptions start active and updates keep
+	// the current status unless the dates imply a change.
+	Status *string `json:"status,omitempty"`
 }
 
 type SubscriptionResp struct {
-	ID          string  `json:"id"`
-	ServiceName string  `json:"service_name"`
-	Price       int     `json:"price"`
-	UserID      string  `json:"user_id"`
-	StartDate   string  `json:"start_date"`
-	EndDate     *string `json:"end_date,omitempty"`
-	CreatedAt   string  `json:"created_at"`
-	UpdatedAt   string  `json:"updated_at"`
+	ID          string   `json:"id"`
+	ServiceName string   `json:"service_name"`
+	ServiceID   *string  `json:"service_id,omitempty"`
+	Price       int      `json:"price"`
+	UserID      string   `json:"user_id"`
+	StartDate   string   `json:"start_date"`
+	EndDate     *string  `json:"end_date,omitempty"`
+	TrialUntil  *string  `json:"trial_until,omitempty"`
+	TrialPrice  *int     `json:"trial_price,omitempty"`
+	Status      string   `json:"status"`
+	Tags        []string `json:"tags"`
+	// DuplicateOf lists the overlapping subscriptions a create with
+	// on_duplicate=warn found.
+	DuplicateOf []string `json:"duplicate_of,omitempty"`
+	DeletedAt   *string  `json:"deleted_at,omitempty"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+}
+
+// Overlap is a pair of subscriptions of one user to the same service that
+// are billed for the same months, From to To inclusive; To is nil when both
+// are open-ended.
+type Overlap struct {
+	First  Subscription
+	Second Subscription
+	From   time.Time
+	To     *time.Time
+}
+
+type OverlapResp struct {
+	ServiceName     string   `json:"service_name"`
+	SubscriptionIDs []string `json:"subscription_ids"`
+	From            string   `json:"from"`
+	To              *string  `json:"to,omitempty"`
+}
+
+type RenewalResp struct {
+	SubscriptionID string `json:"subscription_id"`
+	ServiceName    string `json:"service_name"`
+	UserID         string `json:"user_id"`
+	Price          int    `json:"price"`
+	ChargeDate     string `json:"charge_date"`
+	DaysLeft       int    `json:"days_left"`
+}
+
+type MonthTotalResp struct {
+	Month      string `json:"month"`
+	Total      int64  `json:"total"`
+	Cumulative int64  `json:"cumulative"`
+}
+
+type TagTotalResp struct {
+	Tag   *string `json:"tag"`
+	Total int64   `json:"total"`
+}
+
+// CompareResp is the total of the compared period and the change since.
+type CompareResp struct {
+	Mode     string   `json:"mode"`
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Total    int64    `json:"total"`
+	Delta    int64    `json:"delta"`
+	DeltaPct *float64 `json:"delta_pct"`
+}
+
+// TagCompareResp is a tag group of a compared summary.
+type TagCompareResp struct {
+	Tag           *string  `json:"tag"`
+	Total         int64    `json:"total"`
+	PreviousTotal int64    `json:"previous_total"`
+	Delta         int64    `json:"delta"`
+	DeltaPct      *float64 `json:"delta_pct"`
 }
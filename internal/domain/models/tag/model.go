@@ -0,0 +1,59 @@
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MatchAny selects subscriptions carrying at least one of the tags.
+	MatchAny = "any"
+	// MatchAll selects subscriptions carrying every tag.
+	MatchAll = "all"
+)
+
+// MaxNameLength bounds tag names in runes.
+const MaxNameLength = 64
+
+// Tag is a label a user puts on their subscriptions. Names are unique per
+// user, ignoring case.
+type Tag struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ParseName trims and collapses the whitespace of a tag name and checks it.
+// Commas are rejected because list filters accept comma separated tags.
+func ParseName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	switch {
+	case name == "":
+		return "", errors.New("tag name is required")
+	case utf8.RuneCountInString(name) > MaxNameLength:
+		return "", fmt.Errorf("tag name must be at most %d characters", MaxNameLength)
+	case strings.Contains(name, ","):
+		return "", fmt.Errorf("tag name %q must not contain commas", name)
+	}
+	return name, nil
+}
+
+type TagReq struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+type TagResp struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
@@ -0,0 +1,62 @@
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// Default owns the rows of deployments that do not use tenants and of
+// requests that name none.
+const Default = "default"
+
+// Header selects the tenant of callers whose credentials are not bound to
+// one.
+const Header = "X-Tenant-ID"
+
+var idRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Valid reports whether id can name a tenant: lowercase letters, digits,
+// dashes and underscores, up to 63 characters.
+func Valid(id string) bool {
+	return idRe.MatchString(id)
+}
+
+type tenantKey struct{}
+
+// allTenants marks the context of background jobs that work through the
+// rows of every tenant.
+const allTenants = ""
+
+// WithTenant scopes the repository calls made with ctx to tenant id.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// WithAllTenants lifts the tenant scope for background jobs. Rows they load
+// carry their tenant, which the job scopes the follow-up calls to.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantKey{}, allTenants)
+}
+
+// Scope is the tenant filter of the repository queries: the tenant of ctx,
+// Default when none was set, nil for a job working through every tenant.
+func Scope(ctx context.Context) *string {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	if !ok {
+		id = Default
+	}
+	if id == allTenants {
+		return nil
+	}
+	return &id
+}
+
+// From returns the tenant new rows are created in. Jobs working through
+// every tenant scope their writes to the tenant of the row first; until
+// then they write to Default.
+func From(ctx context.Context) string {
+	if id := Scope(ctx); id != nil {
+		return *id
+	}
+	return Default
+}
@@ -0,0 +1,115 @@
+package user
+
+import (
+	"encoding/json"
+	"time"
+
+	modelsub "test_task/internal/domain/models/subscription"
+
+	"github.com/google/uuid"
+)
+
+// Export formats.
+const (
+	ExportJSON = "json"
+	ExportZIP  = "zip"
+)
+
+var ExportFormats = []string{ExportJSON, ExportZIP}
+
+// Export is everything held about a user. Profile is nil for users that were
+// never registered; the handler renders it as a UserResp. Subscriptions
+// include the deleted ones.
+type Export struct {
+	UserID        uuid.UUID               `json:"user_id"`
+	ExportedAt    time.Time               `json:"exported_at"`
+	Profile       *User                   `json:"-"`
+	Subscriptions []modelsub.Subscription `json:"subscriptions"`
+	Pauses        []ExportPause           `json:"pauses"`
+	PriceHistory  []PriceChange           `json:"price_history"`
+	Tags          []ExportTag             `json:"tags"`
+	Budgets       []ExportBudget          `json:"budgets"`
+	Audit         []ExportAuditEntry      `json:"audit"`
+	Notifications []ExportNotification    `json:"notifications"`
+}
+
+// Empty reports whether nothing is held about the user.
+func (e Export) Empty() bool {
+	return e.Profile == nil && len(e.Subscriptions) == 0 && len(e.Tags) == 0 &&
+		len(e.Budgets) == 0 && len(e.Audit) == 0 && len(e.Notifications) == 0
+}
+
+type ExportPause struct {
+	SubscriptionID uuid.UUID  `json:"subscription_id"`
+	From           time.Time  `json:"from"`
+	To             *time.Time `json:"to"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// PriceChange is a price a subscription was created or updated with, taken
+// from the audit log.
+type PriceChange struct {
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	Price          int       `json:"price"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+type ExportTag struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportBudget struct {
+	ID          uuid.UUID  `json:"id"`
+	Amount      int        `json:"amount"`
+	ServiceName *string    `json:"service_name"`
+	TagID       *uuid.UUID `json:"tag_id"`
+	Enforce     bool       `json:"enforce"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type ExportAuditEntry struct {
+	ID             int64           `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	Operation      string          `json:"operation"`
+	Actor          string          `json:"actor"`
+	Before         json.RawMessage `json:"before,omitempty"`
+	After          json.RawMessage `json:"after,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type ExportNotification struct {
+	ID             int64      `json:"id"`
+	SubscriptionID *uuid.UUID `json:"subscription_id"`
+	Kind           string     `json:"kind"`
+	Channel        string     `json:"channel"`
+	Subject        string     `json:"subject"`
+	Body           string     `json:"body"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	SentAt         *time.Time `json:"sent_at"`
+}
+
+// Erasure counts the rows an erasure removed or pseudonymized. It is also
+// the after snapshot of the tombstone entry of the audit log.
+type Erasure struct {
+	UserID        uuid.UUID `json:"user_id"`
+	Profile       int64     `json:"profile"`
+	Subscriptions int64     `json:"subscriptions"`
+	Tags          int64     `json:"tags"`
+	Budgets       int64     `json:"budgets"`
+	Notifications int64     `json:"notifications"`
+	Webhooks      int64     `json:"webhooks"`
+	APIKeys       int64     `json:"api_keys"`
+	Events        int64     `json:"events"`
+	// Audit entries are kept, with the user replaced by a pseudonym and
+	// the snapshots dropped.
+	Audit int64 `json:"audit_pseudonymized"`
+}
+
+// Empty reports whether the erasure found nothing of the user.
+func (e Erasure) Empty() bool {
+	return e.Profile+e.Subscriptions+e.Tags+e.Budgets+e.Notifications+
+		e.Webhooks+e.APIKeys+e.Events+e.Audit == 0
+}
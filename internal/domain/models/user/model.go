@@ -0,0 +1,121 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// MaxDisplayNameLength bounds display names in runes.
+const MaxDisplayNameLength = 200
+
+const (
+	DefaultTimezone = "UTC"
+	DefaultCurrency = "RUB"
+)
+
+// Delete modes decide what happens to the subscriptions of a deleted user.
+const (
+	// DeleteRefuse keeps the user while they have subscriptions.
+	DeleteRefuse = "refuse"
+	// DeleteCascade deletes the subscriptions together with the user.
+	DeleteCascade = "cascade"
+	// DeleteAnonymize keeps the user and the subscriptions but erases the
+	// profile, so the spend still counts in the totals.
+	DeleteAnonymize = "anonymize"
+)
+
+var DeleteModes = []string{DeleteRefuse, DeleteCascade, DeleteAnonymize}
+
+// AnonymizedName replaces the display name of anonymized users.
+const AnonymizedName = "Deleted user"
+
+var currencyRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// User is the profile of a user subscriptions belong to.
+type User struct {
+	ID              uuid.UUID
+	TenantID        string
+	DisplayName     string
+	Email           *string
+	Timezone        string
+	DefaultCurrency string
+	AnonymizedAt    *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// Normalize trims the profile, fills in the defaults and checks it.
+func (u *User) Normalize() error {
+	u.DisplayName = strings.Join(strings.Fields(u.DisplayName), " ")
+	switch {
+	case u.DisplayName == "":
+		return errors.New("display_name is required")
+	case utf8.RuneCountInString(u.DisplayName) > MaxDisplayNameLength:
+		return fmt.Errorf("display_name must be at most %d characters", MaxDisplayNameLength)
+	}
+
+	if u.Email != nil {
+		email := strings.TrimSpace(*u.Email)
+		if email == "" {
+			u.Email = nil
+		} else {
+			addr, err := mail.ParseAddress(email)
+			if err != nil || addr.Address != email {
+				return fmt.Errorf("email %q is not a valid address", email)
+			}
+			u.Email = &email
+		}
+	}
+
+	u.Timezone = strings.TrimSpace(u.Timezone)
+	if u.Timezone == "" {
+		u.Timezone = DefaultTimezone
+	}
+	if _, err := time.LoadLocation(u.Timezone); err != nil {
+		return fmt.Errorf("timezone %q is not a known IANA time zone", u.Timezone)
+	}
+
+	u.DefaultCurrency = strings.ToUpper(strings.TrimSpace(u.DefaultCurrency))
+	if u.DefaultCurrency == "" {
+		u.DefaultCurrency = DefaultCurrency
+	}
+	if !currencyRe.MatchString(u.DefaultCurrency) {
+		return fmt.Errorf("default_currency %q must be an ISO 4217 code", u.DefaultCurrency)
+	}
+	return nil
+}
+
+type Filter struct {
+	// Email matches case-insensitively.
+	Email  *string
+	Limit  int
+	Offset int
+}
+
+type UserReq struct {
+	// ID registers an existing user_id of subscriptions; a new one is
+	// generated when empty. It is ignored on update.
+	ID              string  `json:"id,omitempty"`
+	DisplayName     string  `json:"display_name"`
+	Email           *string `json:"email,omitempty"`
+	Timezone        string  `json:"timezone,omitempty"`
+	DefaultCurrency string  `json:"default_currency,omitempty"`
+}
+
+type UserResp struct {
+	ID              string  `json:"id"`
+	DisplayName     string  `json:"display_name"`
+	Email           *string `json:"email,omitempty"`
+	Timezone        string  `json:"timezone"`
+	DefaultCurrency string  `json:"default_currency"`
+	AnonymizedAt    *string `json:"anonymized_at,omitempty"`
+	CreatedAt       string  `json:"created_at"`
+	UpdatedAt       string  `json:"updated_at"`
+}
@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Headers sent with every delivery.
+const (
+	SignatureHeader = "Webhook-Signature"
+	DeliveryHeader  = "Webhook-Delivery-Id"
+	EventTypeHeader = "Webhook-Event-Type"
+)
+
+type Webhook struct {
+	ID       uuid.UUID
+	TenantID string
+	URL      string
+	Secret   string
+	// EventTypes filters deliveries; empty means every event type.
+	EventTypes []string
+	// UserID restricts deliveries to events about this user's subscriptions.
+	UserID       *uuid.UUID
+	FailureCount int
+	DisabledAt   *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type Delivery struct {
+	ID             int64
+	TenantID       string
+	WebhookID      uuid.UUID
+	EventID        string
+	EventType      string
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode *int
+	LastError      *string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+}
+
+type Attempt struct {
+	ID         int64
+	DeliveryID int64
+	StatusCode *int
+	Error      *string
+	Duration   time.Duration
+	CreatedAt  time.Time
+}
+
+// PendingDelivery is a claimed delivery together with its endpoint.
+type PendingDelivery struct {
+	Delivery
+	URL    string
+	Secret string
+}
+
+// Sign returns the SignatureHeader value for body sent at ts:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">". Receivers should
+// recompute it and reject stale timestamps to prevent replays.
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type WebhookCreateReq struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	UserID     *string  `json:"user_id,omitempty"`
+}
+
+type WebhookUpdateReq struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Enabled    bool     `json:"enabled"`
+}
+
+type WebhookResp struct {
+	ID           string   `json:"id"`
+	URL          string   `json:"url"`
+	EventTypes   []string `json:"event_types"`
+	UserID       *string  `json:"user_id,omitempty"`
+	Enabled      bool     `json:"enabled"`
+	FailureCount int      `json:"failure_count"`
+	DisabledAt   *string  `json:"disabled_at,omitempty"`
+	CreatedAt    string   `json:"created_at"`
+	UpdatedAt    string   `json:"updated_at"`
+}
+
+// WebhookCreatedResp is returned once on creation; Secret is never shown again.
+type WebhookCreatedResp struct {
+	WebhookResp
+	Secret string `json:"secret"`
+}
+
+type DeliveryResp struct {
+	ID             int64           `json:"id"`
+	WebhookID      string          `json:"webhook_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *string         `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	DeliveredAt    *string         `json:"delivered_at,omitempty"`
+	CreatedAt      string          `json:"created_at"`
+	Payload        json.RawMessage `json:"payload,omitempty"`
+	AttemptLog     []AttemptResp   `json:"attempt_log,omitempty"`
+}
+
+type AttemptResp struct {
+	StatusCode *int    `json:"status_code,omitempty"`
+	Error      *string `json:"error,omitempty"`
+	DurationMS int64   `json:"duration_ms"`
+	CreatedAt  string  `json:"created_at"`
+}
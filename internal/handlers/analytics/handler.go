@@ -0,0 +1,153 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	modelanalytics "test_task/internal/domain/models/analytics"
+	modeldate "test_task/internal/domain/models/month_year"
+	JSONRes "test_task/pkg/JSON_response"
+	myerrors "test_task/pkg/global_errors"
+)
+
+type UsecaseI interface {
+	MRR(ctx context.Context, from, to time.Time) ([]modelanalytics.MRRMonth, error)
+	Churn(ctx context.Context, from, to time.Time) ([]modelanalytics.ServiceChurn, error)
+	Cohorts(ctx context.Context, from, to time.Time) ([]modelanalytics.Cohort, error)
+}
+
+type Handler struct {
+	log     *slog.Logger
+	usecase UsecaseI
+}
+
+func New(log *slog.Logger, usecase UsecaseI) *Handler {
+	return &Handler{log: log, usecase: usecase}
+}
+
+// parsePeriod reads the optional from and to query parameters as MM-YYYY;
+// the usecase defaults missing ones to the last twelve months.
+func parsePeriod(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	q := r.URL.Query()
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		v := strings.TrimSpace(q.Get(p.name))
+		if v == "" {
+			continue
+		}
+		parsed, err := modeldate.ParseMonthYear(v)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, p.name+": "+err.Error())
+			return time.Time{}, time.Time{}, false
+		}
+		*p.dst = parsed
+	}
+	return from, to, true
+}
+
+// writeErr answers the errors shared by the endpoints.
+func (h *Handler) writeErr(w http.ResponseWriter, what string, err error) {
+	if errors.Is(err, myerrors.ErrorValidation) {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.log.Error(what+" failed", slog.Any("err", err))
+	JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to calculate "+what)
+}
+
+// MRR reports the monthly recurring revenue of the platform and its
+// movement for every month of the period.
+func (h *Handler) MRR(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := parsePeriod(w, r)
+	if !ok {
+		return
+	}
+	months, err := h.usecase.MRR(r.Context(), from, to)
+	if err != nil {
+		h.writeErr(w, "mrr", err)
+		return
+	}
+
+	items := make([]modelanalytics.MRRMonthResp, 0, len(months))
+	for _, m := range months {
+		items = append(items, modelanalytics.MRRMonthResp{
+			Month:       modeldate.FormatMonthYear(m.Month),
+			MRR:         m.MRR,
+			New:         m.New,
+			Expansion:   m.Expansion,
+			Contraction: m.Contraction,
+			Churned:     m.Churned,
+			NetChange:   m.NetChange(),
+		})
+	}
+	resp := map[string]any{
+		"currency": "RUB",
+		"months":   items,
+	}
+	if len(items) > 0 {
+		resp["from"] = items[0].Month
+		resp["to"] = items[len(items)-1].Month
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, resp)
+}
+
+// Churn reports the churn rate of every service over the period.
+func (h *Handler) Churn(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := parsePeriod(w, r)
+	if !ok {
+		return
+	}
+	services, err := h.usecase.Churn(r.Context(), from, to)
+	if err != nil {
+		h.writeErr(w, "churn", err)
+		return
+	}
+
+	items := make([]modelanalytics.ServiceChurnResp, 0, len(services))
+	for _, c := range services {
+		items = append(items, modelanalytics.ServiceChurnResp{
+			ServiceName: c.ServiceName,
+			Subscribers: c.Subscribers,
+			Churned:     c.Churned,
+			ChurnRate:   c.Rate(),
+		})
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, map[string]any{
+		"total": len(items),
+		"items": items,
+	})
+}
+
+// Cohorts reports the retention of the subscriptions started in every month
+// of the period.
+func (h *Handler) Cohorts(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := parsePeriod(w, r)
+	if !ok {
+		return
+	}
+	cohorts, err := h.usecase.Cohorts(r.Context(), from, to)
+	if err != nil {
+		h.writeErr(w, "cohorts", err)
+		return
+	}
+
+	items := make([]modelanalytics.CohortResp, 0, len(cohorts))
+	for _, c := range cohorts {
+		items = append(items, modelanalytics.CohortResp{
+			Month:     modeldate.FormatMonthYear(c.Month),
+			Size:      c.Size(),
+			Retained:  c.Retained,
+			Retention: c.Retention(),
+		})
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, map[string]any{
+		"total": len(items),
+		"items": items,
+	})
+}
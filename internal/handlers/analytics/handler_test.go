@@ -0,0 +1,147 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	modelanalytics "test_task/internal/domain/models/analytics"
+	modelauth "test_task/internal/domain/models/auth"
+	"test_task/internal/handlers/routes"
+	authmid "test_task/internal/middleware/auth_middleware"
+	logmid "test_task/internal/middleware/loger_middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type mockUsecase struct {
+	mrrFn     func(ctx context.Context, from, to time.Time) ([]modelanalytics.MRRMonth, error)
+	cohortsFn func(ctx context.Context, from, to time.Time) ([]modelanalytics.Cohort, error)
+}
+
+func (m *mockUsecase) MRR(ctx context.Context, from, to time.Time) ([]modelanalytics.MRRMonth, error) {
+	return m.mrrFn(ctx, from, to)
+}
+func (m *mockUsecase) Churn(context.Context, time.Time, time.Time) ([]modelanalytics.ServiceChurn, error) {
+	return nil, nil
+}
+func (m *mockUsecase) Cohorts(ctx context.Context, from, to time.Time) ([]modelanalytics.Cohort, error) {
+	return m.cohortsFn(ctx, from, to)
+}
+
+type staticAuthenticator map[string]modelauth.Principal
+
+func (a staticAuthenticator) Authenticate(_ context.Context, credentials string) (modelauth.Principal, error) {
+	p, ok := a[credentials]
+	if !ok {
+		return modelauth.Principal{}, authmid.ErrInvalidCredentials
+	}
+	return p, nil
+}
+
+func newTestRouter(u UsecaseI) http.Handler {
+	log := logmid.NewLogger("error")
+	r := chi.NewRouter()
+	r.Use(authmid.Authenticate(log, map[string]authmid.Authenticator{
+		"ApiKey": staticAuthenticator{
+			"admin":  {Subject: "apikey:admin", Scopes: []string{modelauth.ScopeAdmin}},
+			"reader": {Subject: "apikey:reader", Scopes: []string{modelauth.ScopeSubscriptionsRead}},
+		},
+	}))
+	Routes(New(log, u))(r, routes.Middlewares{})
+	return r
+}
+
+func TestMRR(t *testing.T) {
+	var gotFrom, gotTo time.Time
+	u := &mockUsecase{
+		mrrFn: func(_ context.Context, from, to time.Time) ([]modelanalytics.MRRMonth, error) {
+			gotFrom, gotTo = from, to
+			return []modelanalytics.MRRMonth{
+				{Month: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), MRR: 1250, Expansion: 400, Contraction: 250},
+			}, nil
+		},
+	}
+	r := newTestRouter(u)
+
+	tests := []struct {
+		name  string
+		key   string
+		query string
+		want  int
+	}{
+		{"admin", "admin", "?from=03-2025&to=03-2025", http.StatusOK},
+		{"read scope is not enough", "reader", "", http.StatusForbidden},
+		{"bad month", "admin", "?from=2025-03", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/analytics/mrr"+tt.query, nil)
+			req.Header.Set("Authorization", "ApiKey "+tt.key)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("want %d, got %d, body=%s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	want := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	if !gotFrom.Equal(want) || !gotTo.Equal(want) {
+		t.Fatalf("unexpected period %v - %v", gotFrom, gotTo)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/mrr", nil)
+	req.Header.Set("Authorization", "ApiKey admin")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp struct {
+		Months []modelanalytics.MRRMonthResp `json:"months"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Months) != 1 || resp.Months[0].Month != "03-2025" || resp.Months[0].NetChange != 150 {
+		t.Fatalf("unexpected months: %+v", resp.Months)
+	}
+	if !gotFrom.IsZero() || !gotTo.IsZero() {
+		t.Fatalf("missing period should be left to the usecase, got %v - %v", gotFrom, gotTo)
+	}
+}
+
+func TestCohorts(t *testing.T) {
+	u := &mockUsecase{
+		cohortsFn: func(context.Context, time.Time, time.Time) ([]modelanalytics.Cohort, error) {
+			return []modelanalytics.Cohort{
+				{Month: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), Retained: []int64{4, 3, 1}},
+			}, nil
+		},
+	}
+	r := newTestRouter(u)
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/cohorts?from=03-2025&to=05-2025", nil)
+	req.Header.Set("Authorization", "ApiKey admin")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Items []modelanalytics.CohortResp `json:"items"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Items) != 1 {
+		t.Fatalf("unexpected cohorts: %+v", resp.Items)
+	}
+	c := resp.Items[0]
+	if c.Month != "03-2025" || c.Size != 4 || len(c.Retention) != 3 || c.Retention[1] != 0.75 || c.Retention[2] != 0.25 {
+		t.Fatalf("unexpected cohort: %+v", c)
+	}
+}
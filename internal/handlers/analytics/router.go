@@ -0,0 +1,23 @@
+package analytics
+
+import (
+	"test_task/internal/handlers/routes"
+	ratelimit "test_task/internal/middleware/rate_limit_middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Routes returns the platform-wide analytics endpoints for the /api/v1
+// router. They cover every user, so they need the admin scope, and share
+// the rate limit of the summary queries.
+func Routes(h *Handler) routes.Mount {
+	return func(r chi.Router, mw routes.Middlewares) {
+		admin := chi.Chain(mw.Admin(), mw.Limit(ratelimit.GroupSummary))
+
+		r.Route("/analytics", func(r chi.Router) {
+			r.With(admin...).Get("/mrr", h.MRR)
+			r.With(admin...).Get("/churn", h.Churn)
+			r.With(admin...).Get("/cohorts", h.Cohorts)
+		})
+	}
+}
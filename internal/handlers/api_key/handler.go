@@ -0,0 +1,157 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	modelkey "test_task/internal/domain/models/api_key"
+	bodylimit "test_task/internal/middleware/body_limit_middleware"
+	JSONRes "test_task/pkg/JSON_response"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type UsecaseI interface {
+	Create(ctx context.Context, k modelkey.APIKey) (modelkey.APIKey, string, error)
+	List(ctx context.Context) ([]modelkey.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+}
+
+type Handler struct {
+	log     *slog.Logger
+	usecase UsecaseI
+}
+
+func New(log *slog.Logger, usecase UsecaseI) *Handler {
+	return &Handler{log: log, usecase: usecase}
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	v := t.UTC().Format(time.RFC3339)
+	return &v
+}
+
+func toResp(k modelkey.APIKey) modelkey.APIKeyResp {
+	var userID *string
+	if k.UserID != nil {
+		v := k.UserID.String()
+		userID = &v
+	}
+	scopes := k.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return modelkey.APIKeyResp{
+		ID:         k.ID.String(),
+		TenantID:   k.TenantID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     scopes,
+		UserID:     userID,
+		ExpiresAt:  formatTime(k.ExpiresAt),
+		LastUsedAt: formatTime(k.LastUsedAt),
+		RevokedAt:  formatTime(k.RevokedAt),
+		CreatedAt:  k.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req modelkey.APIKeyCreateReq
+	if !bodylimit.Decode(w, r, &req) {
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if len(req.Scopes) == 0 {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "scopes are required")
+		return
+	}
+
+	k := modelkey.APIKey{
+		Name:   req.Name,
+		Scopes: req.Scopes,
+	}
+	if req.UserID != nil {
+		userID, err := uuid.Parse(*req.UserID)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "user_id must be a valid UUID")
+			return
+		}
+		k.UserID = &userID
+	}
+	if req.ExpiresAt != nil {
+		t, err := time.Parse(time.RFC3339, *req.ExpiresAt)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "expires_at must be RFC3339")
+			return
+		}
+		k.ExpiresAt = &t
+	}
+
+	created, plaintext, err := h.usecase.Create(r.Context(), k)
+	if err != nil {
+		if errors.Is(err, myerrors.ErrorValidation) {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.log.Error("create api key failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to create api key")
+		return
+	}
+
+	JSONRes.WriteJSON(w, http.StatusCreated, modelkey.APIKeyCreatedResp{
+		APIKeyResp: toResp(created),
+		Key:        plaintext,
+	})
+}
+
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.usecase.List(r.Context())
+	if err != nil {
+		h.log.Error("list api keys failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to list api keys")
+		return
+	}
+
+	items := make([]modelkey.APIKeyResp, 0, len(keys))
+	for _, k := range keys {
+		items = append(items, toResp(k))
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, map[string]any{
+		"total": len(items),
+		"items": items,
+	})
+}
+
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+		return
+	}
+
+	if err := h.usecase.Revoke(r.Context(), id); err != nil {
+		if errors.Is(err, myerrors.ErrorNotFound) {
+			JSONRes.WriteJSON(w, http.StatusNotFound, "api key not found")
+			return
+		}
+		h.log.Error("revoke api key failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to revoke api key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,142 @@
+package apikey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	modelkey "test_task/internal/domain/models/api_key"
+	modelauth "test_task/internal/domain/models/auth"
+	"test_task/internal/handlers/routes"
+	authmid "test_task/internal/middleware/auth_middleware"
+	logmid "test_task/internal/middleware/loger_middleware"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type mockUsecase struct {
+	createFn func(ctx context.Context, k modelkey.APIKey) (modelkey.APIKey, string, error)
+	listFn   func(ctx context.Context) ([]modelkey.APIKey, error)
+	revokeFn func(ctx context.Context, id uuid.UUID) error
+}
+
+func (m *mockUsecase) Create(ctx context.Context, k modelkey.APIKey) (modelkey.APIKey, string, error) {
+	return m.createFn(ctx, k)
+}
+func (m *mockUsecase) List(ctx context.Context) ([]modelkey.APIKey, error) { return m.listFn(ctx) }
+func (m *mockUsecase) Revoke(ctx context.Context, id uuid.UUID) error      { return m.revokeFn(ctx, id) }
+
+type staticAuthenticator map[string]modelauth.Principal
+
+func (a staticAuthenticator) Authenticate(_ context.Context, credentials string) (modelauth.Principal, error) {
+	p, ok := a[credentials]
+	if !ok {
+		return modelauth.Principal{}, authmid.ErrInvalidCredentials
+	}
+	return p, nil
+}
+
+func newTestRouter(u UsecaseI) http.Handler {
+	log := logmid.NewLogger("error")
+	r := chi.NewRouter()
+	r.Use(authmid.Authenticate(log, map[string]authmid.Authenticator{
+		"ApiKey": staticAuthenticator{
+			"admin":  {Subject: "apikey:admin", Scopes: []string{modelauth.ScopeAdmin}},
+			"reader": {Subject: "apikey:reader", Scopes: []string{modelauth.ScopeSubscriptionsRead}},
+		},
+	}))
+	Routes(New(log, u))(r, routes.Middlewares{})
+	return r
+}
+
+func TestCreateAPIKey_ReturnsPlaintextOnce(t *testing.T) {
+	id := uuid.New()
+	userID := uuid.New()
+	u := &mockUsecase{
+		createFn: func(_ context.Context, k modelkey.APIKey) (modelkey.APIKey, string, error) {
+			if k.Name != "bank-sync" {
+				t.Fatalf("name mismatch: %q", k.Name)
+			}
+			if k.UserID == nil || *k.UserID != userID {
+				t.Fatalf("user mismatch: %v", k.UserID)
+			}
+			k.ID = id
+			k.Prefix = "sk_abcdefgh"
+			k.CreatedAt = time.Now()
+			return k, "sk_abcdefgh-secret", nil
+		},
+		listFn: func(context.Context) ([]modelkey.APIKey, error) {
+			return []modelkey.APIKey{{ID: id, Name: "bank-sync", Prefix: "sk_abcdefgh", KeyHash: "hash"}}, nil
+		},
+	}
+	r := newTestRouter(u)
+
+	b, _ := json.Marshal(map[string]any{
+		"name":    "bank-sync",
+		"scopes":  []string{"subscriptions:write"},
+		"user_id": userID.String(),
+	})
+	req := httptest.NewRequest(http.MethodPost, "/admin/api-keys/", bytes.NewReader(b))
+	req.Header.Set("Authorization", "ApiKey admin")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created modelkey.APIKeyCreatedResp
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if created.Key != "sk_abcdefgh-secret" || created.ID != id.String() {
+		t.Fatalf("unexpected response: %+v", created)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/api-keys/", nil)
+	req.Header.Set("Authorization", "ApiKey admin")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d, got %d", http.StatusOK, w.Code)
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte("secret")) || bytes.Contains(w.Body.Bytes(), []byte("hash")) {
+		t.Fatalf("list must not expose key material: %s", w.Body.String())
+	}
+}
+
+func TestAdminEndpoints_RequireAdmin(t *testing.T) {
+	u := &mockUsecase{
+		listFn: func(context.Context) ([]modelkey.APIKey, error) {
+			t.Fatalf("usecase must not be called without admin scope")
+			return nil, nil
+		},
+	}
+	r := newTestRouter(u)
+
+	cases := []struct {
+		auth string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		{"ApiKey wrong", http.StatusUnauthorized},
+		{"Bearer token", http.StatusUnauthorized},
+		{"ApiKey reader", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api-keys/", nil)
+		if c.auth != "" {
+			req.Header.Set("Authorization", c.auth)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Fatalf("auth %q: want %d, got %d", c.auth, c.want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package apikey
+
+import (
+	"test_task/internal/handlers/routes"
+	ratelimit "test_task/internal/middleware/rate_limit_middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Routes returns the admin API key endpoints for the /api/v1 router.
+func Routes(h *Handler) routes.Mount {
+	return func(r chi.Router, mw routes.Middlewares) {
+		r.Route("/admin/api-keys", func(r chi.Router) {
+			r.Use(mw.Admin())
+
+			r.With(mw.Limit(ratelimit.GroupRead)).Get("/", h.ListAPIKeys)
+			r.With(mw.Limit(ratelimit.GroupWrite), mw.LimitBody()).Post("/", h.CreateAPIKey)
+			r.With(mw.Limit(ratelimit.GroupWrite)).Delete("/{id}/", h.RevokeAPIKey)
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	modelbudget "test_task/internal/domain/models/budget"
+	modeldate "test_task/internal/domain/models/month_year"
+	bodylimit "test_task/internal/middleware/body_limit_middleware"
+	JSONRes "test_task/pkg/JSON_response"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type UsecaseI interface {
+	Create(ctx context.Context, b modelbudget.Budget) (modelbudget.Budget, error)
+	Get(ctx context.Context, id uuid.UUID) (modelbudget.Budget, error)
+	List(ctx context.Context, userID *uuid.UUID) ([]modelbudget.Budget, error)
+	Update(ctx context.Context, b modelbudget.Budget) (modelbudget.Budget, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	Status(ctx context.Context, id uuid.UUID, month time.Time) (modelbudget.Status, error)
+}
+
+type Handler struct {
+	log     *slog.Logger
+	usecase UsecaseI
+}
+
+func New(log *slog.Logger, usecase UsecaseI) *Handler {
+	return &Handler{log: log, usecase: usecase}
+}
+
+func toResp(b modelbudget.Budget) modelbudget.BudgetResp {
+	var tagID *string
+	if b.TagID != nil {
+		v := b.TagID.String()
+		tagID = &v
+	}
+	return modelbudget.BudgetResp{
+		ID:          b.ID.String(),
+		UserID:      b.UserID.String(),
+		Amount:      b.Amount,
+		ServiceName: b.ServiceName,
+		TagID:       tagID,
+		Tag:         b.Tag,
+		Enforce:     b.Enforce,
+		CreatedAt:   b.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:   b.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// boundUser returns the user the caller's credentials are restricted to.
+func boundUser(r *http.Request) *uuid.UUID {
+	if p, ok := modelauth.PrincipalFrom(r.Context()); ok {
+		return p.UserID
+	}
+	return nil
+}
+
+// load fetches the budget in the URL, answering 404 for budgets of another
+// user than the one the credentials are bound to.
+func (h *Handler) load(w http.ResponseWriter, r *http.Request) (modelbudget.Budget, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+		return modelbudget.Budget{}, false
+	}
+	b, err := h.usecase.Get(r.Context(), id)
+	if err != nil && !errors.Is(err, myerrors.ErrorNotFound) {
+		h.log.Error("get budget failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to get budget")
+		return modelbudget.Budget{}, false
+	}
+	if bound := boundUser(r); err != nil || (bound != nil && b.UserID != *bound) {
+		JSONRes.WriteJSON(w, http.StatusNotFound, "budget not found")
+		return modelbudget.Budget{}, false
+	}
+	return b, true
+}
+
+// decode reads the request body into a budget, leaving the owner to the
+// caller.
+func decode(w http.ResponseWriter, r *http.Request) (modelbudget.BudgetReq, modelbudget.Budget, bool) {
+	var req modelbudget.BudgetReq
+	if !bodylimit.Decode(w, r, &req) {
+		return req, modelbudget.Budget{}, false
+	}
+	if req.Amount == nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "amount is required")
+		return req, modelbudget.Budget{}, false
+	}
+
+	b := modelbudget.Budget{
+		Amount:      *req.Amount,
+		ServiceName: req.ServiceName,
+		Enforce:     req.Enforce,
+	}
+	if req.TagID != nil {
+		tagID, err := uuid.Parse(*req.TagID)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "tag_id must be a valid UUID")
+			return req, modelbudget.Budget{}, false
+		}
+		b.TagID = &tagID
+	}
+	return req, b, true
+}
+
+// writeErr answers the errors shared by the write endpoints.
+func (h *Handler) writeErr(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, myerrors.ErrorValidation):
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, myerrors.ErrorNotFound):
+		JSONRes.WriteJSON(w, http.StatusNotFound, "budget not found")
+	default:
+		h.log.Error(op+" budget failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to "+op+" budget")
+	}
+}
+
+func (h *Handler) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	req, b, ok := decode(w, r)
+	if !ok {
+		return
+	}
+
+	if v := strings.TrimSpace(req.UserID); v != "" {
+		userID, err := uuid.Parse(v)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "user_id must be a valid UUID")
+			return
+		}
+		b.UserID = userID
+	}
+	if bound := boundUser(r); bound != nil {
+		if b.UserID != uuid.Nil && b.UserID != *bound {
+			JSONRes.WriteJSON(w, http.StatusForbidden, "user_id is not allowed for these credentials")
+			return
+		}
+		b.UserID = *bound
+	}
+	if b.UserID == uuid.Nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "user_id is required")
+		return
+	}
+
+	created, err := h.usecase.Create(r.Context(), b)
+	if err != nil {
+		h.writeErr(w, "create", err)
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusCreated, toResp(created))
+}
+
+func (h *Handler) ListBudgets(w http.ResponseWriter, r *http.Request) {
+	var userID *uuid.UUID
+	if v := strings.TrimSpace(r.URL.Query().Get("user_id")); v != "" {
+		parsed, err := uuid.Parse(v)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "user_id must be a valid UUID")
+			return
+		}
+		userID = &parsed
+	}
+	if bound := boundUser(r); bound != nil {
+		if userID != nil && *userID != *bound {
+			JSONRes.WriteJSON(w, http.StatusForbidden, "user_id is not allowed for these credentials")
+			return
+		}
+		userID = bound
+	}
+
+	budgets, err := h.usecase.List(r.Context(), userID)
+	if err != nil {
+		h.log.Error("list budgets failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to list budgets")
+		return
+	}
+
+	items := make([]modelbudget.BudgetResp, 0, len(budgets))
+	for _, b := range budgets {
+		items = append(items, toResp(b))
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, map[string]any{
+		"total": len(items),
+		"items": items,
+	})
+}
+
+func (h *Handler) GetBudget(w http.ResponseWriter, r *http.Request) {
+	b, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(b))
+}
+
+// UpdateBudget replaces the amount, scope and enforce flag of a budget.
+func (h *Handler) UpdateBudget(w http.ResponseWriter, r *http.Request) {
+	current, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	_, b, ok := decode(w, r)
+	if !ok {
+		return
+	}
+	b.ID = current.ID
+
+	updated, err := h.usecase.Update(r.Context(), b)
+	if err != nil {
+		h.writeErr(w, "update", err)
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(updated))
+}
+
+func (h *Handler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	b, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.usecase.Delete(r.Context(), b.ID); err != nil {
+		h.writeErr(w, "delete", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BudgetStatus reports the spend against a budget in the month given as
+// MM-YYYY, the current month by default.
+func (h *Handler) BudgetStatus(w http.ResponseWriter, r *http.Request) {
+	b, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	var month time.Time
+	if v := strings.TrimSpace(r.URL.Query().Get("month")); v != "" {
+		parsed, err := modeldate.ParseMonthYear(v)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		month = parsed
+	}
+
+	st, err := h.usecase.Status(r.Context(), b.ID, month)
+	if err != nil {
+		h.writeErr(w, "get status of", err)
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, modelbudget.StatusResp{
+		BudgetID:  st.Budget.ID.String(),
+		Month:     modeldate.FormatMonthYear(st.Month),
+		Amount:    st.Budget.Amount,
+		Spent:     st.Spent,
+		Remaining: st.Remaining(),
+		Projected: st.Projected,
+		Over:      st.Over(),
+	})
+}
@@ -0,0 +1,157 @@
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	modelbudget "test_task/internal/domain/models/budget"
+	"test_task/internal/handlers/routes"
+	authmid "test_task/internal/middleware/auth_middleware"
+	logmid "test_task/internal/middleware/loger_middleware"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type mockUsecase struct {
+	createFn func(ctx context.Context, b modelbudget.Budget) (modelbudget.Budget, error)
+	getFn    func(ctx context.Context, id uuid.UUID) (modelbudget.Budget, error)
+	statusFn func(ctx context.Context, id uuid.UUID, month time.Time) (modelbudget.Status, error)
+}
+
+func (m *mockUsecase) Create(ctx context.Context, b modelbudget.Budget) (modelbudget.Budget, error) {
+	return m.createFn(ctx, b)
+}
+func (m *mockUsecase) Get(ctx context.Context, id uuid.UUID) (modelbudget.Budget, error) {
+	return m.getFn(ctx, id)
+}
+func (m *mockUsecase) List(context.Context, *uuid.UUID) ([]modelbudget.Budget, error) {
+	return nil, nil
+}
+func (m *mockUsecase) Update(context.Context, modelbudget.Budget) (modelbudget.Budget, error) {
+	return modelbudget.Budget{}, nil
+}
+func (m *mockUsecase) Delete(context.Context, uuid.UUID) error { return nil }
+func (m *mockUsecase) Status(ctx context.Context, id uuid.UUID, month time.Time) (modelbudget.Status, error) {
+	return m.statusFn(ctx, id, month)
+}
+
+type staticAuthenticator map[string]modelauth.Principal
+
+func (a staticAuthenticator) Authenticate(_ context.Context, credentials string) (modelauth.Principal, error) {
+	p, ok := a[credentials]
+	if !ok {
+		return modelauth.Principal{}, authmid.ErrInvalidCredentials
+	}
+	return p, nil
+}
+
+func newTestRouter(u UsecaseI, bound uuid.UUID) http.Handler {
+	log := logmid.NewLogger("error")
+	r := chi.NewRouter()
+	r.Use(authmid.Authenticate(log, map[string]authmid.Authenticator{
+		"ApiKey": staticAuthenticator{
+			"bound": {
+				Subject: "apikey:bound",
+				UserID:  &bound,
+				Scopes:  []string{modelauth.ScopeSubscriptionsRead, modelauth.ScopeSubscriptionsWrite},
+			},
+		},
+	}))
+	Routes(New(log, u))(r, routes.Middlewares{})
+	return r
+}
+
+func TestBudgetStatus(t *testing.T) {
+	bound := uuid.New()
+	id := uuid.New()
+	var asked time.Time
+	u := &mockUsecase{
+		getFn: func(context.Context, uuid.UUID) (modelbudget.Budget, error) {
+			return modelbudget.Budget{ID: id, UserID: bound, Amount: 1000}, nil
+		},
+		statusFn: func(_ context.Context, _ uuid.UUID, month time.Time) (modelbudget.Status, error) {
+			asked = month
+			return modelbudget.Status{Budget: modelbudget.Budget{ID: id, Amount: 1000}, Month: month, Spent: 400, Projected: 1200}, nil
+		},
+	}
+	r := newTestRouter(u, bound)
+
+	req := httptest.NewRequest(http.MethodGet, "/budgets/"+id.String()+"/status?month=09-2025", nil)
+	req.Header.Set("Authorization", "ApiKey bound")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !asked.Equal(time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("month mismatch: %v", asked)
+	}
+	var resp modelbudget.StatusResp
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Month != "09-2025" || resp.Spent != 400 || resp.Remaining != 600 || resp.Projected != 1200 || !resp.Over {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/budgets/"+id.String()+"/status?month=2025-09", nil)
+	req.Header.Set("Authorization", "ApiKey bound")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid month: want %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestBudget_OtherUsersBudgetIsNotFound(t *testing.T) {
+	bound := uuid.New()
+	id := uuid.New()
+	u := &mockUsecase{
+		getFn: func(context.Context, uuid.UUID) (modelbudget.Budget, error) {
+			return modelbudget.Budget{ID: id, UserID: uuid.New(), Amount: 1000}, nil
+		},
+		statusFn: func(context.Context, uuid.UUID, time.Time) (modelbudget.Status, error) {
+			t.Fatalf("status of another user's budget must not be read")
+			return modelbudget.Status{}, nil
+		},
+	}
+	r := newTestRouter(u, bound)
+
+	req := httptest.NewRequest(http.MethodGet, "/budgets/"+id.String()+"/status", nil)
+	req.Header.Set("Authorization", "ApiKey bound")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+}
+
+func TestCreateBudget_RequiresAmount(t *testing.T) {
+	bound := uuid.New()
+	u := &mockUsecase{
+		createFn: func(context.Context, modelbudget.Budget) (modelbudget.Budget, error) {
+			t.Fatalf("usecase must not be called without amount")
+			return modelbudget.Budget{}, nil
+		},
+	}
+	r := newTestRouter(u, bound)
+
+	req := httptest.NewRequest(http.MethodPost, "/budgets/", strings.NewReader(`{"service_name":"Netflix"}`))
+	req.Header.Set("Authorization", "ApiKey bound")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
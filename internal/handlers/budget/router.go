@@ -0,0 +1,31 @@
+package budget
+
+import (
+	modelauth "test_task/internal/domain/models/auth"
+	"test_task/internal/handlers/routes"
+	ratelimit "test_task/internal/middleware/rate_limit_middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Routes returns the budget endpoints for the /api/v1 router. The status
+// endpoint runs a summary query and shares its rate limit.
+func Routes(h *Handler) routes.Mount {
+	return func(r chi.Router, mw routes.Middlewares) {
+		read := chi.Chain(mw.Authorize(modelauth.ScopeSubscriptionsRead), mw.Limit(ratelimit.GroupRead))
+		write := chi.Chain(mw.Authorize(modelauth.ScopeSubscriptionsWrite), mw.Limit(ratelimit.GroupWrite))
+		summary := chi.Chain(mw.Authorize(modelauth.ScopeSubscriptionsRead), mw.Limit(ratelimit.GroupSummary))
+
+		r.Route("/budgets", func(r chi.Router) {
+			r.With(read...).Get("/", h.ListBudgets)
+			r.With(write...).With(mw.LimitBody()).Post("/", h.CreateBudget)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(read...).Get("/", h.GetBudget)
+				r.With(write...).With(mw.LimitBody()).Put("/", h.UpdateBudget)
+				r.With(write...).Delete("/", h.DeleteBudget)
+				r.With(summary...).Get("/status", h.BudgetStatus)
+			})
+		})
+	}
+}
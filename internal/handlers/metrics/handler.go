@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"test_task/internal/cache"
+)
+
+// StatsI reports the counters of a cache.
+type StatsI interface {
+	Stats() cache.Stats
+}
+
+type Handler struct {
+	caches map[string]StatsI
+}
+
+// New exposes the counters of caches, keyed by the name used as the cache
+// label.
+func New(caches map[string]StatsI) *Handler {
+	return &Handler{caches: caches}
+}
+
+// Metrics writes the cache counters in the Prometheus text format.
+func (h *Handler) Metrics(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(h.caches))
+	for name := range h.caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	stats := make([]cache.Stats, len(names))
+	for i, name := range names {
+		stats[i] = h.caches[name].Stats()
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics := []struct {
+		name, kind, help string
+		value            func(cache.Stats) any
+	}{
+		{"cache_hits_total", "counter", "Lookups answered from the cache.", func(s cache.Stats) any { return s.Hits }},
+		{"cache_misses_total", "counter", "Lookups that missed the cache.", func(s cache.Stats) any { return s.Misses }},
+		{"cache_evictions_total", "counter", "Entries evicted to make room for new ones.", func(s cache.Stats) any { return s.Evictions }},
+		{"cache_entries", "gauge", "Entries currently stored.", func(s cache.Stats) any { return s.Entries }},
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for i, name := range names {
+			fmt.Fprintf(w, "%s{cache=%q} %v\n", m.name, name, m.value(stats[i]))
+		}
+	}
+}
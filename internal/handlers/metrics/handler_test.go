@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"test_task/internal/cache"
+	modelauth "test_task/internal/domain/models/auth"
+	"test_task/internal/handlers/routes"
+	authmid "test_task/internal/middleware/auth_middleware"
+	logmid "test_task/internal/middleware/loger_middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type staticAuthenticator map[string]modelauth.Principal
+
+func (a staticAuthenticator) Authenticate(_ context.Context, credentials string) (modelauth.Principal, error) {
+	p, ok := a[credentials]
+	if !ok {
+		return modelauth.Principal{}, authmid.ErrInvalidCredentials
+	}
+	return p, nil
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(10, time.Minute)
+	c.Set(ctx, "u1", "a", 1, 0)
+	c.Get(ctx, "a")
+	c.Get(ctx, "b")
+
+	log := logmid.NewLogger("error")
+	r := chi.NewRouter()
+	r.Use(authmid.Authenticate(log, map[string]authmid.Authenticator{
+		"ApiKey": staticAuthenticator{
+			"admin":  {Subject: "apikey:admin", Scopes: []string{modelauth.ScopeAdmin}},
+			"reader": {Subject: "apikey:reader", Scopes: []string{modelauth.ScopeSubscriptionsRead}},
+		},
+	}))
+	Routes(New(map[string]StatsI{"summary": c}))(r, routes.Middlewares{})
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "ApiKey reader")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("read scope: want %d, got %d", http.StatusForbidden, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "ApiKey admin")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE cache_hits_total counter",
+		`cache_hits_total{cache="summary"} 1`,
+		`cache_misses_total{cache="summary"} 1`,
+		`cache_entries{cache="summary"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Fatalf("missing %q in:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package metrics
+
+import (
+	"test_task/internal/handlers/routes"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Routes returns the metrics endpoint for the /api/v1 router. Scrapers need
+// an admin key.
+func Routes(h *Handler) routes.Mount {
+	return func(r chi.Router, mw routes.Middlewares) {
+		r.With(mw.Admin()).Get("/metrics", h.Metrics)
+	}
+}
@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"net/http"
+
+	modelauth "test_task/internal/domain/models/auth"
+	authmid "test_task/internal/middleware/auth_middleware"
+	bodylimit "test_task/internal/middleware/body_limit_middleware"
+	ratelimit "test_task/internal/middleware/rate_limit_middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const DefaultMaxBodyBytes = 1 << 20
+
+// Middlewares are the per-route middlewares shared by every API route group.
+type Middlewares struct {
+	Limiter      *ratelimit.Limiter
+	MaxBodyBytes int64
+	AuthRequired bool
+}
+
+// Mount registers a route group on the /api/v1 router.
+type Mount func(r chi.Router, mw Middlewares)
+
+// Limit applies the rate limit budget of group.
+func (m Middlewares) Limit(group string) func(http.Handler) http.Handler {
+	return m.Limiter.Middleware(group)
+}
+
+// LimitBody caps request bodies of write endpoints.
+func (m Middlewares) LimitBody() func(http.Handler) http.Handler {
+	n := m.MaxBodyBytes
+	if n <= 0 {
+		n = DefaultMaxBodyBytes
+	}
+	return bodylimit.MaxBytes(n)
+}
+
+// Authorize requires scope, letting anonymous callers through unless
+// authentication is required.
+func (m Middlewares) Authorize(scope string) func(http.Handler) http.Handler {
+	return authmid.Authorize(scope, !m.AuthRequired)
+}
+
+// Admin requires the admin scope regardless of AuthRequired.
+func (m Middlewares) Admin() func(http.Handler) http.Handler {
+	return authmid.Authorize(modelauth.ScopeAdmin, false)
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	modelservice "test_task/internal/domain/models/service"
+	bodylimit "test_task/internal/middleware/body_limit_middleware"
+	JSONRes "test_task/pkg/JSON_response"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type UsecaseI interface {
+	Create(ctx context.Context, s modelservice.Service) (modelservice.Service, error)
+	Get(ctx context.Context, id uuid.UUID) (modelservice.Service, error)
+	List(ctx context.Context, f modelservice.Filter) ([]modelservice.Service, error)
+	Update(ctx context.Context, s modelservice.Service) (modelservice.Service, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	SetLogo(ctx context.Context, id uuid.UUID, data []byte) (modelservice.Service, error)
+	Logo(ctx context.Context, id uuid.UUID, thumb bool) (modelservice.LogoFile, error)
+}
+
+type Handler struct {
+	log             *slog.Logger
+	usecase         UsecaseI
+	logoMaxBytes    int64
+	logoCacheMaxAge time.Duration
+}
+
+// Option customises the handler built by New.
+type Option func(*Handler)
+
+// WithLogoLimits sets the largest accepted logo upload and how long clients
+// may cache served logos before revalidating them.
+func WithLogoLimits(maxBytes int64, cacheMaxAge time.Duration) Option {
+	return func(h *Handler) {
+		h.logoMaxBytes = maxBytes
+		h.logoCacheMaxAge = cacheMaxAge
+	}
+}
+
+func New(log *slog.Logger, usecase UsecaseI, opts ...Option) *Handler {
+	h := &Handler{
+		log:             log,
+		usecase:         usecase,
+		logoMaxBytes:    defaultLogoMaxBytes,
+		logoCacheMaxAge: defaultLogoCacheMaxAge,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func toResp(s modelservice.Service) modelservice.ServiceResp {
+	aliases := s.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+	return modelservice.ServiceResp{
+		ID:            s.ID.String(),
+		Name:          s.Name,
+		Aliases:       aliases,
+		Category:      s.Category,
+		DefaultPrice:  s.DefaultPrice,
+		BillingPeriod: s.BillingPeriod,
+		LogoURL:       s.LogoURL,
+		Logo:          toLogoResp(s),
+		CreatedAt:     s.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:     s.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func fromReq(req modelservice.ServiceReq) modelservice.Service {
+	return modelservice.Service{
+		Name:          req.Name,
+		Aliases:       req.Aliases,
+		Category:      req.Category,
+		DefaultPrice:  req.DefaultPrice,
+		BillingPeriod: strings.TrimSpace(req.BillingPeriod),
+		LogoURL:       req.LogoURL,
+	}
+}
+
+func parseID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+		return uuid.UUID{}, false
+	}
+	return id, true
+}
+
+// writeErr answers the errors shared by the write endpoints.
+func (h *Handler) writeErr(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, myerrors.ErrorValidation):
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, myerrors.ErrorConflict):
+		JSONRes.WriteJSON(w, http.StatusConflict, err.Error())
+	case errors.Is(err, myerrors.ErrorUnsupportedMedia):
+		JSONRes.WriteJSON(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, myerrors.ErrorTooLarge):
+		JSONRes.WriteJSON(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, myerrors.ErrorNotFound):
+		JSONRes.WriteJSON(w, http.StatusNotFound, "service not found")
+	default:
+		h.log.Error(op+" service failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to "+op+" service")
+	}
+}
+
+func (h *Handler) CreateService(w http.ResponseWriter, r *http.Request) {
+	var req modelservice.ServiceReq
+	if !bodylimit.Decode(w, r, &req) {
+		return
+	}
+
+	created, err := h.usecase.Create(r.Context(), fromReq(req))
+	if err != nil {
+		h.writeErr(w, "create", err)
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusCreated, toResp(created))
+}
+
+func (h *Handler) ListServices(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var f modelservice.Filter
+	if v := strings.TrimSpace(q.Get("category")); v != "" {
+		f.Category = &v
+	}
+	f.Limit, _ = strconv.Atoi(q.Get("limit"))
+	f.Offset, _ = strconv.Atoi(q.Get("offset"))
+
+	services, err := h.usecase.List(r.Context(), f)
+	if err != nil {
+		h.log.Error("list services failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to list services")
+		return
+	}
+
+	items := make([]modelservice.ServiceResp, 0, len(services))
+	for _, s := range services {
+		items = append(items, toResp(s))
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+	})
+}
+
+func (h *Handler) GetService(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	s, err := h.usecase.Get(r.Context(), id)
+	if err != nil {
+		h.writeErr(w, "get", err)
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(s))
+}
+
+func (h *Handler) UpdateService(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	var req modelservice.ServiceReq
+	if !bodylimit.Decode(w, r, &req) {
+		return
+	}
+
+	s := fromReq(req)
+	s.ID = id
+	updated, err := h.usecase.Update(r.Context(), s)
+	if err != nil {
+		h.writeErr(w, "update", err)
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(updated))
+}
+
+func (h *Handler) DeleteService(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.usecase.Delete(r.Context(), id); err != nil {
+		h.writeErr(w, "delete", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	modelservice "test_task/internal/domain/models/service"
+	"test_task/internal/handlers/routes"
+	authmid "test_task/internal/middleware/auth_middleware"
+	logmid "test_task/internal/middleware/loger_middleware"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type mockUsecase struct {
+	createFn  func(ctx context.Context, s modelservice.Service) (modelservice.Service, error)
+	setLogoFn func(ctx context.Context, id uuid.UUID, data []byte) (modelservice.Service, error)
+	logoFn    func(ctx context.Context, id uuid.UUID, thumb bool) (modelservice.LogoFile, error)
+}
+
+func (m *mockUsecase) Create(ctx context.Context, s modelservice.Service) (modelservice.Service, error) {
+	return m.createFn(ctx, s)
+}
+func (m *mockUsecase) Get(context.Context, uuid.UUID) (modelservice.Service, error) {
+	return modelservice.Service{}, myerrors.ErrorNotFound
+}
+func (m *mockUsecase) List(context.Context, modelservice.Filter) ([]modelservice.Service, error) {
+	return nil, nil
+}
+func (m *mockUsecase) Update(context.Context, modelservice.Service) (modelservice.Service, error) {
+	return modelservice.Service{}, nil
+}
+func (m *mockUsecase) Delete(context.Context, uuid.UUID) error { return nil }
+func (m *mockUsecase) SetLogo(ctx context.Context, id uuid.UUID, data []byte) (modelservice.Service, error) {
+	return m.setLogoFn(ctx, id, data)
+}
+func (m *mockUsecase) Logo(ctx context.Context, id uuid.UUID, thumb bool) (modelservice.LogoFile, error) {
+	return m.logoFn(ctx, id, thumb)
+}
+
+type staticAuthenticator map[string]modelauth.Principal
+
+func (a staticAuthenticator) Authenticate(_ context.Context, credentials string) (modelauth.Principal, error) {
+	p, ok := a[credentials]
+	if !ok {
+		return modelauth.Principal{}, authmid.ErrInvalidCredentials
+	}
+	return p, nil
+}
+
+func newTestRouter(u UsecaseI) http.Handler {
+	log := logmid.NewLogger("error")
+	r := chi.NewRouter()
+	r.Use(authmid.Authenticate(log, map[string]authmid.Authenticator{
+		"ApiKey": staticAuthenticator{
+			"admin":  {Subject: "apikey:admin", Scopes: []string{modelauth.ScopeAdmin}},
+			"writer": {Subject: "apikey:writer", Scopes: []string{modelauth.ScopeSubscriptionsWrite}},
+		},
+	}))
+	Routes(New(log, u))(r, routes.Middlewares{})
+	return r
+}
+
+func TestCreateService(t *testing.T) {
+	u := &mockUsecase{
+		createFn: func(_ context.Context, s modelservice.Service) (modelservice.Service, error) {
+			if s.Name == "Netflix" {
+				return modelservice.Service{}, fmt.Errorf("%w: %q is already used by another service", myerrors.ErrorConflict, "netflix")
+			}
+			s.ID = uuid.New()
+			return s, nil
+		},
+	}
+	r := newTestRouter(u)
+
+	tests := []struct {
+		name string
+		key  string
+		body string
+		want int
+	}{
+		{"admin creates", "admin", `{"name": "Spotify", "aliases": ["Спотифай"], "default_price": 169}`, http.StatusCreated},
+		{"name taken", "admin", `{"name": "Netflix"}`, http.StatusConflict},
+		{"write scope is not enough", "writer", `{"name": "Spotify"}`, http.StatusForbidden},
+		{"invalid json", "admin", `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/services/", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "ApiKey "+tt.key)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("want %d, got %d, body=%s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetService_NotFound(t *testing.T) {
+	r := newTestRouter(&mockUsecase{})
+
+	req := httptest.NewRequest(http.MethodGet, "/services/"+uuid.NewString()+"/", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("want %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func logoForm(t *testing.T, field string, data []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile(field, "logo.png")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	part.Write(data)
+	mw.Close()
+	return &body, mw.FormDataContentType()
+}
+
+func TestUploadLogo(t *testing.T) {
+	id := uuid.New()
+	u := &mockUsecase{
+		setLogoFn: func(_ context.Context, got uuid.UUID, data []byte) (modelservice.Service, error) {
+			if string(data) == "text" {
+				return modelservice.Service{}, fmt.Errorf("%w: logo must be PNG, JPEG or GIF", myerrors.ErrorUnsupportedMedia)
+			}
+			return modelservice.Service{ID: got, Name: "Netflix", Logo: &modelservice.Logo{ContentType: "image/png", ETag: "abc"}}, nil
+		},
+	}
+	h := New(logmid.NewLogger("error"), u, WithLogoLimits(16, time.Hour))
+	r := chi.NewRouter()
+	Routes(h)(r, routes.Middlewares{})
+
+	tests := []struct {
+		name  string
+		field string
+		data  string
+		want  int
+	}{
+		{"stored", "logo", "png-bytes", http.StatusOK},
+		{"unsupported type", "logo", "text", http.StatusUnsupportedMediaType},
+		{"missing field", "file", "png-bytes", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := logoForm(t, tt.field, []byte(tt.data))
+			req := httptest.NewRequest(http.MethodPut, "/services/"+id.String()+"/logo", body)
+			req.Header.Set("Content-Type", contentType)
+			req = req.WithContext(modelauth.WithPrincipal(req.Context(), modelauth.Principal{Subject: "apikey:admin", Scopes: []string{modelauth.ScopeAdmin}}))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("want %d, got %d, body=%s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+type nopSeekCloser struct{ *strings.Reader }
+
+func (nopSeekCloser) Close() error { return nil }
+
+func TestGetLogo_ETag(t *testing.T) {
+	id := uuid.New()
+	u := &mockUsecase{
+		logoFn: func(_ context.Context, _ uuid.UUID, thumb bool) (modelservice.LogoFile, error) {
+			if !thumb {
+				return modelservice.LogoFile{}, myerrors.ErrorNotFound
+			}
+			return modelservice.LogoFile{
+				ContentType: "image/png",
+				ETag:        "abc-thumb",
+				ModTime:     time.Now(),
+				Body:        nopSeekCloser{strings.NewReader("thumbnail")},
+			}, nil
+		},
+	}
+	r := newTestRouter(u)
+
+	req := httptest.NewRequest(http.MethodGet, "/services/"+id.String()+"/logo/thumbnail", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "thumbnail" {
+		t.Fatalf("want thumbnail, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("ETag") != `"abc-thumb"` || w.Header().Get("Cache-Control") != "public, max-age=86400" {
+		t.Fatalf("unexpected headers: %v", w.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/services/"+id.String()+"/logo/thumbnail", nil)
+	req.Header.Set("If-None-Match", `"abc-thumb"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("want %d, got %d", http.StatusNotModified, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/services/"+id.String()+"/logo", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("want %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
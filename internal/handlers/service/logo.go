@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	modelservice "test_task/internal/domain/models/service"
+	bodylimit "test_task/internal/middleware/body_limit_middleware"
+	JSONRes "test_task/pkg/JSON_response"
+	myerrors "test_task/pkg/global_errors"
+)
+
+const (
+	defaultLogoMaxBytes    = 2 << 20
+	defaultLogoCacheMaxAge = 24 * time.Hour
+
+	// logoFormOverhead is the room left for multipart headers and
+	// boundaries on top of the logo itself.
+	logoFormOverhead = 64 << 10
+	logoField        = "logo"
+)
+
+var errNoLogo = errors.New("multipart field " + logoField + " is required")
+
+func logoPath(id string) string {
+	return "/api/v1/services/" + id + "/logo"
+}
+
+func toLogoResp(s modelservice.Service) *modelservice.LogoResp {
+	if s.Logo == nil {
+		return nil
+	}
+	// The ETag in the query lets clients cache the logo URL for as long as
+	// the logo stays the same.
+	query := "?v=" + s.Logo.ETag
+	return &modelservice.LogoResp{
+		URL:          logoPath(s.ID.String()) + query,
+		ThumbnailURL: logoPath(s.ID.String()) + "/thumbnail" + query,
+		ContentType:  s.Logo.ContentType,
+		ETag:         s.Logo.ETag,
+		UpdatedAt:    s.Logo.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// limitLogoBody caps logo uploads instead of the default body limit.
+func (h *Handler) limitLogoBody() func(http.Handler) http.Handler {
+	return bodylimit.MaxBytes(h.logoMaxBytes + logoFormOverhead)
+}
+
+// readLogo returns the contents of the logo field of a multipart body,
+// reading at most one byte over the limit so that the usecase can reject it.
+func (h *Handler) readLogo(r *http.Request) ([]byte, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, errNoLogo
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, errNoLogo
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() != logoField {
+			continue
+		}
+		return io.ReadAll(io.LimitReader(part, h.logoMaxBytes+1))
+	}
+}
+
+func (h *Handler) UploadLogo(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.readLogo(r)
+	if err != nil {
+		switch {
+		case bodylimit.IsTooLarge(err):
+			JSONRes.WriteJSON(w, http.StatusRequestEntityTooLarge, "request body too large")
+		case errors.Is(err, errNoLogo):
+			JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+		default:
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "invalid multipart body")
+		}
+		return
+	}
+
+	updated, err := h.usecase.SetLogo(r.Context(), id, data)
+	if err != nil {
+		h.writeErr(w, "upload logo of", err)
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(updated))
+}
+
+func (h *Handler) GetLogo(w http.ResponseWriter, r *http.Request) {
+	h.serveLogo(w, r, false)
+}
+
+func (h *Handler) GetLogoThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.serveLogo(w, r, true)
+}
+
+// serveLogo answers with the logo bytes; http.ServeContent takes care of
+// If-None-Match, If-Modified-Since and range requests.
+func (h *Handler) serveLogo(w http.ResponseWriter, r *http.Request, thumb bool) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	f, err := h.usecase.Logo(r.Context(), id, thumb)
+	if err != nil {
+		if errors.Is(err, myerrors.ErrorNotFound) {
+			JSONRes.WriteJSON(w, http.StatusNotFound, "logo not found")
+			return
+		}
+		h.log.Error("get logo failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to get logo")
+		return
+	}
+	defer f.Body.Close()
+
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("ETag", `"`+f.ETag+`"`)
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(h.logoCacheMaxAge.Seconds())))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", f.ModTime, f.Body)
+}
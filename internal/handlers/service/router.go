@@ -0,0 +1,33 @@
+package service
+
+import (
+	modelauth "test_task/internal/domain/models/auth"
+	"test_task/internal/handlers/routes"
+	ratelimit "test_task/internal/middleware/rate_limit_middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Routes returns the service catalog endpoints for the /api/v1 router. The
+// catalog is shared by every user, so changing it needs the admin scope.
+func Routes(h *Handler) routes.Mount {
+	return func(r chi.Router, mw routes.Middlewares) {
+		read := chi.Chain(mw.Authorize(modelauth.ScopeSubscriptionsRead), mw.Limit(ratelimit.GroupRead))
+		admin := chi.Chain(mw.Admin(), mw.Limit(ratelimit.GroupWrite))
+
+		r.Route("/services", func(r chi.Router) {
+			r.With(read...).Get("/", h.ListServices)
+			r.With(admin...).With(mw.LimitBody()).Post("/", h.CreateService)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(read...).Get("/", h.GetService)
+				r.With(admin...).With(mw.LimitBody()).Put("/", h.UpdateService)
+				r.With(admin...).Delete("/", h.DeleteService)
+
+				r.With(read...).Get("/logo", h.GetLogo)
+				r.With(read...).Get("/logo/thumbnail", h.GetLogoThumbnail)
+				r.With(admin...).With(h.limitLogoBody()).Put("/logo", h.UploadLogo)
+			})
+		})
+	}
+}
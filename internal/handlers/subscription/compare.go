@@ -0,0 +1,47 @@
+package subscription
+
+import (
+	modelsub "test_task/internal/domain/models/subscription"
+)
+
+// compareGroups pairs the tag totals of a summary with those of the compared
+// period, in the order of cur. Tags only found in the compared period follow
+// with a zero total.
+func compareGroups(cur, prev []modelsub.TagTotal) []modelsub.TagCompareResp {
+	prevTotals := make(map[string]int64, len(prev))
+	for _, t := range prev {
+		prevTotals[tagKey(t.Tag)] = t.Total
+	}
+
+	out := make([]modelsub.TagCompareResp, 0, len(cur))
+	seen := make(map[string]bool, len(cur))
+	add := func(tag *string, total, previous int64) {
+		d := modelsub.NewDelta(total, previous)
+		out = append(out, modelsub.TagCompareResp{
+			Tag:           tag,
+			Total:         total,
+			PreviousTotal: previous,
+			Delta:         d.Change,
+			DeltaPct:      d.Percent,
+		})
+	}
+	for _, t := range cur {
+		seen[tagKey(t.Tag)] = true
+		add(t.Tag, t.Total, prevTotals[tagKey(t.Tag)])
+	}
+	for _, t := range prev {
+		if !seen[tagKey(t.Tag)] {
+			add(t.Tag, 0, t.Total)
+		}
+	}
+	return out
+}
+
+// tagKey identifies a tag group; tag names are never empty, so "" stands for
+// the untagged subscriptions.
+func tagKey(tag *string) string {
+	if tag == nil {
+		return ""
+	}
+	return *tag
+}
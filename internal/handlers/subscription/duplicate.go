@@ -0,0 +1,53 @@
+package subscription
+
+import (
+	"log/slog"
+	"net/http"
+
+	modeldate "test_task/internal/domain/models/month_year"
+	modelsub "test_task/internal/domain/models/subscription"
+	JSONRes "test_task/pkg/JSON_response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// Duplicates lists the pairs of subscriptions of a user to the same service
+// that overlap.
+func (h *Handler) Duplicates(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+		return
+	}
+	if _, ok := scopeUserFilter(w, r, &userID); !ok {
+		return
+	}
+
+	overlaps, err := h.usecase.Duplicates(r.Context(), userID)
+	if err != nil {
+		h.log.Error("find duplicates failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to find duplicates")
+		return
+	}
+
+	items := make([]modelsub.OverlapResp, 0, len(overlaps))
+	for _, o := range overlaps {
+		var to *string
+		if o.To != nil {
+			v := modeldate.FormatMonthYear(*o.To)
+			to = &v
+		}
+		items = append(items, modelsub.OverlapResp{
+			ServiceName:     o.First.ServiceName,
+			SubscriptionIDs: []string{o.First.ID.String(), o.Second.ID.String()},
+			From:            modeldate.FormatMonthYear(o.From),
+			To:              to,
+		})
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, map[string]any{
+		"user_id": userID.String(),
+		"total":   len(items),
+		"items":   items,
+	})
+}
@@ -0,0 +1,106 @@
+package subscription
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	modeldate "test_task/internal/domain/models/month_year"
+	modelsub "test_task/internal/domain/models/subscription"
+	JSONRes "test_task/pkg/JSON_response"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+const defaultForecastMonths = 12
+
+// Forecast projects the monthly spend from the current month on, with the
+// user, service and tag filters of Summary.
+func (h *Handler) Forecast(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	months := defaultForecastMonths
+	if v := strings.TrimSpace(q.Get("months")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "months must be an integer")
+			return
+		}
+		months = n
+	}
+
+	var userID *uuid.UUID
+	if v := strings.TrimSpace(q.Get("user_id")); v != "" {
+		parsed, err := uuid.Parse(v)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "user_id must be a valid UUID")
+			return
+		}
+		userID = &parsed
+	}
+	userID, ok := scopeUserFilter(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var serviceName *string
+	if v := strings.TrimSpace(q.Get("service_name")); v != "" {
+		serviceName = &v
+	}
+
+	tags, tagsAll, err := parseTagFilter(q)
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	totals, err := h.usecase.Forecast(r.Context(), modelsub.SummaryFilter{
+		UserID:      userID,
+		ServiceName: serviceName,
+		Tags:        tags,
+		TagsAll:     tagsAll,
+	}, months)
+	if err != nil {
+		if errors.Is(err, myerrors.ErrorValidation) {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.log.Error("forecast failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to calculate forecast")
+		return
+	}
+
+	items := make([]modelsub.MonthTotalResp, 0, len(totals))
+	var total int64
+	for _, m := range totals {
+		items = append(items, modelsub.MonthTotalResp{
+			Month:      modeldate.FormatMonthYear(m.Month),
+			Total:      m.Total,
+			Cumulative: m.Cumulative,
+		})
+		total = m.Cumulative
+	}
+
+	resp := map[string]any{
+		"total":    total,
+		"currency": "RUB",
+		"months":   items,
+	}
+	if len(items) > 0 {
+		resp["from"] = items[0].Month
+		resp["to"] = items[len(items)-1].Month
+	}
+	if userID != nil {
+		resp["user_id"] = userID.String()
+	}
+	if serviceName != nil {
+		resp["service_name"] = *serviceName
+	}
+	if len(tags) > 0 {
+		resp["tags"] = tags
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, resp)
+}
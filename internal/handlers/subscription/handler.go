@@ -2,16 +2,21 @@ package subscription
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	modelaudit "test_task/internal/domain/models/audit"
+	modelauth "test_task/internal/domain/models/auth"
 	modeldate "test_task/internal/domain/models/month_year"
 	modelsub "test_task/internal/domain/models/subscription"
+	bodylimit "test_task/internal/middleware/body_limit_middleware"
 	JSONRes "test_task/pkg/JSON_response"
 	myerrors "test_task/pkg/global_errors"
 
@@ -20,12 +25,23 @@ import (
 )
 
 type UsecaseI interface {
-	Create(ctx context.Context, s modelsub.Subscription) (modelsub.Subscription, error)
+	CreateChecked(ctx context.Context, s modelsub.Subscription, policy string) (modelsub.Subscription, []modelsub.Subscription, error)
 	GetSub(ctx context.Context, id uuid.UUID) (modelsub.Subscription, error)
 	UpdateSub(ctx context.Context, id uuid.UUID, s modelsub.Subscription) (modelsub.Subscription, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	Restore(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (modelsub.Subscription, error)
 	List(ctx context.Context, f modelsub.ListFilter) ([]modelsub.Subscription, int, error)
 	Summary(ctx context.Context, f modelsub.SummaryFilter) (int64, error)
+	AuditLog(ctx context.Context, f modelaudit.Filter) ([]modelaudit.Entry, error)
+	Upcoming(ctx context.Context, f modelsub.UpcomingFilter, within time.Duration) ([]modelsub.Renewal, error)
+	Pause(ctx context.Context, id uuid.UUID, from time.Time) (modelsub.Subscription, error)
+	Resume(ctx context.Context, id uuid.UUID, from time.Time) (modelsub.Subscription, error)
+	Pauses(ctx context.Context, id uuid.UUID) ([]modelsub.Pause, error)
+	SetTags(ctx context.Context, id uuid.UUID, names []string) (modelsub.Subscription, error)
+	SummaryByTag(ctx context.Context, f modelsub.SummaryFilter) ([]modelsub.TagTotal, error)
+	Duplicates(ctx context.Context, userID uuid.UUID) ([]modelsub.Overlap, error)
+	Forecast(ctx context.Context, f modelsub.SummaryFilter, months int) ([]modelsub.MonthTotal, error)
+	Now() time.Time
 }
 
 type Handler struct {
@@ -43,44 +59,126 @@ func toResp(s modelsub.Subscription) modelsub.SubscriptionResp {
 		v := modeldate.FormatMonthYear(*s.EndDate)
 		end = &v
 	}
+	var serviceID *string
+	if s.ServiceID != nil {
+		v := s.ServiceID.String()
+		serviceID = &v
+	}
+	var trialUntil *string
+	if s.TrialUntil != nil {
+		v := modeldate.FormatMonthYear(*s.TrialUntil)
+		trialUntil = &v
+	}
+	tags := s.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	var deleted *string
+	if s.DeletedAt != nil {
+		v := s.DeletedAt.UTC().Format(time.RFC3339)
+		deleted = &v
+	}
 	return modelsub.SubscriptionResp{
 		ID:          s.ID.String(),
 		ServiceName: s.ServiceName,
+		ServiceID:   serviceID,
 		Price:       s.Price,
 		UserID:      s.UserID.String(),
 		StartDate:   modeldate.FormatMonthYear(s.StartDate),
 		EndDate:     end,
+		TrialUntil:  trialUntil,
+		TrialPrice:  s.TrialPrice,
+		Status:      s.Status,
+		Tags:        tags,
+		DeletedAt:   deleted,
 		CreatedAt:   s.CreatedAt.UTC().Format(time.RFC3339),
 		UpdatedAt:   s.UpdatedAt.UTC().Format(time.RFC3339),
 	}
 }
 
+// boundUser returns the user the caller's credentials are restricted to.
+func boundUser(r *http.Request) *uuid.UUID {
+	if p, ok := modelauth.PrincipalFrom(r.Context()); ok {
+		return p.UserID
+	}
+	return nil
+}
+
+// ensureOwned answers 404 for subscriptions that belong to another user than
+// the one the credentials are bound to.
+func (h *Handler) ensureOwned(w http.ResponseWriter, r *http.Request, id uuid.UUID) bool {
+	bound := boundUser(r)
+	if bound == nil {
+		return true
+	}
+	s, err := h.usecase.GetSub(r.Context(), id)
+	if err != nil && !errors.Is(err, myerrors.ErrorNotFound) {
+		h.log.Error("get subscription failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to get subscription")
+		return false
+	}
+	if err != nil || s.UserID != *bound {
+		JSONRes.WriteJSON(w, http.StatusNotFound, "subscription not found")
+		return false
+	}
+	return true
+}
+
+// scopeUserFilter forces the user filter of bound credentials.
+func scopeUserFilter(w http.ResponseWriter, r *http.Request, userID *uuid.UUID) (*uuid.UUID, bool) {
+	bound := boundUser(r)
+	if bound == nil {
+		return userID, true
+	}
+	if userID != nil && *userID != *bound {
+		JSONRes.WriteJSON(w, http.StatusForbidden, "user_id is not allowed for these credentials")
+		return nil, false
+	}
+	return bound, true
+}
+
 func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
 	JSONRes.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
 func (h *Handler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
 	var req modelsub.SubscriptionCreateReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		JSONRes.WriteJSON(w, http.StatusBadRequest, "invalid json body")
+	if !bodylimit.Decode(w, r, &req) {
 		return
 	}
 
 	req.ServiceName = strings.TrimSpace(req.ServiceName)
-	if req.ServiceName == "" {
-		JSONRes.WriteJSON(w, http.StatusBadRequest, "service_name is required")
-		return
+	var serviceID *uuid.UUID
+	if req.ServiceID != nil {
+		parsed, err := uuid.Parse(*req.ServiceID)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "service_id must be a valid UUID")
+			return
+		}
+		serviceID = &parsed
 	}
-	if req.Price < 0 {
-		JSONRes.WriteJSON(w, http.StatusBadRequest, "price must be >= 0")
+	if req.ServiceName == "" && serviceID == nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "service_name or service_id is required")
 		return
 	}
+	var price int
+	if req.Price != nil {
+		if *req.Price < 0 {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "price must be >= 0")
+			return
+		}
+		price = *req.Price
+	}
 
 	userID, err := uuid.Parse(req.UserID)
 	if err != nil {
 		JSONRes.WriteJSON(w, http.StatusBadRequest, "user_id must be a valid UUID")
 		return
 	}
+	if bound := boundUser(r); bound != nil && userID != *bound {
+		JSONRes.WriteJSON(w, http.StatusForbidden, "user_id is not allowed for these credentials")
+		return
+	}
 
 	start, err := modeldate.ParseMonthYear(req.StartDate)
 	if err != nil {
@@ -102,22 +200,59 @@ func (h *Handler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
 		end = &t
 	}
 
+	trialUntil, err := parseTrial(req, start, end)
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	s := modelsub.Subscription{
 		ServiceName: req.ServiceName,
-		Price:       req.Price,
+		ServiceID:   serviceID,
+		Price:       price,
 		UserID:      userID,
 		StartDate:   start,
 		EndDate:     end,
+		TrialUntil:  trialUntil,
+		TrialPrice:  req.TrialPrice,
+		// Without a price the catalog price of the service applies.
+		PriceFromCatalog: req.Price == nil,
+	}
+	if req.Status != nil {
+		s.Status = strings.TrimSpace(*req.Status)
 	}
 
-	created, err := h.usecase.Create(r.Context(), s)
+	policy := strings.TrimSpace(r.URL.Query().Get("on_duplicate"))
+	if policy == "" {
+		policy = modelsub.DuplicateAllow
+	}
+
+	created, dups, err := h.usecase.CreateChecked(r.Context(), s, policy)
 	if err != nil {
+		if writeStatusErr(w, err) {
+			return
+		}
+		if errors.Is(err, myerrors.ErrorConflict) && len(dups) > 0 {
+			items := make([]modelsub.SubscriptionResp, 0, len(dups))
+			for _, d := range dups {
+				items = append(items, toResp(d))
+			}
+			JSONRes.WriteJSON(w, http.StatusConflict, map[string]any{
+				"message":    err.Error(),
+				"duplicates": items,
+			})
+			return
+		}
 		h.log.Error("create subscription failed", slog.Any("err", err))
 		JSONRes.WriteJSON(w, http.StatusConflict, "failed to create subscription")
 		return
 	}
 
-	JSONRes.WriteJSON(w, http.StatusCreated, toResp(created))
+	resp := toResp(created)
+	for _, d := range dups {
+		resp.DuplicateOf = append(resp.DuplicateOf, d.ID.String())
+	}
+	JSONRes.WriteJSON(w, http.StatusCreated, resp)
 }
 
 func (h *Handler) GetSubscription(w http.ResponseWriter, r *http.Request) {
@@ -138,6 +273,10 @@ func (h *Handler) GetSubscription(w http.ResponseWriter, r *http.Request) {
 		JSONRes.WriteJSON(w, http.StatusPreconditionFailed, "failed to get subscription")
 		return
 	}
+	if bound := boundUser(r); bound != nil && s.UserID != *bound {
+		JSONRes.WriteJSON(w, http.StatusNotFound, "subscription not found")
+		return
+	}
 
 	JSONRes.WriteJSON(w, http.StatusOK, toResp(s))
 }
@@ -151,26 +290,42 @@ func (h *Handler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req modelsub.SubscriptionCreateReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		JSONRes.WriteJSON(w, http.StatusBadRequest, "invalid json body")
+	if !bodylimit.Decode(w, r, &req) {
 		return
 	}
 
 	req.ServiceName = strings.TrimSpace(req.ServiceName)
-	if req.ServiceName == "" {
-		JSONRes.WriteJSON(w, http.StatusBadRequest, "service_name is required")
-		return
+	var serviceID *uuid.UUID
+	if req.ServiceID != nil {
+		parsed, err := uuid.Parse(*req.ServiceID)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "service_id must be a valid UUID")
+			return
+		}
+		serviceID = &parsed
 	}
-	if req.Price < 0 {
-		JSONRes.WriteJSON(w, http.StatusBadRequest, "price must be >= 0")
+	if req.ServiceName == "" && serviceID == nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "service_name or service_id is required")
 		return
 	}
+	var price int
+	if req.Price != nil {
+		if *req.Price < 0 {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "price must be >= 0")
+			return
+		}
+		price = *req.Price
+	}
 
 	userID, err := uuid.Parse(req.UserID)
 	if err != nil {
 		JSONRes.WriteJSON(w, http.StatusBadRequest, "user_id must be a valid UUID")
 		return
 	}
+	if bound := boundUser(r); bound != nil && userID != *bound {
+		JSONRes.WriteJSON(w, http.StatusForbidden, "user_id is not allowed for these credentials")
+		return
+	}
 
 	start, err := modeldate.ParseMonthYear(req.StartDate)
 	if err != nil {
@@ -192,12 +347,30 @@ func (h *Handler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
 		end = &t
 	}
 
+	trialUntil, err := parseTrial(req, start, end)
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	s := modelsub.Subscription{
 		ServiceName: req.ServiceName,
-		Price:       req.Price,
+		ServiceID:   serviceID,
+		Price:       price,
 		UserID:      userID,
 		StartDate:   start,
 		EndDate:     end,
+		TrialUntil:  trialUntil,
+		TrialPrice:  req.TrialPrice,
+		// Without a price the catalog price of the service applies.
+		PriceFromCatalog: req.Price == nil,
+	}
+	if req.Status != nil {
+		s.Status = strings.TrimSpace(*req.Status)
+	}
+
+	if !h.ensureOwned(w, r, id) {
+		return
 	}
 
 	updated, err := h.usecase.UpdateSub(r.Context(), id, s)
@@ -206,6 +379,9 @@ func (h *Handler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
 			JSONRes.WriteJSON(w, http.StatusNotFound, "subscription not found")
 			return
 		}
+		if writeStatusErr(w, err) {
+			return
+		}
 		h.log.Error("update subscription failed", slog.Any("err", err))
 		JSONRes.WriteJSON(w, http.StatusForbidden, "failed to update subscription")
 		return
@@ -222,6 +398,10 @@ func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.ensureOwned(w, r, id) {
+		return
+	}
+
 	if err := h.usecase.Delete(r.Context(), id); err != nil {
 		if errors.Is(err, myerrors.ErrorNotFound) {
 			JSONRes.WriteJSON(w, http.StatusNotFound, "subscription not found")
@@ -235,6 +415,29 @@ func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// RestoreSubscription undoes a soft delete.
+func (h *Handler) RestoreSubscription(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+		return
+	}
+
+	s, err := h.usecase.Restore(r.Context(), id, boundUser(r))
+	if err != nil {
+		if errors.Is(err, myerrors.ErrorNotFound) {
+			JSONRes.WriteJSON(w, http.StatusNotFound, "deleted subscription not found")
+			return
+		}
+		h.log.Error("restore subscription failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to restore subscription")
+		return
+	}
+
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(s))
+}
+
 func (h *Handler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 
@@ -248,29 +451,79 @@ func (h *Handler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
 		userID = &parsed
 	}
 
+	userID, ok := scopeUserFilter(w, r, userID)
+	if !ok {
+		return
+	}
+
 	var serviceName *string
 	if v := strings.TrimSpace(q.Get("service_name")); v != "" {
 		serviceName = &v
 	}
 
-	limit := 50
-	offset := 0
-	if v := q.Get("limit"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			limit = n
+	var serviceID *uuid.UUID
+	if v := strings.TrimSpace(q.Get("service_id")); v != "" {
+		parsed, err := uuid.Parse(v)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "service_id must be a valid UUID")
+			return
 		}
+		serviceID = &parsed
 	}
-	if v := q.Get("offset"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			offset = n
+
+	var status *string
+	if v := strings.TrimSpace(q.Get("status")); v != "" {
+		if !slices.Contains(modelsub.Statuses, v) {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "status must be one of: "+strings.Join(modelsub.Statuses, ", "))
+			return
 		}
+		status = &v
 	}
 
+	var trialAfter, trialBefore *time.Time
+	if v := strings.TrimSpace(q.Get("trial_ending_within")); v != "" {
+		within, err := parseWithin(v)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "trial_ending_within: "+err.Error())
+			return
+		}
+		now := h.usecase.Now().UTC()
+		until := now.Add(within)
+		trialAfter, trialBefore = &now, &until
+	}
+
+	tags, tagsAll, err := parseTagFilter(q)
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	includeDeleted, err := parseBool(q, "include_deleted")
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if includeDeleted {
+		if p, ok := modelauth.PrincipalFrom(r.Context()); !ok || !p.HasScope(modelauth.ScopeAdmin) {
+			JSONRes.WriteJSON(w, http.StatusForbidden, "include_deleted requires admin scope")
+			return
+		}
+	}
+
+	limit, offset := parsePage(q)
+
 	items, total, err := h.usecase.List(r.Context(), modelsub.ListFilter{
-		UserID:      userID,
-		ServiceName: serviceName,
-		Limit:       limit,
-		Offset:      offset,
+		UserID:          userID,
+		ServiceName:     serviceName,
+		ServiceID:       serviceID,
+		Status:          status,
+		TrialEndsAfter:  trialAfter,
+		TrialEndsBefore: trialBefore,
+		Tags:            tags,
+		TagsAll:         tagsAll,
+		IncludeDeleted:  includeDeleted,
+		Limit:           limit,
+		Offset:          offset,
 	})
 	if err != nil {
 		h.log.Error("list subscriptions failed", slog.Any("err", err))
@@ -326,17 +579,50 @@ func (h *Handler) Summary(w http.ResponseWriter, r *http.Request) {
 		userID = &parsed
 	}
 
+	userID, ok := scopeUserFilter(w, r, userID)
+	if !ok {
+		return
+	}
+
 	var serviceName *string
 	if v := strings.TrimSpace(q.Get("service_name")); v != "" {
 		serviceName = &v
 	}
 
-	total, err := h.usecase.Summary(r.Context(), modelsub.SummaryFilter{
-		From:        from,
-		To:          to,
-		UserID:      userID,
-		ServiceName: serviceName,
-	})
+	excludeDeleted, err := parseBool(q, "exclude_deleted")
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tags, tagsAll, err := parseTagFilter(q)
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	groupBy := strings.TrimSpace(q.Get("group_by"))
+	if groupBy != "" && groupBy != "tag" {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "group_by must be tag")
+		return
+	}
+
+	compare := strings.TrimSpace(q.Get("compare"))
+	if compare != "" && !slices.Contains(modelsub.CompareModes, compare) {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "compare must be one of "+strings.Join(modelsub.CompareModes, ", "))
+		return
+	}
+
+	f := modelsub.SummaryFilter{
+		From:           from,
+		To:             to,
+		UserID:         userID,
+		ServiceName:    serviceName,
+		Tags:           tags,
+		TagsAll:        tagsAll,
+		ExcludeDeleted: excludeDeleted,
+	}
+	total, err := h.usecase.Summary(r.Context(), f)
 	if err != nil {
 		h.log.Error("summary failed", slog.Any("err", err))
 		JSONRes.WriteJSON(w, http.StatusForbidden, "failed to calculate summary")
@@ -355,6 +641,313 @@ func (h *Handler) Summary(w http.ResponseWriter, r *http.Request) {
 	if serviceName != nil {
 		resp["service_name"] = *serviceName
 	}
+	if len(tags) > 0 {
+		resp["tags"] = tags
+	}
+
+	// The compared period goes through the same filter as the requested one.
+	prev := f.Compared(compare)
+	if compare != "" {
+		prevTotal, err := h.usecase.Summary(r.Context(), prev)
+		if err != nil {
+			h.log.Error("summary of compared period failed", slog.Any("err", err))
+			JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to calculate summary")
+			return
+		}
+		d := modelsub.NewDelta(total, prevTotal)
+		resp["compare"] = modelsub.CompareResp{
+			Mode:     compare,
+			From:     modeldate.FormatMonthYear(prev.From),
+			To:       modeldate.FormatMonthYear(prev.To),
+			Total:    prevTotal,
+			Delta:    d.Change,
+			DeltaPct: d.Percent,
+		}
+	}
+	if groupBy == "tag" {
+		totals, err := h.usecase.SummaryByTag(r.Context(), f)
+		if err != nil {
+			h.log.Error("summary by tag failed", slog.Any("err", err))
+			JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to calculate summary")
+			return
+		}
+		if compare != "" {
+			prevTotals, err := h.usecase.SummaryByTag(r.Context(), prev)
+			if err != nil {
+				h.log.Error("summary by tag of compared period failed", slog.Any("err", err))
+				JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to calculate summary")
+				return
+			}
+			resp["groups"] = compareGroups(totals, prevTotals)
+		} else {
+			groups := make([]modelsub.TagTotalResp, 0, len(totals))
+			for _, t := range totals {
+				groups = append(groups, modelsub.TagTotalResp{Tag: t.Tag, Total: t.Total})
+			}
+			resp["groups"] = groups
+		}
+	}
 
 	JSONRes.WriteJSON(w, http.StatusOK, resp)
 }
+
+const (
+	defaultWithin = 30 * 24 * time.Hour
+	maxWithin     = 366 * 24 * time.Hour
+)
+
+// parseWithin accepts a number of days ("30d") or a Go duration ("72h").
+func parseWithin(v string) (time.Duration, error) {
+	if v == "" {
+		return defaultWithin, nil
+	}
+	var d time.Duration
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, errors.New("within must look like 30d or 72h")
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(v); err != nil {
+			return 0, errors.New("within must look like 30d or 72h")
+		}
+	}
+	if d <= 0 || d > maxWithin {
+		return 0, errors.New("within must be between 1h and 366d")
+	}
+	return d, nil
+}
+
+// Upcoming lists the next charge of every active subscription due within the
+// requested window.
+func (h *Handler) Upcoming(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	within, err := parseWithin(strings.TrimSpace(q.Get("within")))
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var userID *uuid.UUID
+	if v := strings.TrimSpace(q.Get("user_id")); v != "" {
+		parsed, err := uuid.Parse(v)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "user_id must be a valid UUID")
+			return
+		}
+		userID = &parsed
+	}
+
+	userID, ok := scopeUserFilter(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var serviceName *string
+	if v := strings.TrimSpace(q.Get("service_name")); v != "" {
+		serviceName = &v
+	}
+
+	renewals, err := h.usecase.Upcoming(r.Context(), modelsub.UpcomingFilter{
+		UserID:      userID,
+		ServiceName: serviceName,
+	}, within)
+	if err != nil {
+		h.log.Error("upcoming renewals failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to list upcoming renewals")
+		return
+	}
+
+	today := h.usecase.Now().UTC().Truncate(24 * time.Hour)
+	var total int64
+	items := make([]modelsub.RenewalResp, 0, len(renewals))
+	for _, rn := range renewals {
+		total += int64(rn.Amount)
+		items = append(items, modelsub.RenewalResp{
+			SubscriptionID: rn.Subscription.ID.String(),
+			ServiceName:    rn.Subscription.ServiceName,
+			UserID:         rn.Subscription.UserID.String(),
+			Price:          rn.Amount,
+			ChargeDate:     rn.ChargeDate.Format(time.DateOnly),
+			DaysLeft:       int(rn.ChargeDate.Sub(today).Hours() / 24),
+		})
+	}
+
+	JSONRes.WriteJSON(w, http.StatusOK, map[string]any{
+		"total":    total,
+		"currency": "RUB",
+		"items":    items,
+	})
+}
+
+func toAuditResp(e modelaudit.Entry) modelaudit.EntryResp {
+	return modelaudit.EntryResp{
+		ID:             e.ID,
+		SubscriptionID: e.SubscriptionID.String(),
+		UserID:         e.UserID.String(),
+		Operation:      e.Operation,
+		Actor:          e.Actor,
+		RequestID:      e.RequestID,
+		Before:         e.Before,
+		After:          e.After,
+		CreatedAt:      e.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// parseTrial validates the optional trial of a create or update request and
+// returns the last trial month.
+func parseTrial(req modelsub.SubscriptionCreateReq, start time.Time, end *time.Time) (*time.Time, error) {
+	if req.TrialPrice != nil && *req.TrialPrice < 0 {
+		return nil, errors.New("trial_price must be >= 0")
+	}
+	if req.TrialUntil == nil {
+		if req.TrialPrice != nil {
+			return nil, errors.New("trial_price requires trial_until")
+		}
+		return nil, nil
+	}
+
+	t, err := modeldate.ParseMonthYear(*req.TrialUntil)
+	if err != nil {
+		return nil, err
+	}
+	if t.Before(start) {
+		return nil, errors.New("trial_until must be >= start_date")
+	}
+	if end != nil && t.After(*end) {
+		return nil, errors.New("trial_until must be <= end_date")
+	}
+	return &t, nil
+}
+
+// writeStatusErr answers status validation and state machine errors and
+// reports whether it did.
+func writeStatusErr(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, myerrors.ErrorValidation):
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, myerrors.ErrorInvalidTransition):
+		JSONRes.WriteJSON(w, http.StatusConflict, err.Error())
+	case errors.Is(err, myerrors.ErrorBudgetExceeded):
+		JSONRes.WriteJSON(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+func parsePage(q url.Values) (limit, offset int) {
+	limit = 50
+	if v := q.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			limit = n
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			offset = n
+		}
+	}
+	return limit, offset
+}
+
+func parseBool(q url.Values, name string) (bool, error) {
+	v := strings.TrimSpace(q.Get(name))
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean", name)
+	}
+	return b, nil
+}
+
+func (h *Handler) writeAudit(w http.ResponseWriter, r *http.Request, f modelaudit.Filter) {
+	entries, err := h.usecase.AuditLog(r.Context(), f)
+	if err != nil {
+		h.log.Error("audit log failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to load audit log")
+		return
+	}
+
+	items := make([]modelaudit.EntryResp, 0, len(entries))
+	for _, e := range entries {
+		items = append(items, toAuditResp(e))
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, map[string]any{
+		"limit":  f.Limit,
+		"offset": f.Offset,
+		"items":  items,
+	})
+}
+
+// History returns the audit trail of one subscription, newest first. It also
+// works for deleted subscriptions.
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+		return
+	}
+
+	limit, offset := parsePage(r.URL.Query())
+	f := modelaudit.Filter{
+		SubscriptionID: &id,
+		UserID:         boundUser(r),
+		Limit:          limit,
+		Offset:         offset,
+	}
+	h.writeAudit(w, r, f)
+}
+
+// AuditLog is the admin view over every subscription change.
+func (h *Handler) AuditLog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, offset := parsePage(q)
+	f := modelaudit.Filter{Limit: limit, Offset: offset}
+
+	for name, dst := range map[string]**uuid.UUID{
+		"subscription_id": &f.SubscriptionID,
+		"user_id":         &f.UserID,
+	} {
+		if v := strings.TrimSpace(q.Get(name)); v != "" {
+			parsed, err := uuid.Parse(v)
+			if err != nil {
+				JSONRes.WriteJSON(w, http.StatusBadRequest, name+" must be a valid UUID")
+				return
+			}
+			*dst = &parsed
+		}
+	}
+	if v := strings.TrimSpace(q.Get("actor")); v != "" {
+		f.Actor = &v
+	}
+	if v := strings.TrimSpace(q.Get("operation")); v != "" {
+		switch v {
+		case modelaudit.OperationCreate, modelaudit.OperationUpdate, modelaudit.OperationDelete, modelaudit.OperationErase:
+		default:
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "operation must be one of create, update, delete, erase")
+			return
+		}
+		f.Operation = &v
+	}
+	for name, dst := range map[string]**time.Time{
+		"from": &f.From,
+		"to":   &f.To,
+	} {
+		if v := strings.TrimSpace(q.Get(name)); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				JSONRes.WriteJSON(w, http.StatusBadRequest, name+" must be RFC3339")
+				return
+			}
+			*dst = &t
+		}
+	}
+
+	h.writeAudit(w, r, f)
+}
@@ -4,24 +4,53 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	modelaudit "test_task/internal/domain/models/audit"
+	modelauth "test_task/internal/domain/models/auth"
+	modeldate "test_task/internal/domain/models/month_year"
 	modelsub "test_task/internal/domain/models/subscription"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"test_task/internal/handlers/routes"
+	authmid "test_task/internal/middleware/auth_middleware"
 	logmid "test_task/internal/middleware/loger_middleware"
+	myerrors "test_task/pkg/global_errors"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
 type mockUsecase struct {
-	createFn func(ctx context.Context, s modelsub.Subscription) (modelsub.Subscription, error)
-	getFn    func(ctx context.Context, id uuid.UUID) (modelsub.Subscription, error)
-	updateFn func(ctx context.Context, id uuid.UUID, s modelsub.Subscription) (modelsub.Subscription, error)
-	deleteFn func(ctx context.Context, id uuid.UUID) error
-	listFn   func(ctx context.Context, f modelsub.ListFilter) ([]modelsub.Subscription, int, error)
-	sumFn    func(ctx context.Context, f modelsub.SummaryFilter) (int64, error)
+	createFn   func(ctx context.Context, s modelsub.Subscription) (modelsub.Subscription, error)
+	getFn      func(ctx context.Context, id uuid.UUID) (modelsub.Subscription, error)
+	updateFn   func(ctx context.Context, id uuid.UUID, s modelsub.Subscription) (modelsub.Subscription, error)
+	deleteFn   func(ctx context.Context, id uuid.UUID) error
+	restoreFn  func(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (modelsub.Subscription, error)
+	listFn     func(ctx context.Context, f modelsub.ListFilter) ([]modelsub.Subscription, int, error)
+	sumFn      func(ctx context.Context, f modelsub.SummaryFilter) (int64, error)
+	auditFn    func(ctx context.Context, f modelaudit.Filter) ([]modelaudit.Entry, error)
+	upcomingFn func(ctx context.Context, f modelsub.UpcomingFilter, within time.Duration) ([]modelsub.Renewal, error)
+	pauseFn    func(ctx context.Context, id uuid.UUID, from time.Time) (modelsub.Subscription, error)
+	resumeFn   func(ctx context.Context, id uuid.UUID, from time.Time) (modelsub.Subscription, error)
+	pausesFn   func(ctx context.Context, id uuid.UUID) ([]modelsub.Pause, error)
+	setTagsFn  func(ctx context.Context, id uuid.UUID, names []string) (modelsub.Subscription, error)
+	byTagFn    func(ctx context.Context, f modelsub.SummaryFilter) ([]modelsub.TagTotal, error)
+	checkedFn  func(ctx context.Context, s modelsub.Subscription, policy string) (modelsub.Subscription, []modelsub.Subscription, error)
+	dupsFn     func(ctx context.Context, userID uuid.UUID) ([]modelsub.Overlap, error)
+	forecastFn func(ctx context.Context, f modelsub.SummaryFilter, months int) ([]modelsub.MonthTotal, error)
+	now        time.Time
+}
+
+func (m *mockUsecase) Now() time.Time {
+	if m.now.IsZero() {
+		return time.Now()
+	}
+	return m.now
 }
 
 func (m *mockUsecase) Create(ctx context.Context, s modelsub.Subscription) (modelsub.Subscription, error) {
@@ -34,12 +63,56 @@ func (m *mockUsecase) UpdateSub(ctx context.Context, id uuid.UUID, s modelsub.Su
 	return m.updateFn(ctx, id, s)
 }
 func (m *mockUsecase) Delete(ctx context.Context, id uuid.UUID) error { return m.deleteFn(ctx, id) }
+func (m *mockUsecase) Restore(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (modelsub.Subscription, error) {
+	return m.restoreFn(ctx, id, userID)
+}
 func (m *mockUsecase) List(ctx context.Context, f modelsub.ListFilter) ([]modelsub.Subscription, int, error) {
 	return m.listFn(ctx, f)
 }
 func (m *mockUsecase) Summary(ctx context.Context, f modelsub.SummaryFilter) (int64, error) {
 	return m.sumFn(ctx, f)
 }
+func (m *mockUsecase) AuditLog(ctx context.Context, f modelaudit.Filter) ([]modelaudit.Entry, error) {
+	return m.auditFn(ctx, f)
+}
+func (m *mockUsecase) Upcoming(ctx context.Context, f modelsub.UpcomingFilter, within time.Duration) ([]modelsub.Renewal, error) {
+	return m.upcomingFn(ctx, f, within)
+}
+func (m *mockUsecase) Pause(ctx context.Context, id uuid.UUID, from time.Time) (modelsub.Subscription, error) {
+	return m.pauseFn(ctx, id, from)
+}
+func (m *mockUsecase) Resume(ctx context.Context, id uuid.UUID, from time.Time) (modelsub.Subscription, error) {
+	return m.resumeFn(ctx, id, from)
+}
+func (m *mockUsecase) Pauses(ctx context.Context, id uuid.UUID) ([]modelsub.Pause, error) {
+	return m.pausesFn(ctx, id)
+}
+
+// CreateChecked falls back to createFn for tests that do not care about
+// the duplicate policy.
+func (m *mockUsecase) CreateChecked(ctx context.Context, s modelsub.Subscription, policy string) (modelsub.Subscription, []modelsub.Subscription, error) {
+	if m.checkedFn != nil {
+		return m.checkedFn(ctx, s, policy)
+	}
+	created, err := m.createFn(ctx, s)
+	return created, nil, err
+}
+
+func (m *mockUsecase) Forecast(ctx context.Context, f modelsub.SummaryFilter, months int) ([]modelsub.MonthTotal, error) {
+	return m.forecastFn(ctx, f, months)
+}
+
+func (m *mockUsecase) Duplicates(ctx context.Context, userID uuid.UUID) ([]modelsub.Overlap, error) {
+	return m.dupsFn(ctx, userID)
+}
+
+func (m *mockUsecase) SetTags(ctx context.Context, id uuid.UUID, names []string) (modelsub.Subscription, error) {
+	return m.setTagsFn(ctx, id, names)
+}
+
+func (m *mockUsecase) SummaryByTag(ctx context.Context, f modelsub.SummaryFilter) ([]modelsub.TagTotal, error) {
+	return m.byTagFn(ctx, f)
+}
 
 func TestCreateSubscription_OK(t *testing.T) {
 	now := time.Now().UTC()
@@ -177,3 +250,819 @@ func TestSummary_RequiresDates(t *testing.T) {
 		t.Fatalf("want %d, got %d", http.StatusBadRequest, w.Code)
 	}
 }
+
+func TestUserRoutes_FilterByPathUser(t *testing.T) {
+	userID := uuid.New()
+	var listed, summed *uuid.UUID
+	u := &mockUsecase{
+		listFn: func(_ context.Context, f modelsub.ListFilter) ([]modelsub.Subscription, int, error) {
+			listed = f.UserID
+			return nil, 0, nil
+		},
+		sumFn: func(_ context.Context, f modelsub.SummaryFilter) (int64, error) {
+			summed = f.UserID
+			return 1200, nil
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	// The users resource mounts /users next to the routes of this router.
+	users := func(r chi.Router, _ routes.Middlewares) {
+		r.Route("/users", func(r chi.Router) {
+			r.Get("/{id}/", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusTeapot) })
+		})
+	}
+	r := Router(log, New(log, u), WithMount(users))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+userID.String()+"/subscriptions?status=active", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || listed == nil || *listed != userID {
+		t.Fatalf("subscriptions: want %d for %s, got %d for %v", http.StatusOK, userID, w.Code, listed)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/users/"+userID.String()+"/summary?from=01-2025&to=12-2025", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || summed == nil || *summed != userID {
+		t.Fatalf("summary: want %d for %s, got %d for %v", http.StatusOK, userID, w.Code, summed)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/users/"+userID.String()+"/summary?from=01-2025&to=12-2025&user_id="+uuid.NewString(), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("conflicting user_id: want %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/users/"+userID.String()+"/", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusTeapot {
+		t.Fatalf("users mount: want %d, got %d", http.StatusTeapot, w.Code)
+	}
+}
+
+func TestWriteEndpoints_BodyTooLarge(t *testing.T) {
+	u := &mockUsecase{
+		createFn: func(context.Context, modelsub.Subscription) (modelsub.Subscription, error) {
+			t.Fatalf("usecase must not be called on oversize body")
+			return modelsub.Subscription{}, nil
+		},
+		updateFn: func(context.Context, uuid.UUID, modelsub.Subscription) (modelsub.Subscription, error) {
+			t.Fatalf("usecase must not be called on oversize body")
+			return modelsub.Subscription{}, nil
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	h := New(log, u)
+	r := Router(log, h, WithMaxBodyBytes(64))
+
+	body := `{"service_name":"` + strings.Repeat("x", 128) + `","price":400}`
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("create: want %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+
+	// Unknown length bodies are cut by the reader limit instead of the header check.
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/subscriptions/"+uuid.NewString()+"/", strings.NewReader(body))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("update: want %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+type staticAuthenticator map[string]modelauth.Principal
+
+func (a staticAuthenticator) Authenticate(_ context.Context, credentials string) (modelauth.Principal, error) {
+	p, ok := a[credentials]
+	if !ok {
+		return modelauth.Principal{}, authmid.ErrInvalidCredentials
+	}
+	return p, nil
+}
+
+func TestListSubscriptions_BoundCredentials(t *testing.T) {
+	bound := uuid.New()
+	u := &mockUsecase{
+		listFn: func(_ context.Context, f modelsub.ListFilter) ([]modelsub.Subscription, int, error) {
+			if f.UserID == nil || *f.UserID != bound {
+				t.Fatalf("user filter must be forced to bound user, got %v", f.UserID)
+			}
+			return nil, 0, nil
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	authn := authmid.Authenticate(log, map[string]authmid.Authenticator{
+		"ApiKey": staticAuthenticator{
+			"bound": {Subject: "apikey:bound", UserID: &bound, Scopes: []string{modelauth.ScopeSubscriptionsRead}},
+		},
+	})
+	r := Router(log, New(log, u), WithAuthenticator(authn, true))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous: want %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/", nil)
+	req.Header.Set("Authorization", "ApiKey bound")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bound: want %d, got %d", http.StatusOK, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/?user_id="+uuid.NewString(), nil)
+	req.Header.Set("Authorization", "ApiKey bound")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("foreign user: want %d, got %d", http.StatusForbidden, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions/", strings.NewReader("{}"))
+	req.Header.Set("Authorization", "ApiKey bound")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("missing write scope: want %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestListSubscriptions_Tenant(t *testing.T) {
+	var got string
+	u := &mockUsecase{
+		listFn: func(ctx context.Context, _ modelsub.ListFilter) ([]modelsub.Subscription, int, error) {
+			got = modeltenant.From(ctx)
+			return nil, 0, nil
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	authn := authmid.Authenticate(log, map[string]authmid.Authenticator{
+		"ApiKey": staticAuthenticator{
+			"acme":  {Subject: "apikey:acme", TenantID: "acme", Scopes: []string{modelauth.ScopeSubscriptionsRead}},
+			"admin": {Subject: "apikey:bootstrap", Scopes: []string{modelauth.ScopeAdmin}},
+		},
+	})
+	r := Router(log, New(log, u), WithAuthenticator(authn, false))
+
+	tests := []struct {
+		name, key, header string
+		wantStatus        int
+		wantTenant        string
+	}{
+		{name: "no tenant", wantStatus: http.StatusOK, wantTenant: modeltenant.Default},
+		{name: "anonymous with header", header: "globex", wantStatus: http.StatusForbidden},
+		{name: "admin with header", key: "admin", header: "globex", wantStatus: http.StatusOK, wantTenant: "globex"},
+		{name: "bound key", key: "acme", wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "bound key with another tenant", key: "acme", header: "globex", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		got = ""
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/", nil)
+		if tt.key != "" {
+			req.Header.Set("Authorization", "ApiKey "+tt.key)
+		}
+		if tt.header != "" {
+			req.Header.Set(modeltenant.Header, tt.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus {
+			t.Fatalf("%s: want %d, got %d", tt.name, tt.wantStatus, w.Code)
+		}
+		if got != tt.wantTenant {
+			t.Fatalf("%s: want tenant %q, got %q", tt.name, tt.wantTenant, got)
+		}
+	}
+}
+
+func TestAuditEndpoints(t *testing.T) {
+	subID := uuid.New()
+	userID := uuid.New()
+	var got []modelaudit.Filter
+	u := &mockUsecase{
+		auditFn: func(_ context.Context, f modelaudit.Filter) ([]modelaudit.Entry, error) {
+			got = append(got, f)
+			return []modelaudit.Entry{{
+				ID:             1,
+				SubscriptionID: subID,
+				UserID:         userID,
+				Operation:      modelaudit.OperationCreate,
+				Actor:          "anonymous",
+				After:          json.RawMessage(`{"price":400}`),
+			}}, nil
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	authn := authmid.Authenticate(log, map[string]authmid.Authenticator{
+		"ApiKey": staticAuthenticator{
+			"admin": {Subject: "apikey:admin", Scopes: []string{modelauth.ScopeAdmin}},
+		},
+	})
+	r := Router(log, New(log, u), WithAuthenticator(authn, false))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/"+subID.String()+"/history", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("history: want %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"after":{"price":400}`) {
+		t.Fatalf("snapshot must be embedded as json: %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit?operation=delete", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("audit anonymous: want %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit?operation=delete&user_id="+userID.String()+"&from=2025-07-01T00:00:00Z", nil)
+	req.Header.Set("Authorization", "ApiKey admin")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("audit admin: want %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("want 2 usecase calls, got %d", len(got))
+	}
+	if got[0].SubscriptionID == nil || *got[0].SubscriptionID != subID {
+		t.Fatalf("history must filter by subscription: %+v", got[0])
+	}
+	f := got[1]
+	if f.Operation == nil || *f.Operation != "delete" || f.UserID == nil || *f.UserID != userID || f.From == nil {
+		t.Fatalf("unexpected audit filter: %+v", f)
+	}
+}
+
+func TestSoftDeleteEndpoints(t *testing.T) {
+	id := uuid.New()
+	var listFilter modelsub.ListFilter
+	var sumFilter modelsub.SummaryFilter
+	u := &mockUsecase{
+		restoreFn: func(_ context.Context, got uuid.UUID, userID *uuid.UUID) (modelsub.Subscription, error) {
+			if got != id || userID != nil {
+				t.Fatalf("unexpected restore args: %s %v", got, userID)
+			}
+			return modelsub.Subscription{ID: id, ServiceName: "Netflix"}, nil
+		},
+		listFn: func(_ context.Context, f modelsub.ListFilter) ([]modelsub.Subscription, int, error) {
+			listFilter = f
+			return nil, 0, nil
+		},
+		sumFn: func(_ context.Context, f modelsub.SummaryFilter) (int64, error) {
+			sumFilter = f
+			return 0, nil
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	authn := authmid.Authenticate(log, map[string]authmid.Authenticator{
+		"ApiKey": staticAuthenticator{
+			"admin": {Subject: "apikey:admin", Scopes: []string{modelauth.ScopeAdmin}},
+		},
+	})
+	r := Router(log, New(log, u), WithAuthenticator(authn, false))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions/"+id.String()+"/restore", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("restore: want %d, got %d", http.StatusOK, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/?include_deleted=true", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("include_deleted anonymous: want %d, got %d", http.StatusForbidden, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/?include_deleted=true", nil)
+	req.Header.Set("Authorization", "ApiKey admin")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !listFilter.IncludeDeleted {
+		t.Fatalf("include_deleted admin: got %d, filter %+v", w.Code, listFilter)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/summary?from=01-2025&to=12-2025&exclude_deleted=true", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !sumFilter.ExcludeDeleted {
+		t.Fatalf("exclude_deleted: got %d, filter %+v", w.Code, sumFilter)
+	}
+}
+
+func TestUpcoming_ParsesWithinAndScopesUser(t *testing.T) {
+	bound := uuid.New()
+	now := time.Date(2025, time.June, 30, 23, 59, 0, 0, time.UTC)
+	charge := time.Date(2025, time.July, 5, 0, 0, 0, 0, time.UTC)
+	u := &mockUsecase{
+		now: now,
+		upcomingFn: func(_ context.Context, f modelsub.UpcomingFilter, within time.Duration) ([]modelsub.Renewal, error) {
+			if within != 14*24*time.Hour {
+				t.Fatalf("want 14 days, got %v", within)
+			}
+			if f.UserID == nil || *f.UserID != bound {
+				t.Fatalf("filter must be scoped to %s, got %v", bound, f.UserID)
+			}
+			return []modelsub.Renewal{
+				{Subscription: modelsub.Subscription{ID: uuid.New(), ServiceName: "Netflix", Price: 700, UserID: bound}, ChargeDate: charge, Amount: 700},
+			}, nil
+		},
+	}
+	log := logmid.NewLogger("error")
+	r := Router(log, New(log, u), WithAuthenticator(authmid.Authenticate(log, map[string]authmid.Authenticator{
+		"ApiKey": staticAuthenticator{
+			"bound": {Subject: "apikey:bound", UserID: &bound, Scopes: []string{modelauth.ScopeSubscriptionsRead}},
+		},
+	}), false))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/upcoming?within=14d", nil)
+	req.Header.Set("Authorization", "ApiKey bound")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp struct {
+		Total int64                  `json:"total"`
+		Items []modelsub.RenewalResp `json:"items"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Total != 700 || len(resp.Items) != 1 || resp.Items[0].DaysLeft != 5 || resp.Items[0].ChargeDate != charge.Format(time.DateOnly) {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/upcoming?within=soon", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("want %d for bad within, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestUpdateSubscription_InvalidTransition(t *testing.T) {
+	id := uuid.New()
+	userID := uuid.New()
+
+	u := &mockUsecase{
+		getFn: func(context.Context, uuid.UUID) (modelsub.Subscription, error) {
+			return modelsub.Subscription{ID: id, UserID: userID}, nil
+		},
+		updateFn: func(_ context.Context, _ uuid.UUID, s modelsub.Subscription) (modelsub.Subscription, error) {
+			if s.Status != modelsub.StatusPaused {
+				t.Fatalf("status must be passed through, got %q", s.Status)
+			}
+			return modelsub.Subscription{}, fmt.Errorf("%w: expired -> paused", myerrors.ErrorInvalidTransition)
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	r := Router(log, New(log, u))
+
+	body := `{"service_name":"Netflix","price":700,"user_id":"` + userID.String() + `","start_date":"07-2025","status":"paused"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/subscriptions/"+id.String()+"/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusConflict, w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/?status=gone", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown status filter: want %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	id := uuid.New()
+	userID := uuid.New()
+
+	var pausedFrom, resumedFrom time.Time
+	u := &mockUsecase{
+		getFn: func(context.Context, uuid.UUID) (modelsub.Subscription, error) {
+			return modelsub.Subscription{ID: id, UserID: userID}, nil
+		},
+		pauseFn: func(_ context.Context, _ uuid.UUID, from time.Time) (modelsub.Subscription, error) {
+			pausedFrom = from
+			return modelsub.Subscription{ID: id, UserID: userID, Status: modelsub.StatusPaused}, nil
+		},
+		resumeFn: func(_ context.Context, _ uuid.UUID, from time.Time) (modelsub.Subscription, error) {
+			resumedFrom = from
+			return modelsub.Subscription{}, fmt.Errorf("%w: active subscription cannot be resumed", myerrors.ErrorInvalidTransition)
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	r := Router(log, New(log, u))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions/"+id.String()+"/pause", strings.NewReader(`{"from":"09-2025"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("pause: want %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !pausedFrom.Equal(time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("pause month mismatch: %v", pausedFrom)
+	}
+	var resp modelsub.SubscriptionResp
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Status != modelsub.StatusPaused {
+		t.Fatalf("want paused subscription, got %+v (%v)", resp, err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions/"+id.String()+"/resume", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("resume: want %d, got %d, body=%s", http.StatusConflict, w.Code, w.Body.String())
+	}
+	if !resumedFrom.IsZero() {
+		t.Fatalf("empty body must leave the month to the usecase, got %v", resumedFrom)
+	}
+}
+
+func TestSummary_GroupByTag(t *testing.T) {
+	work := "work"
+	var got modelsub.SummaryFilter
+	u := &mockUsecase{
+		sumFn: func(_ context.Context, f modelsub.SummaryFilter) (int64, error) {
+			got = f
+			return 1500, nil
+		},
+		byTagFn: func(context.Context, modelsub.SummaryFilter) ([]modelsub.TagTotal, error) {
+			return []modelsub.TagTotal{{Tag: &work, Total: 1200}, {Total: 300}}, nil
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	r := Router(log, New(log, u))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/summary?from=01-2025&to=12-2025&tag=work,music&tag_mode=all&group_by=tag", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "work" || got.Tags[1] != "music" || !got.TagsAll {
+		t.Fatalf("unexpected tag filter: %+v", got)
+	}
+	var resp struct {
+		Total  int64                   `json:"total"`
+		Groups []modelsub.TagTotalResp `json:"groups"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Total != 1500 || len(resp.Groups) != 2 || resp.Groups[1].Tag != nil || resp.Groups[1].Total != 300 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/summary?from=01-2025&to=12-2025&group_by=service", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown group_by: want %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestSummary_Compare(t *testing.T) {
+	work, music := "work", "music"
+	var periods []string
+	u := &mockUsecase{
+		sumFn: func(_ context.Context, f modelsub.SummaryFilter) (int64, error) {
+			periods = append(periods, modeldate.FormatMonthYear(f.From)+"/"+modeldate.FormatMonthYear(f.To))
+			if f.From.Year() == 2025 && f.From.Month() == time.April {
+				return 1500, nil
+			}
+			return 1200, nil
+		},
+		byTagFn: func(_ context.Context, f modelsub.SummaryFilter) ([]modelsub.TagTotal, error) {
+			if f.From.Month() == time.April {
+				return []modelsub.TagTotal{{Tag: &work, Total: 1000}, {Total: 500}}, nil
+			}
+			return []modelsub.TagTotal{{Tag: &music, Total: 400}, {Tag: &work, Total: 800}}, nil
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	r := Router(log, New(log, u))
+
+	tests := []struct {
+		name    string
+		compare string
+		want    string
+	}{
+		{"previous period", "previous_period", "01-2025/03-2025"},
+		{"previous year", "previous_year", "04-2024/06-2024"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			periods = nil
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/summary?from=04-2025&to=06-2025&compare="+tt.compare, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("want %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+			}
+			if len(periods) != 2 || periods[0] != "04-2025/06-2025" || periods[1] != tt.want {
+				t.Fatalf("unexpected periods: %v", periods)
+			}
+			var resp struct {
+				Total   int64                `json:"total"`
+				Compare modelsub.CompareResp `json:"compare"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			c := resp.Compare
+			if resp.Total != 1500 || c.Mode != tt.compare || c.Total != 1200 || c.Delta != 300 || c.DeltaPct == nil || *c.DeltaPct != 25 {
+				t.Fatalf("unexpected response: %+v", resp)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/summary?from=04-2025&to=06-2025&compare=previous_period&group_by=tag", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp struct {
+		Groups []modelsub.TagCompareResp `json:"groups"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Groups) != 3 {
+		t.Fatalf("unexpected groups: %+v", resp.Groups)
+	}
+	if g := resp.Groups[0]; *g.Tag != "work" || g.PreviousTotal != 800 || g.Delta != 200 || *g.DeltaPct != 25 {
+		t.Fatalf("unexpected work group: %+v", g)
+	}
+	if g := resp.Groups[1]; g.Tag != nil || g.PreviousTotal != 0 || g.Delta != 500 || g.DeltaPct != nil {
+		t.Fatalf("unexpected untagged group: %+v", g)
+	}
+	if g := resp.Groups[2]; *g.Tag != "music" || g.Total != 0 || g.Delta != -400 || *g.DeltaPct != -100 {
+		t.Fatalf("unexpected music group: %+v", g)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/summary?from=04-2025&to=06-2025&compare=last_week", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown compare: want %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestSetSubscriptionTags(t *testing.T) {
+	id := uuid.New()
+	userID := uuid.New()
+	u := &mockUsecase{
+		getFn: func(context.Context, uuid.UUID) (modelsub.Subscription, error) {
+			return modelsub.Subscription{ID: id, UserID: userID}, nil
+		},
+		setTagsFn: func(_ context.Context, _ uuid.UUID, names []string) (modelsub.Subscription, error) {
+			if len(names) == 0 {
+				return modelsub.Subscription{ID: id, UserID: userID}, nil
+			}
+			return modelsub.Subscription{}, fmt.Errorf("%w: tag name %q must not contain commas", myerrors.ErrorValidation, names[0])
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	r := Router(log, New(log, u))
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/subscriptions/"+id.String()+"/tags", strings.NewReader(`{"tags":[]}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"tags":[]`) {
+		t.Fatalf("untagged subscription must answer an empty tags array, body=%s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/subscriptions/"+id.String()+"/tags", strings.NewReader(`{"tags":["a,b"]}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func TestCreateSubscription_BudgetExceeded(t *testing.T) {
+	u := &mockUsecase{
+		createFn: func(context.Context, modelsub.Subscription) (modelsub.Subscription, error) {
+			return modelsub.Subscription{}, fmt.Errorf("%w: 07-2025 would bring the spend to 1100 of 1000", myerrors.ErrorBudgetExceeded)
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	r := Router(log, New(log, u))
+
+	body := `{"service_name":"Netflix","price":700,"user_id":"` + uuid.NewString() + `","start_date":"07-2025"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+}
+
+func TestCreateSubscription_OnDuplicate(t *testing.T) {
+	userID := uuid.New()
+	dup := modelsub.Subscription{ID: uuid.New(), UserID: userID, ServiceName: "Netflix", Price: 700}
+	var policies []string
+	u := &mockUsecase{
+		checkedFn: func(_ context.Context, s modelsub.Subscription, policy string) (modelsub.Subscription, []modelsub.Subscription, error) {
+			policies = append(policies, policy)
+			if policy == modelsub.DuplicateReject {
+				return modelsub.Subscription{}, []modelsub.Subscription{dup}, fmt.Errorf("%w: subscription to Netflix overlaps 1 existing one(s)", myerrors.ErrorConflict)
+			}
+			s.ID = uuid.New()
+			return s, []modelsub.Subscription{dup}, nil
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	r := Router(log, New(log, u))
+	body := `{"service_name":"Netflix","price":300,"user_id":"` + userID.String() + `","start_date":"07-2025"}`
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions/?on_duplicate=reject", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), dup.ID.String()) {
+		t.Fatalf("reject: want %d listing the duplicate, got %d, body=%s", http.StatusConflict, w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions/?on_duplicate=warn", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("warn: want %d, got %d, body=%s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var resp modelsub.SubscriptionResp
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || len(resp.DuplicateOf) != 1 || resp.DuplicateOf[0] != dup.ID.String() {
+		t.Fatalf("warn: want duplicate_of, got %+v (%v)", resp, err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions/", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if len(policies) != 3 || policies[2] != modelsub.DuplicateAllow {
+		t.Fatalf("policy must default to allow, got %q", policies)
+	}
+}
+
+func TestDuplicates_BoundCredentials(t *testing.T) {
+	bound := uuid.New()
+	end := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	u := &mockUsecase{
+		dupsFn: func(_ context.Context, userID uuid.UUID) ([]modelsub.Overlap, error) {
+			if userID != bound {
+				t.Fatalf("duplicates of %s requested", userID)
+			}
+			return []modelsub.Overlap{{
+				First:  modelsub.Subscription{ID: uuid.New(), ServiceName: "Netflix"},
+				Second: modelsub.Subscription{ID: uuid.New(), ServiceName: "Netflix"},
+				From:   time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC),
+				To:     &end,
+			}}, nil
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	h := New(log, u)
+	r := Router(log, h)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+bound.String()+"/duplicates", nil)
+	req = req.WithContext(modelauth.WithPrincipal(req.Context(), modelauth.Principal{Subject: "apikey:bound", UserID: &bound, Scopes: []string{modelauth.ScopeSubscriptionsRead}}))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"from":"03-2025"`) || !strings.Contains(w.Body.String(), `"to":"06-2025"`) {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/users/"+uuid.NewString()+"/duplicates", nil)
+	req = req.WithContext(modelauth.WithPrincipal(req.Context(), modelauth.Principal{Subject: "apikey:bound", UserID: &bound, Scopes: []string{modelauth.ScopeSubscriptionsRead}}))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("other user: want %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestForecast(t *testing.T) {
+	july := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	var gotMonths int
+	u := &mockUsecase{
+		forecastFn: func(_ context.Context, f modelsub.SummaryFilter, months int) ([]modelsub.MonthTotal, error) {
+			gotMonths = months
+			if f.ServiceName == nil || *f.ServiceName != "Netflix" {
+				t.Fatalf("service filter mismatch: %+v", f)
+			}
+			return []modelsub.MonthTotal{
+				{Month: july, Total: 700, Cumulative: 700},
+				{Month: july.AddDate(0, 1, 0), Total: 700, Cumulative: 1400},
+			}, nil
+		},
+	}
+
+	log := logmid.NewLogger("error")
+	r := Router(log, New(log, u))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/forecast?service_name=Netflix", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if gotMonths != 12 {
+		t.Fatalf("months must default to 12, got %d", gotMonths)
+	}
+	var resp struct {
+		Total  int64                     `json:"total"`
+		From   string                    `json:"from"`
+		To     string                    `json:"to"`
+		Months []modelsub.MonthTotalResp `json:"months"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Total != 1400 || resp.From != "07-2025" || resp.To != "08-2025" || len(resp.Months) != 2 || resp.Months[1].Cumulative != 1400 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/forecast?months=twelve", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid months: want %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestListSubscriptions_TrialEndingWithinUsesUsecaseClock(t *testing.T) {
+	now := time.Date(2025, time.June, 30, 23, 59, 0, 0, time.UTC)
+	var got modelsub.ListFilter
+	u := &mockUsecase{
+		now: now,
+		listFn: func(_ context.Context, f modelsub.ListFilter) ([]modelsub.Subscription, int, error) {
+			got = f
+			return nil, 0, nil
+		},
+	}
+	log := logmid.NewLogger("error")
+	r := Router(log, New(log, u))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/?trial_ending_within=7d", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got.TrialEndsAfter == nil || !got.TrialEndsAfter.Equal(now) ||
+		got.TrialEndsBefore == nil || !got.TrialEndsBefore.Equal(now.AddDate(0, 0, 7)) {
+		t.Fatalf("want trials ending in [%v, %v], got %v and %v", now, now.AddDate(0, 0, 7), got.TrialEndsAfter, got.TrialEndsBefore)
+	}
+}
@@ -0,0 +1,113 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	modeldate "test_task/internal/domain/models/month_year"
+	modelsub "test_task/internal/domain/models/subscription"
+	bodylimit "test_task/internal/middleware/body_limit_middleware"
+	JSONRes "test_task/pkg/JSON_response"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// PauseSubscription stops billing until the subscription is resumed.
+func (h *Handler) PauseSubscription(w http.ResponseWriter, r *http.Request) {
+	var req modelsub.PauseReq
+	h.changePause(w, r, &req, &req.From, h.usecase.Pause)
+}
+
+// ResumeSubscription ends the open pause.
+func (h *Handler) ResumeSubscription(w http.ResponseWriter, r *http.Request) {
+	var req modelsub.ResumeReq
+	h.changePause(w, r, &req, &req.From, h.usecase.Resume)
+}
+
+func (h *Handler) changePause(
+	w http.ResponseWriter,
+	r *http.Request,
+	req any,
+	from **string,
+	change func(ctx context.Context, id uuid.UUID, from time.Time) (modelsub.Subscription, error),
+) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+		return
+	}
+
+	// The body is optional, both calls default to the current month.
+	if !bodylimit.DecodeOptional(w, r, req) {
+		return
+	}
+
+	var month time.Time
+	if *from != nil {
+		month, err = modeldate.ParseMonthYear(**from)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if !h.ensureOwned(w, r, id) {
+		return
+	}
+
+	s, err := change(r.Context(), id, month)
+	if err != nil {
+		if errors.Is(err, myerrors.ErrorNotFound) {
+			JSONRes.WriteJSON(w, http.StatusNotFound, "subscription not found")
+			return
+		}
+		if writeStatusErr(w, err) {
+			return
+		}
+		h.log.Error("change pause failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to change pause")
+		return
+	}
+
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(s))
+}
+
+// Pauses lists the pause periods of a subscription.
+func (h *Handler) Pauses(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+		return
+	}
+	if !h.ensureOwned(w, r, id) {
+		return
+	}
+
+	pauses, err := h.usecase.Pauses(r.Context(), id)
+	if err != nil {
+		h.log.Error("list pauses failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to list pauses")
+		return
+	}
+
+	items := make([]modelsub.PauseResp, 0, len(pauses))
+	for _, p := range pauses {
+		var to *string
+		if p.To != nil {
+			v := modeldate.FormatMonthYear(*p.To)
+			to = &v
+		}
+		items = append(items, modelsub.PauseResp{
+			ID:        p.ID,
+			From:      modeldate.FormatMonthYear(p.From),
+			To:        to,
+			CreatedAt: p.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, map[string]any{"items": items})
+}
@@ -7,11 +7,65 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
+	modelauth "test_task/internal/domain/models/auth"
+	"test_task/internal/handlers/routes"
 	logmid "test_task/internal/middleware/loger_middleware"
+	ratelimit "test_task/internal/middleware/rate_limit_middleware"
+	tenantmid "test_task/internal/middleware/tenant_middleware"
 	"test_task/swagger"
 )
 
-func Router(log *slog.Logger, h *Handler) http.Handler {
+type routerOptions struct {
+	mw            routes.Middlewares
+	authenticator func(http.Handler) http.Handler
+	mounts        []routes.Mount
+}
+
+// Option customises the router built by Router.
+type Option func(*routerOptions)
+
+// WithMaxBodyBytes limits the size of JSON bodies accepted by write endpoints.
+func WithMaxBodyBytes(n int64) Option {
+	return func(o *routerOptions) {
+		o.mw.MaxBodyBytes = n
+	}
+}
+
+// WithRateLimiter applies per-client budgets to the read, write and summary
+// route groups and a per-IP budget in front of authentication.
+func WithRateLimiter(l *ratelimit.Limiter) Option {
+	return func(o *routerOptions) {
+		o.mw.Limiter = l
+	}
+}
+
+// WithAuthenticator installs the middleware that resolves the request
+// principal. When required is set anonymous requests are rejected.
+func WithAuthenticator(mw func(http.Handler) http.Handler, required bool) Option {
+	return func(o *routerOptions) {
+		o.authenticator = mw
+		o.mw.AuthRequired = required
+	}
+}
+
+// WithMount registers an additional route group under /api/v1.
+func WithMount(m routes.Mount) Option {
+	return func(o *routerOptions) {
+		o.mounts = append(o.mounts, m)
+	}
+}
+
+func Router(log *slog.Logger, h *Handler, opts ...Option) http.Handler {
+	var o routerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	mw := o.mw
+	limitBody := mw.LimitBody()
+	read := chi.Chain(mw.Authorize(modelauth.ScopeSubscriptionsRead), mw.Limit(ratelimit.GroupRead))
+	write := chi.Chain(mw.Authorize(modelauth.ScopeSubscriptionsWrite), mw.Limit(ratelimit.GroupWrite))
+	summary := chi.Chain(mw.Authorize(modelauth.ScopeSubscriptionsRead), mw.Limit(ratelimit.GroupSummary))
+
 	r := chi.NewRouter()
 
 	r.Use(middleware.RealIP)
@@ -33,18 +87,42 @@ func Router(log *slog.Logger, h *Handler) http.Handler {
 	))
 
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(mw.Limiter.MiddlewareByIP(ratelimit.GroupIP))
+		if o.authenticator != nil {
+			r.Use(o.authenticator)
+		}
+		r.Use(tenantmid.Resolve)
+
 		r.Route("/subscriptions", func(r chi.Router) {
-			r.Get("/", h.ListSubscriptions)
-			r.Post("/", h.CreateSubscription)
+			r.With(read...).Get("/", h.ListSubscriptions)
+			r.With(write...).With(limitBody).Post("/", h.CreateSubscription)
 
-			r.Get("/summary", h.Summary)
+			r.With(summary...).Get("/summary", h.Summary)
+			r.With(summary...).Get("/forecast", h.Forecast)
+			r.With(read...).Get("/upcoming", h.Upcoming)
 
 			r.Route("/{id}", func(r chi.Router) {
-				r.Get("/", h.GetSubscription)
-				r.Put("/", h.UpdateSubscription)
-				r.Delete("/", h.DeleteSubscription)
+				r.With(read...).Get("/", h.GetSubscription)
+				r.With(write...).With(limitBody).Put("/", h.UpdateSubscription)
+				r.With(write...).Delete("/", h.DeleteSubscription)
+				r.With(write...).Post("/restore", h.RestoreSubscription)
+				r.With(write...).With(limitBody).Post("/pause", h.PauseSubscription)
+				r.With(write...).With(limitBody).Post("/resume", h.ResumeSubscription)
+				r.With(read...).Get("/pauses", h.Pauses)
+				r.With(write...).With(limitBody).Put("/tags", h.SetSubscriptionTags)
+				r.With(read...).Get("/history", h.History)
 			})
 		})
+
+		r.With(read...).Get("/users/{id}/duplicates", h.Duplicates)
+		r.With(read...).Get("/users/{id}/subscriptions", h.UserSubscriptions)
+		r.With(summary...).Get("/users/{id}/summary", h.UserSummary)
+
+		r.With(mw.Admin(), mw.Limit(ratelimit.GroupRead)).Get("/admin/audit", h.AuditLog)
+
+		for _, m := range o.mounts {
+			m(r, mw)
+		}
 	})
 
 	return r
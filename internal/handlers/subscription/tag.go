@@ -0,0 +1,73 @@
+package subscription
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	modelsub "test_task/internal/domain/models/subscription"
+	modeltag "test_task/internal/domain/models/tag"
+	bodylimit "test_task/internal/middleware/body_limit_middleware"
+	JSONRes "test_task/pkg/JSON_response"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// SetSubscriptionTags replaces the tags of a subscription.
+func (h *Handler) SetSubscriptionTags(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+		return
+	}
+
+	var req modelsub.TagsReq
+	if !bodylimit.Decode(w, r, &req) {
+		return
+	}
+
+	if !h.ensureOwned(w, r, id) {
+		return
+	}
+
+	s, err := h.usecase.SetTags(r.Context(), id, req.Tags)
+	if err != nil {
+		switch {
+		case errors.Is(err, myerrors.ErrorNotFound):
+			JSONRes.WriteJSON(w, http.StatusNotFound, "subscription not found")
+		case errors.Is(err, myerrors.ErrorValidation):
+			JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+		default:
+			h.log.Error("set tags failed", slog.Any("err", err))
+			JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to set tags")
+		}
+		return
+	}
+
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(s))
+}
+
+// parseTagFilter reads the tag filter of list and summary requests: tag may
+// repeat or hold comma separated names, tag_mode is any (default) or all.
+func parseTagFilter(q url.Values) (tags []string, all bool, err error) {
+	for _, v := range q["tag"] {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				tags = append(tags, name)
+			}
+		}
+	}
+
+	switch mode := strings.TrimSpace(q.Get("tag_mode")); mode {
+	case "", modeltag.MatchAny:
+	case modeltag.MatchAll:
+		all = true
+	default:
+		return nil, false, errors.New("tag_mode must be any or all")
+	}
+	return tags, all, nil
+}
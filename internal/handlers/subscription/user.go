@@ -0,0 +1,41 @@
+package subscription
+
+import (
+	"net/http"
+
+	JSONRes "test_task/pkg/JSON_response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// UserSubscriptions lists the subscriptions of the user in the path; it
+// takes the filters of ListSubscriptions except user_id.
+func (h *Handler) UserSubscriptions(w http.ResponseWriter, r *http.Request) {
+	withPathUser(w, r, h.ListSubscriptions)
+}
+
+// UserSummary sums the subscriptions of the user in the path; it takes the
+// parameters of Summary except user_id.
+func (h *Handler) UserSummary(w http.ResponseWriter, r *http.Request) {
+	withPathUser(w, r, h.Summary)
+}
+
+// withPathUser serves r with next, filtered by the user in the path.
+func withPathUser(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+		return
+	}
+	q := r.URL.Query()
+	if v := q.Get("user_id"); v != "" && v != userID.String() {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "user_id must not differ from the user in the path")
+		return
+	}
+	q.Set("user_id", userID.String())
+
+	r = r.Clone(r.Context())
+	r.URL.RawQuery = q.Encode()
+	next(w, r)
+}
@@ -0,0 +1,200 @@
+package tag
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	modeltag "test_task/internal/domain/models/tag"
+	bodylimit "test_task/internal/middleware/body_limit_middleware"
+	JSONRes "test_task/pkg/JSON_response"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type UsecaseI interface {
+	Create(ctx context.Context, t modeltag.Tag) (modeltag.Tag, error)
+	Get(ctx context.Context, id uuid.UUID) (modeltag.Tag, error)
+	List(ctx context.Context, userID *uuid.UUID) ([]modeltag.Tag, error)
+	Rename(ctx context.Context, id uuid.UUID, name string) (modeltag.Tag, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type Handler struct {
+	log     *slog.Logger
+	usecase UsecaseI
+}
+
+func New(log *slog.Logger, usecase UsecaseI) *Handler {
+	return &Handler{log: log, usecase: usecase}
+}
+
+func toResp(t modeltag.Tag) modeltag.TagResp {
+	return modeltag.TagResp{
+		ID:        t.ID.String(),
+		UserID:    t.UserID.String(),
+		Name:      t.Name,
+		CreatedAt: t.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt: t.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// boundUser returns the user the caller's credentials are restricted to.
+func boundUser(r *http.Request) *uuid.UUID {
+	if p, ok := modelauth.PrincipalFrom(r.Context()); ok {
+		return p.UserID
+	}
+	return nil
+}
+
+// load fetches the tag in the URL, answering 404 for tags of another user
+// than the one the credentials are bound to.
+func (h *Handler) load(w http.ResponseWriter, r *http.Request) (modeltag.Tag, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+		return modeltag.Tag{}, false
+	}
+	t, err := h.usecase.Get(r.Context(), id)
+	if err != nil && !errors.Is(err, myerrors.ErrorNotFound) {
+		h.log.Error("get tag failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to get tag")
+		return modeltag.Tag{}, false
+	}
+	if bound := boundUser(r); err != nil || (bound != nil && t.UserID != *bound) {
+		JSONRes.WriteJSON(w, http.StatusNotFound, "tag not found")
+		return modeltag.Tag{}, false
+	}
+	return t, true
+}
+
+// writeErr answers the errors shared by the write endpoints.
+func (h *Handler) writeErr(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, myerrors.ErrorValidation):
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, myerrors.ErrorConflict):
+		JSONRes.WriteJSON(w, http.StatusConflict, err.Error())
+	case errors.Is(err, myerrors.ErrorNotFound):
+		JSONRes.WriteJSON(w, http.StatusNotFound, "tag not found")
+	default:
+		h.log.Error(op+" tag failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to "+op+" tag")
+	}
+}
+
+func (h *Handler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var req modeltag.TagReq
+	if !bodylimit.Decode(w, r, &req) {
+		return
+	}
+
+	t := modeltag.Tag{Name: req.Name}
+	if v := strings.TrimSpace(req.UserID); v != "" {
+		userID, err := uuid.Parse(v)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "user_id must be a valid UUID")
+			return
+		}
+		t.UserID = userID
+	}
+	if bound := boundUser(r); bound != nil {
+		if t.UserID != uuid.Nil && t.UserID != *bound {
+			JSONRes.WriteJSON(w, http.StatusForbidden, "user_id is not allowed for these credentials")
+			return
+		}
+		t.UserID = *bound
+	}
+	if t.UserID == uuid.Nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "user_id is required")
+		return
+	}
+
+	created, err := h.usecase.Create(r.Context(), t)
+	if err != nil {
+		h.writeErr(w, "create", err)
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusCreated, toResp(created))
+}
+
+func (h *Handler) ListTags(w http.ResponseWriter, r *http.Request) {
+	var userID *uuid.UUID
+	if v := strings.TrimSpace(r.URL.Query().Get("user_id")); v != "" {
+		parsed, err := uuid.Parse(v)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "user_id must be a valid UUID")
+			return
+		}
+		userID = &parsed
+	}
+	if bound := boundUser(r); bound != nil {
+		if userID != nil && *userID != *bound {
+			JSONRes.WriteJSON(w, http.StatusForbidden, "user_id is not allowed for these credentials")
+			return
+		}
+		userID = bound
+	}
+
+	tags, err := h.usecase.List(r.Context(), userID)
+	if err != nil {
+		h.log.Error("list tags failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to list tags")
+		return
+	}
+
+	items := make([]modeltag.TagResp, 0, len(tags))
+	for _, t := range tags {
+		items = append(items, toResp(t))
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, map[string]any{
+		"total": len(items),
+		"items": items,
+	})
+}
+
+func (h *Handler) GetTag(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(t))
+}
+
+// RenameTag changes the name of a tag; the owner cannot be changed.
+func (h *Handler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	var req modeltag.TagReq
+	if !bodylimit.Decode(w, r, &req) {
+		return
+	}
+
+	renamed, err := h.usecase.Rename(r.Context(), t.ID, req.Name)
+	if err != nil {
+		h.writeErr(w, "rename", err)
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(renamed))
+}
+
+func (h *Handler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.usecase.Delete(r.Context(), t.ID); err != nil {
+		h.writeErr(w, "delete", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,113 @@
+package tag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	modeltag "test_task/internal/domain/models/tag"
+	"test_task/internal/handlers/routes"
+	authmid "test_task/internal/middleware/auth_middleware"
+	logmid "test_task/internal/middleware/loger_middleware"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type mockUsecase struct {
+	createFn func(ctx context.Context, t modeltag.Tag) (modeltag.Tag, error)
+	getFn    func(ctx context.Context, id uuid.UUID) (modeltag.Tag, error)
+	deleteFn func(ctx context.Context, id uuid.UUID) error
+}
+
+func (m *mockUsecase) Create(ctx context.Context, t modeltag.Tag) (modeltag.Tag, error) {
+	return m.createFn(ctx, t)
+}
+func (m *mockUsecase) Get(ctx context.Context, id uuid.UUID) (modeltag.Tag, error) {
+	return m.getFn(ctx, id)
+}
+func (m *mockUsecase) List(context.Context, *uuid.UUID) ([]modeltag.Tag, error) { return nil, nil }
+func (m *mockUsecase) Rename(context.Context, uuid.UUID, string) (modeltag.Tag, error) {
+	return modeltag.Tag{}, nil
+}
+func (m *mockUsecase) Delete(ctx context.Context, id uuid.UUID) error { return m.deleteFn(ctx, id) }
+
+type staticAuthenticator map[string]modelauth.Principal
+
+func (a staticAuthenticator) Authenticate(_ context.Context, credentials string) (modelauth.Principal, error) {
+	p, ok := a[credentials]
+	if !ok {
+		return modelauth.Principal{}, authmid.ErrInvalidCredentials
+	}
+	return p, nil
+}
+
+func newTestRouter(u UsecaseI, bound uuid.UUID) http.Handler {
+	log := logmid.NewLogger("error")
+	r := chi.NewRouter()
+	r.Use(authmid.Authenticate(log, map[string]authmid.Authenticator{
+		"ApiKey": staticAuthenticator{
+			"bound": {
+				Subject: "apikey:bound",
+				UserID:  &bound,
+				Scopes:  []string{modelauth.ScopeSubscriptionsRead, modelauth.ScopeSubscriptionsWrite},
+			},
+		},
+	}))
+	Routes(New(log, u))(r, routes.Middlewares{})
+	return r
+}
+
+func TestCreateTag_DuplicateConflicts(t *testing.T) {
+	bound := uuid.New()
+	u := &mockUsecase{
+		createFn: func(_ context.Context, tag modeltag.Tag) (modeltag.Tag, error) {
+			if tag.UserID != bound {
+				t.Fatalf("tag must belong to %s, got %s", bound, tag.UserID)
+			}
+			return modeltag.Tag{}, fmt.Errorf("%w: tag %q", myerrors.ErrorConflict, tag.Name)
+		},
+	}
+	r := newTestRouter(u, bound)
+
+	b, _ := json.Marshal(map[string]any{"name": "Work"})
+	req := httptest.NewRequest(http.MethodPost, "/tags/", bytes.NewReader(b))
+	req.Header.Set("Authorization", "ApiKey bound")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusConflict, w.Code, w.Body.String())
+	}
+}
+
+func TestDeleteTag_OtherUsersTagIsNotFound(t *testing.T) {
+	bound := uuid.New()
+	id := uuid.New()
+	u := &mockUsecase{
+		getFn: func(context.Context, uuid.UUID) (modeltag.Tag, error) {
+			return modeltag.Tag{ID: id, UserID: uuid.New(), Name: "Work", CreatedAt: time.Now(), UpdatedAt: time.Now()}, nil
+		},
+		deleteFn: func(context.Context, uuid.UUID) error {
+			t.Fatalf("tag of another user must not be deleted")
+			return nil
+		},
+	}
+	r := newTestRouter(u, bound)
+
+	req := httptest.NewRequest(http.MethodDelete, "/tags/"+id.String()+"/", nil)
+	req.Header.Set("Authorization", "ApiKey bound")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+}
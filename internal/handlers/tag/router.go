@@ -0,0 +1,29 @@
+package tag
+
+import (
+	modelauth "test_task/internal/domain/models/auth"
+	"test_task/internal/handlers/routes"
+	ratelimit "test_task/internal/middleware/rate_limit_middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Routes returns the tag endpoints for the /api/v1 router. Tags are put on
+// subscriptions with PUT /subscriptions/{id}/tags.
+func Routes(h *Handler) routes.Mount {
+	return func(r chi.Router, mw routes.Middlewares) {
+		read := chi.Chain(mw.Authorize(modelauth.ScopeSubscriptionsRead), mw.Limit(ratelimit.GroupRead))
+		write := chi.Chain(mw.Authorize(modelauth.ScopeSubscriptionsWrite), mw.Limit(ratelimit.GroupWrite))
+
+		r.Route("/tags", func(r chi.Router) {
+			r.With(read...).Get("/", h.ListTags)
+			r.With(write...).With(mw.LimitBody()).Post("/", h.CreateTag)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(read...).Get("/", h.GetTag)
+				r.With(write...).With(mw.LimitBody()).Put("/", h.RenameTag)
+				r.With(write...).Delete("/", h.DeleteTag)
+			})
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package user
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	modeluser "test_task/internal/domain/models/user"
+	JSONRes "test_task/pkg/JSON_response"
+	myerrors "test_task/pkg/global_errors"
+)
+
+// exportResp renders the profile of an export like the other user endpoints.
+type exportResp struct {
+	Profile *modeluser.UserResp `json:"profile"`
+	modeluser.Export
+}
+
+// ExportData answers everything held about the user in the path, registered
+// or not: one JSON document, or with ?format=zip an archive with a JSON file
+// per section.
+func (h *Handler) ExportData(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathUser(w, r)
+	if !ok {
+		return
+	}
+	format := strings.TrimSpace(r.URL.Query().Get("format"))
+	if format == "" {
+		format = modeluser.ExportJSON
+	}
+	if !slices.Contains(modeluser.ExportFormats, format) {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "format must be one of "+strings.Join(modeluser.ExportFormats, ", "))
+		return
+	}
+
+	e, err := h.usecase.Export(r.Context(), id)
+	if errors.Is(err, myerrors.ErrorNotFound) {
+		JSONRes.WriteJSON(w, http.StatusNotFound, "no data held about the user")
+		return
+	}
+	if err != nil {
+		h.log.Error("export user data failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to export user data")
+		return
+	}
+
+	resp := exportResp{Export: e}
+	if e.Profile != nil {
+		p := toResp(*e.Profile)
+		resp.Profile = &p
+	}
+	if format == modeluser.ExportJSON {
+		JSONRes.WriteJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	archive, err := zipExport(resp)
+	if err != nil {
+		h.log.Error("export user data failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to export user data")
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="user-`+id.String()+`.zip"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(archive)
+}
+
+// zipExport packs every section of the export into a JSON file of its own.
+// The archive is built in memory, so a failure still answers 500.
+func zipExport(e exportResp) ([]byte, error) {
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", e.Profile},
+		{"subscriptions.json", e.Subscriptions},
+		{"pauses.json", e.Pauses},
+		{"price_history.json", e.PriceHistory},
+		{"tags.json", e.Tags},
+		{"budgets.json", e.Budgets},
+		{"audit.json", e.Audit},
+		{"notifications.json", e.Notifications},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: e.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EraseData erases the user in the path, registered or not, and answers with
+// the number of rows removed or pseudonymized per kind.
+func (h *Handler) EraseData(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathUser(w, r)
+	if !ok {
+		return
+	}
+	e, err := h.usecase.Erase(r.Context(), id)
+	if errors.Is(err, myerrors.ErrorNotFound) {
+		JSONRes.WriteJSON(w, http.StatusNotFound, "no data held about the user")
+		return
+	}
+	if err != nil {
+		h.log.Error("erase user data failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to erase user data")
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, e)
+}
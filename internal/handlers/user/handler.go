@@ -0,0 +1,252 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	modeluser "test_task/internal/domain/models/user"
+	bodylimit "test_task/internal/middleware/body_limit_middleware"
+	JSONRes "test_task/pkg/JSON_response"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type UsecaseI interface {
+	Create(ctx context.Context, u modeluser.User) (modeluser.User, error)
+	Get(ctx context.Context, id uuid.UUID) (modeluser.User, error)
+	List(ctx context.Context, f modeluser.Filter) ([]modeluser.User, error)
+	Update(ctx context.Context, u modeluser.User) (modeluser.User, error)
+	Delete(ctx context.Context, id uuid.UUID, mode string) (*modeluser.User, error)
+	Export(ctx context.Context, id uuid.UUID) (modeluser.Export, error)
+	Erase(ctx context.Context, id uuid.UUID) (modeluser.Erasure, error)
+}
+
+type Handler struct {
+	log     *slog.Logger
+	usecase UsecaseI
+}
+
+func New(log *slog.Logger, usecase UsecaseI) *Handler {
+	return &Handler{log: log, usecase: usecase}
+}
+
+func toResp(u modeluser.User) modeluser.UserResp {
+	var anonymized *string
+	if u.AnonymizedAt != nil {
+		v := u.AnonymizedAt.UTC().Format(time.RFC3339)
+		anonymized = &v
+	}
+	return modeluser.UserResp{
+		ID:              u.ID.String(),
+		DisplayName:     u.DisplayName,
+		Email:           u.Email,
+		Timezone:        u.Timezone,
+		DefaultCurrency: u.DefaultCurrency,
+		AnonymizedAt:    anonymized,
+		CreatedAt:       u.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:       u.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// boundUser returns the user the caller's credentials are restricted to.
+func boundUser(r *http.Request) *uuid.UUID {
+	if p, ok := modelauth.PrincipalFrom(r.Context()); ok {
+		return p.UserID
+	}
+	return nil
+}
+
+// pathUser parses the user in the URL, answering 404 for other users than
+// the one the credentials are bound to.
+func pathUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+		return uuid.Nil, false
+	}
+	if bound := boundUser(r); bound != nil && id != *bound {
+		JSONRes.WriteJSON(w, http.StatusNotFound, "user not found")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// load fetches the registered user in the URL.
+func (h *Handler) load(w http.ResponseWriter, r *http.Request) (modeluser.User, bool) {
+	id, ok := pathUser(w, r)
+	if !ok {
+		return modeluser.User{}, false
+	}
+	u, err := h.usecase.Get(r.Context(), id)
+	if errors.Is(err, myerrors.ErrorNotFound) {
+		JSONRes.WriteJSON(w, http.StatusNotFound, "user not found")
+		return modeluser.User{}, false
+	}
+	if err != nil {
+		h.log.Error("get user failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to get user")
+		return modeluser.User{}, false
+	}
+	return u, true
+}
+
+// writeErr answers the errors shared by the write endpoints.
+func (h *Handler) writeErr(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, myerrors.ErrorValidation):
+		JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, myerrors.ErrorConflict):
+		JSONRes.WriteJSON(w, http.StatusConflict, err.Error())
+	case errors.Is(err, myerrors.ErrorNotFound):
+		JSONRes.WriteJSON(w, http.StatusNotFound, "user not found")
+	default:
+		h.log.Error(op+" user failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to "+op+" user")
+	}
+}
+
+func fromReq(req modeluser.UserReq) modeluser.User {
+	return modeluser.User{
+		DisplayName:     req.DisplayName,
+		Email:           req.Email,
+		Timezone:        req.Timezone,
+		DefaultCurrency: req.DefaultCurrency,
+	}
+}
+
+// CreateUser registers a user. Credentials bound to a user can only register
+// that user.
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req modeluser.UserReq
+	if !bodylimit.Decode(w, r, &req) {
+		return
+	}
+
+	u := fromReq(req)
+	if v := strings.TrimSpace(req.ID); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+			return
+		}
+		u.ID = id
+	}
+	if bound := boundUser(r); bound != nil {
+		if u.ID != uuid.Nil && u.ID != *bound {
+			JSONRes.WriteJSON(w, http.StatusForbidden, "id is not allowed for these credentials")
+			return
+		}
+		u.ID = *bound
+	}
+
+	created, err := h.usecase.Create(r.Context(), u)
+	if err != nil {
+		h.writeErr(w, "create", err)
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusCreated, toResp(created))
+}
+
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var f modeluser.Filter
+	if v := strings.TrimSpace(q.Get("email")); v != "" {
+		f.Email = &v
+	}
+	f.Limit, _ = strconv.Atoi(q.Get("limit"))
+	f.Offset, _ = strconv.Atoi(q.Get("offset"))
+
+	var users []modeluser.User
+	if bound := boundUser(r); bound != nil {
+		// Bound credentials only see their own user.
+		u, err := h.usecase.Get(r.Context(), *bound)
+		if err != nil && !errors.Is(err, myerrors.ErrorNotFound) {
+			h.log.Error("list users failed", slog.Any("err", err))
+			JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to list users")
+			return
+		}
+		if err == nil && (f.Email == nil || (u.Email != nil && strings.EqualFold(*u.Email, *f.Email))) {
+			users = append(users, u)
+		}
+	} else {
+		var err error
+		users, err = h.usecase.List(r.Context(), f)
+		if err != nil {
+			h.log.Error("list users failed", slog.Any("err", err))
+			JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to list users")
+			return
+		}
+	}
+
+	items := make([]modeluser.UserResp, 0, len(users))
+	for _, u := range users {
+		items = append(items, toResp(u))
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, map[string]any{
+		"total": len(items),
+		"items": items,
+	})
+}
+
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(u))
+}
+
+// UpdateUser replaces the profile; the id cannot be changed.
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	var req modeluser.UserReq
+	if !bodylimit.Decode(w, r, &req) {
+		return
+	}
+
+	next := fromReq(req)
+	next.ID = u.ID
+	updated, err := h.usecase.Update(r.Context(), next)
+	if err != nil {
+		h.writeErr(w, "update", err)
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(updated))
+}
+
+// DeleteUser removes the user; ?mode= decides what happens to their
+// subscriptions and defaults to refuse. Anonymizing answers with the erased
+// profile.
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	mode := strings.TrimSpace(r.URL.Query().Get("mode"))
+	if mode == "" {
+		mode = modeluser.DeleteRefuse
+	}
+
+	anonymized, err := h.usecase.Delete(r.Context(), u.ID, mode)
+	if err != nil {
+		h.writeErr(w, "delete", err)
+		return
+	}
+	if anonymized != nil {
+		JSONRes.WriteJSON(w, http.StatusOK, toResp(*anonymized))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
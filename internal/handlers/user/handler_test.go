@@ -0,0 +1,290 @@
+package user
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	modelsub "test_task/internal/domain/models/subscription"
+	modeluser "test_task/internal/domain/models/user"
+	"test_task/internal/handlers/routes"
+	authmid "test_task/internal/middleware/auth_middleware"
+	logmid "test_task/internal/middleware/loger_middleware"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type mockUsecase struct {
+	createFn func(ctx context.Context, u modeluser.User) (modeluser.User, error)
+	deleteFn func(ctx context.Context, id uuid.UUID, mode string) (*modeluser.User, error)
+	exportFn func(ctx context.Context, id uuid.UUID) (modeluser.Export, error)
+	users    map[uuid.UUID]modeluser.User
+}
+
+func (m *mockUsecase) Create(ctx context.Context, u modeluser.User) (modeluser.User, error) {
+	return m.createFn(ctx, u)
+}
+func (m *mockUsecase) Get(_ context.Context, id uuid.UUID) (modeluser.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return modeluser.User{}, myerrors.ErrorNotFound
+	}
+	return u, nil
+}
+func (m *mockUsecase) List(context.Context, modeluser.Filter) ([]modeluser.User, error) {
+	return nil, nil
+}
+func (m *mockUsecase) Update(_ context.Context, u modeluser.User) (modeluser.User, error) {
+	return u, nil
+}
+func (m *mockUsecase) Delete(ctx context.Context, id uuid.UUID, mode string) (*modeluser.User, error) {
+	return m.deleteFn(ctx, id, mode)
+}
+func (m *mockUsecase) Export(ctx context.Context, id uuid.UUID) (modeluser.Export, error) {
+	return m.exportFn(ctx, id)
+}
+func (m *mockUsecase) Erase(_ context.Context, id uuid.UUID) (modeluser.Erasure, error) {
+	return modeluser.Erasure{UserID: id, Profile: 1}, nil
+}
+
+type staticAuthenticator map[string]modelauth.Principal
+
+func (a staticAuthenticator) Authenticate(_ context.Context, credentials string) (modelauth.Principal, error) {
+	p, ok := a[credentials]
+	if !ok {
+		return modelauth.Principal{}, authmid.ErrInvalidCredentials
+	}
+	return p, nil
+}
+
+func newTestRouter(u UsecaseI, bound uuid.UUID) http.Handler {
+	log := logmid.NewLogger("error")
+	r := chi.NewRouter()
+	r.Use(authmid.Authenticate(log, map[string]authmid.Authenticator{
+		"ApiKey": staticAuthenticator{
+			"bound": {
+				Subject: "apikey:bound",
+				UserID:  &bound,
+				Scopes:  []string{modelauth.ScopeSubscriptionsRead, modelauth.ScopeSubscriptionsWrite},
+			},
+			"admin": {
+				Subject: "apikey:admin",
+				UserID:  &bound,
+				Scopes:  []string{modelauth.ScopeAdmin},
+			},
+		},
+	}))
+	Routes(New(log, u))(r, routes.Middlewares{})
+	return r
+}
+
+func TestCreateUser_BoundCredentials(t *testing.T) {
+	bound := uuid.New()
+	u := &mockUsecase{
+		createFn: func(_ context.Context, usr modeluser.User) (modeluser.User, error) {
+			if usr.ID != bound {
+				t.Fatalf("user must be registered as %s, got %s", bound, usr.ID)
+			}
+			return usr, nil
+		},
+	}
+	r := newTestRouter(u, bound)
+
+	b, _ := json.Marshal(map[string]any{"display_name": "Ann"})
+	req := httptest.NewRequest(http.MethodPost, "/users/", bytes.NewReader(b))
+	req.Header.Set("Authorization", "ApiKey bound")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("want %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	b, _ = json.Marshal(map[string]any{"id": uuid.NewString(), "display_name": "Bob"})
+	req = httptest.NewRequest(http.MethodPost, "/users/", bytes.NewReader(b))
+	req.Header.Set("Authorization", "ApiKey bound")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("foreign id: want %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestDeleteUser_Modes(t *testing.T) {
+	id := uuid.New()
+	other := uuid.New()
+	u := &mockUsecase{
+		users: map[uuid.UUID]modeluser.User{
+			id:    {ID: id, DisplayName: "Ann"},
+			other: {ID: other, DisplayName: "Bob"},
+		},
+		deleteFn: func(_ context.Context, _ uuid.UUID, mode string) (*modeluser.User, error) {
+			switch mode {
+			case modeluser.DeleteRefuse:
+				return nil, fmt.Errorf("%w: user has 2 subscription(s)", myerrors.ErrorConflict)
+			case modeluser.DeleteAnonymize:
+				return &modeluser.User{ID: id, DisplayName: modeluser.AnonymizedName}, nil
+			case modeluser.DeleteCascade:
+				return nil, nil
+			}
+			return nil, fmt.Errorf("%w: bad mode", myerrors.ErrorValidation)
+		},
+	}
+	r := newTestRouter(u, id)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{path: "/users/" + id.String() + "/", wantStatus: http.StatusConflict},
+		{path: "/users/" + id.String() + "/?mode=anonymize", wantStatus: http.StatusOK},
+		{path: "/users/" + id.String() + "/?mode=cascade", wantStatus: http.StatusNoContent},
+		{path: "/users/" + id.String() + "/?mode=purge", wantStatus: http.StatusBadRequest},
+		{path: "/users/" + other.String() + "/?mode=cascade", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, tt.path, nil)
+		req.Header.Set("Authorization", "ApiKey admin")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus {
+			t.Fatalf("%s: want %d, got %d", tt.path, tt.wantStatus, w.Code)
+		}
+	}
+
+	for key, want := range map[string]int{"": http.StatusUnauthorized, "bound": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodDelete, "/users/"+id.String()+"/?mode=cascade", nil)
+		if key != "" {
+			req.Header.Set("Authorization", "ApiKey "+key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("key %q: want %d, got %d", key, want, w.Code)
+		}
+	}
+}
+
+func TestExportData_Formats(t *testing.T) {
+	id := uuid.New()
+	u := &mockUsecase{
+		exportFn: func(_ context.Context, uid uuid.UUID) (modeluser.Export, error) {
+			if uid != id {
+				return modeluser.Export{}, myerrors.ErrorNotFound
+			}
+			return modeluser.Export{
+				UserID:        id,
+				ExportedAt:    time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+				Profile:       &modeluser.User{ID: id, DisplayName: "Ann"},
+				Subscriptions: []modelsub.Subscription{{ID: uuid.New(), UserID: id, ServiceName: "Netflix", Price: 400}},
+			}, nil
+		},
+	}
+	r := newTestRouter(u, id)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/"+id.String()+"/data-export", nil)
+	req.Header.Set("Authorization", "ApiKey admin")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("json: want %d, got %d", http.StatusOK, w.Code)
+	}
+	var doc struct {
+		Profile       modeluser.UserResp      `json:"profile"`
+		Subscriptions []modelsub.Subscription `json:"subscriptions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.Profile.DisplayName != "Ann" || len(doc.Subscriptions) != 1 {
+		t.Fatalf("unexpected export %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/users/"+id.String()+"/data-export?format=zip", nil)
+	req.Header.Set("Authorization", "ApiKey admin")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("zip: want %d application/zip, got %d %q", http.StatusOK, w.Code, w.Header().Get("Content-Type"))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"profile.json", "subscriptions.json", "price_history.json", "audit.json", "notifications.json"} {
+		if files[name] == nil {
+			t.Fatalf("zip misses %s", name)
+		}
+	}
+	rc, err := files["subscriptions.json"].Open()
+	if err != nil {
+		t.Fatalf("open subscriptions.json: %v", err)
+	}
+	defer rc.Close()
+	b, _ := io.ReadAll(rc)
+	var subs []modelsub.Subscription
+	if err := json.Unmarshal(b, &subs); err != nil || len(subs) != 1 || subs[0].ServiceName != "Netflix" {
+		t.Fatalf("unexpected subscriptions.json %s (%v)", b, err)
+	}
+
+	for path, want := range map[string]int{
+		"/users/" + id.String() + "/data-export?format=csv": http.StatusBadRequest,
+		"/users/" + uuid.NewString() + "/data-export":       http.StatusNotFound,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "ApiKey admin")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("%s: want %d, got %d", path, want, w.Code)
+		}
+	}
+}
+
+func TestDataRoutes_RequireAdmin(t *testing.T) {
+	id := uuid.New()
+	u := &mockUsecase{
+		exportFn: func(_ context.Context, uid uuid.UUID) (modeluser.Export, error) {
+			return modeluser.Export{UserID: uid}, nil
+		},
+	}
+	r := newTestRouter(u, id)
+	other := uuid.New()
+
+	tests := []struct {
+		name, method, path, key string
+		wantStatus              int
+	}{
+		{"anonymous export", http.MethodGet, "/users/" + id.String() + "/data-export", "", http.StatusUnauthorized},
+		{"anonymous erase", http.MethodDelete, "/users/" + id.String() + "/data", "", http.StatusUnauthorized},
+		{"write scope export", http.MethodGet, "/users/" + id.String() + "/data-export", "bound", http.StatusForbidden},
+		{"write scope erase", http.MethodDelete, "/users/" + id.String() + "/data", "bound", http.StatusForbidden},
+		{"admin of another user export", http.MethodGet, "/users/" + other.String() + "/data-export", "admin", http.StatusNotFound},
+		{"admin of another user erase", http.MethodDelete, "/users/" + other.String() + "/data", "admin", http.StatusNotFound},
+		{"admin erase", http.MethodDelete, "/users/" + id.String() + "/data", "admin", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.key != "" {
+			req.Header.Set("Authorization", "ApiKey "+tt.key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus {
+			t.Fatalf("%s: want %d, got %d", tt.name, tt.wantStatus, w.Code)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package user
+
+import (
+	modelauth "test_task/internal/domain/models/auth"
+	"test_task/internal/handlers/routes"
+	ratelimit "test_task/internal/middleware/rate_limit_middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Routes returns the user endpoints for the /api/v1 router. The
+// subscriptions of a user are served by the subscription router under
+// /users/{id}. Deleting a user and exporting or erasing their data expose or
+// remove what they hold, so they need the admin scope whatever
+// auth.required says.
+func Routes(h *Handler) routes.Mount {
+	return func(r chi.Router, mw routes.Middlewares) {
+		read := chi.Chain(mw.Authorize(modelauth.ScopeSubscriptionsRead), mw.Limit(ratelimit.GroupRead))
+		write := chi.Chain(mw.Authorize(modelauth.ScopeSubscriptionsWrite), mw.Limit(ratelimit.GroupWrite))
+		admin := chi.Chain(mw.Admin(), mw.Limit(ratelimit.GroupWrite))
+
+		r.Route("/users", func(r chi.Router) {
+			r.With(read...).Get("/", h.ListUsers)
+			r.With(write...).With(mw.LimitBody()).Post("/", h.CreateUser)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(read...).Get("/", h.GetUser)
+				r.With(write...).With(mw.LimitBody()).Put("/", h.UpdateUser)
+				r.With(admin...).Delete("/", h.DeleteUser)
+				r.With(admin...).Get("/data-export", h.ExportData)
+				r.With(admin...).Delete("/data", h.EraseData)
+			})
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	modelwebhook "test_task/internal/domain/models/webhook"
+	bodylimit "test_task/internal/middleware/body_limit_middleware"
+	JSONRes "test_task/pkg/JSON_response"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type UsecaseI interface {
+	Create(ctx context.Context, w modelwebhook.Webhook) (modelwebhook.Webhook, error)
+	Get(ctx context.Context, id uuid.UUID) (modelwebhook.Webhook, error)
+	List(ctx context.Context, userID *uuid.UUID) ([]modelwebhook.Webhook, error)
+	Update(ctx context.Context, w modelwebhook.Webhook) (modelwebhook.Webhook, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	Deliveries(ctx context.Context, webhookID uuid.UUID, status *string, limit, offset int) ([]modelwebhook.Delivery, error)
+	Delivery(ctx context.Context, webhookID uuid.UUID, id int64) (modelwebhook.Delivery, []modelwebhook.Attempt, error)
+	Redeliver(ctx context.Context, webhookID uuid.UUID, id int64) (modelwebhook.Delivery, error)
+}
+
+type Handler struct {
+	log     *slog.Logger
+	usecase UsecaseI
+}
+
+func New(log *slog.Logger, usecase UsecaseI) *Handler {
+	return &Handler{log: log, usecase: usecase}
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	v := t.UTC().Format(time.RFC3339)
+	return &v
+}
+
+func toResp(wh modelwebhook.Webhook) modelwebhook.WebhookResp {
+	var userID *string
+	if wh.UserID != nil {
+		v := wh.UserID.String()
+		userID = &v
+	}
+	types := wh.EventTypes
+	if types == nil {
+		types = []string{}
+	}
+	return modelwebhook.WebhookResp{
+		ID:           wh.ID.String(),
+		URL:          wh.URL,
+		EventTypes:   types,
+		UserID:       userID,
+		Enabled:      wh.DisabledAt == nil,
+		FailureCount: wh.FailureCount,
+		DisabledAt:   formatTime(wh.DisabledAt),
+		CreatedAt:    wh.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:    wh.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func toDeliveryResp(d modelwebhook.Delivery) modelwebhook.DeliveryResp {
+	resp := modelwebhook.DeliveryResp{
+		ID:             d.ID,
+		WebhookID:      d.WebhookID.String(),
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		DeliveredAt:    formatTime(d.DeliveredAt),
+		CreatedAt:      d.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if d.Status == modelwebhook.DeliveryPending {
+		resp.NextAttemptAt = formatTime(&d.NextAttemptAt)
+	}
+	return resp
+}
+
+// boundUser returns the user the caller's credentials are restricted to.
+func boundUser(r *http.Request) *uuid.UUID {
+	if p, ok := modelauth.PrincipalFrom(r.Context()); ok {
+		return p.UserID
+	}
+	return nil
+}
+
+// isAdmin reports whether the caller holds the admin scope. Webhooks without
+// a user receive the events of every user of the tenant, so only admins can
+// manage them, even when authentication is not required.
+func isAdmin(r *http.Request) bool {
+	p, ok := modelauth.PrincipalFrom(r.Context())
+	return ok && p.HasScope(modelauth.ScopeAdmin)
+}
+
+// visible reports whether the caller may see wh.
+func visible(r *http.Request, wh modelwebhook.Webhook) bool {
+	if bound := boundUser(r); bound != nil {
+		return wh.UserID != nil && *wh.UserID == *bound
+	}
+	return wh.UserID != nil || isAdmin(r)
+}
+
+// load fetches the webhook in the URL, answering 404 for webhooks of another
+// user than the one the credentials are bound to and, for non-admins, for
+// webhooks without a user.
+func (h *Handler) load(w http.ResponseWriter, r *http.Request) (modelwebhook.Webhook, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "id must be a valid UUID")
+		return modelwebhook.Webhook{}, false
+	}
+	wh, err := h.usecase.Get(r.Context(), id)
+	if err != nil && !errors.Is(err, myerrors.ErrorNotFound) {
+		h.log.Error("get webhook failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to get webhook")
+		return modelwebhook.Webhook{}, false
+	}
+	if err != nil || !visible(r, wh) {
+		JSONRes.WriteJSON(w, http.StatusNotFound, "webhook not found")
+		return modelwebhook.Webhook{}, false
+	}
+	return wh, true
+}
+
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req modelwebhook.WebhookCreateReq
+	if !bodylimit.Decode(w, r, &req) {
+		return
+	}
+
+	wh := modelwebhook.Webhook{
+		URL:        strings.TrimSpace(req.URL),
+		EventTypes: req.EventTypes,
+	}
+	if req.UserID != nil {
+		userID, err := uuid.Parse(*req.UserID)
+		if err != nil {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "user_id must be a valid UUID")
+			return
+		}
+		wh.UserID = &userID
+	}
+	if bound := boundUser(r); bound != nil {
+		if wh.UserID != nil && *wh.UserID != *bound {
+			JSONRes.WriteJSON(w, http.StatusForbidden, "user_id is not allowed for these credentials")
+			return
+		}
+		wh.UserID = bound
+	}
+	if wh.UserID == nil && !isAdmin(r) {
+		JSONRes.WriteJSON(w, http.StatusForbidden, "webhooks without user_id require the admin scope")
+		return
+	}
+
+	created, err := h.usecase.Create(r.Context(), wh)
+	if err != nil {
+		if errors.Is(err, myerrors.ErrorValidation) {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.log.Error("create webhook failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to create webhook")
+		return
+	}
+
+	JSONRes.WriteJSON(w, http.StatusCreated, modelwebhook.WebhookCreatedResp{
+		WebhookResp: toResp(created),
+		Secret:      created.Secret,
+	})
+}
+
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.usecase.List(r.Context(), boundUser(r))
+	if err != nil {
+		h.log.Error("list webhooks failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to list webhooks")
+		return
+	}
+
+	items := make([]modelwebhook.WebhookResp, 0, len(hooks))
+	for _, wh := range hooks {
+		if visible(r, wh) {
+			items = append(items, toResp(wh))
+		}
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, map[string]any{
+		"total": len(items),
+		"items": items,
+	})
+}
+
+func (h *Handler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	wh, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(wh))
+}
+
+func (h *Handler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	wh, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	var req modelwebhook.WebhookUpdateReq
+	if !bodylimit.Decode(w, r, &req) {
+		return
+	}
+
+	wh.URL = strings.TrimSpace(req.URL)
+	wh.EventTypes = req.EventTypes
+	if req.Enabled {
+		wh.DisabledAt = nil
+	} else if wh.DisabledAt == nil {
+		now := time.Now()
+		wh.DisabledAt = &now
+	}
+
+	updated, err := h.usecase.Update(r.Context(), wh)
+	if err != nil {
+		switch {
+		case errors.Is(err, myerrors.ErrorValidation):
+			JSONRes.WriteJSON(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, myerrors.ErrorNotFound):
+			JSONRes.WriteJSON(w, http.StatusNotFound, "webhook not found")
+		default:
+			h.log.Error("update webhook failed", slog.Any("err", err))
+			JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to update webhook")
+		}
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, toResp(updated))
+}
+
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	wh, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	if err := h.usecase.Delete(r.Context(), wh.ID); err != nil {
+		if errors.Is(err, myerrors.ErrorNotFound) {
+			JSONRes.WriteJSON(w, http.StatusNotFound, "webhook not found")
+			return
+		}
+		h.log.Error("delete webhook failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to delete webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	wh, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	var status *string
+	if v := strings.TrimSpace(q.Get("status")); v != "" {
+		switch v {
+		case modelwebhook.DeliveryPending, modelwebhook.DeliverySucceeded, modelwebhook.DeliveryFailed:
+			status = &v
+		default:
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "status must be one of pending, succeeded, failed")
+			return
+		}
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	offset, _ := strconv.Atoi(q.Get("offset"))
+
+	deliveries, err := h.usecase.Deliveries(r.Context(), wh.ID, status, limit, offset)
+	if err != nil {
+		h.log.Error("list webhook deliveries failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to list deliveries")
+		return
+	}
+
+	items := make([]modelwebhook.DeliveryResp, 0, len(deliveries))
+	for _, d := range deliveries {
+		items = append(items, toDeliveryResp(d))
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+	})
+}
+
+func parseDeliveryID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil || id <= 0 {
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "delivery id must be a positive integer")
+		return 0, false
+	}
+	return id, true
+}
+
+// GetDelivery returns a delivery with its payload and every attempt made.
+func (h *Handler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	wh, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	id, ok := parseDeliveryID(w, r)
+	if !ok {
+		return
+	}
+
+	d, attempts, err := h.usecase.Delivery(r.Context(), wh.ID, id)
+	if err != nil {
+		if errors.Is(err, myerrors.ErrorNotFound) {
+			JSONRes.WriteJSON(w, http.StatusNotFound, "delivery not found")
+			return
+		}
+		h.log.Error("get webhook delivery failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to get delivery")
+		return
+	}
+
+	resp := toDeliveryResp(d)
+	resp.Payload = d.Payload
+	resp.AttemptLog = make([]modelwebhook.AttemptResp, 0, len(attempts))
+	for _, a := range attempts {
+		resp.AttemptLog = append(resp.AttemptLog, modelwebhook.AttemptResp{
+			StatusCode: a.StatusCode,
+			Error:      a.Error,
+			DurationMS: a.Duration.Milliseconds(),
+			CreatedAt:  a.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	JSONRes.WriteJSON(w, http.StatusOK, resp)
+}
+
+// Redeliver queues a delivery again with a fresh retry budget.
+func (h *Handler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	wh, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	id, ok := parseDeliveryID(w, r)
+	if !ok {
+		return
+	}
+
+	d, err := h.usecase.Redeliver(r.Context(), wh.ID, id)
+	if err != nil {
+		if errors.Is(err, myerrors.ErrorNotFound) {
+			JSONRes.WriteJSON(w, http.StatusNotFound, "delivery not found")
+			return
+		}
+		h.log.Error("redeliver webhook delivery failed", slog.Any("err", err))
+		JSONRes.WriteJSON(w, http.StatusInternalServerError, "failed to redeliver")
+		return
+	}
+	JSONRes.WriteJSON(w, http.StatusAccepted, toDeliveryResp(d))
+}
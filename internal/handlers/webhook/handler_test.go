@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	modelwebhook "test_task/internal/domain/models/webhook"
+	"test_task/internal/handlers/routes"
+	authmid "test_task/internal/middleware/auth_middleware"
+	logmid "test_task/internal/middleware/loger_middleware"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type mockUsecase struct {
+	createFn    func(ctx context.Context, w modelwebhook.Webhook) (modelwebhook.Webhook, error)
+	getFn       func(ctx context.Context, id uuid.UUID) (modelwebhook.Webhook, error)
+	listFn      func(ctx context.Context, userID *uuid.UUID) ([]modelwebhook.Webhook, error)
+	redeliverFn func(ctx context.Context, webhookID uuid.UUID, id int64) (modelwebhook.Delivery, error)
+}
+
+func (m *mockUsecase) Create(ctx context.Context, w modelwebhook.Webhook) (modelwebhook.Webhook, error) {
+	return m.createFn(ctx, w)
+}
+func (m *mockUsecase) Get(ctx context.Context, id uuid.UUID) (modelwebhook.Webhook, error) {
+	return m.getFn(ctx, id)
+}
+func (m *mockUsecase) List(ctx context.Context, userID *uuid.UUID) ([]modelwebhook.Webhook, error) {
+	return m.listFn(ctx, userID)
+}
+func (m *mockUsecase) Update(context.Context, modelwebhook.Webhook) (modelwebhook.Webhook, error) {
+	return modelwebhook.Webhook{}, nil
+}
+func (m *mockUsecase) Delete(context.Context, uuid.UUID) error { return nil }
+func (m *mockUsecase) Deliveries(context.Context, uuid.UUID, *string, int, int) ([]modelwebhook.Delivery, error) {
+	return nil, nil
+}
+func (m *mockUsecase) Delivery(context.Context, uuid.UUID, int64) (modelwebhook.Delivery, []modelwebhook.Attempt, error) {
+	return modelwebhook.Delivery{}, nil, nil
+}
+func (m *mockUsecase) Redeliver(ctx context.Context, webhookID uuid.UUID, id int64) (modelwebhook.Delivery, error) {
+	return m.redeliverFn(ctx, webhookID, id)
+}
+
+type staticAuthenticator map[string]modelauth.Principal
+
+func (a staticAuthenticator) Authenticate(_ context.Context, credentials string) (modelauth.Principal, error) {
+	p, ok := a[credentials]
+	if !ok {
+		return modelauth.Principal{}, authmid.ErrInvalidCredentials
+	}
+	return p, nil
+}
+
+func newTestRouter(u UsecaseI, bound uuid.UUID) http.Handler {
+	log := logmid.NewLogger("error")
+	r := chi.NewRouter()
+	r.Use(authmid.Authenticate(log, map[string]authmid.Authenticator{
+		"ApiKey": staticAuthenticator{
+			"bound": {
+				Subject: "apikey:bound",
+				UserID:  &bound,
+				Scopes:  []string{modelauth.ScopeSubscriptionsRead, modelauth.ScopeSubscriptionsWrite},
+			},
+			"admin": {
+				Subject: "apikey:admin",
+				Scopes:  []string{modelauth.ScopeAdmin},
+			},
+		},
+	}))
+	Routes(New(log, u))(r, routes.Middlewares{})
+	return r
+}
+
+func TestCreateWebhook_ReturnsSecretOnce(t *testing.T) {
+	id := uuid.New()
+	bound := uuid.New()
+	u := &mockUsecase{
+		createFn: func(_ context.Context, w modelwebhook.Webhook) (modelwebhook.Webhook, error) {
+			if w.UserID == nil || *w.UserID != bound {
+				t.Fatalf("webhook must be bound to %s, got %v", bound, w.UserID)
+			}
+			w.ID = id
+			w.Secret = "whsec_secret"
+			w.CreatedAt = time.Now()
+			return w, nil
+		},
+		listFn: func(_ context.Context, userID *uuid.UUID) ([]modelwebhook.Webhook, error) {
+			if userID == nil || *userID != bound {
+				t.Fatalf("list must be scoped to %s, got %v", bound, userID)
+			}
+			return []modelwebhook.Webhook{{ID: id, URL: "https://example.com/hook", Secret: "whsec_secret", UserID: &bound}}, nil
+		},
+	}
+	r := newTestRouter(u, bound)
+
+	b, _ := json.Marshal(map[string]any{
+		"url":         "https://example.com/hook",
+		"event_types": []string{"subscription.created.v1"},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/", bytes.NewReader(b))
+	req.Header.Set("Authorization", "ApiKey bound")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created modelwebhook.WebhookCreatedResp
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if created.Secret != "whsec_secret" || !created.Enabled {
+		t.Fatalf("unexpected response: %+v", created)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/webhooks/", nil)
+	req.Header.Set("Authorization", "ApiKey bound")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d, got %d", http.StatusOK, w.Code)
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte("whsec_")) {
+		t.Fatalf("list must not expose the secret: %s", w.Body.String())
+	}
+}
+
+func TestRedeliver_OtherUsersWebhookNotFound(t *testing.T) {
+	bound := uuid.New()
+	other := uuid.New()
+	webhookID := uuid.New()
+	u := &mockUsecase{
+		getFn: func(_ context.Context, id uuid.UUID) (modelwebhook.Webhook, error) {
+			if id != webhookID {
+				return modelwebhook.Webhook{}, myerrors.ErrorNotFound
+			}
+			return modelwebhook.Webhook{ID: id, UserID: &other}, nil
+		},
+		redeliverFn: func(context.Context, uuid.UUID, int64) (modelwebhook.Delivery, error) {
+			t.Fatalf("usecase must not be called for another user's webhook")
+			return modelwebhook.Delivery{}, nil
+		},
+	}
+	r := newTestRouter(u, bound)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/"+webhookID.String()+"/deliveries/7/redeliver", nil)
+	req.Header.Set("Authorization", "ApiKey bound")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("want %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestRedeliver_Accepted(t *testing.T) {
+	webhookID := uuid.New()
+	owner := uuid.New()
+	u := &mockUsecase{
+		getFn: func(_ context.Context, id uuid.UUID) (modelwebhook.Webhook, error) {
+			return modelwebhook.Webhook{ID: id, UserID: &owner}, nil
+		},
+		redeliverFn: func(_ context.Context, wid uuid.UUID, id int64) (modelwebhook.Delivery, error) {
+			if wid != webhookID || id != 7 {
+				t.Fatalf("unexpected delivery %s/%d", wid, id)
+			}
+			return modelwebhook.Delivery{ID: 7, WebhookID: wid, Status: modelwebhook.DeliveryPending}, nil
+		},
+	}
+	r := newTestRouter(u, uuid.New())
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/"+webhookID.String()+"/deliveries/7/redeliver", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("want %d, got %d, body=%s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+}
+
+func TestTenantWideWebhook_RequiresAdmin(t *testing.T) {
+	webhookID := uuid.New()
+	u := &mockUsecase{
+		createFn: func(_ context.Context, w modelwebhook.Webhook) (modelwebhook.Webhook, error) {
+			w.ID = webhookID
+			return w, nil
+		},
+		getFn: func(_ context.Context, id uuid.UUID) (modelwebhook.Webhook, error) {
+			return modelwebhook.Webhook{ID: id}, nil
+		},
+	}
+	r := newTestRouter(u, uuid.New())
+
+	tests := []struct {
+		method     string
+		path       string
+		auth       string
+		wantStatus int
+	}{
+		{method: http.MethodPost, path: "/webhooks/", wantStatus: http.StatusForbidden},
+		{method: http.MethodPost, path: "/webhooks/", auth: "ApiKey admin", wantStatus: http.StatusCreated},
+		{method: http.MethodGet, path: "/webhooks/" + webhookID.String() + "/", wantStatus: http.StatusNotFound},
+		{method: http.MethodGet, path: "/webhooks/" + webhookID.String() + "/", auth: "ApiKey admin", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		b, _ := json.Marshal(map[string]any{"url": "https://example.com/hook"})
+		req := httptest.NewRequest(tt.method, tt.path, bytes.NewReader(b))
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus {
+			t.Fatalf("%s %s (%q): want %d, got %d, body=%s", tt.method, tt.path, tt.auth, tt.wantStatus, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package webhook
+
+import (
+	modelauth "test_task/internal/domain/models/auth"
+	"test_task/internal/handlers/routes"
+	ratelimit "test_task/internal/middleware/rate_limit_middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Routes returns the webhook endpoints for the /api/v1 router.
+func Routes(h *Handler) routes.Mount {
+	return func(r chi.Router, mw routes.Middlewares) {
+		read := chi.Chain(mw.Authorize(modelauth.ScopeSubscriptionsRead), mw.Limit(ratelimit.GroupRead))
+		write := chi.Chain(mw.Authorize(modelauth.ScopeSubscriptionsWrite), mw.Limit(ratelimit.GroupWrite))
+
+		r.Route("/webhooks", func(r chi.Router) {
+			r.With(read...).Get("/", h.ListWebhooks)
+			r.With(write...).With(mw.LimitBody()).Post("/", h.CreateWebhook)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(read...).Get("/", h.GetWebhook)
+				r.With(write...).With(mw.LimitBody()).Put("/", h.UpdateWebhook)
+				r.With(write...).Delete("/", h.DeleteWebhook)
+
+				r.With(read...).Get("/deliveries", h.ListDeliveries)
+				r.With(read...).Get("/deliveries/{deliveryID}", h.GetDelivery)
+				r.With(write...).Post("/deliveries/{deliveryID}/redeliver", h.Redeliver)
+			})
+		})
+	}
+}
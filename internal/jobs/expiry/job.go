@@ -0,0 +1,81 @@
+package expiry
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	modeltenant "test_task/internal/domain/models/tenant"
+)
+
+// Actor is recorded in the audit log for subscriptions changed by the job.
+const Actor = "system:expiry"
+
+type ExpirerI interface {
+	ExpireEnded(ctx context.Context) (int, error)
+	EndTrials(ctx context.Context) (int, error)
+	ApplyPauses(ctx context.Context) (int, error)
+}
+
+// Job moves subscriptions whose end_date has passed to status expired,
+// subscriptions whose trial is over from trial to active, and subscriptions
+// whose scheduled pause starts or ends in or out of status paused.
+type Job struct {
+	log      *slog.Logger
+	expirer  ExpirerI
+	interval time.Duration
+}
+
+func New(log *slog.Logger, expirer ExpirerI, interval time.Duration) *Job {
+	return &Job{
+		log:      log,
+		expirer:  expirer,
+		interval: interval,
+	}
+}
+
+// Run expires once immediately and then every interval until ctx is done.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			j.log.Error("expire subscriptions failed", slog.Any("err", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce returns the number of subscriptions changed across every tenant.
+func (j *Job) RunOnce(ctx context.Context) (int, error) {
+	ctx = modelauth.WithPrincipal(ctx, modelauth.Principal{Subject: Actor, Method: "system"})
+	ctx = modeltenant.WithAllTenants(ctx)
+
+	trials, err := j.expirer.EndTrials(ctx)
+	if trials > 0 {
+		j.log.Info("ended trials", slog.Int("count", trials))
+	}
+	if err != nil {
+		return trials, err
+	}
+
+	pauses, err := j.expirer.ApplyPauses(ctx)
+	if pauses > 0 {
+		j.log.Info("applied pauses", slog.Int("count", pauses))
+	}
+	if err != nil {
+		return trials + pauses, err
+	}
+
+	n, err := j.expirer.ExpireEnded(ctx)
+	if n > 0 {
+		j.log.Info("expired subscriptions", slog.Int("count", n))
+	}
+	return trials + pauses + n, err
+}
@@ -0,0 +1,40 @@
+package expiry
+
+import (
+	"context"
+	"testing"
+
+	modelauth "test_task/internal/domain/models/auth"
+	logmid "test_task/internal/middleware/loger_middleware"
+)
+
+type expirerFunc func(ctx context.Context) (int, error)
+
+func (f expirerFunc) ExpireEnded(ctx context.Context) (int, error) {
+	return f(ctx)
+}
+
+func (f expirerFunc) EndTrials(context.Context) (int, error) {
+	return 1, nil
+}
+
+func (f expirerFunc) ApplyPauses(context.Context) (int, error) {
+	return 1, nil
+}
+
+func TestJob_RunOnce_RunsAsSystem(t *testing.T) {
+	j := New(logmid.NewLogger("error"), expirerFunc(func(ctx context.Context) (int, error) {
+		if p, ok := modelauth.PrincipalFrom(ctx); !ok || p.Subject != Actor {
+			t.Fatalf("expiry must run as %s, got %+v", Actor, p)
+		}
+		return 2, nil
+	}), 0)
+
+	n, err := j.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if n != 4 {
+		t.Fatalf("want 1 trial ended, 1 pause applied and 2 expired, got %d", n)
+	}
+}
@@ -0,0 +1,121 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	modelevent "test_task/internal/domain/models/event"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"test_task/pkg/backoff"
+)
+
+type RepoI interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]modelevent.OutboxRecord, error)
+	MarkPublished(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, reason string, nextAttempt time.Time, dead bool) error
+}
+
+type PublisherI interface {
+	Publish(ctx context.Context, ev modelevent.Event) error
+}
+
+type Options struct {
+	BatchSize    int
+	PollInterval time.Duration
+	// Lease is how long a claimed event stays hidden from other dispatchers.
+	Lease       time.Duration
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// Dispatcher delivers outbox events to a publisher. Failed events are retried
+// with exponential backoff; an event that keeps failing is marked dead after
+// MaxAttempts.
+type Dispatcher struct {
+	log       *slog.Logger
+	repo      RepoI
+	publisher PublisherI
+	opts      Options
+	now       func() time.Time
+}
+
+func New(log *slog.Logger, repo RepoI, publisher PublisherI, opts Options) *Dispatcher {
+	return &Dispatcher{
+		log:       log,
+		repo:      repo,
+		publisher: publisher,
+		opts:      opts,
+		now:       time.Now,
+	}
+}
+
+// Run polls the outbox until ctx is done. A full batch is followed by the
+// next one right away so a backlog drains without waiting for the ticker.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := d.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.log.Error("outbox dispatch failed", slog.Any("err", err))
+		}
+		if err == nil && n == d.opts.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims and publishes a single batch and returns its size. Events
+// of every tenant are claimed; each is published in its own tenant.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	records, err := d.repo.Claim(modeltenant.WithAllTenants(ctx), d.opts.BatchSize, d.opts.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, rec := range records {
+		ctx := modeltenant.WithTenant(ctx, rec.TenantID)
+		if err := d.publisher.Publish(ctx, rec.Event); err != nil {
+			if err := d.fail(ctx, rec, err); err != nil {
+				return len(records), err
+			}
+			continue
+		}
+		if err := d.repo.MarkPublished(ctx, rec.ID); err != nil {
+			return len(records), err
+		}
+	}
+	return len(records), nil
+}
+
+func (d *Dispatcher) fail(ctx context.Context, rec modelevent.OutboxRecord, cause error) error {
+	attempt := rec.Attempts + 1
+	dead := attempt >= d.opts.MaxAttempts
+	next := d.now().Add(backoff.Exponential(attempt, d.opts.BackoffBase, d.opts.BackoffMax))
+
+	attrs := []any{
+		slog.Int64("outbox_id", rec.ID),
+		slog.String("tenant_id", rec.TenantID),
+		slog.String("event_id", rec.Event.ID),
+		slog.String("type", rec.Event.Type),
+		slog.Int("attempt", attempt),
+		slog.Any("err", cause),
+	}
+	if dead {
+		d.log.Error("event dropped after max attempts", attrs...)
+	} else {
+		d.log.Warn("event publish failed", append(attrs, slog.Time("next_attempt_at", next))...)
+	}
+	return d.repo.MarkFailed(ctx, rec.ID, cause.Error(), next, dead)
+}
@@ -0,0 +1,110 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	modelevent "test_task/internal/domain/models/event"
+	modeltenant "test_task/internal/domain/models/tenant"
+	logmid "test_task/internal/middleware/loger_middleware"
+)
+
+type failure struct {
+	id   int64
+	next time.Time
+	dead bool
+}
+
+type fakeRepo struct {
+	records   []modelevent.OutboxRecord
+	published []int64
+	failed    []failure
+}
+
+func (r *fakeRepo) Claim(context.Context, int, time.Duration) ([]modelevent.OutboxRecord, error) {
+	return r.records, nil
+}
+
+func (r *fakeRepo) MarkPublished(_ context.Context, id int64) error {
+	r.published = append(r.published, id)
+	return nil
+}
+
+func (r *fakeRepo) MarkFailed(_ context.Context, id int64, _ string, next time.Time, dead bool) error {
+	r.failed = append(r.failed, failure{id: id, next: next, dead: dead})
+	return nil
+}
+
+type publisherFunc func(ctx context.Context, ev modelevent.Event) error
+
+func (f publisherFunc) Publish(ctx context.Context, ev modelevent.Event) error {
+	return f(ctx, ev)
+}
+
+func TestDispatcher_RunOnce_RetriesAndDrops(t *testing.T) {
+	now := time.Date(2025, time.July, 1, 12, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{records: []modelevent.OutboxRecord{
+		{ID: 1, Event: modelevent.Event{ID: "ok"}},
+		{ID: 2, Event: modelevent.Event{ID: "retry"}, Attempts: 2},
+		{ID: 3, Event: modelevent.Event{ID: "dead"}, Attempts: 4},
+	}}
+	pub := publisherFunc(func(_ context.Context, ev modelevent.Event) error {
+		if ev.ID == "ok" {
+			return nil
+		}
+		return errors.New("unavailable")
+	})
+
+	d := New(logmid.NewLogger("error"), repo, pub, Options{
+		BatchSize:   10,
+		MaxAttempts: 5,
+		BackoffBase: time.Second,
+		BackoffMax:  time.Minute,
+	})
+	d.now = func() time.Time { return now }
+
+	n, err := d.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("want 3 records, got %d", n)
+	}
+	if len(repo.published) != 1 || repo.published[0] != 1 {
+		t.Fatalf("unexpected published %v", repo.published)
+	}
+	want := []failure{
+		{id: 2, next: now.Add(4 * time.Second), dead: false},
+		{id: 3, next: now.Add(16 * time.Second), dead: true},
+	}
+	if len(repo.failed) != len(want) {
+		t.Fatalf("unexpected failures %+v", repo.failed)
+	}
+	for i := range want {
+		if repo.failed[i] != want[i] {
+			t.Fatalf("failure %d: want %+v, got %+v", i, want[i], repo.failed[i])
+		}
+	}
+}
+
+func TestDispatcher_RunOnce_PublishesInTenant(t *testing.T) {
+	repo := &fakeRepo{records: []modelevent.OutboxRecord{
+		{ID: 1, TenantID: "acme", Event: modelevent.Event{ID: "a"}},
+		{ID: 2, TenantID: "globex", Event: modelevent.Event{ID: "b"}},
+	}}
+	tenants := map[string]string{}
+	pub := publisherFunc(func(ctx context.Context, ev modelevent.Event) error {
+		tenants[ev.ID] = modeltenant.From(ctx)
+		return nil
+	})
+
+	d := New(logmid.NewLogger("error"), repo, pub, Options{BatchSize: 10, MaxAttempts: 5})
+	if _, err := d.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if tenants["a"] != "acme" || tenants["b"] != "globex" {
+		t.Fatalf("events must be published in their tenant, got %v", tenants)
+	}
+}
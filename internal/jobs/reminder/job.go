@@ -0,0 +1,159 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	modelnotify "test_task/internal/domain/models/notification"
+	modelsub "test_task/internal/domain/models/subscription"
+	modeltenant "test_task/internal/domain/models/tenant"
+
+	"github.com/google/uuid"
+)
+
+type UpcomingI interface {
+	Upcoming(ctx context.Context, f modelsub.UpcomingFilter, within time.Duration) ([]modelsub.Renewal, error)
+	TrialsEnding(ctx context.Context, f modelsub.UpcomingFilter, within time.Duration) ([]modelsub.Renewal, error)
+}
+
+type RepoI interface {
+	Claim(ctx context.Context, n modelnotify.Notification) (modelnotify.Notification, bool, error)
+	MarkSent(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, reason string) error
+}
+
+type NotifierI interface {
+	Channel() string
+	Notify(ctx context.Context, n modelnotify.Notification) error
+}
+
+// Job sends a "renewal due" notification for every charge that is at most
+// daysBefore days away, and a "trial ending" one instead when the charge is
+// the first paid one after a trial. Free trial months are not notified. Each
+// charge is notified once; failed notifications are retried on the next run.
+type Job struct {
+	log      *slog.Logger
+	upcoming UpcomingI
+	repo     RepoI
+	notifier NotifierI
+	before   time.Duration
+	interval time.Duration
+}
+
+func New(log *slog.Logger, upcoming UpcomingI, repo RepoI, notifier NotifierI, daysBefore int, interval time.Duration) *Job {
+	return &Job{
+		log:      log,
+		upcoming: upcoming,
+		repo:     repo,
+		notifier: notifier,
+		before:   time.Duration(daysBefore) * 24 * time.Hour,
+		interval: interval,
+	}
+}
+
+// Run checks once immediately and then every interval until ctx is done.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			j.log.Error("renewal reminders failed", slog.Any("err", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce returns the number of notifications sent. Charges of every tenant
+// are checked; each notification is recorded in the tenant of its
+// subscription.
+func (j *Job) RunOnce(ctx context.Context) (int, error) {
+	all := modeltenant.WithAllTenants(ctx)
+	trials, err := j.upcoming.TrialsEnding(all, modelsub.UpcomingFilter{}, j.before)
+	if err != nil {
+		return 0, err
+	}
+	renewals, err := j.upcoming.Upcoming(all, modelsub.UpcomingFilter{}, j.before)
+	if err != nil {
+		return 0, err
+	}
+
+	channel := j.notifier.Channel()
+	var pending []modelnotify.Notification
+	trialEnds := make(map[uuid.UUID]bool, len(trials))
+	for _, rn := range trials {
+		trialEnds[rn.Subscription.ID] = true
+		pending = append(pending, trialEnding(rn, channel))
+	}
+	for _, rn := range renewals {
+		if rn.Amount == 0 || trialEnds[rn.Subscription.ID] {
+			continue
+		}
+		pending = append(pending, renewalDue(rn, channel))
+	}
+
+	sent := 0
+	for _, msg := range pending {
+		ctx := modeltenant.WithTenant(ctx, msg.TenantID)
+		n, ok, err := j.repo.Claim(ctx, msg)
+		if err != nil {
+			return sent, err
+		}
+		if !ok {
+			continue
+		}
+		if err := j.notifier.Notify(ctx, n); err != nil {
+			j.log.Warn("reminder failed",
+				slog.String("kind", n.Kind),
+				slog.String("subscription_id", n.SubscriptionID.String()),
+				slog.Any("err", err),
+			)
+			if err := j.repo.MarkFailed(ctx, n.ID, err.Error()); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		if err := j.repo.MarkSent(ctx, n.ID); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func renewalDue(rn modelsub.Renewal, channel string) modelnotify.Notification {
+	s := rn.Subscription
+	date := rn.ChargeDate.Format(time.DateOnly)
+	return modelnotify.Notification{
+		TenantID:       s.TenantID,
+		UserID:         s.UserID,
+		SubscriptionID: &s.ID,
+		Kind:           modelnotify.KindRenewalDue,
+		Channel:        channel,
+		Subject:        fmt.Sprintf("%s renews on %s", s.ServiceName, date),
+		Body:           fmt.Sprintf("Your %s subscription will be charged %d RUB on %s.", s.ServiceName, rn.Amount, date),
+		DedupKey:       fmt.Sprintf("%s:%s:%s", modelnotify.KindRenewalDue, s.ID, date),
+	}
+}
+
+func trialEnding(rn modelsub.Renewal, channel string) modelnotify.Notification {
+	s := rn.Subscription
+	date := rn.ChargeDate.Format(time.DateOnly)
+	return modelnotify.Notification{
+		TenantID:       s.TenantID,
+		UserID:         s.UserID,
+		SubscriptionID: &s.ID,
+		Kind:           modelnotify.KindTrialEnding,
+		Channel:        channel,
+		Subject:        fmt.Sprintf("%s trial ends, first charge on %s", s.ServiceName, date),
+		Body: fmt.Sprintf("Your %s trial is ending. Unless you cancel, the subscription will be charged %d RUB on %s.",
+			s.ServiceName, rn.Amount, date),
+		DedupKey: fmt.Sprintf("%s:%s:%s", modelnotify.KindTrialEnding, s.ID, date),
+	}
+}
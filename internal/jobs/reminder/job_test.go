@@ -0,0 +1,184 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	modelnotify "test_task/internal/domain/models/notification"
+	modelsub "test_task/internal/domain/models/subscription"
+	modeltenant "test_task/internal/domain/models/tenant"
+	logmid "test_task/internal/middleware/loger_middleware"
+
+	"github.com/google/uuid"
+)
+
+type upcomingFunc func(ctx context.Context, f modelsub.UpcomingFilter, within time.Duration) ([]modelsub.Renewal, error)
+
+func (f upcomingFunc) Upcoming(ctx context.Context, filter modelsub.UpcomingFilter, within time.Duration) ([]modelsub.Renewal, error) {
+	return f(ctx, filter, within)
+}
+
+func (f upcomingFunc) TrialsEnding(context.Context, modelsub.UpcomingFilter, time.Duration) ([]modelsub.Renewal, error) {
+	return nil, nil
+}
+
+// fakeUpcoming returns fixed renewals and trials.
+type fakeUpcoming struct {
+	renewals []modelsub.Renewal
+	trials   []modelsub.Renewal
+}
+
+func (f fakeUpcoming) Upcoming(context.Context, modelsub.UpcomingFilter, time.Duration) ([]modelsub.Renewal, error) {
+	return f.renewals, nil
+}
+
+func (f fakeUpcoming) TrialsEnding(context.Context, modelsub.UpcomingFilter, time.Duration) ([]modelsub.Renewal, error) {
+	return f.trials, nil
+}
+
+// memRepo mimics the dedup semantics of the notifications table and records
+// the tenant each notification is claimed in.
+type memRepo struct {
+	byKey   map[string]*modelnotify.Notification
+	nextID  int64
+	tenants []string
+}
+
+func (r *memRepo) Claim(ctx context.Context, n modelnotify.Notification) (modelnotify.Notification, bool, error) {
+	r.tenants = append(r.tenants, modeltenant.From(ctx))
+	if cur, ok := r.byKey[n.DedupKey]; ok && cur.Status != modelnotify.StatusFailed {
+		return modelnotify.Notification{}, false, nil
+	}
+	r.nextID++
+	n.ID = r.nextID
+	n.Status = modelnotify.StatusPending
+	r.byKey[n.DedupKey] = &n
+	return n, true, nil
+}
+
+func (r *memRepo) set(id int64, status string) {
+	for _, n := range r.byKey {
+		if n.ID == id {
+			n.Status = status
+		}
+	}
+}
+
+func (r *memRepo) MarkSent(_ context.Context, id int64) error {
+	r.set(id, modelnotify.StatusSent)
+	return nil
+}
+
+func (r *memRepo) MarkFailed(_ context.Context, id int64, _ string) error {
+	r.set(id, modelnotify.StatusFailed)
+	return nil
+}
+
+type flakyNotifier struct {
+	fail bool
+	sent []modelnotify.Notification
+}
+
+func (n *flakyNotifier) Channel() string { return "test" }
+
+func (n *flakyNotifier) Notify(_ context.Context, msg modelnotify.Notification) error {
+	if n.fail {
+		return errors.New("smtp down")
+	}
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+func TestJob_NotifiesOncePerCharge(t *testing.T) {
+	sub := modelsub.Subscription{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Netflix", Price: 700}
+	charge := time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC)
+
+	upcoming := upcomingFunc(func(_ context.Context, _ modelsub.UpcomingFilter, within time.Duration) ([]modelsub.Renewal, error) {
+		if within != 3*24*time.Hour {
+			t.Fatalf("want 3 days window, got %v", within)
+		}
+		return []modelsub.Renewal{{Subscription: sub, ChargeDate: charge, Amount: 700}}, nil
+	})
+	repo := &memRepo{byKey: map[string]*modelnotify.Notification{}}
+	notifier := &flakyNotifier{fail: true}
+	j := New(logmid.NewLogger("error"), upcoming, repo, notifier, 3, time.Hour)
+
+	if n, err := j.RunOnce(context.Background()); err != nil || n != 0 {
+		t.Fatalf("failing notifier: want 0 sent, got %d %v", n, err)
+	}
+
+	notifier.fail = false
+	if n, err := j.RunOnce(context.Background()); err != nil || n != 1 {
+		t.Fatalf("want failed reminder retried, got %d %v", n, err)
+	}
+	if n, err := j.RunOnce(context.Background()); err != nil || n != 0 {
+		t.Fatalf("want no duplicate reminder, got %d %v", n, err)
+	}
+
+	if len(notifier.sent) != 1 {
+		t.Fatalf("want 1 notification, got %d", len(notifier.sent))
+	}
+	got := notifier.sent[0]
+	if got.Kind != modelnotify.KindRenewalDue || got.UserID != sub.UserID || got.Channel != "test" {
+		t.Fatalf("unexpected notification %+v", got)
+	}
+	if got.Subject != "Netflix renews on 2025-08-01" {
+		t.Fatalf("unexpected subject %q", got.Subject)
+	}
+}
+
+func TestJob_TrialEndingReplacesRenewal(t *testing.T) {
+	trialUntil := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	charge := time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC)
+	trial := modelsub.Subscription{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Kinopoisk", Price: 300, TrialUntil: &trialUntil}
+	free := modelsub.Subscription{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Okko", Price: 400, TrialUntil: &charge}
+
+	upcoming := fakeUpcoming{
+		renewals: []modelsub.Renewal{
+			{Subscription: trial, ChargeDate: charge, Amount: 300},
+			{Subscription: free, ChargeDate: charge, Amount: 0},
+		},
+		trials: []modelsub.Renewal{{Subscription: trial, ChargeDate: charge, Amount: 300}},
+	}
+	repo := &memRepo{byKey: map[string]*modelnotify.Notification{}}
+	notifier := &flakyNotifier{}
+	j := New(logmid.NewLogger("error"), upcoming, repo, notifier, 3, time.Hour)
+
+	if n, err := j.RunOnce(context.Background()); err != nil || n != 1 {
+		t.Fatalf("want 1 sent, got %d %v", n, err)
+	}
+	got := notifier.sent[0]
+	if got.Kind != modelnotify.KindTrialEnding || *got.SubscriptionID != trial.ID {
+		t.Fatalf("want trial ending for %s, got %+v", trial.ID, got)
+	}
+	if got.Subject != "Kinopoisk trial ends, first charge on 2025-08-01" {
+		t.Fatalf("unexpected subject %q", got.Subject)
+	}
+}
+
+func TestJob_ClaimsInTenantOfSubscription(t *testing.T) {
+	charge := time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC)
+	acme := modelsub.Subscription{ID: uuid.New(), TenantID: "acme", UserID: uuid.New(), ServiceName: "Netflix", Price: 700}
+	globex := modelsub.Subscription{ID: uuid.New(), TenantID: "globex", UserID: uuid.New(), ServiceName: "Spotify", Price: 300}
+
+	upcoming := upcomingFunc(func(ctx context.Context, _ modelsub.UpcomingFilter, _ time.Duration) ([]modelsub.Renewal, error) {
+		if modeltenant.Scope(ctx) != nil {
+			t.Fatalf("renewals must be loaded across tenants")
+		}
+		return []modelsub.Renewal{
+			{Subscription: acme, ChargeDate: charge, Amount: 700},
+			{Subscription: globex, ChargeDate: charge, Amount: 300},
+		}, nil
+	})
+	repo := &memRepo{byKey: map[string]*modelnotify.Notification{}}
+	j := New(logmid.NewLogger("error"), upcoming, repo, &flakyNotifier{}, 3, time.Hour)
+
+	if n, err := j.RunOnce(context.Background()); err != nil || n != 2 {
+		t.Fatalf("want 2 sent, got %d %v", n, err)
+	}
+	if len(repo.tenants) != 2 || repo.tenants[0] != "acme" || repo.tenants[1] != "globex" {
+		t.Fatalf("want claims in acme and globex, got %v", repo.tenants)
+	}
+}
@@ -0,0 +1,74 @@
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	modeltenant "test_task/internal/domain/models/tenant"
+)
+
+// Actor is recorded in the audit log for purged subscriptions.
+const Actor = "system:retention"
+
+type PurgerI interface {
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// Job hard-deletes subscriptions that stayed soft-deleted longer than the
+// retention period.
+type Job struct {
+	log       *slog.Logger
+	purger    PurgerI
+	retention time.Duration
+	interval  time.Duration
+	now       func() time.Time
+}
+
+func New(log *slog.Logger, purger PurgerI, retention, interval time.Duration) *Job {
+	return &Job{
+		log:       log,
+		purger:    purger,
+		retention: retention,
+		interval:  interval,
+		now:       time.Now,
+	}
+}
+
+// Run purges once immediately and then every interval until ctx is done.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			j.log.Error("retention purge failed", slog.Any("err", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce purges the subscriptions of every tenant and returns how many were
+// deleted.
+func (j *Job) RunOnce(ctx context.Context) (int64, error) {
+	ctx = modelauth.WithPrincipal(ctx, modelauth.Principal{Subject: Actor, Method: "system"})
+	ctx = modeltenant.WithAllTenants(ctx)
+	cutoff := j.now().Add(-j.retention)
+
+	n, err := j.purger.Purge(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		j.log.Info("purged deleted subscriptions",
+			slog.Int64("count", n),
+			slog.Time("deleted_before", cutoff),
+		)
+	}
+	return n, nil
+}
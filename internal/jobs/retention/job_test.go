@@ -0,0 +1,40 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	logmid "test_task/internal/middleware/loger_middleware"
+)
+
+type purgerFunc func(ctx context.Context, deletedBefore time.Time) (int64, error)
+
+func (f purgerFunc) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return f(ctx, deletedBefore)
+}
+
+func TestJob_RunOnce_Cutoff(t *testing.T) {
+	now := time.Date(2025, time.December, 31, 12, 0, 0, 0, time.UTC)
+
+	j := New(logmid.NewLogger("error"), purgerFunc(func(ctx context.Context, deletedBefore time.Time) (int64, error) {
+		want := now.Add(-30 * 24 * time.Hour)
+		if !deletedBefore.Equal(want) {
+			t.Fatalf("want cutoff %v, got %v", want, deletedBefore)
+		}
+		if p, ok := modelauth.PrincipalFrom(ctx); !ok || p.Subject != Actor {
+			t.Fatalf("purge must run as %s, got %+v", Actor, p)
+		}
+		return 3, nil
+	}), 30*24*time.Hour, time.Hour)
+	j.now = func() time.Time { return now }
+
+	n, err := j.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("want 3 purged, got %d", n)
+	}
+}
@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	modeltenant "test_task/internal/domain/models/tenant"
+	modelwebhook "test_task/internal/domain/models/webhook"
+	"test_task/internal/publisher"
+	"test_task/pkg/backoff"
+	"test_task/pkg/netguard"
+)
+
+// maxDrain is how much of the receiver's answer is read, so the connection
+// can be reused. The answer itself is not kept: it is not ours to show.
+const maxDrain = 4 << 10
+
+type RepoI interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]modelwebhook.PendingDelivery, error)
+	RecordAttempt(ctx context.Context, d modelwebhook.PendingDelivery, a modelwebhook.Attempt, status string, nextAttempt time.Time, disableAfter int) (bool, error)
+}
+
+type Options struct {
+	BatchSize    int
+	PollInterval time.Duration
+	Timeout      time.Duration
+	MaxAttempts  int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	// DisableAfter consecutive failed attempts disable the webhook.
+	DisableAfter int
+	// AllowPrivateNetworks lets deliveries reach loopback and private
+	// addresses, which are refused by default.
+	AllowPrivateNetworks bool
+}
+
+// Deliverer sends pending webhook deliveries, signing each request with the
+// webhook secret and recording every attempt.
+type Deliverer struct {
+	log    *slog.Logger
+	repo   RepoI
+	client *http.Client
+	opts   Options
+	now    func() time.Time
+}
+
+func New(log *slog.Logger, repo RepoI, opts Options) *Deliverer {
+	return &Deliverer{
+		log:    log,
+		repo:   repo,
+		client: newClient(opts),
+		opts:   opts,
+		now:    time.Now,
+	}
+}
+
+// newClient returns the HTTP client of the deliveries. Unless private
+// networks are allowed, it refuses to connect to addresses that are not
+// public, checked once the host is resolved, so DNS rebinding cannot get past
+// the check made when the webhook was created. Proxies and redirects would
+// get around it, so neither is followed.
+func newClient(opts Options) *http.Client {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivateNetworks {
+		dialer.Control = netguard.Control
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Run delivers until ctx is done, draining full batches without waiting.
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := d.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.log.Error("webhook delivery failed", slog.Any("err", err))
+		}
+		if err == nil && n == d.opts.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims and sends a single batch and returns its size. Deliveries
+// are claimed across tenants and recorded in the tenant of each.
+func (d *Deliverer) RunOnce(ctx context.Context) (int, error) {
+	// Deliveries are sent one by one, so the lease covers a batch of timeouts.
+	lease := d.opts.Timeout*time.Duration(d.opts.BatchSize) + time.Minute
+	batch, err := d.repo.Claim(modeltenant.WithAllTenants(ctx), d.opts.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range batch {
+		if err := d.deliver(modeltenant.WithTenant(ctx, p.TenantID), p); err != nil {
+			return len(batch), err
+		}
+	}
+	return len(batch), nil
+}
+
+func (d *Deliverer) deliver(ctx context.Context, p modelwebhook.PendingDelivery) error {
+	a := d.send(ctx, p)
+	attempt := p.Attempts + 1
+
+	status := modelwebhook.DeliverySucceeded
+	next := d.now()
+	if a.Error != nil {
+		status = modelwebhook.DeliveryPending
+		next = next.Add(backoff.Exponential(attempt, d.opts.BackoffBase, d.opts.BackoffMax))
+		if attempt >= d.opts.MaxAttempts {
+			status = modelwebhook.DeliveryFailed
+		}
+	}
+
+	disabled, err := d.repo.RecordAttempt(ctx, p, a, status, next, d.opts.DisableAfter)
+	if err != nil {
+		return err
+	}
+	if a.Error != nil {
+		d.log.Warn("webhook delivery attempt failed",
+			slog.Int64("delivery_id", p.ID),
+			slog.String("webhook_id", p.WebhookID.String()),
+			slog.Int("attempt", attempt),
+			slog.String("status", status),
+			slog.String("err", *a.Error),
+		)
+	}
+	if disabled {
+		d.log.Warn("webhook disabled after repeated failures", slog.String("webhook_id", p.WebhookID.String()))
+	}
+	return nil
+}
+
+// send performs one signed POST. Transport errors and non-2xx answers are
+// reported through Attempt.Error.
+func (d *Deliverer) send(ctx context.Context, p modelwebhook.PendingDelivery) modelwebhook.Attempt {
+	var a modelwebhook.Attempt
+	fail := func(err error) modelwebhook.Attempt {
+		msg := err.Error()
+		a.Error = &msg
+		return a
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(p.Payload))
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("Content-Type", publisher.ContentType)
+	req.Header.Set(modelwebhook.SignatureHeader, modelwebhook.Sign(p.Secret, d.now(), p.Payload))
+	req.Header.Set(modelwebhook.DeliveryHeader, strconv.FormatInt(p.ID, 10))
+	req.Header.Set(modelwebhook.EventTypeHeader, p.EventType)
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	a.Duration = time.Since(start)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+
+	code := resp.StatusCode
+	a.StatusCode = &code
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrain))
+	if code < 200 || code > 299 {
+		return fail(fmt.Errorf("receiver answered %s", resp.Status))
+	}
+	return a
+}
@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	modelwebhook "test_task/internal/domain/models/webhook"
+	logmid "test_task/internal/middleware/loger_middleware"
+	"test_task/pkg/netguard"
+
+	"github.com/google/uuid"
+)
+
+type recorded struct {
+	attempt modelwebhook.Attempt
+	status  string
+	next    time.Time
+}
+
+type fakeRepo struct {
+	batch    []modelwebhook.PendingDelivery
+	recorded []recorded
+}
+
+func (r *fakeRepo) Claim(context.Context, int, time.Duration) ([]modelwebhook.PendingDelivery, error) {
+	return r.batch, nil
+}
+
+func (r *fakeRepo) RecordAttempt(_ context.Context, _ modelwebhook.PendingDelivery, a modelwebhook.Attempt, status string, next time.Time, _ int) (bool, error) {
+	r.recorded = append(r.recorded, recorded{attempt: a, status: status, next: next})
+	return false, nil
+}
+
+func TestDeliverer_SignsAndRecordsAttempts(t *testing.T) {
+	now := time.Date(2025, time.July, 1, 12, 0, 0, 0, time.UTC)
+	payload := []byte(`{"specversion":"1.0","type":"subscription.created.v1"}`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		secret := "whsec_" + r.URL.Query().Get("secret")
+		if got, want := r.Header.Get(modelwebhook.SignatureHeader), modelwebhook.Sign(secret, now, body); got != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(modelwebhook.EventTypeHeader) != "subscription.created.v1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("fail") != "" {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	pending := func(id int64, query string, attempts int) modelwebhook.PendingDelivery {
+		return modelwebhook.PendingDelivery{
+			Delivery: modelwebhook.Delivery{
+				ID:        id,
+				WebhookID: uuid.New(),
+				EventType: "subscription.created.v1",
+				Payload:   payload,
+				Attempts:  attempts,
+			},
+			URL:    srv.URL + "/hook?" + query,
+			Secret: "whsec_abc",
+		}
+	}
+	repo := &fakeRepo{batch: []modelwebhook.PendingDelivery{
+		pending(1, "secret=abc", 0),
+		pending(2, "secret=abc&fail=1", 1),
+		pending(3, "secret=abc&fail=1", 2),
+		pending(4, "secret=wrong", 0),
+	}}
+
+	d := New(logmid.NewLogger("error"), repo, Options{
+		BatchSize:    10,
+		Timeout:      time.Second,
+		MaxAttempts:  3,
+		BackoffBase:  time.Minute,
+		BackoffMax:   time.Hour,
+		DisableAfter: 5,
+		// httptest listens on loopback.
+		AllowPrivateNetworks: true,
+	})
+	d.now = func() time.Time { return now }
+
+	if _, err := d.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if len(repo.recorded) != 4 {
+		t.Fatalf("want 4 attempts, got %d", len(repo.recorded))
+	}
+
+	want := []struct {
+		status string
+		code   int
+		next   time.Time
+	}{
+		{modelwebhook.DeliverySucceeded, http.StatusNoContent, now},
+		{modelwebhook.DeliveryPending, http.StatusServiceUnavailable, now.Add(2 * time.Minute)},
+		{modelwebhook.DeliveryFailed, http.StatusServiceUnavailable, now.Add(4 * time.Minute)},
+		{modelwebhook.DeliveryPending, http.StatusUnauthorized, now.Add(time.Minute)},
+	}
+	for i, w := range want {
+		got := repo.recorded[i]
+		if got.status != w.status || got.next != w.next {
+			t.Fatalf("delivery %d: want %s at %v, got %s at %v", i+1, w.status, w.next, got.status, got.next)
+		}
+		if got.attempt.StatusCode == nil || *got.attempt.StatusCode != w.code {
+			t.Fatalf("delivery %d: want status code %d, got %v", i+1, w.code, got.attempt.StatusCode)
+		}
+		if (w.status == modelwebhook.DeliverySucceeded) != (got.attempt.Error == nil) {
+			t.Fatalf("delivery %d: unexpected error %v", i+1, got.attempt.Error)
+		}
+	}
+}
+
+func TestDeliverer_RefusesPrivateAddresses(t *testing.T) {
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	repo := &fakeRepo{batch: []modelwebhook.PendingDelivery{{
+		Delivery: modelwebhook.Delivery{ID: 1, WebhookID: uuid.New(), EventType: "subscription.created.v1", Payload: []byte(`{}`)},
+		URL:      srv.URL + "/hook",
+		Secret:   "whsec_abc",
+	}}}
+	d := New(logmid.NewLogger("error"), repo, Options{
+		BatchSize:    10,
+		Timeout:      time.Second,
+		MaxAttempts:  3,
+		BackoffBase:  time.Minute,
+		BackoffMax:   time.Hour,
+		DisableAfter: 5,
+	})
+	if _, err := d.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if hit {
+		t.Fatal("delivery reached a loopback receiver")
+	}
+	got := repo.recorded[0].attempt
+	if got.Error == nil || !strings.Contains(*got.Error, netguard.ErrNotPublic.Error()) {
+		t.Fatalf("want the address refused, got %v", got.Error)
+	}
+}
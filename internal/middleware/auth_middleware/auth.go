@@ -0,0 +1,87 @@
+package authmiddleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	modelauth "test_task/internal/domain/models/auth"
+	JSONRes "test_task/pkg/JSON_response"
+)
+
+// ErrInvalidCredentials is returned by authenticators for unknown, expired or
+// revoked credentials.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator validates the credentials of one Authorization scheme.
+type Authenticator interface {
+	Authenticate(ctx context.Context, credentials string) (modelauth.Principal, error)
+}
+
+// Authenticate resolves the Authorization header with the authenticator
+// registered for its scheme and stores the principal in the request context.
+// Requests without the header continue anonymously; Authorize decides whether
+// that is acceptable.
+func Authenticate(log *slog.Logger, authenticators map[string]Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scheme, credentials, _ := strings.Cut(header, " ")
+			authenticator, ok := authenticators[scheme]
+			if !ok {
+				unauthorized(w, "unsupported authorization scheme")
+				return
+			}
+
+			p, err := authenticator.Authenticate(r.Context(), strings.TrimSpace(credentials))
+			if err != nil {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					log.Error("authentication failed", slog.String("scheme", scheme), slog.Any("err", err))
+				}
+				unauthorized(w, "invalid credentials")
+				return
+			}
+			if p.Method == "" {
+				p.Method = scheme
+			}
+
+			next.ServeHTTP(w, r.WithContext(modelauth.WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+// Authorize requires a principal holding scope. Anonymous requests are let
+// through only when allowAnonymous is set, which keeps the API open for
+// deployments that have not enabled authentication.
+func Authorize(scope string, allowAnonymous bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := modelauth.PrincipalFrom(r.Context())
+			if !ok {
+				if allowAnonymous {
+					next.ServeHTTP(w, r)
+					return
+				}
+				unauthorized(w, "authentication required")
+				return
+			}
+			if !p.HasScope(scope) {
+				JSONRes.WriteJSON(w, http.StatusForbidden, "missing scope "+scope)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `ApiKey realm="subscriptions"`)
+	JSONRes.WriteJSON(w, http.StatusUnauthorized, msg)
+}
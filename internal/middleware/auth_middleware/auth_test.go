@@ -0,0 +1,80 @@
+package authmiddleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	modelauth "test_task/internal/domain/models/auth"
+)
+
+// keys authenticates the credentials it holds.
+type keys map[string]modelauth.Principal
+
+func (k keys) Authenticate(_ context.Context, credentials string) (modelauth.Principal, error) {
+	if credentials == "broken" {
+		return modelauth.Principal{}, errors.New("connection refused")
+	}
+	p, ok := k[credentials]
+	if !ok {
+		return modelauth.Principal{}, ErrInvalidCredentials
+	}
+	return p, nil
+}
+
+func TestAuthenticateAuthorize(t *testing.T) {
+	authenticators := map[string]Authenticator{"ApiKey": keys{
+		"reader": {Subject: "apikey:1", Scopes: []string{modelauth.ScopeSubscriptionsRead}},
+		"admin":  {Subject: "apikey:2", Scopes: []string{modelauth.ScopeAdmin}},
+	}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name           string
+		header         string
+		allowAnonymous bool
+		wantStatus     int
+		wantChallenge  bool
+	}{
+		{name: "missing key", wantStatus: http.StatusUnauthorized, wantChallenge: true},
+		{name: "missing key allowed", allowAnonymous: true, wantStatus: http.StatusOK},
+		{name: "unknown key", header: "ApiKey nope", allowAnonymous: true, wantStatus: http.StatusUnauthorized, wantChallenge: true},
+		{name: "unsupported scheme", header: "Basic cmVhZGVy", allowAnonymous: true, wantStatus: http.StatusUnauthorized, wantChallenge: true},
+		{name: "authenticator error", header: "ApiKey broken", wantStatus: http.StatusUnauthorized, wantChallenge: true},
+		{name: "missing scope", header: "ApiKey reader", wantStatus: http.StatusForbidden},
+		{name: "scope", header: "ApiKey admin", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *modelauth.Principal
+			h := Authenticate(log, authenticators)(Authorize(modelauth.ScopeSubscriptionsWrite, tt.allowAnonymous)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if p, ok := modelauth.PrincipalFrom(r.Context()); ok {
+						got = &p
+					}
+					w.WriteHeader(http.StatusOK)
+				})))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("want status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if challenge := w.Header().Get("WWW-Authenticate") != ""; challenge != tt.wantChallenge {
+				t.Fatalf("want WWW-Authenticate %v, got %q", tt.wantChallenge, w.Header().Get("WWW-Authenticate"))
+			}
+			if tt.header == "ApiKey admin" && (got == nil || got.Subject != "apikey:2" || got.Method != "ApiKey") {
+				t.Fatalf("the principal must reach the handler with its method, got %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package bodylimitmiddleware
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	JSONRes "test_task/pkg/JSON_response"
+)
+
+// MaxBytes rejects requests that declare a body larger than limit and caps
+// the body reader for the rest, so handlers see *http.MaxBytesError when a
+// chunked body goes over the limit.
+func MaxBytes(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				JSONRes.WriteJSON(w, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Decode decodes the JSON body of r into dst. On failure it writes 413 for a
+// body over the MaxBytes limit and 400 otherwise, and returns false.
+func Decode(w http.ResponseWriter, r *http.Request, dst any) bool {
+	return decode(w, r, dst, false)
+}
+
+// DecodeOptional is Decode for endpoints whose body may be left out, which
+// keeps dst as it is.
+func DecodeOptional(w http.ResponseWriter, r *http.Request, dst any) bool {
+	return decode(w, r, dst, true)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, dst any, optional bool) bool {
+	err := json.NewDecoder(r.Body).Decode(dst)
+	switch {
+	case err == nil, optional && errors.Is(err, io.EOF):
+		return true
+	case IsTooLarge(err):
+		JSONRes.WriteJSON(w, http.StatusRequestEntityTooLarge, "request body too large")
+	default:
+		JSONRes.WriteJSON(w, http.StatusBadRequest, "invalid json body")
+	}
+	return false
+}
+
+// IsTooLarge reports whether err was caused by a body over the MaxBytes limit.
+func IsTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
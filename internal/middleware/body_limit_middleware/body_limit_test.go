@@ -0,0 +1,48 @@
+package bodylimitmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		optional   bool
+		wantStatus int
+	}{
+		{name: "ok", body: `{"name":"a"}`, wantStatus: http.StatusOK},
+		{name: "invalid", body: `{"name":`, wantStatus: http.StatusBadRequest},
+		{name: "empty", wantStatus: http.StatusBadRequest},
+		{name: "empty optional", optional: true, wantStatus: http.StatusOK},
+		{name: "too large", body: `{"name":"` + strings.Repeat("a", 64) + `"}`, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "too large optional", body: `{"name":"` + strings.Repeat("a", 64) + `"}`, optional: true, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := MaxBytes(32)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var dst struct{ Name string }
+				decode := Decode
+				if tt.optional {
+					decode = DecodeOptional
+				}
+				if decode(w, r, &dst) {
+					w.WriteHeader(http.StatusOK)
+				}
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			// A chunked body is only cut off while it is read.
+			req.ContentLength = -1
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("want status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
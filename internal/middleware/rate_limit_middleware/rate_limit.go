@@ -0,0 +1,118 @@
+package ratelimitmiddleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	JSONRes "test_task/pkg/JSON_response"
+)
+
+// Route groups with separate budgets.
+const (
+	GroupRead    = "read"
+	GroupWrite   = "write"
+	GroupSummary = "summary"
+	// GroupIP limits every request of a client IP before authentication, so
+	// that guessing keys costs the same budget whatever key is presented.
+	GroupIP = "ip"
+)
+
+type Limiter struct {
+	log    *slog.Logger
+	store  Store
+	limits map[string]Limit
+}
+
+// New creates a Limiter with a budget per route group. Groups missing from
+// limits are not limited.
+func New(log *slog.Logger, store Store, limits map[string]Limit) *Limiter {
+	return &Limiter{log: log, store: store, limits: limits}
+}
+
+// Middleware limits requests of the given route group per client. A nil
+// Limiter limits nothing.
+func (l *Limiter) Middleware(group string) func(http.Handler) http.Handler {
+	return l.middleware(group, ClientKey)
+}
+
+// MiddlewareByIP limits requests of the given route group per client IP,
+// whatever credentials they carry. A nil Limiter limits nothing.
+func (l *Limiter) MiddlewareByIP(group string) func(http.Handler) http.Handler {
+	return l.middleware(group, IPKey)
+}
+
+func (l *Limiter) middleware(group string, key func(r *http.Request) string) func(http.Handler) http.Handler {
+	if l == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	limit, ok := l.limits[group]
+	if !ok || limit.Rate <= 0 || limit.Burst <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := l.store.Take(r.Context(), group+"|"+key(r), limit)
+			if err != nil {
+				// Fail open: an unavailable store must not take the API down.
+				l.log.Error("rate limit store failed", slog.Any("err", err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			hdr := w.Header()
+			hdr.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			hdr.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			hdr.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+			hdr.Set("RateLimit-Policy", strconv.Itoa(limit.Burst)+";w="+strconv.Itoa(ceilSeconds(window(limit))))
+
+			if !res.Allowed {
+				hdr.Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(res.RetryAfter))))
+				JSONRes.WriteJSON(w, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientKey identifies the caller: the authenticated subject when there is
+// one, then the presented API key, then the client IP. It must run after
+// middleware.RealIP so that RemoteAddr holds the real client address.
+func ClientKey(r *http.Request) string {
+	if p, ok := modelauth.PrincipalFrom(r.Context()); ok && p.Subject != "" {
+		return "sub:" + p.Subject
+	}
+	if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "ApiKey "); ok && key != "" {
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:8])
+	}
+	return IPKey(r)
+}
+
+// IPKey identifies the caller by the client IP. It must run after
+// middleware.RealIP so that RemoteAddr holds the real client address.
+func IPKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// window is the time needed to refill an empty bucket.
+func window(l Limit) time.Duration {
+	return secondsToDuration(float64(l.Burst) / l.Rate)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
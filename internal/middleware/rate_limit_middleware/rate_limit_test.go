@@ -0,0 +1,124 @@
+package ratelimitmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	modelauth "test_task/internal/domain/models/auth"
+	logmid "test_task/internal/middleware/loger_middleware"
+)
+
+func TestMemoryStore_RefillsOverTime(t *testing.T) {
+	now := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		res, _ := s.Take(context.Background(), "k", limit)
+		if !res.Allowed {
+			t.Fatalf("take %d must be allowed", i)
+		}
+	}
+	res, _ := s.Take(context.Background(), "k", limit)
+	if res.Allowed {
+		t.Fatalf("third take must be rejected")
+	}
+	if res.RetryAfter != time.Second {
+		t.Fatalf("want retry after 1s, got %v", res.RetryAfter)
+	}
+
+	now = now.Add(time.Second)
+	res, _ = s.Take(context.Background(), "k", limit)
+	if !res.Allowed {
+		t.Fatalf("take after refill must be allowed")
+	}
+
+	res, _ = s.Take(context.Background(), "other", limit)
+	if !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("other key must have its own bucket, got %+v", res)
+	}
+}
+
+func TestMiddleware_RejectsWithHeaders(t *testing.T) {
+	l := New(logmid.NewLogger("error"), NewMemoryStore(), map[string]Limit{
+		GroupSummary: {Rate: 0.5, Burst: 1},
+	})
+	h := l.Middleware(GroupSummary)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/summary", nil)
+		req.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("10.0.0.1:5000")
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d", w.Code)
+	}
+	if w.Header().Get("RateLimit-Limit") != "1" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("unexpected headers: %v", w.Header())
+	}
+
+	w = do("10.0.0.1:5001")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("want 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "2" {
+		t.Fatalf("want Retry-After 2, got %q", w.Header().Get("Retry-After"))
+	}
+
+	if w := do("10.0.0.2:5000"); w.Code != http.StatusOK {
+		t.Fatalf("other client must not be limited, got %d", w.Code)
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	if got := ClientKey(req); got != "ip:10.0.0.1" {
+		t.Fatalf("want ip key, got %q", got)
+	}
+
+	req.Header.Set("Authorization", "ApiKey secret")
+	if got := ClientKey(req); got[:4] != "key:" {
+		t.Fatalf("want api key based key, got %q", got)
+	}
+
+	req = req.WithContext(modelauth.WithPrincipal(req.Context(), modelauth.Principal{Subject: "billing"}))
+	if got := ClientKey(req); got != "sub:billing" {
+		t.Fatalf("want subject key, got %q", got)
+	}
+}
+
+func TestMiddlewareByIP_IgnoresCredentials(t *testing.T) {
+	l := New(logmid.NewLogger("error"), NewMemoryStore(), map[string]Limit{
+		GroupIP: {Rate: 0.5, Burst: 1},
+	})
+	h := l.MiddlewareByIP(GroupIP)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions", nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		req.Header.Set("Authorization", "ApiKey "+key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := do("guess-1"); code != http.StatusOK {
+		t.Fatalf("want 200, got %d", code)
+	}
+	if code := do("guess-2"); code != http.StatusTooManyRequests {
+		t.Fatalf("a new key must not reset the IP budget, got %d", code)
+	}
+}
@@ -0,0 +1,46 @@
+package tenantmiddleware
+
+import (
+	"net/http"
+
+	modelauth "test_task/internal/domain/models/auth"
+	modeltenant "test_task/internal/domain/models/tenant"
+	JSONRes "test_task/pkg/JSON_response"
+)
+
+// Resolve stores the tenant of the request in its context. The credentials
+// decide it: keys are created in a tenant and work only there, anonymous
+// callers get modeltenant.Default. Only admins whose credentials belong to no
+// tenant, such as the bootstrap key, pick it with the tenant header; for
+// everybody else a header naming another tenant is refused. It runs after
+// Authenticate.
+func Resolve(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(modeltenant.Header)
+		if header != "" && !modeltenant.Valid(header) {
+			JSONRes.WriteJSON(w, http.StatusBadRequest, "invalid "+modeltenant.Header)
+			return
+		}
+
+		id := modeltenant.Default
+		p, ok := modelauth.PrincipalFrom(r.Context())
+		switch {
+		case ok && p.TenantID != "":
+			id = p.TenantID
+		case ok && p.HasScope(modelauth.ScopeAdmin):
+			if header != "" {
+				id = header
+			}
+		}
+		if header != "" && header != id {
+			msg := "credentials belong to another tenant"
+			if !ok {
+				msg = modeltenant.Header + " requires admin credentials"
+			}
+			JSONRes.WriteJSON(w, http.StatusForbidden, msg)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(modeltenant.WithTenant(r.Context(), id)))
+	})
+}
@@ -0,0 +1,64 @@
+package tenantmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	modelauth "test_task/internal/domain/models/auth"
+	modeltenant "test_task/internal/domain/models/tenant"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *modelauth.Principal
+		header     string
+		wantStatus int
+		wantTenant string
+	}{
+		{name: "anonymous", wantStatus: http.StatusOK, wantTenant: modeltenant.Default},
+		{name: "anonymous with header", header: "acme", wantStatus: http.StatusForbidden},
+		{name: "anonymous with default header", header: modeltenant.Default, wantStatus: http.StatusOK, wantTenant: modeltenant.Default},
+		{name: "invalid header", header: "Acme Corp", wantStatus: http.StatusBadRequest},
+		{name: "unbound admin with header", principal: &modelauth.Principal{Subject: "apikey:bootstrap", Scopes: []string{modelauth.ScopeAdmin}}, header: "acme", wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "unbound admin", principal: &modelauth.Principal{Subject: "apikey:bootstrap", Scopes: []string{modelauth.ScopeAdmin}}, wantStatus: http.StatusOK, wantTenant: modeltenant.Default},
+		{name: "unbound non-admin with header", principal: &modelauth.Principal{Subject: "jwt:ann", Scopes: []string{modelauth.ScopeSubscriptionsWrite}}, header: "acme", wantStatus: http.StatusForbidden},
+		{name: "bound admin with another header", principal: &modelauth.Principal{Subject: "apikey:2", TenantID: "globex", Scopes: []string{modelauth.ScopeAdmin}}, header: "acme", wantStatus: http.StatusForbidden},
+		{name: "bound key", principal: &modelauth.Principal{Subject: "apikey:1", TenantID: "globex"}, wantStatus: http.StatusOK, wantTenant: "globex"},
+		{name: "bound key with its header", principal: &modelauth.Principal{Subject: "apikey:1", TenantID: "globex"}, header: "globex", wantStatus: http.StatusOK, wantTenant: "globex"},
+		{name: "bound key with another header", principal: &modelauth.Principal{Subject: "apikey:1", TenantID: "globex"}, header: "acme", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *string
+			h := Resolve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = modeltenant.Scope(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions", nil)
+			if tt.principal != nil {
+				req = req.WithContext(modelauth.WithPrincipal(req.Context(), *tt.principal))
+			}
+			if tt.header != "" {
+				req.Header.Set(modeltenant.Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("want status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if got != nil {
+					t.Fatalf("handler must not run")
+				}
+				return
+			}
+			if got == nil || *got != tt.wantTenant {
+				t.Fatalf("want tenant %s, got %v", tt.wantTenant, got)
+			}
+		})
+	}
+}
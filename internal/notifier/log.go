@@ -0,0 +1,35 @@
+package notifier
+
+import (
+	"context"
+	"log/slog"
+
+	modelnotify "test_task/internal/domain/models/notification"
+)
+
+const (
+	ChannelLog     = "log"
+	ChannelWebhook = "webhook"
+	ChannelSMTP    = "smtp"
+)
+
+// Log writes notifications to the application log.
+type Log struct {
+	log *slog.Logger
+}
+
+func NewLog(log *slog.Logger) *Log {
+	return &Log{log: log}
+}
+
+func (n *Log) Channel() string { return ChannelLog }
+
+func (n *Log) Notify(_ context.Context, msg modelnotify.Notification) error {
+	n.log.Info("notification",
+		slog.String("kind", msg.Kind),
+		slog.String("user_id", msg.UserID.String()),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+	return nil
+}
@@ -0,0 +1,122 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	modelnotify "test_task/internal/domain/models/notification"
+
+	"github.com/google/uuid"
+)
+
+// fakeSMTP accepts a single message and hands it over on the returned channel.
+func fakeSMTP(t *testing.T) (host string, port int, mail <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	out := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tp := textproto.NewConn(conn)
+		_ = tp.PrintfLine("220 fake ESMTP")
+		var msg strings.Builder
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				_ = tp.PrintfLine("250 fake")
+			case strings.HasPrefix(cmd, "MAIL FROM"), strings.HasPrefix(cmd, "RCPT TO"):
+				msg.WriteString(line + "\n")
+				_ = tp.PrintfLine("250 OK")
+			case cmd == "DATA":
+				_ = tp.PrintfLine("354 go ahead")
+				data, err := tp.ReadDotLines()
+				if err != nil {
+					return
+				}
+				msg.WriteString(strings.Join(data, "\n"))
+				_ = tp.PrintfLine("250 queued")
+			case cmd == "QUIT":
+				_ = tp.PrintfLine("221 bye")
+				out <- msg.String()
+				return
+			default:
+				_ = tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+
+	h, p, _ := net.SplitHostPort(ln.Addr().String())
+	portNum, _ := strconv.Atoi(p)
+	return h, portNum, out
+}
+
+func TestSMTP_Notify(t *testing.T) {
+	host, port, mail := fakeSMTP(t)
+
+	n := NewSMTP(SMTPConfig{Host: host, Port: port, From: "noreply@example.com", To: "user@example.com"})
+	err := n.Notify(context.Background(), modelnotify.Notification{
+		UserID:  uuid.New(),
+		Kind:    modelnotify.KindRenewalDue,
+		Subject: "Netflix renews on 2025-08-01",
+		Body:    "Netflix will charge 700 RUB on 2025-08-01.",
+	})
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	got := <-mail
+	for _, want := range []string{
+		"MAIL FROM:<noreply@example.com>",
+		"RCPT TO:<user@example.com>",
+		"Subject: Netflix renews on 2025-08-01",
+		"Netflix will charge 700 RUB on 2025-08-01.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("mail must contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestSMTP_Rejected(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		w := bufio.NewWriter(conn)
+		_, _ = w.WriteString("554 no service\r\n")
+		_ = w.Flush()
+	}()
+
+	h, p, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ := strconv.Atoi(p)
+	n := NewSMTP(SMTPConfig{Host: h, Port: port, From: "a@example.com", To: "b@example.com"})
+	if err := n.Notify(context.Background(), modelnotify.Notification{Subject: "x"}); err == nil {
+		t.Fatalf("expected error from rejecting server")
+	}
+}
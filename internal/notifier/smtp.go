@@ -0,0 +1,64 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	modelnotify "test_task/internal/domain/models/notification"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// To receives every notification until users have their own addresses.
+	To string
+}
+
+// SMTP sends notifications as plain text e-mails. STARTTLS is used when the
+// server offers it; credentials are only sent when a username is configured.
+type SMTP struct {
+	cfg  SMTPConfig
+	addr string
+}
+
+func NewSMTP(cfg SMTPConfig) *SMTP {
+	return &SMTP{
+		cfg:  cfg,
+		addr: net.JoinHostPort(cfg.Host, fmt.Sprint(cfg.Port)),
+	}
+}
+
+func (n *SMTP) Channel() string { return ChannelSMTP }
+
+func (n *SMTP) Notify(_ context.Context, msg modelnotify.Notification) error {
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+	}
+	if err := smtp.SendMail(n.addr, auth, n.cfg.From, []string{n.cfg.To}, n.message(msg)); err != nil {
+		return fmt.Errorf("send mail: %w", err)
+	}
+	return nil
+}
+
+func (n *SMTP) message(msg modelnotify.Notification) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + n.cfg.From + "\r\n")
+	b.WriteString("To: " + n.cfg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().UTC().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
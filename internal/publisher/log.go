@@ -0,0 +1,28 @@
+package publisher
+
+import (
+	"context"
+	"log/slog"
+
+	modelevent "test_task/internal/domain/models/event"
+)
+
+// Log writes events to the application log. It is the default publisher and
+// is handy when nobody consumes events yet.
+type Log struct {
+	log *slog.Logger
+}
+
+func NewLog(log *slog.Logger) *Log {
+	return &Log{log: log}
+}
+
+func (p *Log) Publish(_ context.Context, ev modelevent.Event) error {
+	p.log.Info("event published",
+		slog.String("id", ev.ID),
+		slog.String("type", ev.Type),
+		slog.String("subject", ev.Subject),
+		slog.String("data", string(ev.Data)),
+	)
+	return nil
+}
@@ -0,0 +1,27 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+
+	modelevent "test_task/internal/domain/models/event"
+)
+
+type Publisher interface {
+	Publish(ctx context.Context, ev modelevent.Event) error
+}
+
+// Multi publishes every event to all publishers. An event is considered
+// published only when all of them succeed, so publishers must tolerate
+// receiving an event again.
+type Multi []Publisher
+
+func (m Multi) Publish(ctx context.Context, ev modelevent.Event) error {
+	var errs []error
+	for _, p := range m {
+		if err := p.Publish(ctx, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
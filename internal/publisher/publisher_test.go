@@ -0,0 +1,89 @@
+package publisher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	modelevent "test_task/internal/domain/models/event"
+)
+
+func newEvent(t *testing.T, eventType string) modelevent.Event {
+	t.Helper()
+	ev, err := modelevent.New(eventType, "60601fee-2bf1-4721-ae6f-7636e79a0cba", map[string]int{"price": 400})
+	if err != nil {
+		t.Fatalf("event: %v", err)
+	}
+	return ev
+}
+
+func TestFile_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	p := NewFile(path)
+
+	first := newEvent(t, modelevent.TypeSubscriptionCreated)
+	second := newEvent(t, modelevent.TypeSubscriptionDeleted)
+	for _, ev := range []modelevent.Event{first, second} {
+		if err := p.Publish(context.Background(), ev); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+
+	var got []modelevent.Event
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var ev modelevent.Event
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		got = append(got, ev)
+	}
+	if len(got) != 2 || got[0].ID != first.ID || got[1].ID != second.ID {
+		t.Fatalf("unexpected events %+v", got)
+	}
+	if got[0].SpecVersion != modelevent.SpecVersion {
+		t.Fatalf("want specversion %s, got %s", modelevent.SpecVersion, got[0].SpecVersion)
+	}
+}
+
+func TestWebhook_Publish(t *testing.T) {
+	var received modelevent.Event
+	status := http.StatusAccepted
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != ContentType {
+			t.Errorf("want content type %s, got %s", ContentType, ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	p := NewWebhook(srv.URL, time.Second)
+	ev := newEvent(t, modelevent.TypeSubscriptionUpdated)
+
+	if err := p.Publish(context.Background(), ev); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if received.ID != ev.ID || received.Type != ev.Type {
+		t.Fatalf("unexpected event %+v", received)
+	}
+
+	status = http.StatusBadGateway
+	if err := p.Publish(context.Background(), ev); err == nil {
+		t.Fatalf("expected error for 502")
+	}
+}
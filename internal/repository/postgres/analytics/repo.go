@@ -0,0 +1,191 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	modelanalytics "test_task/internal/domain/models/analytics"
+	modeltenant "test_task/internal/domain/models/tenant"
+)
+
+const (
+	// subsCTE lists the subscriptions of tenant $3 that can be billed between
+	// $1 and $2 with the last month they are billed in: their end_date or, for deleted
+	// ones, the month they were deleted in. The filter on the raw dates is
+	// answered by idx_subscriptions_dates and idx_subscriptions_end_date.
+	subsCTE = `WITH params AS (
+	SELECT $1::date AS p_from, $2::date AS p_to
+	),
+	subs AS (
+	SELECT
+		s.id,
+		s.user_id,
+		s.service_name,
+		s.price,
+		s.trial_until,
+		s.trial_price,
+		s.start_date,
+		LEAST(s.end_date, date_trunc('month', s.deleted_at AT TIME ZONE 'UTC')::date) AS end_date
+	FROM subscriptions s
+	CROSS JOIN params
+	WHERE ($3::text IS NULL OR s.tenant_id = $3)
+		AND s.start_date <= params.p_to
+		AND (s.end_date IS NULL OR s.end_date >= (params.p_from - interval '1 month')::date)
+	)
+	`
+	// sqlTextForMRR sums the charges per user and month, from the month
+	// before $1 so the first month has something to move from, and compares
+	// every user with the month before. Charges follow the summary: trial
+	// months at the trial price and paused months left out.
+	sqlTextForMRR = subsCTE + `,
+	charges AS (
+	SELECT s.user_id, m::date AS month,
+		CASE WHEN s.trial_until IS NOT NULL AND m::date <= s.trial_until
+			THEN COALESCE(s.trial_price, 0)
+			ELSE s.price
+		END AS amount
+	FROM subs s
+	CROSS JOIN params
+	CROSS JOIN LATERAL generate_series(
+		GREATEST(s.start_date, (params.p_from - interval '1 month')::date),
+		LEAST(COALESCE(s.end_date, params.p_to), params.p_to),
+		interval '1 month') AS m
+	WHERE NOT EXISTS (
+		SELECT 1 FROM subscription_pauses p
+		WHERE p.subscription_id = s.id
+		AND m::date >= p.from_date
+		AND (p.to_date IS NULL OR m::date <= p.to_date)
+	)
+	),
+	user_months AS (
+	SELECT user_id, month, (month + interval '1 month')::date AS next_month, SUM(amount) AS mrr
+	FROM charges
+	GROUP BY user_id, month
+	),
+	pairs AS (
+	SELECT COALESCE(c.month, p.next_month) AS month, COALESCE(c.mrr, 0) AS cur, COALESCE(p.mrr, 0) AS prev
+	FROM user_months c
+	FULL JOIN user_months p ON p.user_id = c.user_id AND p.next_month = c.month
+	)
+	SELECT g::date AS month,
+		COALESCE(SUM(pairs.cur), 0)::bigint AS mrr,
+		COALESCE(SUM(pairs.cur) FILTER (WHERE pairs.prev = 0), 0)::bigint AS new,
+		COALESCE(SUM(pairs.cur - pairs.prev) FILTER (WHERE pairs.prev > 0 AND pairs.cur > pairs.prev), 0)::bigint AS expansion,
+		COALESCE(SUM(pairs.prev - pairs.cur) FILTER (WHERE pairs.cur > 0 AND pairs.cur < pairs.prev), 0)::bigint AS contraction,
+		COALESCE(SUM(pairs.prev) FILTER (WHERE pairs.cur = 0), 0)::bigint AS churned
+	FROM params
+	CROSS JOIN generate_series(params.p_from, params.p_to, interval '1 month') AS g
+	LEFT JOIN pairs ON pairs.month = g::date
+	GROUP BY g
+	ORDER BY g;`
+	// sqlTextForChurn counts per service the subscriptions billed in the
+	// period and those whose last month is before $2. Pauses are not churn.
+	sqlTextForChurn = subsCTE + `SELECT s.service_name,
+		count(*) AS subscribers,
+		count(*) FILTER (WHERE s.end_date < params.p_to) AS churned
+	FROM subs s
+	CROSS JOIN params
+	WHERE s.end_date IS NULL OR s.end_date >= GREATEST(s.start_date, params.p_from)
+	GROUP BY s.service_name
+	ORDER BY s.service_name;`
+	// sqlTextForCohorts counts, for every start month from $1 to $2, the
+	// subscriptions started then that are still active in each month up to
+	// $2. Months without active subscriptions are left out.
+	sqlTextForCohorts = subsCTE + `SELECT s.start_date AS cohort, m::date AS month, count(*) AS active
+	FROM subs s
+	CROSS JOIN params
+	CROSS JOIN LATERAL generate_series(s.start_date, LEAST(COALESCE(s.end_date, params.p_to), params.p_to), interval '1 month') AS m
+	WHERE s.start_date >= params.p_from
+	GROUP BY s.start_date, m
+	ORDER BY s.start_date, m;`
+)
+
+type DB struct {
+	sql *sql.DB
+}
+
+func New(sql *sql.DB) *DB {
+	return &DB{
+		sql: sql,
+	}
+}
+
+// MRR returns the recurring revenue and its movement for every month from
+// from to to.
+func (r *DB) MRR(ctx context.Context, from, to time.Time) ([]modelanalytics.MRRMonth, error) {
+	ctx, cancel := context.WithTimeout(ctx, 7*time.Second)
+	defer cancel()
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForMRR, from, to, modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("mrr query: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelanalytics.MRRMonth
+	for rows.Next() {
+		var m modelanalytics.MRRMonth
+		if err := rows.Scan(&m.Month, &m.MRR, &m.New, &m.Expansion, &m.Contraction, &m.Churned); err != nil {
+			return nil, fmt.Errorf("mrr scan: %w", err)
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("mrr rows: %w", err)
+	}
+	return out, nil
+}
+
+// Churn returns the churn of every service with subscriptions billed between
+// from and to.
+func (r *DB) Churn(ctx context.Context, from, to time.Time) ([]modelanalytics.ServiceChurn, error) {
+	ctx, cancel := context.WithTimeout(ctx, 7*time.Second)
+	defer cancel()
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForChurn, from, to, modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("churn query: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelanalytics.ServiceChurn
+	for rows.Next() {
+		var c modelanalytics.ServiceChurn
+		if err := rows.Scan(&c.ServiceName, &c.Subscribers, &c.Churned); err != nil {
+			return nil, fmt.Errorf("churn scan: %w", err)
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("churn rows: %w", err)
+	}
+	return out, nil
+}
+
+// Cohorts returns the active subscriptions per start month and month for the
+// subscriptions started between from and to.
+func (r *DB) Cohorts(ctx context.Context, from, to time.Time) ([]modelanalytics.CohortMonth, error) {
+	ctx, cancel := context.WithTimeout(ctx, 7*time.Second)
+	defer cancel()
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForCohorts, from, to, modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("cohorts query: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelanalytics.CohortMonth
+	for rows.Next() {
+		var c modelanalytics.CohortMonth
+		if err := rows.Scan(&c.Cohort, &c.Month, &c.Active); err != nil {
+			return nil, fmt.Errorf("cohorts scan: %w", err)
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("cohorts rows: %w", err)
+	}
+	return out, nil
+}
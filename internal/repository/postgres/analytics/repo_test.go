@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRepo_MRR(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	from := time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForMRR)).
+		WithArgs(from, to, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"month", "mrr", "new", "expansion", "contraction", "churned"}).
+			AddRow(from, 1100, 100, 0, 0, 0).
+			AddRow(to, 1250, 0, 400, 250, 0))
+
+	got, err := repo.MRR(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("MRR error: %v", err)
+	}
+	if len(got) != 2 || got[1].MRR != 1250 || got[1].Expansion != 400 || got[1].Contraction != 250 || got[1].NetChange() != 150 {
+		t.Fatalf("unexpected months: %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Churn(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	from := time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForChurn)).
+		WithArgs(from, to, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"service_name", "subscribers", "churned"}).
+			AddRow("Netflix", 2, 1).
+			AddRow("Spotify", 4, 3))
+
+	got, err := repo.Churn(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("Churn error: %v", err)
+	}
+	if len(got) != 2 || got[0].Rate() != 0.5 || got[1].Rate() != 0.75 {
+		t.Fatalf("unexpected churn: %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Cohorts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	from := time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForCohorts)).
+		WithArgs(from, to, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"cohort", "month", "active"}).
+			AddRow(from, from, 3).
+			AddRow(from, to, 1))
+
+	got, err := repo.Cohorts(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("Cohorts error: %v", err)
+	}
+	if len(got) != 2 || !got[1].Cohort.Equal(from) || !got[1].Month.Equal(to) || got[1].Active != 1 {
+		t.Fatalf("unexpected cells: %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
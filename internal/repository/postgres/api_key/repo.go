@@ -0,0 +1,166 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	modelkey "test_task/internal/domain/models/api_key"
+	modeltenant "test_task/internal/domain/models/tenant"
+	myerror "test_task/pkg/global_errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	keyColumns = `id, tenant_id, name, prefix, key_hash, array_to_string(scopes, ','), user_id, expires_at, last_used_at, revoked_at, created_at`
+
+	sqlTextForCreate = `INSERT INTO api_keys(name, prefix, key_hash, scopes, user_id, expires_at, tenant_id)
+	VALUES ($1, $2, $3, string_to_array($4, ','), $5, $6, $7)
+	RETURNING id, created_at`
+	sqlTextForList = `SELECT ` + keyColumns + `
+	FROM api_keys
+	WHERE ($1::text IS NULL OR tenant_id = $1)
+	ORDER BY created_at DESC`
+	// sqlTextForGetByHash finds the key of a request before its tenant is
+	// known, the tenant of the key decides it, so it is not scoped.
+	sqlTextForGetByHash = `SELECT ` + keyColumns + `
+	FROM api_keys
+	WHERE key_hash = $1 AND revoked_at IS NULL`
+	sqlTextForRevoke = `UPDATE api_keys
+	SET revoked_at = now()
+	WHERE id = $1 AND revoked_at IS NULL
+	AND ($2::text IS NULL OR tenant_id = $2)`
+	// sqlTextForTouch runs during authentication too, for the key just found.
+	sqlTextForTouch = `UPDATE api_keys
+	SET last_used_at = now()
+	WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`
+)
+
+type DB struct {
+	sql *sql.DB
+}
+
+func New(sql *sql.DB) *DB {
+	return &DB{
+		sql: sql,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row scanner) (modelkey.APIKey, error) {
+	var k modelkey.APIKey
+	var scopes string
+	err := row.Scan(
+		&k.ID,
+		&k.TenantID,
+		&k.Name,
+		&k.Prefix,
+		&k.KeyHash,
+		&scopes,
+		&k.UserID,
+		&k.ExpiresAt,
+		&k.LastUsedAt,
+		&k.RevokedAt,
+		&k.CreatedAt,
+	)
+	if err != nil {
+		return modelkey.APIKey{}, err
+	}
+	if scopes != "" {
+		k.Scopes = strings.Split(scopes, ",")
+	}
+	return k, nil
+}
+
+func (r *DB) Create(ctx context.Context, k modelkey.APIKey) (modelkey.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	k.TenantID = modeltenant.From(ctx)
+	err := r.sql.QueryRowContext(ctx, sqlTextForCreate,
+		k.Name,
+		k.Prefix,
+		k.KeyHash,
+		strings.Join(k.Scopes, ","),
+		k.UserID,
+		k.ExpiresAt,
+		k.TenantID,
+	).Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return modelkey.APIKey{}, fmt.Errorf("create api key: %w", err)
+	}
+	return k, nil
+}
+
+func (r *DB) List(ctx context.Context) ([]modelkey.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForList, modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelkey.APIKey
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list api keys scan: %w", err)
+		}
+		out = append(out, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list api keys rows: %w", err)
+	}
+	return out, nil
+}
+
+func (r *DB) GetByHash(ctx context.Context, hash string) (modelkey.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	k, err := scanKey(r.sql.QueryRowContext(ctx, sqlTextForGetByHash, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelkey.APIKey{}, myerror.ErrorAPIKeyNotFound
+		}
+		return modelkey.APIKey{}, fmt.Errorf("get api key: %w", err)
+	}
+	return k, nil
+}
+
+func (r *DB) Revoke(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tag, err := r.sql.ExecContext(ctx, sqlTextForRevoke, id, modeltenant.Scope(ctx))
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	rows, err := tag.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if rows == 0 {
+		return myerror.ErrorAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed records usage at most once a minute per key.
+func (r *DB) TouchLastUsed(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := r.sql.ExecContext(ctx, sqlTextForTouch, id); err != nil {
+		return fmt.Errorf("touch api key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	modelkey "test_task/internal/domain/models/api_key"
+	modeltenant "test_task/internal/domain/models/tenant"
+	myerror "test_task/pkg/global_errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestRepo_Create_OK(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	id := uuid.New()
+	userID := uuid.New()
+	now := time.Now().UTC()
+
+	k := modelkey.APIKey{
+		Name:    "billing",
+		Prefix:  "sk_abcdefgh",
+		KeyHash: "hash",
+		Scopes:  []string{"subscriptions:read", "subscriptions:write"},
+		UserID:  &userID,
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForCreate)).
+		WithArgs(k.Name, k.Prefix, k.KeyHash, "subscriptions:read,subscriptions:write", k.UserID, k.ExpiresAt, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(id.String(), now))
+
+	got, err := repo.Create(modeltenant.WithTenant(context.Background(), "acme"), k)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if got.ID != id || got.TenantID != "acme" {
+		t.Fatalf("unexpected key: %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_GetByHash_ScopesAndNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	id := uuid.New()
+	now := time.Now().UTC()
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForGetByHash)).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "tenant_id", "name", "prefix", "key_hash", "scopes", "user_id", "expires_at", "last_used_at", "revoked_at", "created_at",
+		}).AddRow(id.String(), "acme", "billing", "sk_abcdefgh", "hash", "admin,subscriptions:read", nil, nil, nil, nil, now))
+
+	k, err := repo.GetByHash(context.Background(), "hash")
+	if err != nil {
+		t.Fatalf("GetByHash error: %v", err)
+	}
+	if len(k.Scopes) != 2 || k.Scopes[0] != "admin" {
+		t.Fatalf("scopes mismatch: %v", k.Scopes)
+	}
+	if k.TenantID != "acme" {
+		t.Fatalf("tenant mismatch: %s", k.TenantID)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForGetByHash)).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+
+	if _, err := repo.GetByHash(context.Background(), "missing"); err != myerror.ErrorAPIKeyNotFound {
+		t.Fatalf("want ErrorAPIKeyNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package budget
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	modelbudget "test_task/internal/domain/models/budget"
+	modelevent "test_task/internal/domain/models/event"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"test_task/internal/repository/postgres/tenancy"
+	myerror "test_task/pkg/global_errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	budgetColumns = `b.id, b.user_id, b.amount, b.service_name, b.tag_id, t.name, b.enforce, b.created_at, b.updated_at`
+
+	sqlTextForCreate = `WITH b AS (
+		INSERT INTO budgets(user_id, amount, service_name, tag_id, enforce, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING *
+	)
+	SELECT ` + budgetColumns + `
+	FROM b LEFT JOIN tags t ON t.id = b.tag_id`
+	sqlTextForGet = `SELECT ` + budgetColumns + `
+	FROM budgets b LEFT JOIN tags t ON t.id = b.tag_id
+	WHERE b.id = $1 AND ($2::text IS NULL OR b.tenant_id = $2)`
+	sqlTextForList = `SELECT ` + budgetColumns + `
+	FROM budgets b LEFT JOIN tags t ON t.id = b.tag_id
+	WHERE ($1::uuid IS NULL OR b.user_id = $1)
+	AND ($2::text IS NULL OR b.tenant_id = $2)
+	ORDER BY b.created_at, b.id`
+	sqlTextForUpdate = `WITH b AS (
+		UPDATE budgets
+		SET amount = $2, service_name = $3, tag_id = $4, enforce = $5
+		WHERE id = $1 AND ($6::text IS NULL OR tenant_id = $6)
+		RETURNING *
+	)
+	SELECT ` + budgetColumns + `
+	FROM b LEFT JOIN tags t ON t.id = b.tag_id`
+	sqlTextForDelete = `DELETE FROM budgets WHERE id = $1 AND ($2::text IS NULL OR tenant_id = $2)`
+	sqlTextForAlert  = `INSERT INTO outbox(aggregate_id, event_type, payload, tenant_id)
+	VALUES ($1, $2, $3::jsonb, $4)`
+)
+
+type DB struct {
+	sql *sql.DB
+}
+
+func New(sql *sql.DB) *DB {
+	return &DB{
+		sql: sql,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanBudget(row scanner) (modelbudget.Budget, error) {
+	var b modelbudget.Budget
+	err := row.Scan(&b.ID, &b.UserID, &b.Amount, &b.ServiceName, &b.TagID, &b.Tag, &b.Enforce, &b.CreatedAt, &b.UpdatedAt)
+	return b, err
+}
+
+func (r *DB) Create(ctx context.Context, b modelbudget.Budget) (modelbudget.Budget, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	out, err := scanBudget(r.sql.QueryRowContext(ctx, sqlTextForCreate, b.UserID, b.Amount, b.ServiceName, b.TagID, b.Enforce, modeltenant.From(ctx)))
+	if err != nil {
+		return modelbudget.Budget{}, fmt.Errorf("create budget: %w", err)
+	}
+	return out, nil
+}
+
+func (r *DB) Get(ctx context.Context, id uuid.UUID) (modelbudget.Budget, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	b, err := scanBudget(r.sql.QueryRowContext(ctx, sqlTextForGet, id, modeltenant.Scope(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelbudget.Budget{}, myerror.ErrorBudgetNotFound
+		}
+		return modelbudget.Budget{}, fmt.Errorf("get budget: %w", err)
+	}
+	return b, nil
+}
+
+// List returns the budgets of a user, or of everybody when userID is nil.
+// Inside a subscription guard it reads in the guarded transaction.
+func (r *DB) List(ctx context.Context, userID *uuid.UUID) ([]modelbudget.Budget, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := tenancy.Conn(ctx, r.sql).QueryContext(ctx, sqlTextForList, userID, modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list budgets: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelbudget.Budget
+	for rows.Next() {
+		b, err := scanBudget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list budgets scan: %w", err)
+		}
+		out = append(out, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list budgets rows: %w", err)
+	}
+	return out, nil
+}
+
+// Update replaces the limits of a budget; the owner cannot be changed.
+func (r *DB) Update(ctx context.Context, b modelbudget.Budget) (modelbudget.Budget, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	out, err := scanBudget(r.sql.QueryRowContext(ctx, sqlTextForUpdate, b.ID, b.Amount, b.ServiceName, b.TagID, b.Enforce, modeltenant.Scope(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelbudget.Budget{}, myerror.ErrorBudgetNotFound
+		}
+		return modelbudget.Budget{}, fmt.Errorf("update budget: %w", err)
+	}
+	return out, nil
+}
+
+func (r *DB) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.sql.ExecContext(ctx, sqlTextForDelete, id, modeltenant.Scope(ctx))
+	if err != nil {
+		return fmt.Errorf("delete budget: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete budget rows: %w", err)
+	}
+	if n == 0 {
+		return myerror.ErrorBudgetNotFound
+	}
+	return nil
+}
+
+// WriteAlert queues a budget event in the outbox; events of one budget are
+// delivered in order.
+func (r *DB) WriteAlert(ctx context.Context, budgetID uuid.UUID, ev modelevent.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	if _, err := r.sql.ExecContext(ctx, sqlTextForAlert, budgetID, ev.Type, string(payload), modeltenant.From(ctx)); err != nil {
+		return fmt.Errorf("write outbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package budget
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	modelbudget "test_task/internal/domain/models/budget"
+	modelevent "test_task/internal/domain/models/event"
+	"test_task/internal/repository/postgres/tenancy"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var budgetRowColumns = []string{"id", "user_id", "amount", "service_name", "tag_id", "name", "enforce", "created_at", "updated_at"}
+
+func TestRepo_Create_ReturnsTagName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	id := uuid.New()
+	userID := uuid.New()
+	tagID := uuid.New()
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForCreate)).
+		WithArgs(userID, 1000, nil, &tagID, true, "default").
+		WillReturnRows(sqlmock.NewRows(budgetRowColumns).AddRow(id.String(), userID.String(), 1000, nil, tagID.String(), "Work", true, now, now))
+
+	got, err := repo.Create(context.Background(), modelbudget.Budget{UserID: userID, Amount: 1000, TagID: &tagID, Enforce: true})
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if got.ID != id || got.Tag == nil || *got.Tag != "Work" || !got.Enforce {
+		t.Fatalf("unexpected budget: %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_WriteAlert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	id := uuid.New()
+	ev, err := modelevent.New(modelevent.TypeBudgetExceeded, id.String(), modelbudget.Alert{BudgetID: id})
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForAlert)).
+		WithArgs(id, modelevent.TypeBudgetExceeded, sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := repo.WriteAlert(context.Background(), id, ev); err != nil {
+		t.Fatalf("WriteAlert error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_List_InGuardedTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	userID := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForList)).
+		WithArgs(userID, "default").
+		WillReturnRows(sqlmock.NewRows(budgetRowColumns).
+			AddRow(uuid.New(), userID, 1000, nil, nil, nil, true, time.Now(), time.Now()))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	budgets, err := New(db).List(tenancy.WithTx(context.Background(), tx), &userID)
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(budgets) != 1 {
+		t.Fatalf("want 1 budget, got %d", len(budgets))
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	modelnotify "test_task/internal/domain/models/notification"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"time"
+)
+
+const (
+	// sqlTextForClaim inserts a pending notification, or takes over a failed
+	// one with the same dedup key. Pending and sent ones are left alone, so a
+	// notification is sent at most once.
+	sqlTextForClaim = `INSERT INTO notifications(user_id, subscription_id, kind, channel, subject, body, dedup_key, tenant_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (tenant_id, dedup_key) DO UPDATE
+	SET status = 'pending', error = NULL, channel = EXCLUDED.channel, subject = EXCLUDED.subject, body = EXCLUDED.body
+	WHERE notifications.status = 'failed'
+	RETURNING id, created_at`
+	sqlTextForSent = `UPDATE notifications
+	SET status = 'sent', sent_at = now()
+	WHERE id = $1 AND ($2::text IS NULL OR tenant_id = $2)`
+	sqlTextForFailed = `UPDATE notifications
+	SET status = 'failed', error = $2
+	WHERE id = $1 AND ($3::text IS NULL OR tenant_id = $3)`
+)
+
+type DB struct {
+	sql *sql.DB
+}
+
+func New(sql *sql.DB) *DB {
+	return &DB{
+		sql: sql,
+	}
+}
+
+// Claim records n as pending. It reports false when n was already sent or is
+// being sent.
+func (r *DB) Claim(ctx context.Context, n modelnotify.Notification) (modelnotify.Notification, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	n.TenantID = modeltenant.From(ctx)
+	err := r.sql.QueryRowContext(ctx, sqlTextForClaim,
+		n.UserID,
+		n.SubscriptionID,
+		n.Kind,
+		n.Channel,
+		n.Subject,
+		n.Body,
+		n.DedupKey,
+		n.TenantID,
+	).Scan(&n.ID, &n.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelnotify.Notification{}, false, nil
+		}
+		return modelnotify.Notification{}, false, fmt.Errorf("claim notification: %w", err)
+	}
+	n.Status = modelnotify.StatusPending
+	return n, true, nil
+}
+
+func (r *DB) MarkSent(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := r.sql.ExecContext(ctx, sqlTextForSent, id, modeltenant.Scope(ctx)); err != nil {
+		return fmt.Errorf("mark notification sent: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed lets the next Claim with the same dedup key retry the notification.
+func (r *DB) MarkFailed(ctx context.Context, id int64, reason string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := r.sql.ExecContext(ctx, sqlTextForFailed, id, reason, modeltenant.Scope(ctx)); err != nil {
+		return fmt.Errorf("mark notification failed: %w", err)
+	}
+	return nil
+}
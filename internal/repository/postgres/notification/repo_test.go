@@ -0,0 +1,58 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	modelnotify "test_task/internal/domain/models/notification"
+	modeltenant "test_task/internal/domain/models/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestRepo_Claim(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	subID := uuid.New()
+	n := modelnotify.Notification{
+		UserID:         uuid.New(),
+		SubscriptionID: &subID,
+		Kind:           modelnotify.KindRenewalDue,
+		Channel:        "log",
+		Subject:        "Netflix renews on 2025-08-01",
+		Body:           "700 RUB",
+		DedupKey:       "renewal_due:" + subID.String() + ":2025-08-01",
+	}
+	ctx := modeltenant.WithTenant(context.Background(), "acme")
+	args := []driver.Value{n.UserID, n.SubscriptionID, n.Kind, n.Channel, n.Subject, n.Body, n.DedupKey, "acme"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForClaim)).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(int64(5), time.Now()))
+
+	got, ok, err := repo.Claim(ctx, n)
+	if err != nil || !ok || got.ID != 5 || got.TenantID != "acme" {
+		t.Fatalf("want claimed notification 5, got %+v %v %v", got, ok, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForClaim)).
+		WithArgs(args...).
+		WillReturnError(sql.ErrNoRows)
+
+	if _, ok, err := repo.Claim(ctx, n); err != nil || ok {
+		t.Fatalf("already sent notification must not be claimed, got %v %v", ok, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	modelevent "test_task/internal/domain/models/event"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"time"
+)
+
+const (
+	// sqlTextForClaim leases the oldest pending event of every subscription
+	// that has no earlier event still in flight, which keeps delivery ordered
+	// per subscription even with several dispatchers.
+	sqlTextForClaim = `UPDATE outbox
+	SET locked_until = now() + make_interval(secs => $2)
+	WHERE id IN (
+		SELECT o.id
+		FROM outbox o
+		WHERE o.published_at IS NULL AND o.dead_at IS NULL
+			AND ($3::text IS NULL OR o.tenant_id = $3)
+			AND o.next_attempt_at <= now()
+			AND (o.locked_until IS NULL OR o.locked_until < now())
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox p
+				WHERE p.tenant_id = o.tenant_id AND p.aggregate_id = o.aggregate_id
+					AND p.published_at IS NULL AND p.dead_at IS NULL
+					AND p.id < o.id
+			)
+		ORDER BY o.id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, tenant_id, aggregate_id, payload, attempts`
+	sqlTextForPublished = `UPDATE outbox
+	SET published_at = now(), locked_until = NULL, last_error = NULL
+	WHERE id = $1 AND ($2::text IS NULL OR tenant_id = $2)`
+	sqlTextForFailed = `UPDATE outbox
+	SET attempts = attempts + 1,
+		last_error = $2,
+		next_attempt_at = $3,
+		locked_until = NULL,
+		dead_at = CASE WHEN $4 THEN now() END
+	WHERE id = $1 AND ($5::text IS NULL OR tenant_id = $5)`
+)
+
+type DB struct {
+	sql *sql.DB
+}
+
+func New(sql *sql.DB) *DB {
+	return &DB{
+		sql: sql,
+	}
+}
+
+// Claim leases up to limit deliverable events for the lease duration.
+func (r *DB) Claim(ctx context.Context, limit int, lease time.Duration) ([]modelevent.OutboxRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForClaim, limit, lease.Seconds(), modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []modelevent.OutboxRecord
+	for rows.Next() {
+		var rec modelevent.OutboxRecord
+		var payload []byte
+		if err := rows.Scan(&rec.ID, &rec.TenantID, &rec.AggregateID, &payload, &rec.Attempts); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(payload, &rec.Event); err != nil {
+			return nil, fmt.Errorf("outbox %d: decode payload: %w", rec.ID, err)
+		}
+		out = append(out, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *DB) MarkPublished(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.sql.ExecContext(ctx, sqlTextForPublished, id, modeltenant.Scope(ctx))
+	return err
+}
+
+// MarkFailed records a failed attempt. A dead event is never retried and no
+// longer holds back later events of its subscription.
+func (r *DB) MarkFailed(ctx context.Context, id int64, reason string, nextAttempt time.Time, dead bool) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.sql.ExecContext(ctx, sqlTextForFailed, id, reason, nextAttempt, dead, modeltenant.Scope(ctx))
+	return err
+}
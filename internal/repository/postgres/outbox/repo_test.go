@@ -0,0 +1,73 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	modelevent "test_task/internal/domain/models/event"
+	modeltenant "test_task/internal/domain/models/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestRepo_Claim_DecodesEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	subID := uuid.New()
+	ev, err := modelevent.New(modelevent.TypeSubscriptionCreated, subID.String(), map[string]int{"price": 400})
+	if err != nil {
+		t.Fatalf("event: %v", err)
+	}
+	payload, _ := json.Marshal(ev)
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForClaim)).
+		WithArgs(10, float64(30), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "aggregate_id", "payload", "attempts"}).
+			AddRow(int64(7), "acme", subID.String(), payload, 2))
+
+	got, err := repo.Claim(modeltenant.WithAllTenants(context.Background()), 10, 30*time.Second)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != 7 || got[0].TenantID != "acme" || got[0].AggregateID != subID || got[0].Attempts != 2 {
+		t.Fatalf("unexpected records %+v", got)
+	}
+	if got[0].Event.ID != ev.ID || got[0].Event.Type != modelevent.TypeSubscriptionCreated {
+		t.Fatalf("unexpected event %+v", got[0].Event)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_MarkFailed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	next := time.Now().Add(time.Minute)
+
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForFailed)).
+		WithArgs(int64(7), "status 502", next, true, "default").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.MarkFailed(context.Background(), 7, "status 502", next, true); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
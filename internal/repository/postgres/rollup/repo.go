@@ -0,0 +1,34 @@
+package rollup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// sqlTextForRebuild refills monthly_spend up to $1 in one transaction; the
+// migration defines the function next to the triggers that keep the table in
+// sync afterwards.
+const sqlTextForRebuild = `SELECT monthly_spend_rebuild($1::date);`
+
+type DB struct {
+	sql *sql.DB
+}
+
+func New(sql *sql.DB) *DB {
+	return &DB{
+		sql: sql,
+	}
+}
+
+// Rebuild recomputes the monthly spend rollup from the subscriptions,
+// open-ended ones up to horizon, and returns the number of rows written.
+// It scans every subscription, so it is bounded by ctx only.
+func (r *DB) Rebuild(ctx context.Context, horizon time.Time) (int64, error) {
+	var rows int64
+	if err := r.sql.QueryRowContext(ctx, sqlTextForRebuild, horizon).Scan(&rows); err != nil {
+		return 0, fmt.Errorf("rebuild monthly spend: %w", err)
+	}
+	return rows, nil
+}
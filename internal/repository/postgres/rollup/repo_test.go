@@ -0,0 +1,36 @@
+package rollup
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRepo_Rebuild(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	horizon := time.Date(2030, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForRebuild)).
+		WithArgs(horizon).
+		WillReturnRows(sqlmock.NewRows([]string{"monthly_spend_rebuild"}).AddRow(int64(1234)))
+
+	rows, err := repo.Rebuild(context.Background(), horizon)
+	if err != nil {
+		t.Fatalf("Rebuild error: %v", err)
+	}
+	if rows != 1234 {
+		t.Fatalf("want 1234 rows, got %d", rows)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	modelservice "test_task/internal/domain/models/service"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"test_task/internal/repository/postgres/tenancy"
+	myerror "test_task/pkg/global_errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Aliases travel as one newline separated string; the usecase rejects
+// aliases containing newlines.
+const serviceColumns = `id, tenant_id, name, array_to_string(aliases, E'\n'), category, default_price, billing_period, logo_url,
+	logo_content_type, logo_etag, logo_updated_at, created_at, updated_at`
+
+const (
+	sqlTextForCreate = `INSERT INTO services(name, aliases, category, default_price, billing_period, logo_url, tenant_id)
+	VALUES ($1, string_to_array($2, E'\n'), $3, $4, $5, $6, $7)
+	RETURNING ` + serviceColumns
+	sqlTextForGet = `SELECT ` + serviceColumns + `
+	FROM services
+	WHERE id = $1 AND ($2::text IS NULL OR tenant_id = $2)`
+	sqlTextForResolve = `SELECT ` + serviceColumns + `
+	FROM services
+	WHERE tenant_id = $2
+		AND id = (SELECT service_id FROM service_aliases WHERE tenant_id = $2 AND alias = $1)`
+	sqlTextForList = `SELECT ` + serviceColumns + `
+	FROM services
+	WHERE ($1::text IS NULL OR category = $1)
+	AND ($4::text IS NULL OR tenant_id = $4)
+	ORDER BY name
+	LIMIT $2 OFFSET $3`
+	sqlTextForUpdate = `UPDATE services
+	SET name = $2,
+		aliases = string_to_array($3, E'\n'),
+		category = $4,
+		default_price = $5,
+		billing_period = $6,
+		logo_url = $7
+	WHERE id = $1 AND ($8::text IS NULL OR tenant_id = $8)
+	RETURNING ` + serviceColumns
+	sqlTextForSetLogo = `UPDATE services
+	SET logo_content_type = $2,
+		logo_etag = $3,
+		logo_updated_at = now()
+	WHERE id = $1 AND ($4::text IS NULL OR tenant_id = $4)
+	RETURNING ` + serviceColumns
+	sqlTextForDelete = `DELETE FROM services WHERE id = $1 AND ($2::text IS NULL OR tenant_id = $2)`
+
+	// sqlTextForInsertKey claims a normalized name or alias; no row is
+	// inserted when another service of the tenant already owns it.
+	sqlTextForInsertKey = `INSERT INTO service_aliases(alias, service_id, tenant_id)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (tenant_id, alias) DO NOTHING`
+	sqlTextForDeleteKeys = `DELETE FROM service_aliases WHERE service_id = $1 AND tenant_id = $2`
+
+	// normalizedName is modelservice.Normalize of the service name of a
+	// subscription s.
+	normalizedName = `lower(regexp_replace(btrim(s.service_name), '\s+', ' ', 'g'))`
+	// sqlTextForGetName reads the name a service is renamed from.
+	sqlTextForGetName = `SELECT name FROM services WHERE id = $1 AND ($2::text IS NULL OR tenant_id = $2) FOR UPDATE`
+	// sqlTextForRenameSubscriptions keeps the subscriptions linked to the
+	// service named after it.
+	sqlTextForRenameSubscriptions = `UPDATE subscriptions
+	SET service_name = $2
+	WHERE service_id = $1 AND tenant_id = $3 AND service_name <> $2`
+	// sqlTextForLinkSubscriptions links the subscriptions written with a
+	// name or alias of the service before the catalog knew it.
+	sqlTextForLinkSubscriptions = `UPDATE subscriptions s
+	SET service_id = $1, service_name = $2
+	WHERE s.tenant_id = $3 AND s.service_id IS NULL
+	AND EXISTS (
+		SELECT 1 FROM service_aliases a
+		WHERE a.tenant_id = $3 AND a.service_id = $1 AND a.alias = ` + normalizedName + `
+	)`
+	// sqlTextForRenameBudgets moves the budgets of the old name $1 of a
+	// service to its new name $2.
+	sqlTextForRenameBudgets = `UPDATE budgets
+	SET service_name = $2
+	WHERE tenant_id = $3 AND lower(regexp_replace(btrim(service_name), '\s+', ' ', 'g')) = $1`
+)
+
+type DB struct {
+	sql *sql.DB
+}
+
+func New(sql *sql.DB) *DB {
+	return &DB{
+		sql: sql,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanService(row scanner) (modelservice.Service, error) {
+	var s modelservice.Service
+	var aliases string
+	var logoType, logoETag sql.NullString
+	var logoUpdated sql.NullTime
+	err := row.Scan(
+		&s.ID,
+		&s.TenantID,
+		&s.Name,
+		&aliases,
+		&s.Category,
+		&s.DefaultPrice,
+		&s.BillingPeriod,
+		&s.LogoURL,
+		&logoType,
+		&logoETag,
+		&logoUpdated,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	if aliases != "" {
+		s.Aliases = strings.Split(aliases, "\n")
+	}
+	if logoETag.Valid {
+		s.Logo = &modelservice.Logo{
+			ContentType: logoType.String,
+			ETag:        logoETag.String,
+			UpdatedAt:   logoUpdated.Time,
+		}
+	}
+	return s, err
+}
+
+// Create inserts the service together with its lookup keys. It fails with
+// ErrorConflict when the name or an alias belongs to another service.
+func (r *DB) Create(ctx context.Context, s modelservice.Service) (modelservice.Service, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return modelservice.Service{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	out, err := scanService(tx.QueryRowContext(ctx, sqlTextForCreate,
+		s.Name,
+		strings.Join(s.Aliases, "\n"),
+		s.Category,
+		s.DefaultPrice,
+		s.BillingPeriod,
+		s.LogoURL,
+		modeltenant.From(ctx),
+	))
+	if err != nil {
+		return modelservice.Service{}, fmt.Errorf("create service: %w", err)
+	}
+	if err := insertKeys(ctx, tx, out); err != nil {
+		return modelservice.Service{}, err
+	}
+	if err := linkSubscriptions(ctx, tx, out); err != nil {
+		return modelservice.Service{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return modelservice.Service{}, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
+
+func (r *DB) Get(ctx context.Context, id uuid.UUID) (modelservice.Service, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	s, err := scanService(r.sql.QueryRowContext(ctx, sqlTextForGet, id, modeltenant.Scope(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelservice.Service{}, myerror.ErrorServiceNotFound
+		}
+		return modelservice.Service{}, fmt.Errorf("get service: %w", err)
+	}
+	return s, nil
+}
+
+// Resolve finds the service of the tenant of ctx whose name or alias
+// normalizes to key.
+func (r *DB) Resolve(ctx context.Context, key string) (modelservice.Service, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	s, err := scanService(r.sql.QueryRowContext(ctx, sqlTextForResolve, key, modeltenant.From(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelservice.Service{}, myerror.ErrorServiceNotFound
+		}
+		return modelservice.Service{}, fmt.Errorf("resolve service: %w", err)
+	}
+	return s, nil
+}
+
+func (r *DB) List(ctx context.Context, f modelservice.Filter) ([]modelservice.Service, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if f.Limit <= 0 || f.Limit > 200 {
+		f.Limit = 50
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForList, f.Category, f.Limit, f.Offset, modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list services: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelservice.Service
+	for rows.Next() {
+		s, err := scanService(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list services scan: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list services rows: %w", err)
+	}
+	return out, nil
+}
+
+// Update replaces the service and its lookup keys.
+func (r *DB) Update(ctx context.Context, s modelservice.Service) (modelservice.Service, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return modelservice.Service{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var oldName string
+	if err := tx.QueryRowContext(ctx, sqlTextForGetName, s.ID, modeltenant.Scope(ctx)).Scan(&oldName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelservice.Service{}, myerror.ErrorServiceNotFound
+		}
+		return modelservice.Service{}, fmt.Errorf("get service name: %w", err)
+	}
+	out, err := scanService(tx.QueryRowContext(ctx, sqlTextForUpdate,
+		s.ID,
+		s.Name,
+		strings.Join(s.Aliases, "\n"),
+		s.Category,
+		s.DefaultPrice,
+		s.BillingPeriod,
+		s.LogoURL,
+		modeltenant.Scope(ctx),
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelservice.Service{}, myerror.ErrorServiceNotFound
+		}
+		return modelservice.Service{}, fmt.Errorf("update service: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, sqlTextForDeleteKeys, out.ID, out.TenantID); err != nil {
+		return modelservice.Service{}, fmt.Errorf("delete service keys: %w", err)
+	}
+	if err := insertKeys(ctx, tx, out); err != nil {
+		return modelservice.Service{}, err
+	}
+	if err := linkSubscriptions(ctx, tx, out); err != nil {
+		return modelservice.Service{}, err
+	}
+	if oldName != out.Name {
+		if _, err := tx.ExecContext(ctx, sqlTextForRenameBudgets, modelservice.Normalize(oldName), out.Name, out.TenantID); err != nil {
+			return modelservice.Service{}, fmt.Errorf("rename budgets: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return modelservice.Service{}, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
+
+// SetLogo points the service at the logo stored under etag.
+func (r *DB) SetLogo(ctx context.Context, id uuid.UUID, logo modelservice.Logo) (modelservice.Service, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	s, err := scanService(r.sql.QueryRowContext(ctx, sqlTextForSetLogo, id, logo.ContentType, logo.ETag, modeltenant.Scope(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelservice.Service{}, myerror.ErrorServiceNotFound
+		}
+		return modelservice.Service{}, fmt.Errorf("set service logo: %w", err)
+	}
+	return s, nil
+}
+
+// Delete removes the service; linked subscriptions keep their name and lose
+// the link.
+func (r *DB) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.sql.ExecContext(ctx, sqlTextForDelete, id, modeltenant.Scope(ctx))
+	if err != nil {
+		return fmt.Errorf("delete service: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete service rows: %w", err)
+	}
+	if n == 0 {
+		return myerror.ErrorServiceNotFound
+	}
+	return nil
+}
+
+// linkSubscriptions names the subscriptions linked to s after it and links
+// the ones written with one of its keys.
+func linkSubscriptions(ctx context.Context, tx *sql.Tx, s modelservice.Service) error {
+	if _, err := tx.ExecContext(ctx, sqlTextForRenameSubscriptions, s.ID, s.Name, s.TenantID); err != nil {
+		return fmt.Errorf("rename subscriptions: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, sqlTextForLinkSubscriptions, s.ID, s.Name, s.TenantID); err != nil {
+		return fmt.Errorf("link subscriptions: %w", err)
+	}
+	return nil
+}
+
+func insertKeys(ctx context.Context, tx *sql.Tx, s modelservice.Service) error {
+	for _, key := range s.Keys() {
+		res, err := tx.ExecContext(ctx, sqlTextForInsertKey, key, s.ID, s.TenantID)
+		if err != nil {
+			return fmt.Errorf("insert service key: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("insert service key rows: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("%w: %q is already used by another service", myerror.ErrorConflict, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	modelservice "test_task/internal/domain/models/service"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"test_task/internal/repository/postgres/tenancy"
+	myerror "test_task/pkg/global_errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var serviceRowColumns = []string{"id", "tenant_id", "name", "aliases", "category", "default_price", "billing_period", "logo_url", "logo_content_type", "logo_etag", "logo_updated_at", "created_at", "updated_at"}
+
+func TestRepo_Create_InsertsKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	id := uuid.New()
+	now := time.Now()
+	price := 299
+	s := modelservice.Service{Name: "Yandex Plus", Aliases: []string{"Яндекс Плюс"}, DefaultPrice: &price, BillingPeriod: modelservice.PeriodMonth}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(tenancy.SQLTextForSetTenant)).
+		WithArgs("acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForCreate)).
+		WithArgs("Yandex Plus", "Яндекс Плюс", nil, &price, modelservice.PeriodMonth, nil, "acme").
+		WillReturnRows(sqlmock.NewRows(serviceRowColumns).AddRow(id.String(), "acme", "Yandex Plus", "Яндекс Плюс", nil, 299, "month", nil, nil, nil, nil, now, now))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForInsertKey)).
+		WithArgs("yandex plus", id, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForInsertKey)).
+		WithArgs("яндекс плюс", id, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForRenameSubscriptions)).
+		WithArgs(id, "Yandex Plus", "acme").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForLinkSubscriptions)).
+		WithArgs(id, "Yandex Plus", "acme").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	got, err := repo.Create(modeltenant.WithTenant(context.Background(), "acme"), s)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if got.ID != id || len(got.Aliases) != 1 || got.Aliases[0] != "Яндекс Плюс" {
+		t.Fatalf("unexpected service: %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Create_AliasTakenConflicts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	id := uuid.New()
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(tenancy.SQLTextForSetTenant)).
+		WithArgs(modeltenant.Default).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForCreate)).
+		WillReturnRows(sqlmock.NewRows(serviceRowColumns).AddRow(id.String(), modeltenant.Default, "Netflix", "", nil, nil, "month", nil, nil, nil, nil, now, now))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForInsertKey)).
+		WithArgs("netflix", id, modeltenant.Default).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err = repo.Create(context.Background(), modelservice.Service{Name: "Netflix", BillingPeriod: modelservice.PeriodMonth})
+	if !errors.Is(err, myerror.ErrorConflict) {
+		t.Fatalf("want ErrorConflict, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Resolve_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForResolve)).
+		WithArgs("spotify", modeltenant.Default).
+		WillReturnRows(sqlmock.NewRows(serviceRowColumns))
+
+	_, err = New(db).Resolve(context.Background(), "spotify")
+	if !errors.Is(err, myerror.ErrorNotFound) {
+		t.Fatalf("want ErrorNotFound, got %v", err)
+	}
+}
+
+func TestRepo_Update_RenameCascades(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	id := uuid.New()
+	now := time.Now()
+	s := modelservice.Service{ID: id, Name: "Kinopoisk", BillingPeriod: modelservice.PeriodMonth}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(tenancy.SQLTextForSetTenant)).
+		WithArgs("acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForGetName)).
+		WithArgs(id, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Kinopoisk HD"))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForUpdate)).
+		WithArgs(id, "Kinopoisk", "", nil, nil, modelservice.PeriodMonth, nil, "acme").
+		WillReturnRows(sqlmock.NewRows(serviceRowColumns).AddRow(id.String(), "acme", "Kinopoisk", "", nil, nil, "month", nil, nil, nil, nil, now, now))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForDeleteKeys)).
+		WithArgs(id, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForInsertKey)).
+		WithArgs("kinopoisk", id, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForRenameSubscriptions)).
+		WithArgs(id, "Kinopoisk", "acme").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForLinkSubscriptions)).
+		WithArgs(id, "Kinopoisk", "acme").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForRenameBudgets)).
+		WithArgs("kinopoisk hd", "Kinopoisk", "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if _, err := repo.Update(modeltenant.WithTenant(context.Background(), "acme"), s); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package subscription
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	modelaudit "test_task/internal/domain/models/audit"
+	modelsub "test_task/internal/domain/models/subscription"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"time"
+)
+
+const (
+	sqlTextForAudit = `INSERT INTO subscription_audit(subscription_id, user_id, operation, actor, request_id, before, after, tenant_id)
+	VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7::jsonb, $8)`
+	sqlTextForAuditList = `SELECT id, subscription_id, user_id, operation, actor, COALESCE(request_id, ''), before, after, created_at
+	FROM subscription_audit
+	WHERE ($1::uuid IS NULL OR subscription_id = $1)
+	AND ($2::uuid IS NULL OR user_id = $2)
+	AND ($3::text IS NULL OR actor = $3)
+	AND ($4::text IS NULL OR operation = $4)
+	AND ($5::timestamptz IS NULL OR created_at >= $5)
+	AND ($6::timestamptz IS NULL OR created_at < $6)
+	AND ($9::text IS NULL OR tenant_id = $9)
+	ORDER BY id DESC
+	LIMIT $7 OFFSET $8`
+)
+
+// writeAudit appends a change record in the same transaction as the change.
+func writeAudit(ctx context.Context, tx *sql.Tx, op string, before, after *modelsub.Subscription) error {
+	snapshot := func(s *modelsub.Subscription) (sql.NullString, error) {
+		if s == nil {
+			return sql.NullString{}, nil
+		}
+		b, err := json.Marshal(s)
+		if err != nil {
+			return sql.NullString{}, err
+		}
+		return sql.NullString{String: string(b), Valid: true}, nil
+	}
+
+	subject := after
+	if subject == nil {
+		subject = before
+	}
+	b, err := snapshot(before)
+	if err != nil {
+		return fmt.Errorf("audit snapshot: %w", err)
+	}
+	a, err := snapshot(after)
+	if err != nil {
+		return fmt.Errorf("audit snapshot: %w", err)
+	}
+	requestID := sql.NullString{String: modelaudit.RequestIDFrom(ctx)}
+	requestID.Valid = requestID.String != ""
+
+	if _, err := tx.ExecContext(ctx, sqlTextForAudit,
+		subject.ID,
+		subject.UserID,
+		op,
+		modelaudit.ActorFrom(ctx),
+		requestID,
+		b,
+		a,
+		subject.TenantID,
+	); err != nil {
+		return fmt.Errorf("write audit: %w", err)
+	}
+	return nil
+}
+
+func (r *DB) AuditLog(ctx context.Context, f modelaudit.Filter) ([]modelaudit.Entry, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if f.Limit <= 0 || f.Limit > 200 {
+		f.Limit = 50
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForAuditList,
+		f.SubscriptionID,
+		f.UserID,
+		f.Actor,
+		f.Operation,
+		f.From,
+		f.To,
+		f.Limit,
+		f.Offset,
+		modeltenant.Scope(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("audit query: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]modelaudit.Entry, 0, f.Limit)
+	for rows.Next() {
+		var e modelaudit.Entry
+		var before, after []byte
+		if err := rows.Scan(
+			&e.ID,
+			&e.SubscriptionID,
+			&e.UserID,
+			&e.Operation,
+			&e.Actor,
+			&e.RequestID,
+			&before,
+			&after,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("audit scan: %w", err)
+		}
+		e.Before = before
+		e.After = after
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("audit rows: %w", err)
+	}
+	return out, nil
+}
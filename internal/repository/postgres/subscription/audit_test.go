@@ -0,0 +1,86 @@
+package subscription
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	modelaudit "test_task/internal/domain/models/audit"
+	modeltenant "test_task/internal/domain/models/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var auditRowColumns = []string{"id", "subscription_id", "user_id", "operation", "actor", "request_id", "before", "after", "created_at"}
+
+func TestRepo_AuditLog_Filter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	id := uuid.New()
+	userID := uuid.New()
+	actor := "apikey:1"
+	op := modelaudit.OperationUpdate
+	from := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Now().UTC()
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForAuditList)).
+		WithArgs(&id, &userID, &actor, &op, &from, nil, 20, 40, "acme").
+		WillReturnRows(sqlmock.NewRows(auditRowColumns).
+			AddRow(int64(2), id.String(), userID.String(), op, actor, "req-1", []byte(`{"price":700}`), []byte(`{"price":800}`), now))
+
+	ctx := modeltenant.WithTenant(context.Background(), "acme")
+	entries, err := repo.AuditLog(ctx, modelaudit.Filter{
+		SubscriptionID: &id,
+		UserID:         &userID,
+		Actor:          &actor,
+		Operation:      &op,
+		From:           &from,
+		Limit:          20,
+		Offset:         40,
+	})
+	if err != nil {
+		t.Fatalf("AuditLog error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != 2 || entries[0].RequestID != "req-1" || string(entries[0].After) != `{"price":800}` {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_AuditLog_DefaultPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	// Out of range limits fall back to 50, negative offsets to 0, and jobs
+	// working through every tenant read every tenant.
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForAuditList)).
+		WithArgs(nil, nil, nil, nil, nil, nil, 50, 0, nil).
+		WillReturnRows(sqlmock.NewRows(auditRowColumns))
+
+	ctx := modeltenant.WithAllTenants(context.Background())
+	entries, err := repo.AuditLog(ctx, modelaudit.Filter{Limit: 500, Offset: -1})
+	if err != nil {
+		t.Fatalf("AuditLog error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("want no entries, got %+v", entries)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
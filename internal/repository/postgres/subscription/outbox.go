@@ -0,0 +1,50 @@
+package subscription
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	modelevent "test_task/internal/domain/models/event"
+	modelsub "test_task/internal/domain/models/subscription"
+)
+
+const sqlTextForOutbox = `INSERT INTO outbox(aggregate_id, event_type, payload, tenant_id)
+	VALUES ($1, $2, $3::jsonb, $4)`
+
+// writeOutbox queues lifecycle events in the same transaction as the change,
+// so an event is published if and only if the change is committed.
+func writeOutbox(ctx context.Context, tx *sql.Tx, s *modelsub.Subscription, types ...string) error {
+	for _, t := range types {
+		ev, err := modelevent.New(t, s.ID.String(), s)
+		if err != nil {
+			return err
+		}
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("marshal event: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, sqlTextForOutbox, s.ID, t, string(payload), s.TenantID); err != nil {
+			return fmt.Errorf("write outbox: %w", err)
+		}
+	}
+	return nil
+}
+
+// updateEvents returns the events describing the change from before to after.
+func updateEvents(before, after modelsub.Subscription) []string {
+	types := []string{modelevent.TypeSubscriptionUpdated}
+	if before.EndDate == nil && after.EndDate != nil {
+		types = append(types, modelevent.TypeSubscriptionCancelled)
+	}
+	if before.Status != modelsub.StatusExpired && after.Status == modelsub.StatusExpired {
+		types = append(types, modelevent.TypeSubscriptionExpired)
+	}
+	if before.Status != modelsub.StatusPaused && after.Status == modelsub.StatusPaused {
+		types = append(types, modelevent.TypeSubscriptionPaused)
+	}
+	if before.Status == modelsub.StatusPaused && after.Status == modelsub.StatusActive {
+		types = append(types, modelevent.TypeSubscriptionResumed)
+	}
+	return types
+}
@@ -0,0 +1,246 @@
+package subscription
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	modelaudit "test_task/internal/domain/models/audit"
+	modelsub "test_task/internal/domain/models/subscription"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"test_task/internal/repository/postgres/tenancy"
+	myerror "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+const (
+	pauseColumns = `id, subscription_id, from_date, to_date, created_at`
+
+	sqlTextForPauses = `SELECT ` + pauseColumns + `
+	FROM subscription_pauses
+	WHERE subscription_id = $1
+	AND ($2::text IS NULL OR tenant_id = $2)
+	ORDER BY from_date`
+	// sqlTextForPauseOverlap checks for a pause that is still open or ends
+	// in or after month $2.
+	sqlTextForPauseOverlap = `SELECT EXISTS (
+	SELECT 1 FROM subscription_pauses
+	WHERE subscription_id = $1 AND tenant_id = $3
+	AND (to_date IS NULL OR to_date >= $2::date)
+	)`
+	sqlTextForPauseInsert = `INSERT INTO subscription_pauses(subscription_id, from_date, tenant_id)
+	VALUES ($1, $2, $3)
+	RETURNING ` + pauseColumns
+	// sqlTextForPauseClose ends the open pause with month $2; a pause that
+	// would end before it starts is removed by sqlTextForPauseDrop.
+	sqlTextForPauseClose = `UPDATE subscription_pauses
+	SET to_date = $2
+	WHERE subscription_id = $1 AND tenant_id = $3 AND to_date IS NULL AND from_date <= $2::date`
+	sqlTextForPauseDrop = `DELETE FROM subscription_pauses
+	WHERE subscription_id = $1 AND tenant_id = $2 AND to_date IS NULL`
+	sqlTextForPauseOpen = `SELECT EXISTS (
+	SELECT 1 FROM subscription_pauses
+	WHERE subscription_id = $1 AND tenant_id = $2 AND to_date IS NULL
+	)`
+	// pauseCovers matches the subscriptions with a pause covering month $1.
+	pauseCovers = `EXISTS (
+		SELECT 1 FROM subscription_pauses p
+		WHERE p.subscription_id = subscriptions.id
+		AND p.from_date <= $1::date
+		AND (p.to_date IS NULL OR p.to_date >= $1::date)
+	)`
+	// sqlTextForPausesStarted finds subscriptions not paused yet although a
+	// pause covers month $1.
+	sqlTextForPausesStarted = `SELECT ` + subColumns + `
+	FROM subscriptions
+	WHERE deleted_at IS NULL
+	AND status IN ('active', 'trial')
+	AND ` + pauseCovers + `
+	AND ($3::text IS NULL OR tenant_id = $3)
+	ORDER BY id
+	LIMIT $2`
+	// sqlTextForPausesEnded finds subscriptions still paused although no
+	// pause covers month $1.
+	sqlTextForPausesEnded = `SELECT ` + subColumns + `
+	FROM subscriptions
+	WHERE deleted_at IS NULL
+	AND status = 'paused'
+	AND NOT ` + pauseCovers + `
+	AND ($3::text IS NULL OR tenant_id = $3)
+	ORDER BY id
+	LIMIT $2`
+)
+
+// Pause opens a pause starting with month from and sets the subscription to
+// next: paused when the pause starts now, status for a later month, when
+// ListPausesStarted picks it up. status is the status the caller validated
+// the transition against.
+func (r *DB) Pause(ctx context.Context, id uuid.UUID, status string, from time.Time, next string) (modelsub.Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	before, err := lockSub(ctx, tx, sqlTextForLock, id, modeltenant.Scope(ctx))
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if before.Status != status {
+		return modelsub.Subscription{}, fmt.Errorf("%w: status is %s, not %s", myerror.ErrorInvalidTransition, before.Status, status)
+	}
+
+	var overlaps bool
+	if err := tx.QueryRowContext(ctx, sqlTextForPauseOverlap, id, from, before.TenantID).Scan(&overlaps); err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("check pauses: %w", err)
+	}
+	if overlaps {
+		return modelsub.Subscription{}, fmt.Errorf("%w: pause overlaps an existing pause", myerror.ErrorInvalidTransition)
+	}
+	if _, err := scanPause(tx.QueryRowContext(ctx, sqlTextForPauseInsert, id, from, before.TenantID)); err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("insert pause: %w", err)
+	}
+
+	return r.commitPause(ctx, tx, before, next)
+}
+
+// Resume closes the open pause so that month from is billed again and sets
+// the subscription to next: active when from is now, status until then, when
+// ListPausesEnded picks it up. status is the status the caller validated the
+// transition against; a pause that has not started yet can be resumed too.
+func (r *DB) Resume(ctx context.Context, id uuid.UUID, status string, from time.Time, next string) (modelsub.Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	before, err := lockSub(ctx, tx, sqlTextForLock, id, modeltenant.Scope(ctx))
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if before.Status != status {
+		return modelsub.Subscription{}, fmt.Errorf("%w: status is %s, not %s", myerror.ErrorInvalidTransition, before.Status, status)
+	}
+	var open bool
+	if err := tx.QueryRowContext(ctx, sqlTextForPauseOpen, id, before.TenantID).Scan(&open); err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("check pauses: %w", err)
+	}
+	if !open {
+		return modelsub.Subscription{}, fmt.Errorf("%w: subscription is not paused", myerror.ErrorInvalidTransition)
+	}
+	if err := closePause(ctx, tx, before, from.AddDate(0, -1, 0)); err != nil {
+		return modelsub.Subscription{}, err
+	}
+
+	return r.commitPause(ctx, tx, before, next)
+}
+
+// ListPausesStarted returns up to limit active or trial subscriptions with a
+// pause covering the month of cutoff.
+func (r *DB) ListPausesStarted(ctx context.Context, cutoff time.Time, limit int) ([]modelsub.Subscription, error) {
+	return r.querySubs(ctx, "list pauses started", sqlTextForPausesStarted, cutoff, limit, modeltenant.Scope(ctx))
+}
+
+// ListPausesEnded returns up to limit paused subscriptions without a pause
+// covering the month of cutoff.
+func (r *DB) ListPausesEnded(ctx context.Context, cutoff time.Time, limit int) ([]modelsub.Subscription, error) {
+	return r.querySubs(ctx, "list pauses ended", sqlTextForPausesEnded, cutoff, limit, modeltenant.Scope(ctx))
+}
+
+// ListPauses returns the pauses of a subscription, oldest first. Inside a
+// guard it reads in the guarded transaction.
+func (r *DB) ListPauses(ctx context.Context, id uuid.UUID) ([]modelsub.Pause, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := tenancy.Conn(ctx, r.sql).QueryContext(ctx, sqlTextForPauses, id, modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list pauses: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelsub.Pause
+	for rows.Next() {
+		p, err := scanPause(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list pauses scan: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list pauses rows: %w", err)
+	}
+	return out, nil
+}
+
+// commitPause commits the pause periods changed in tx and sets the status
+// of the locked subscription to next when it changes. A pause or resume
+// from a later month keeps the status and is recorded as an update all the
+// same.
+func (r *DB) commitPause(ctx context.Context, tx *sql.Tx, before modelsub.Subscription, next string) (modelsub.Subscription, error) {
+	if next != before.Status {
+		return r.commitStatus(ctx, tx, before, next)
+	}
+	return commitChange(ctx, tx, before, before)
+}
+
+// commitStatus sets the status of the locked subscription, records the
+// change and commits tx.
+func (r *DB) commitStatus(ctx context.Context, tx *sql.Tx, before modelsub.Subscription, status string) (modelsub.Subscription, error) {
+	out, err := scanSub(tx.QueryRowContext(ctx, sqlTextForSetStatus, before.ID, status, before.TenantID))
+	if err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("set subscription status: %w", err)
+	}
+	return commitChange(ctx, tx, before, out)
+}
+
+// commitChange records the change of the locked subscription from before to
+// out in the audit log and the outbox and commits tx.
+func commitChange(ctx context.Context, tx *sql.Tx, before, out modelsub.Subscription) (modelsub.Subscription, error) {
+	if err := writeAudit(ctx, tx, modelaudit.OperationUpdate, &before, &out); err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if err := writeOutbox(ctx, tx, &out, updateEvents(before, out)...); err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
+
+// closePause ends the open pause of a subscription with month last, or drops
+// it when it has not started by then.
+func closePause(ctx context.Context, tx *sql.Tx, s modelsub.Subscription, last time.Time) error {
+	if _, err := tx.ExecContext(ctx, sqlTextForPauseClose, s.ID, last, s.TenantID); err != nil {
+		return fmt.Errorf("close pause: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, sqlTextForPauseDrop, s.ID, s.TenantID); err != nil {
+		return fmt.Errorf("drop pause: %w", err)
+	}
+	return nil
+}
+
+// leavePause closes the open pause when an update moves a paused
+// subscription to a final status; the pause then lasts until end_date.
+func leavePause(ctx context.Context, tx *sql.Tx, before, after modelsub.Subscription) error {
+	if before.Status != modelsub.StatusPaused || after.Status == modelsub.StatusPaused || after.EndDate == nil {
+		return nil
+	}
+	return closePause(ctx, tx, after, *after.EndDate)
+}
+
+func scanPause(row scanner) (modelsub.Pause, error) {
+	var p modelsub.Pause
+	err := row.Scan(&p.ID, &p.SubscriptionID, &p.From, &p.To, &p.CreatedAt)
+	return p, err
+}
@@ -0,0 +1,151 @@
+package subscription
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	modelaudit "test_task/internal/domain/models/audit"
+	modelevent "test_task/internal/domain/models/event"
+	modelsub "test_task/internal/domain/models/subscription"
+	myerror "test_task/pkg/global_errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestRepo_Pause_RejectsOverlap(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	id := uuid.New()
+	userID := uuid.New()
+	now := time.Now().UTC()
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	from := time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC)
+
+	expectBegin(mock, "default")
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLock)).
+		WithArgs(id, "default").
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "default", "Netflix", nil, 700, userID.String(), start, nil, nil, nil, "active", nil, now, now, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForPauseOverlap)).
+		WithArgs(id, from, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	if _, err := repo.Pause(context.Background(), id, modelsub.StatusActive, from, modelsub.StatusPaused); !errors.Is(err, myerror.ErrorInvalidTransition) {
+		t.Fatalf("want ErrorInvalidTransition, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Resume_ClosesPause(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	id := uuid.New()
+	userID := uuid.New()
+	now := time.Now().UTC()
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	from := time.Date(2025, time.November, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2025, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+	expectBegin(mock, "default")
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLock)).
+		WithArgs(id, "default").
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "default", "Netflix", nil, 700, userID.String(), start, nil, nil, nil, "paused", nil, now, now, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForPauseOpen)).
+		WithArgs(id, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForPauseClose)).
+		WithArgs(id, last, "default").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForPauseDrop)).
+		WithArgs(id, "default").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForSetStatus)).
+		WithArgs(id, modelsub.StatusActive, "default").
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "default", "Netflix", nil, 700, userID.String(), start, nil, nil, nil, "active", nil, now, now, ""))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForAudit)).
+		WithArgs(id, userID, modelaudit.OperationUpdate, "anonymous", sql.NullString{}, sqlmock.AnyArg(), sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForOutbox)).
+		WithArgs(id, modelevent.TypeSubscriptionUpdated, sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForOutbox)).
+		WithArgs(id, modelevent.TypeSubscriptionResumed, sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	got, err := repo.Resume(context.Background(), id, modelsub.StatusPaused, from, modelsub.StatusActive)
+	if err != nil {
+		t.Fatalf("Resume error: %v", err)
+	}
+	if got.Status != modelsub.StatusActive {
+		t.Fatalf("status mismatch: %s", got.Status)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Pause_LaterMonthKeepsStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	id := uuid.New()
+	userID := uuid.New()
+	now := time.Now().UTC()
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	from := time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC)
+
+	expectBegin(mock, "default")
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLock)).
+		WithArgs(id, "default").
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "default", "Netflix", nil, 700, userID.String(), start, nil, nil, nil, "active", nil, now, now, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForPauseOverlap)).
+		WithArgs(id, from, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForPauseInsert)).
+		WithArgs(id, from, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subscription_id", "from_date", "to_date", "created_at"}).AddRow(int64(1), id.String(), from, nil, now))
+	// The status is kept, the scheduled pause is recorded all the same.
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForAudit)).
+		WithArgs(id, userID, modelaudit.OperationUpdate, "anonymous", sql.NullString{}, sqlmock.AnyArg(), sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForOutbox)).
+		WithArgs(id, modelevent.TypeSubscriptionUpdated, sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	got, err := repo.Pause(context.Background(), id, modelsub.StatusActive, from, modelsub.StatusActive)
+	if err != nil {
+		t.Fatalf("Pause error: %v", err)
+	}
+	if got.Status != modelsub.StatusActive {
+		t.Fatalf("status mismatch: %s", got.Status)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
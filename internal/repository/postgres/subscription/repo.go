@@ -5,7 +5,12 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	modelaudit "test_task/internal/domain/models/audit"
+	modelevent "test_task/internal/domain/models/event"
 	modelsub "test_task/internal/domain/models/subscription"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"test_task/internal/repository/postgres/tenancy"
 	myerror "test_task/pkg/global_errors"
 	"time"
 
@@ -13,73 +18,365 @@ import (
 )
 
 const (
-	sqlTextForCreate = `INSERT INTO subscriptions(service_name, price, user_id, start_date, end_date)
-	VALUES ($1, $2, $3, $4, $5)
+	// subColumns end with the tag names of the subscription as one newline
+	// separated string; tag names cannot contain newlines.
+	subColumns = `id, tenant_id, service_name, service_id, price, user_id, start_date, end_date, trial_until, trial_price, status, deleted_at, created_at, updated_at,
+	array_to_string(ARRAY(
+		SELECT t.name FROM subscription_tags st JOIN tags t ON t.id = st.tag_id
+		WHERE st.subscription_id = subscriptions.id
+		ORDER BY lower(t.name)
+	), E'\n') AS tags`
+
+	sqlTextForCreate = `INSERT INTO subscriptions(service_name, service_id, price, user_id, start_date, end_date, trial_until, trial_price, status, tenant_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	RETURNING id, created_at, updated_at`
-	sqlTextForGet = `SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at
+	sqlTextForGet = `SELECT ` + subColumns + `
+	FROM subscriptions
+	WHERE id = $1 AND deleted_at IS NULL
+	AND ($2::text IS NULL OR tenant_id = $2)`
+	sqlTextForLock = `SELECT ` + subColumns + `
+	FROM subscriptions
+	WHERE id = $1 AND deleted_at IS NULL
+	AND ($2::text IS NULL OR tenant_id = $2)
+	FOR UPDATE`
+	// sqlTextForLockUser holds the registered user of a new subscription, so
+	// that deleting the user waits for it and then counts it.
+	sqlTextForLockUser = `SELECT 1 FROM users WHERE id = $1 AND tenant_id = $2 FOR KEY SHARE`
+	// sqlTextForLockWrites serializes the guarded writes of a user until the
+	// transaction ends; it is an advisory lock so that it also covers
+	// subscriptions that do not exist yet.
+	sqlTextForLockWrites = `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`
+	// sqlTextForLockByUser locks the live subscriptions of a user.
+	sqlTextForLockByUser = `SELECT ` + subColumns + `
+	FROM subscriptions
+	WHERE user_id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+	ORDER BY id
+	FOR UPDATE`
+	sqlTextForLockDeleted = `SELECT ` + subColumns + `
 	FROM subscriptions
-	WHERE id = $1`
+	WHERE id = $1 AND deleted_at IS NOT NULL
+	AND ($2::uuid IS NULL OR user_id = $2)
+	AND ($3::text IS NULL OR tenant_id = $3)
+	FOR UPDATE`
 	sqlTextForUpdate = `UPDATE subscriptions
-	SET service_name=$2, price=$3, user_id=$4, start_date=$5, end_date=$6, updated_at=now()
-	WHERE id=$1
-	RETURNING id, service_name, price, user_id, start_date, end_date, created_at, updated_at`
-	sqlTextForDelete = `DELETE FROM subscriptions
-	WHERE id=$1`
+	SET service_name=$2, service_id=$3, price=$4, user_id=$5, start_date=$6, end_date=$7,
+		trial_until=$8, trial_price=$9, status=$10, updated_at=now()
+	WHERE id=$1 AND deleted_at IS NULL AND tenant_id = $11
+	RETURNING ` + subColumns
+	sqlTextForDelete = `UPDATE subscriptions
+	SET deleted_at=now()
+	WHERE id=$1 AND deleted_at IS NULL AND tenant_id = $2
+	RETURNING ` + subColumns
+	sqlTextForSetStatus = `UPDATE subscriptions
+	SET status=$2, updated_at=now()
+	WHERE id=$1 AND deleted_at IS NULL AND tenant_id = $3
+	RETURNING ` + subColumns
+	// sqlTextForEnded finds subscriptions whose last paid month is over but
+	// that are not expired yet.
+	sqlTextForEnded = `SELECT ` + subColumns + `
+	FROM subscriptions
+	WHERE deleted_at IS NULL
+	AND status <> 'expired'
+	AND end_date < $1::date
+	AND ($3::text IS NULL OR tenant_id = $3)
+	ORDER BY end_date, id
+	LIMIT $2`
+	// sqlTextForTrialsEnded finds subscriptions still in status trial after
+	// their last trial month.
+	sqlTextForTrialsEnded = `SELECT ` + subColumns + `
+	FROM subscriptions
+	WHERE deleted_at IS NULL
+	AND status = 'trial'
+	AND (trial_until IS NULL OR trial_until < $1::date)
+	AND ($3::text IS NULL OR tenant_id = $3)
+	ORDER BY trial_until NULLS FIRST, id
+	LIMIT $2`
+	sqlTextForRestore = `UPDATE subscriptions
+	SET deleted_at=NULL
+	WHERE id=$1 AND tenant_id = $2
+	RETURNING ` + subColumns
+	sqlTextForPurge = `WITH purged AS (
+	DELETE FROM subscriptions
+	WHERE deleted_at IS NOT NULL AND deleted_at < $1
+	AND ($3::text IS NULL OR tenant_id = $3)
+	RETURNING ` + subColumns + `
+	)
+	INSERT INTO subscription_audit(tenant_id, subscription_id, user_id, operation, actor, before)
+	SELECT tenant_id, id, user_id, 'purge', $2, to_jsonb(purged)
+	FROM purged`
 	sqlTextForCount = `SELECT COUNT(*)
 	FROM subscriptions
-	WHERE ($1::uuid IS NULL OR user_id = $1)
-	AND ($2::text IS NULL OR service_name = $2)`
-	sqlTextForList = `SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at
+	WHERE ($10::text IS NULL OR tenant_id = $10)
+	AND ($1::uuid IS NULL OR user_id = $1)
+	AND ($2::text IS NULL OR service_name = $2)
+	AND ($3::bool OR deleted_at IS NULL)
+	AND ($4::text IS NULL OR status = $4)
+	AND ($5::timestamptz IS NULL OR trial_until + interval '1 month' > $5)
+	AND ($6::timestamptz IS NULL OR trial_until + interval '1 month' <= $6)
+	AND ($7::uuid IS NULL OR service_id = $7)
+	AND ($8::text IS NULL OR (
+		SELECT count(DISTINCT lower(t.name)) FROM subscription_tags st JOIN tags t ON t.id = st.tag_id
+		WHERE st.subscription_id = subscriptions.id
+		AND lower(t.name) = ANY(string_to_array(lower($8), E'\n'))
+	) >= CASE WHEN $9::bool THEN cardinality(string_to_array($8, E'\n')) ELSE 1 END)`
+	sqlTextForList = `SELECT ` + subColumns + `
 	FROM subscriptions
-	WHERE ($1::uuid IS NULL OR user_id = $1)
+	WHERE ($12::text IS NULL OR tenant_id = $12)
+	AND ($1::uuid IS NULL OR user_id = $1)
 	AND ($2::text IS NULL OR service_name = $2)
+	AND ($5::bool OR deleted_at IS NULL)
+	AND ($6::text IS NULL OR status = $6)
+	AND ($7::timestamptz IS NULL OR trial_until + interval '1 month' > $7)
+	AND ($8::timestamptz IS NULL OR trial_until + interval '1 month' <= $8)
+	AND ($9::uuid IS NULL OR service_id = $9)
+	AND ($10::text IS NULL OR (
+		SELECT count(DISTINCT lower(t.name)) FROM subscription_tags st JOIN tags t ON t.id = st.tag_id
+		WHERE st.subscription_id = subscriptions.id
+		AND lower(t.name) = ANY(string_to_array(lower($10), E'\n'))
+	) >= CASE WHEN $11::bool THEN cardinality(string_to_array($10, E'\n')) ELSE 1 END)
 	ORDER BY start_date DESC, created_at DESC
 	LIMIT $3 OFFSET $4`
-	sqlTextForSum = `WITH params AS (
+	// sqlTextForActive selects subscriptions charged at least once between $1
+	// and $2; the exact charge date is computed by the caller. Paused
+	// subscriptions are left out until they are resumed.
+	sqlTextForActive = `SELECT ` + subColumns + `
+	FROM subscriptions
+	WHERE deleted_at IS NULL
+	AND status <> 'paused'
+	AND (end_date IS NULL OR end_date >= date_trunc('month', $1::timestamptz)::date)
+	AND start_date <= $2::date
+	AND ($3::uuid IS NULL OR user_id = $3)
+	AND ($4::text IS NULL OR service_name = $4)
+	AND ($5::text IS NULL OR tenant_id = $5)
+	AND ($6::uuid IS NULL OR service_id = $6)
+	ORDER BY start_date, id`
+	sqlTextForByUser = `SELECT ` + subColumns + `
+	FROM subscriptions
+	WHERE user_id = $1 AND deleted_at IS NULL
+	AND ($2::text IS NULL OR tenant_id = $2)
+	ORDER BY start_date, id`
+	// sumFiltered selects the subscriptions of a summary with the months
+	// from start_eff to end_eff it covers. Tags in $6 are matched like in
+	// sqlTextForList, $8 is the tenant and $9 the catalog service.
+	sumFiltered = `WITH params AS (
 	SELECT $1::date AS p_from, $2::date AS p_to
 	),
 	filtered AS (
 	SELECT
+		id,
+		service_id,
 		price,
+		start_date,
+		trial_until,
+		trial_price,
+		deleted_at,
 		GREATEST(start_date, (SELECT p_from FROM params)) AS start_eff,
 		LEAST(COALESCE(end_date, (SELECT p_to FROM params)), (SELECT p_to FROM params)) AS end_eff
 	FROM subscriptions
-	WHERE ($3::uuid IS NULL OR user_id = $3)
+	WHERE ($8::text IS NULL OR tenant_id = $8)
+		AND ($3::uuid IS NULL OR user_id = $3)
 		AND ($4::text IS NULL OR service_name = $4)
+		AND ($9::uuid IS NULL OR service_id = $9)
+		AND (NOT $5::bool OR deleted_at IS NULL)
+		AND ($6::text IS NULL OR (
+			SELECT count(DISTINCT lower(t.name)) FROM subscription_tags st JOIN tags t ON t.id = st.tag_id
+			WHERE st.subscription_id = subscriptions.id
+			AND lower(t.name) = ANY(string_to_array(lower($6), E'\n'))
+		) >= CASE WHEN $7::bool THEN cardinality(string_to_array($6, E'\n')) ELSE 1 END)
+	)
+	`
+	// sumMonths lists every month between start_eff and end_eff that is
+	// billed, at the trial or the regular price; months covered by a pause
+	// are left out. Services of the catalog billed yearly charge twelve
+	// prices once a year from the first month after the trial, or from
+	// start_date without one, and nothing in between, see
+	// Subscription.ChargeFor.
+	sumMonths = sumFiltered + `,
+	months AS (
+	SELECT f.id, m::date AS month,
+		CASE WHEN f.trial_until IS NOT NULL AND m::date <= f.trial_until
+			THEN COALESCE(f.trial_price, 0)
+		WHEN NOT EXISTS (
+			SELECT 1 FROM services sv WHERE sv.id = f.service_id AND sv.billing_period = 'year'
+		) THEN f.price
+		WHEN ((date_part('year', m) - date_part('year', y.anchor)) * 12
+			+ date_part('month', m) - date_part('month', y.anchor))::int % 12 = 0
+			THEN f.price * 12
+		ELSE 0
+		END AS amount
+	FROM filtered f
+	CROSS JOIN LATERAL generate_series(f.start_eff, f.end_eff, interval '1 month') AS m
+	CROSS JOIN LATERAL (
+		SELECT COALESCE((f.trial_until + interval '1 month')::date, f.start_date) AS anchor
+	) y
+	WHERE f.end_eff >= f.start_eff
+	AND NOT EXISTS (
+		SELECT 1 FROM subscription_pauses p
+		WHERE p.subscription_id = f.id
+		AND m::date >= p.from_date
+		AND (p.to_date IS NULL OR m::date <= p.to_date)
 	)
-	SELECT COALESCE(SUM(
-	price * (
-		(EXTRACT(YEAR FROM end_eff)::int * 12 + EXTRACT(MONTH FROM end_eff)::int) -
-		(EXTRACT(YEAR FROM start_eff)::int * 12 + EXTRACT(MONTH FROM start_eff)::int) + 1
 	)
-	), 0)::bigint AS total
-	FROM filtered
-	WHERE end_eff >= start_eff;`
+	`
+	sqlTextForSum = sumMonths + `SELECT COALESCE(SUM(amount), 0)::bigint AS total
+	FROM months;`
+	// sqlTextForSumRollup sums the same months from monthly_spend, which
+	// monthly_spend_rows fills with the rules of sumMonths. The total is NULL
+	// when the rollup ends before $2 and there is no row before it was first
+	// built. It takes the arguments of sqlTextForSum except the tag and
+	// catalog service filters, the tenant comes sixth.
+	sqlTextForSumRollup = `SELECT
+		CASE WHEN m.horizon >= $2::date THEN (
+			SELECT COALESCE(SUM(ms.amount), 0)::bigint
+			FROM monthly_spend ms
+			WHERE ms.month BETWEEN $1::date AND $2::date
+				AND ($6::text IS NULL OR ms.tenant_id = $6)
+				AND ($3::uuid IS NULL OR ms.user_id = $3)
+				AND ($4::text IS NULL OR ms.service_name = $4)
+				AND (NOT $5::bool OR NOT ms.deleted)
+		) END AS total
+	FROM monthly_spend_meta m;`
+	// sqlTextForForecast totals every month of sumMonths from $1 to $2,
+	// months without charges included.
+	sqlTextForForecast = sumMonths + `SELECT g::date AS month, COALESCE(SUM(m.amount), 0)::bigint AS total
+	FROM generate_series((SELECT p_from FROM params), (SELECT p_to FROM params), interval '1 month') AS g
+	LEFT JOIN months m ON m.month = g::date
+	GROUP BY g
+	ORDER BY g;`
+	// sqlTextForSumByMonth totals every month of sumMonths from $1 to $2,
+	// months without charges included. Months up to $10 count the deleted
+	// subscriptions like the summary, later months leave them out.
+	sqlTextForSumByMonth = sumMonths + `SELECT g::date AS month,
+		COALESCE(SUM(m.amount) FILTER (WHERE g::date <= $10::date OR f.deleted_at IS NULL), 0)::bigint AS total
+	FROM generate_series((SELECT p_from FROM params), (SELECT p_to FROM params), interval '1 month') AS g
+	LEFT JOIN months m ON m.month = g::date
+	LEFT JOIN filtered f ON f.id = m.id
+	GROUP BY g
+	ORDER BY g;`
+	// sqlTextForBilledYearly tells whether a catalog service is billed
+	// yearly, which changes the months sumMonths charges.
+	sqlTextForBilledYearly = `SELECT EXISTS (
+	SELECT 1 FROM services
+	WHERE id = $1 AND billing_period = 'year'
+	AND ($2::text IS NULL OR tenant_id = $2)
+	)`
+	// sqlTextForSumByTag totals the months per tag; a subscription with
+	// several tags counts towards each of them.
+	sqlTextForSumByTag = sumMonths + `SELECT t.name, COALESCE(SUM(m.amount), 0)::bigint AS total
+	FROM months m
+	LEFT JOIN subscription_tags st ON st.subscription_id = m.id
+	LEFT JOIN tags t ON t.id = st.tag_id
+	GROUP BY t.name
+	ORDER BY total DESC, t.name NULLS LAST;`
 )
 
 type DB struct {
-	sql *sql.DB
+	sql             *sql.DB
+	registeredUsers bool
+}
+
+// Option customises the repository built by New.
+type Option func(*DB)
+
+// WithRegisteredUsers makes Create fail with ErrorValidation when the user
+// row it locks is missing, so that a user deleted after the usecase checked
+// the registration gets no new subscriptions.
+func WithRegisteredUsers() Option {
+	return func(r *DB) {
+		r.registeredUsers = true
+	}
 }
 
-func New(sql *sql.DB) *DB {
-	return &DB{
+func New(sql *sql.DB, opts ...Option) *DB {
+	r := &DB{
 		sql: sql,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSub(row scanner) (modelsub.Subscription, error) {
+	var s modelsub.Subscription
+	var tags string
+	err := row.Scan(
+		&s.ID,
+		&s.TenantID,
+		&s.ServiceName,
+		&s.ServiceID,
+		&s.Price,
+		&s.UserID,
+		&s.StartDate,
+		&s.EndDate,
+		&s.TrialUntil,
+		&s.TrialPrice,
+		&s.Status,
+		&s.DeletedAt,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+		&tags,
+	)
+	if tags != "" {
+		s.Tags = strings.Split(tags, "\n")
+	}
+	return s, err
 }
 
-func (r *DB) Create(ctx context.Context, s modelsub.Subscription) (modelsub.Subscription, error) {
+// guardWrite takes the write lock of the user and runs guard in tx. Without
+// a guard it does nothing, so unguarded writes never wait for the lock.
+func guardWrite(ctx context.Context, tx *sql.Tx, guard modelsub.Guard, tenant string, userID uuid.UUID, before *modelsub.Subscription) error {
+	if guard == nil {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, sqlTextForLockWrites, "subscriptions:"+tenant+":"+userID.String()); err != nil {
+		return fmt.Errorf("lock user writes: %w", err)
+	}
+	return guard(tenancy.WithTx(ctx, tx), before)
+}
+
+// Create inserts s once guard, when given, accepts it.
+func (r *DB) Create(ctx context.Context, s modelsub.Subscription, guard modelsub.Guard) (modelsub.Subscription, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
 	var id uuid.UUID
 	var created, updated time.Time
+	s.TenantID = modeltenant.From(ctx)
+
+	var registered int
+	err = tx.QueryRowContext(ctx, sqlTextForLockUser, s.UserID, s.TenantID).Scan(&registered)
+	if errors.Is(err, sql.ErrNoRows) && r.registeredUsers {
+		return modelsub.Subscription{}, fmt.Errorf("%w: user %s is not registered", myerror.ErrorValidation, s.UserID)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return modelsub.Subscription{}, fmt.Errorf("lock user: %w", err)
+	}
+	if err := guardWrite(ctx, tx, guard, s.TenantID, s.UserID, nil); err != nil {
+		return modelsub.Subscription{}, err
+	}
 
-	err := r.sql.QueryRowContext(ctx, sqlTextForCreate,
+	err = tx.QueryRowContext(ctx, sqlTextForCreate,
 		s.ServiceName,
+		s.ServiceID,
 		s.Price,
 		s.UserID,
 		s.StartDate,
 		s.EndDate,
+		s.TrialUntil,
+		s.TrialPrice,
+		s.Status,
+		s.TenantID,
 	).Scan(&id, &created, &updated)
 	if err != nil {
 		return modelsub.Subscription{}, fmt.Errorf("create subscription: %w", err)
@@ -88,6 +385,16 @@ func (r *DB) Create(ctx context.Context, s modelsub.Subscription) (modelsub.Subs
 	s.ID = id
 	s.CreatedAt = created
 	s.UpdatedAt = updated
+
+	if err := writeAudit(ctx, tx, modelaudit.OperationCreate, nil, &s); err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if err := writeOutbox(ctx, tx, &s, modelevent.TypeSubscriptionCreated); err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("commit: %w", err)
+	}
 	return s, nil
 }
 
@@ -95,75 +402,258 @@ func (r *DB) GetSub(ctx context.Context, id uuid.UUID) (modelsub.Subscription, e
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	var s modelsub.Subscription
-	err := r.sql.QueryRowContext(ctx, sqlTextForGet, id).Scan(
-		&s.ID,
-		&s.ServiceName,
-		&s.Price,
-		&s.UserID,
-		&s.StartDate,
-		&s.EndDate,
-		&s.CreatedAt,
-		&s.UpdatedAt,
-	)
+	s, err := scanSub(r.sql.QueryRowContext(ctx, sqlTextForGet, id, modeltenant.Scope(ctx)))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return modelsub.Subscription{}, myerror.ErrorNotFound
+			return modelsub.Subscription{}, myerror.ErrorSubscriptionNotFound
 		}
 		return modelsub.Subscription{}, fmt.Errorf("get subscription: %w", err)
 	}
 	return s, nil
 }
 
-func (r *DB) UpdateSub(ctx context.Context, id uuid.UUID, s modelsub.Subscription) (modelsub.Subscription, error) {
+// UpdateSub replaces the subscription once guard, when given, accepts it.
+func (r *DB) UpdateSub(ctx context.Context, id uuid.UUID, s modelsub.Subscription, guard modelsub.Guard) (modelsub.Subscription, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	var out modelsub.Subscription
-	err := r.sql.QueryRowContext(ctx, sqlTextForUpdate,
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	before, err := lockSub(ctx, tx, sqlTextForLock, id, modeltenant.Scope(ctx))
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if err := guardWrite(ctx, tx, guard, before.TenantID, s.UserID, &before); err != nil {
+		return modelsub.Subscription{}, err
+	}
+
+	out, err := scanSub(tx.QueryRowContext(ctx, sqlTextForUpdate,
 		id,
 		s.ServiceName,
+		s.ServiceID,
 		s.Price,
 		s.UserID,
 		s.StartDate,
 		s.EndDate,
-	).Scan(
-		&out.ID,
-		&out.ServiceName,
-		&out.Price,
-		&out.UserID,
-		&out.StartDate,
-		&out.EndDate,
-		&out.CreatedAt,
-		&out.UpdatedAt,
-	)
+		s.TrialUntil,
+		s.TrialPrice,
+		s.Status,
+		before.TenantID,
+	))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return modelsub.Subscription{}, myerror.ErrorNotFound
+			return modelsub.Subscription{}, myerror.ErrorSubscriptionNotFound
 		}
 		return modelsub.Subscription{}, fmt.Errorf("update subscription: %w", err)
 	}
+	if err := leavePause(ctx, tx, before, out); err != nil {
+		return modelsub.Subscription{}, err
+	}
+
+	if err := writeAudit(ctx, tx, modelaudit.OperationUpdate, &before, &out); err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if err := writeOutbox(ctx, tx, &out, updateEvents(before, out)...); err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
+
+// SetStatus moves the subscription from status from to status to. It fails
+// with ErrorInvalidTransition when the status changed in the meantime.
+func (r *DB) SetStatus(ctx context.Context, id uuid.UUID, from, to string) (modelsub.Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	before, err := lockSub(ctx, tx, sqlTextForLock, id, modeltenant.Scope(ctx))
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if before.Status != from {
+		return modelsub.Subscription{}, fmt.Errorf("%w: status is %s, not %s", myerror.ErrorInvalidTransition, before.Status, from)
+	}
+	after := before
+	after.Status = to
+	if err := leavePause(ctx, tx, before, after); err != nil {
+		return modelsub.Subscription{}, err
+	}
+
+	return r.commitStatus(ctx, tx, before, to)
+}
+
+// ListEnded returns up to limit subscriptions that ended before the month of
+// cutoff and are not expired yet.
+func (r *DB) ListEnded(ctx context.Context, cutoff time.Time, limit int) ([]modelsub.Subscription, error) {
+	return r.querySubs(ctx, "list ended", sqlTextForEnded, cutoff, limit, modeltenant.Scope(ctx))
+}
+
+// ListTrialsEnded returns up to limit subscriptions in status trial whose
+// last trial month is before the month of cutoff.
+func (r *DB) ListTrialsEnded(ctx context.Context, cutoff time.Time, limit int) ([]modelsub.Subscription, error) {
+	return r.querySubs(ctx, "list trials ended", sqlTextForTrialsEnded, cutoff, limit, modeltenant.Scope(ctx))
+}
+
+func (r *DB) querySubs(ctx context.Context, op, query string, args ...any) ([]modelsub.Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.sql.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var out []modelsub.Subscription
+	for rows.Next() {
+		s, err := scanSub(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s scan: %w", op, err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s rows: %w", op, err)
+	}
 	return out, nil
 }
 
+// Delete soft-deletes the subscription: it disappears from GetSub and List
+// but still counts in historical summaries until it is purged.
 func (r *DB) Delete(ctx context.Context, id uuid.UUID) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	tag, err := r.sql.ExecContext(ctx, sqlTextForDelete, id)
+	tx, err := tenancy.BeginTx(ctx, r.sql)
 	if err != nil {
-		return fmt.Errorf("delete subscription: %w", err)
+		return fmt.Errorf("begin tx: %w", err)
 	}
-	rows, err := tag.RowsAffected()
+	defer func() { _ = tx.Rollback() }()
+
+	before, err := lockSub(ctx, tx, sqlTextForLock, id, modeltenant.Scope(ctx))
+	if err != nil {
+		return err
+	}
+
+	after, err := scanSub(tx.QueryRowContext(ctx, sqlTextForDelete, id, before.TenantID))
 	if err != nil {
-		return fmt.Errorf("rows affected: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return myerror.ErrorSubscriptionNotFound
+		}
+		return fmt.Errorf("delete subscription: %w", err)
 	}
-	if rows == 0 {
-		return myerror.ErrorNotFound
+
+	if err := writeAudit(ctx, tx, modelaudit.OperationDelete, &before, &after); err != nil {
+		return err
+	}
+	if err := writeOutbox(ctx, tx, &after, modelevent.TypeSubscriptionDeleted); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
 	}
 	return nil
 }
 
+// DeleteByUser soft-deletes every live subscription of the user of tenant
+// in tx, recording each like Delete, and returns how many were deleted.
+func DeleteByUser(ctx context.Context, tx *sql.Tx, userID uuid.UUID, tenant string) (int, error) {
+	rows, err := tx.QueryContext(ctx, sqlTextForLockByUser, userID, tenant)
+	if err != nil {
+		return 0, fmt.Errorf("lock user subscriptions: %w", err)
+	}
+	var subs []modelsub.Subscription
+	for rows.Next() {
+		s, err := scanSub(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("lock user subscriptions scan: %w", err)
+		}
+		subs = append(subs, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("lock user subscriptions rows: %w", err)
+	}
+
+	for _, before := range subs {
+		after, err := scanSub(tx.QueryRowContext(ctx, sqlTextForDelete, before.ID, tenant))
+		if err != nil {
+			return 0, fmt.Errorf("delete subscription %s: %w", before.ID, err)
+		}
+		if err := writeAudit(ctx, tx, modelaudit.OperationDelete, &before, &after); err != nil {
+			return 0, err
+		}
+		if err := writeOutbox(ctx, tx, &after, modelevent.TypeSubscriptionDeleted); err != nil {
+			return 0, err
+		}
+	}
+	return len(subs), nil
+}
+
+// Restore undoes a soft delete. When userID is set only that user's
+// subscriptions can be restored.
+func (r *DB) Restore(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (modelsub.Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	before, err := lockSub(ctx, tx, sqlTextForLockDeleted, id, userID, modeltenant.Scope(ctx))
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+
+	out, err := scanSub(tx.QueryRowContext(ctx, sqlTextForRestore, id, before.TenantID))
+	if err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("restore subscription: %w", err)
+	}
+
+	if err := writeAudit(ctx, tx, modelaudit.OperationRestore, &before, &out); err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if err := writeOutbox(ctx, tx, &out, modelevent.TypeSubscriptionRestored); err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
+
+// Purge hard-deletes subscriptions soft-deleted before the given time and
+// records a purge entry with the last snapshot of each of them.
+func (r *DB) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := r.sql.ExecContext(ctx, sqlTextForPurge, deletedBefore, modelaudit.ActorFrom(ctx), modeltenant.Scope(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("purge subscriptions: %w", err)
+	}
+	rows, err := tag.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+	return rows, nil
+}
+
 func (r *DB) List(ctx context.Context, f modelsub.ListFilter) ([]modelsub.Subscription, int, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -175,29 +665,21 @@ func (r *DB) List(ctx context.Context, f modelsub.ListFilter) ([]modelsub.Subscr
 		f.Offset = 0
 	}
 
+	tenant := modeltenant.Scope(ctx)
 	var total int
-	if err := r.sql.QueryRowContext(ctx, sqlTextForCount, f.UserID, f.ServiceName).Scan(&total); err != nil {
+	if err := r.sql.QueryRowContext(ctx, sqlTextForCount, f.UserID, f.ServiceName, f.IncludeDeleted, f.Status, f.TrialEndsAfter, f.TrialEndsBefore, f.ServiceID, tagsParam(f.Tags), f.TagsAll, tenant).Scan(&total); err != nil {
 		return nil, 0, fmt.Errorf("list count: %w", err)
 	}
 
-	rows, err := r.sql.QueryContext(ctx, sqlTextForList, f.UserID, f.ServiceName, f.Limit, f.Offset)
+	rows, err := r.sql.QueryContext(ctx, sqlTextForList, f.UserID, f.ServiceName, f.Limit, f.Offset, f.IncludeDeleted, f.Status, f.TrialEndsAfter, f.TrialEndsBefore, f.ServiceID, tagsParam(f.Tags), f.TagsAll, tenant)
 	if err != nil {
 		return nil, 0, fmt.Errorf("list query: %w", err)
 	}
 	defer rows.Close()
 	out := make([]modelsub.Subscription, 0, f.Limit)
 	for rows.Next() {
-		var s modelsub.Subscription
-		if err := rows.Scan(
-			&s.ID,
-			&s.ServiceName,
-			&s.Price,
-			&s.UserID,
-			&s.StartDate,
-			&s.EndDate,
-			&s.CreatedAt,
-			&s.UpdatedAt,
-		); err != nil {
+		s, err := scanSub(rows)
+		if err != nil {
 			return nil, 0, fmt.Errorf("list scan: %w", err)
 		}
 		out = append(out, s)
@@ -213,9 +695,192 @@ func (r *DB) Summary(ctx context.Context, f modelsub.SummaryFilter) (int64, erro
 	ctx, cancel := context.WithTimeout(ctx, 7*time.Second)
 	defer cancel()
 
+	// The rollup has no tags and is keyed by the service name; other filters
+	// are read from it once it covers the period.
+	if len(f.Tags) == 0 && f.ServiceID == nil {
+		var rolled sql.NullInt64
+		err := r.sql.QueryRowContext(ctx, sqlTextForSumRollup, f.From, f.To, f.UserID, f.ServiceName, f.ExcludeDeleted, modeltenant.Scope(ctx)).Scan(&rolled)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("summary rollup query: %w", err)
+		}
+		if rolled.Valid {
+			return rolled.Int64, nil
+		}
+	}
+
 	var total int64
-	if err := r.sql.QueryRowContext(ctx, sqlTextForSum, f.From, f.To, f.UserID, f.ServiceName).Scan(&total); err != nil {
+	if err := r.sql.QueryRowContext(ctx, sqlTextForSum, sumArgs(ctx, f)...).Scan(&total); err != nil {
 		return 0, fmt.Errorf("summary query: %w", err)
 	}
 	return total, nil
 }
+
+// SummaryByTag splits the summary of f by tag.
+func (r *DB) SummaryByTag(ctx context.Context, f modelsub.SummaryFilter) ([]modelsub.TagTotal, error) {
+	ctx, cancel := context.WithTimeout(ctx, 7*time.Second)
+	defer cancel()
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForSumByTag, sumArgs(ctx, f)...)
+	if err != nil {
+		return nil, fmt.Errorf("summary by tag query: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelsub.TagTotal
+	for rows.Next() {
+		var t modelsub.TagTotal
+		if err := rows.Scan(&t.Tag, &t.Total); err != nil {
+			return nil, fmt.Errorf("summary by tag scan: %w", err)
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("summary by tag rows: %w", err)
+	}
+	return out, nil
+}
+
+// SummaryByMonth totals the months from f.From to f.To, each as Summary
+// would for that month alone; deleted subscriptions only count up to month
+// started, so f.ExcludeDeleted is ignored. Inside a guard it reads in the
+// guarded transaction.
+func (r *DB) SummaryByMonth(ctx context.Context, f modelsub.SummaryFilter, started time.Time) ([]modelsub.MonthTotal, error) {
+	ctx, cancel := context.WithTimeout(ctx, 7*time.Second)
+	defer cancel()
+
+	f.ExcludeDeleted = false
+	rows, err := tenancy.Conn(ctx, r.sql).QueryContext(ctx, sqlTextForSumByMonth, append(sumArgs(ctx, f), started)...)
+	if err != nil {
+		return nil, fmt.Errorf("summary by month query: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelsub.MonthTotal
+	for rows.Next() {
+		var m modelsub.MonthTotal
+		if err := rows.Scan(&m.Month, &m.Total); err != nil {
+			return nil, fmt.Errorf("summary by month scan: %w", err)
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("summary by month rows: %w", err)
+	}
+	return out, nil
+}
+
+// BilledYearly reports whether the catalog service is billed yearly. Inside
+// a guard it reads in the guarded transaction.
+func (r *DB) BilledYearly(ctx context.Context, serviceID uuid.UUID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var yearly bool
+	err := tenancy.Conn(ctx, r.sql).QueryRowContext(ctx, sqlTextForBilledYearly, serviceID, modeltenant.Scope(ctx)).Scan(&yearly)
+	if err != nil {
+		return false, fmt.Errorf("billed yearly: %w", err)
+	}
+	return yearly, nil
+}
+
+// Forecast totals the expected charges of the months from f.From to f.To.
+func (r *DB) Forecast(ctx context.Context, f modelsub.SummaryFilter) ([]modelsub.MonthTotal, error) {
+	ctx, cancel := context.WithTimeout(ctx, 7*time.Second)
+	defer cancel()
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForForecast, sumArgs(ctx, f)...)
+	if err != nil {
+		return nil, fmt.Errorf("forecast query: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelsub.MonthTotal
+	for rows.Next() {
+		var m modelsub.MonthTotal
+		if err := rows.Scan(&m.Month, &m.Total); err != nil {
+			return nil, fmt.Errorf("forecast scan: %w", err)
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("forecast rows: %w", err)
+	}
+	return out, nil
+}
+
+func sumArgs(ctx context.Context, f modelsub.SummaryFilter) []any {
+	return []any{f.From, f.To, f.UserID, f.ServiceName, f.ExcludeDeleted, tagsParam(f.Tags), f.TagsAll, modeltenant.Scope(ctx), f.ServiceID}
+}
+
+// tagsParam passes a tag filter as one newline separated string, nil when
+// there is no filter.
+func tagsParam(tags []string) *string {
+	if len(tags) == 0 {
+		return nil
+	}
+	v := strings.Join(tags, "\n")
+	return &v
+}
+
+// lockSub loads a row with one of the FOR UPDATE queries and keeps it locked
+// until the transaction ends.
+func lockSub(ctx context.Context, tx *sql.Tx, query string, args ...any) (modelsub.Subscription, error) {
+	s, err := scanSub(tx.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelsub.Subscription{}, myerror.ErrorSubscriptionNotFound
+		}
+		return modelsub.Subscription{}, fmt.Errorf("lock subscription: %w", err)
+	}
+	return s, nil
+}
+
+// ListByUser returns every subscription of the user that is not deleted.
+// Inside a guard it reads in the guarded transaction.
+func (r *DB) ListByUser(ctx context.Context, userID uuid.UUID) ([]modelsub.Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := tenancy.Conn(ctx, r.sql).QueryContext(ctx, sqlTextForByUser, userID, modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list by user: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelsub.Subscription
+	for rows.Next() {
+		s, err := scanSub(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list by user scan: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list by user rows: %w", err)
+	}
+	return out, nil
+}
+
+func (r *DB) ListActive(ctx context.Context, f modelsub.UpcomingFilter) ([]modelsub.Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForActive, f.From, f.To, f.UserID, f.ServiceName, modeltenant.Scope(ctx), f.ServiceID)
+	if err != nil {
+		return nil, fmt.Errorf("list active: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelsub.Subscription
+	for rows.Next() {
+		s, err := scanSub(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list active scan: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list active rows: %w", err)
+	}
+	return out, nil
+}
@@ -3,17 +3,33 @@ package subscription
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"regexp"
 	"testing"
 	"time"
 
+	modelaudit "test_task/internal/domain/models/audit"
+	modelauth "test_task/internal/domain/models/auth"
+	modelevent "test_task/internal/domain/models/event"
 	modelsub "test_task/internal/domain/models/subscription"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"test_task/internal/repository/postgres/tenancy"
 	myerror "test_task/pkg/global_errors"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
 )
 
+// expectBegin expects a transaction scoped to tenant.
+func expectBegin(mock sqlmock.Sqlmock, tenant string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(tenancy.SQLTextForSetTenant)).
+		WithArgs(tenant).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
 func TestRepo_Create_OK(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -33,15 +49,27 @@ func TestRepo_Create_OK(t *testing.T) {
 		UserID:      userID,
 		StartDate:   time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
 		EndDate:     nil,
+		Status:      modelsub.StatusActive,
 	}
 
+	expectBegin(mock, "default")
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLockUser)).
+		WithArgs(userID, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}))
 	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForCreate)).
-		WithArgs(s.ServiceName, s.Price, s.UserID, s.StartDate, s.EndDate).
+		WithArgs(s.ServiceName, s.ServiceID, s.Price, s.UserID, s.StartDate, s.EndDate, s.TrialUntil, s.TrialPrice, s.Status, "default").
 		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
 			AddRow(id.String(), now, now),
 		)
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForAudit)).
+		WithArgs(id, userID, modelaudit.OperationCreate, "anonymous", sql.NullString{}, sql.NullString{}, sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForOutbox)).
+		WithArgs(id, modelevent.TypeSubscriptionCreated, sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
-	got, err := repo.Create(context.Background(), s)
+	got, err := repo.Create(context.Background(), s, nil)
 	if err != nil {
 		t.Fatalf("Create error: %v", err)
 	}
@@ -53,6 +81,73 @@ func TestRepo_Create_OK(t *testing.T) {
 	}
 }
 
+func TestRepo_Create_DeletedUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	userID := uuid.New()
+	expectBegin(mock, "default")
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLockUser)).
+		WithArgs(userID, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}))
+	mock.ExpectRollback()
+
+	_, err = New(db, WithRegisteredUsers()).Create(context.Background(), modelsub.Subscription{
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      userID,
+		StartDate:   time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+		Status:      modelsub.StatusActive,
+	}, nil)
+	if !errors.Is(err, myerror.ErrorValidation) {
+		t.Fatalf("want ErrorValidation, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Create_GuardRunsInTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	userID := uuid.New()
+	expectBegin(mock, "default")
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLockUser)).
+		WithArgs(userID, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForLockWrites)).
+		WithArgs("subscriptions:default:" + userID.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	refused := errors.New("refused")
+	_, err = New(db).Create(context.Background(), modelsub.Subscription{
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      userID,
+		StartDate:   time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+		Status:      modelsub.StatusActive,
+	}, func(ctx context.Context, before *modelsub.Subscription) error {
+		if _, ok := tenancy.TxFrom(ctx); !ok || before != nil {
+			t.Fatalf("guard must run in the transaction without a row, got before %v", before)
+		}
+		return refused
+	})
+	if !errors.Is(err, refused) {
+		t.Fatalf("want the guard error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
 func TestRepo_GetSub_NotFound(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -65,15 +160,15 @@ func TestRepo_GetSub_NotFound(t *testing.T) {
 	id := uuid.New()
 
 	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForGet)).
-		WithArgs(id).
+		WithArgs(id, "default").
 		WillReturnError(sql.ErrNoRows)
 
 	_, err = repo.GetSub(context.Background(), id)
 	if err == nil {
 		t.Fatalf("expected error")
 	}
-	if err != myerror.ErrorNotFound {
-		t.Fatalf("want ErrorNotFound, got %v", err)
+	if err != myerror.ErrorSubscriptionNotFound {
+		t.Fatalf("want ErrorSubscriptionNotFound, got %v", err)
 	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -96,8 +191,12 @@ func TestRepo_Summary_OK(t *testing.T) {
 
 	service := "Yandex Plus"
 
+	// Before the rollup is first built the summary falls back to the months.
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForSumRollup)).
+		WithArgs(from, to, &userID, &service, false, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"total"}))
 	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForSum)).
-		WithArgs(from, to, &userID, &service).
+		WithArgs(from, to, &userID, &service, false, nil, false, "default", nil).
 		WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(int64(2400)))
 
 	total, err := repo.Summary(context.Background(), modelsub.SummaryFilter{
@@ -117,3 +216,454 @@ func TestRepo_Summary_OK(t *testing.T) {
 		t.Fatalf("expectations: %v", err)
 	}
 }
+
+func TestRepo_Summary_Rollup(t *testing.T) {
+	from := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)
+	serviceID := uuid.New()
+
+	tests := []struct {
+		name   string
+		f      modelsub.SummaryFilter
+		rollup bool
+		rolled driver.Value
+		want   int64
+	}{
+		{"covered by the rollup", modelsub.SummaryFilter{From: from, To: to, ExcludeDeleted: true}, true, int64(3600), 3600},
+		{"past the horizon", modelsub.SummaryFilter{From: from, To: to}, true, nil, 2400},
+		{"tag filter", modelsub.SummaryFilter{From: from, To: to, Tags: []string{"work"}}, false, nil, 2400},
+		{"catalog service filter", modelsub.SummaryFilter{From: from, To: to, ServiceID: &serviceID}, false, nil, 2400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New: %v", err)
+			}
+			defer db.Close()
+
+			if tt.rollup {
+				mock.ExpectQuery(regexp.QuoteMeta(sqlTextForSumRollup)).
+					WithArgs(from, to, tt.f.UserID, tt.f.ServiceName, tt.f.ExcludeDeleted, "default").
+					WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(tt.rolled))
+			}
+			if tt.rolled == nil {
+				var args []driver.Value
+				for _, a := range sumArgs(context.Background(), tt.f) {
+					args = append(args, a)
+				}
+				mock.ExpectQuery(regexp.QuoteMeta(sqlTextForSum)).
+					WithArgs(args...).
+					WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(int64(2400)))
+			}
+
+			total, err := New(db).Summary(context.Background(), tt.f)
+			if err != nil {
+				t.Fatalf("Summary error: %v", err)
+			}
+			if total != tt.want {
+				t.Fatalf("want %d, got %d", tt.want, total)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatalf("expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestRepo_SummaryByTag_PassesTagFilter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	from := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)
+	tags := "work\nmusic"
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForSumByTag)).
+		WithArgs(from, to, nil, nil, false, &tags, true, "default", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"tag", "total"}).
+			AddRow("work", int64(1200)).
+			AddRow(nil, int64(300)))
+
+	totals, err := repo.SummaryByTag(context.Background(), modelsub.SummaryFilter{
+		From:    from,
+		To:      to,
+		Tags:    []string{"work", "music"},
+		TagsAll: true,
+	})
+	if err != nil {
+		t.Fatalf("SummaryByTag error: %v", err)
+	}
+	if len(totals) != 2 {
+		t.Fatalf("want 2 groups, got %d", len(totals))
+	}
+	if totals[0].Tag == nil || *totals[0].Tag != "work" || totals[0].Total != 1200 {
+		t.Fatalf("unexpected first group: %+v", totals[0])
+	}
+	if totals[1].Tag != nil || totals[1].Total != 300 {
+		t.Fatalf("want untagged group of 300, got %+v", totals[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_BilledYearly(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	serviceID := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForBilledYearly)).
+		WithArgs(serviceID, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	yearly, err := repo.BilledYearly(context.Background(), serviceID)
+	if err != nil {
+		t.Fatalf("BilledYearly error: %v", err)
+	}
+	if !yearly {
+		t.Fatalf("want yearly")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Forecast(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	userID := uuid.New()
+	from := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForForecast)).
+		WithArgs(from, to, &userID, nil, true, nil, false, "default", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"month", "total"}).
+			AddRow(from, int64(700)).
+			AddRow(from.AddDate(0, 1, 0), int64(1100)).
+			AddRow(to, int64(0)))
+
+	totals, err := repo.Forecast(context.Background(), modelsub.SummaryFilter{
+		From:           from,
+		To:             to,
+		UserID:         &userID,
+		ExcludeDeleted: true,
+	})
+	if err != nil {
+		t.Fatalf("Forecast error: %v", err)
+	}
+	if len(totals) != 3 || !totals[0].Month.Equal(from) || totals[1].Total != 1100 || totals[2].Total != 0 {
+		t.Fatalf("unexpected totals: %+v", totals)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+var subRowColumns = []string{"id", "tenant_id", "service_name", "service_id", "price", "user_id", "start_date", "end_date", "trial_until", "trial_price", "status", "deleted_at", "created_at", "updated_at", "tags"}
+
+func TestRepo_UpdateSub_WritesAuditAndEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	id := uuid.New()
+	userID := uuid.New()
+	now := time.Now().UTC()
+	start := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+	end := time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)
+
+	s := modelsub.Subscription{ServiceName: "Yandex Plus", Price: 500, UserID: userID, StartDate: start, EndDate: &end, Status: modelsub.StatusCancelled}
+
+	expectBegin(mock, "default")
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLock)).
+		WithArgs(id, "default").
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "default", "Yandex Plus", nil, 400, userID.String(), start, nil, nil, nil, "active", nil, now, now, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForUpdate)).
+		WithArgs(id, s.ServiceName, s.ServiceID, s.Price, s.UserID, s.StartDate, s.EndDate, s.TrialUntil, s.TrialPrice, s.Status, "default").
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "default", "Yandex Plus", nil, 500, userID.String(), start, end, nil, nil, "cancelled", nil, now, now, ""))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForAudit)).
+		WithArgs(id, userID, modelaudit.OperationUpdate, "apikey:billing",
+			sql.NullString{String: "req-1", Valid: true}, sqlmock.AnyArg(), sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForOutbox)).
+		WithArgs(id, modelevent.TypeSubscriptionUpdated, sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForOutbox)).
+		WithArgs(id, modelevent.TypeSubscriptionCancelled, sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	ctx := modelauth.WithPrincipal(context.Background(), modelauth.Principal{Subject: "apikey:billing"})
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, "req-1")
+
+	got, err := repo.UpdateSub(ctx, id, s, nil)
+	if err != nil {
+		t.Fatalf("UpdateSub error: %v", err)
+	}
+	if got.Price != 500 {
+		t.Fatalf("price mismatch: %d", got.Price)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Delete_NotFoundRollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	id := uuid.New()
+
+	expectBegin(mock, "default")
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLock)).
+		WithArgs(id, "default").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	if err := repo.Delete(context.Background(), id); err != myerror.ErrorSubscriptionNotFound {
+		t.Fatalf("want ErrorSubscriptionNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Restore_OnlyDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	id := uuid.New()
+	userID := uuid.New()
+	now := time.Now().UTC()
+	start := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+	expectBegin(mock, "default")
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLockDeleted)).
+		WithArgs(id, &userID, "default").
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "default", "Netflix", nil, 700, userID.String(), start, nil, nil, nil, "active", now, now, now, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForRestore)).
+		WithArgs(id, "default").
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "default", "Netflix", nil, 700, userID.String(), start, nil, nil, nil, "active", nil, now, now, ""))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForAudit)).
+		WithArgs(id, userID, modelaudit.OperationRestore, "anonymous", sql.NullString{}, sqlmock.AnyArg(), sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForOutbox)).
+		WithArgs(id, modelevent.TypeSubscriptionRestored, sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	got, err := repo.Restore(context.Background(), id, &userID)
+	if err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	if got.DeletedAt != nil {
+		t.Fatalf("deleted_at must be cleared, got %v", got.DeletedAt)
+	}
+
+	expectBegin(mock, "default")
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLockDeleted)).
+		WithArgs(id, nil, "default").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	if _, err := repo.Restore(context.Background(), id, nil); err != myerror.ErrorSubscriptionNotFound {
+		t.Fatalf("want ErrorSubscriptionNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_SetStatus_ExpiresAndEmits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	id := uuid.New()
+	userID := uuid.New()
+	now := time.Now().UTC()
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)
+
+	expectBegin(mock, "default")
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLock)).
+		WithArgs(id, "default").
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "default", "Netflix", nil, 700, userID.String(), start, end, nil, nil, "cancelled", nil, now, now, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForSetStatus)).
+		WithArgs(id, modelsub.StatusExpired, "default").
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "default", "Netflix", nil, 700, userID.String(), start, end, nil, nil, "expired", nil, now, now, ""))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForAudit)).
+		WithArgs(id, userID, modelaudit.OperationUpdate, "anonymous", sql.NullString{}, sqlmock.AnyArg(), sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForOutbox)).
+		WithArgs(id, modelevent.TypeSubscriptionUpdated, sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForOutbox)).
+		WithArgs(id, modelevent.TypeSubscriptionExpired, sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	got, err := repo.SetStatus(context.Background(), id, modelsub.StatusCancelled, modelsub.StatusExpired)
+	if err != nil {
+		t.Fatalf("SetStatus error: %v", err)
+	}
+	if got.Status != modelsub.StatusExpired {
+		t.Fatalf("status mismatch: %s", got.Status)
+	}
+
+	expectBegin(mock, "default")
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLock)).
+		WithArgs(id, "default").
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "default", "Netflix", nil, 700, userID.String(), start, end, nil, nil, "paused", nil, now, now, ""))
+	mock.ExpectRollback()
+
+	if _, err := repo.SetStatus(context.Background(), id, modelsub.StatusCancelled, modelsub.StatusExpired); !errors.Is(err, myerror.ErrorInvalidTransition) {
+		t.Fatalf("want ErrorInvalidTransition, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_ScopedToTenant(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	ctx := modeltenant.WithTenant(context.Background(), "acme")
+	id := uuid.New()
+	userID := uuid.New()
+	now := time.Now().UTC()
+	start := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForCount)).
+		WithArgs(nil, nil, false, nil, nil, nil, nil, nil, false, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForList)).
+		WithArgs(nil, nil, 50, 0, false, nil, nil, nil, nil, nil, false, "acme").
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "acme", "Netflix", nil, 700, userID.String(), start, nil, nil, nil, "active", nil, now, now, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForSumRollup)).
+		WithArgs(start, to, nil, nil, false, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(int64(4200)))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForGet)).
+		WithArgs(id, "globex").
+		WillReturnError(sql.ErrNoRows)
+
+	subs, total, err := repo.List(ctx, modelsub.ListFilter{})
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if total != 1 || len(subs) != 1 || subs[0].TenantID != "acme" {
+		t.Fatalf("unexpected list: %d %+v", total, subs)
+	}
+	sum, err := repo.Summary(ctx, modelsub.SummaryFilter{From: start, To: to})
+	if err != nil {
+		t.Fatalf("Summary error: %v", err)
+	}
+	if sum != 4200 {
+		t.Fatalf("want 4200, got %d", sum)
+	}
+	if _, err := repo.GetSub(modeltenant.WithTenant(context.Background(), "globex"), id); err != myerror.ErrorSubscriptionNotFound {
+		t.Fatalf("a subscription of another tenant must not be found, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_AllTenants(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	cutoff := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	id := uuid.New()
+	userID := uuid.New()
+	now := time.Now().UTC()
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)
+
+	// Jobs find the rows of every tenant and change them in their tenant
+	// without scoping the transaction.
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForEnded)).
+		WithArgs(cutoff, 10, nil).
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "acme", "Netflix", nil, 700, userID.String(), start, end, nil, nil, "cancelled", nil, now, now, ""))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLock)).
+		WithArgs(id, nil).
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "acme", "Netflix", nil, 700, userID.String(), start, end, nil, nil, "cancelled", nil, now, now, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForSetStatus)).
+		WithArgs(id, modelsub.StatusExpired, "acme").
+		WillReturnRows(sqlmock.NewRows(subRowColumns).AddRow(id.String(), "acme", "Netflix", nil, 700, userID.String(), start, end, nil, nil, "expired", nil, now, now, ""))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForAudit)).
+		WithArgs(id, userID, modelaudit.OperationUpdate, "anonymous", sql.NullString{}, sqlmock.AnyArg(), sqlmock.AnyArg(), "acme").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForOutbox)).
+		WithArgs(id, modelevent.TypeSubscriptionUpdated, sqlmock.AnyArg(), "acme").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForOutbox)).
+		WithArgs(id, modelevent.TypeSubscriptionExpired, sqlmock.AnyArg(), "acme").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	ctx := modeltenant.WithAllTenants(context.Background())
+	ended, err := repo.ListEnded(ctx, cutoff, 10)
+	if err != nil {
+		t.Fatalf("ListEnded error: %v", err)
+	}
+	if len(ended) != 1 {
+		t.Fatalf("want 1 ended subscription, got %d", len(ended))
+	}
+	if _, err := repo.SetStatus(ctx, id, modelsub.StatusCancelled, modelsub.StatusExpired); err != nil {
+		t.Fatalf("SetStatus error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	modelaudit "test_task/internal/domain/models/audit"
+	modelevent "test_task/internal/domain/models/event"
+	modelsub "test_task/internal/domain/models/subscription"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"test_task/internal/repository/postgres/tenancy"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// sqlTextForTagEnsure creates the tags of user $1 of tenant $3 named in
+	// $2 that do not exist yet.
+	sqlTextForTagEnsure = `INSERT INTO tags(user_id, name, tenant_id)
+	SELECT $1, name, $3 FROM unnest(string_to_array($2, E'\n')) AS name
+	ON CONFLICT (tenant_id, user_id, lower(name)) DO NOTHING`
+	sqlTextForTagsClear = `DELETE FROM subscription_tags WHERE subscription_id = $1 AND tenant_id = $2`
+	sqlTextForTagsLink  = `INSERT INTO subscription_tags(subscription_id, tag_id, tenant_id)
+	SELECT $1, id, tenant_id FROM tags
+	WHERE user_id = $2 AND tenant_id = $4
+	AND lower(name) = ANY(string_to_array(lower($3), E'\n'))`
+)
+
+// SetTags replaces the tags of a subscription with the named tags of its
+// user, creating the missing ones, once guard, when given, accepts it.
+func (r *DB) SetTags(ctx context.Context, id uuid.UUID, names []string, guard modelsub.Guard) (modelsub.Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	before, err := lockSub(ctx, tx, sqlTextForLock, id, modeltenant.Scope(ctx))
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if err := guardWrite(ctx, tx, guard, before.TenantID, before.UserID, &before); err != nil {
+		return modelsub.Subscription{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, sqlTextForTagsClear, id, before.TenantID); err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("clear tags: %w", err)
+	}
+	if len(names) > 0 {
+		joined := strings.Join(names, "\n")
+		if _, err := tx.ExecContext(ctx, sqlTextForTagEnsure, before.UserID, joined, before.TenantID); err != nil {
+			return modelsub.Subscription{}, fmt.Errorf("create tags: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, sqlTextForTagsLink, id, before.UserID, joined, before.TenantID); err != nil {
+			return modelsub.Subscription{}, fmt.Errorf("link tags: %w", err)
+		}
+	}
+
+	after, err := scanSub(tx.QueryRowContext(ctx, sqlTextForGet, id, before.TenantID))
+	if err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("get subscription: %w", err)
+	}
+	if err := writeAudit(ctx, tx, modelaudit.OperationUpdate, &before, &after); err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if err := writeOutbox(ctx, tx, &after, modelevent.TypeSubscriptionUpdated); err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return modelsub.Subscription{}, fmt.Errorf("commit: %w", err)
+	}
+	return after, nil
+}
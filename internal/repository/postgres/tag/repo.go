@@ -0,0 +1,171 @@
+package tag
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	modeltag "test_task/internal/domain/models/tag"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"test_task/internal/repository/postgres/tenancy"
+	myerror "test_task/pkg/global_errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	tagColumns = `id, user_id, name, created_at, updated_at`
+
+	// sqlTextForCreate returns no row when the user already has a tag with
+	// the same name.
+	sqlTextForCreate = `INSERT INTO tags(user_id, name, tenant_id)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (tenant_id, user_id, lower(name)) DO NOTHING
+	RETURNING ` + tagColumns
+	sqlTextForGet = `SELECT ` + tagColumns + `
+	FROM tags
+	WHERE id = $1 AND ($2::text IS NULL OR tenant_id = $2)`
+	sqlTextForList = `SELECT ` + tagColumns + `
+	FROM tags
+	WHERE ($1::uuid IS NULL OR user_id = $1)
+	AND ($2::text IS NULL OR tenant_id = $2)
+	ORDER BY lower(name), id`
+	sqlTextForTaken = `SELECT EXISTS (
+	SELECT 1 FROM tags
+	WHERE (tenant_id, user_id) = (SELECT tenant_id, user_id FROM tags WHERE id = $1)
+	AND lower(name) = lower($2)
+	AND id <> $1
+	)`
+	sqlTextForRename = `UPDATE tags
+	SET name = $2
+	WHERE id = $1 AND ($3::text IS NULL OR tenant_id = $3)
+	RETURNING ` + tagColumns
+	sqlTextForDelete = `DELETE FROM tags WHERE id = $1 AND ($2::text IS NULL OR tenant_id = $2)`
+)
+
+type DB struct {
+	sql *sql.DB
+}
+
+func New(sql *sql.DB) *DB {
+	return &DB{
+		sql: sql,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTag(row scanner) (modeltag.Tag, error) {
+	var t modeltag.Tag
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt, &t.UpdatedAt)
+	return t, err
+}
+
+// Create adds a tag. It fails with ErrorConflict when the user already has a
+// tag with the same name.
+func (r *DB) Create(ctx context.Context, t modeltag.Tag) (modeltag.Tag, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	out, err := scanTag(r.sql.QueryRowContext(ctx, sqlTextForCreate, t.UserID, t.Name, modeltenant.From(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modeltag.Tag{}, fmt.Errorf("%w: tag %q", myerror.ErrorConflict, t.Name)
+		}
+		return modeltag.Tag{}, fmt.Errorf("create tag: %w", err)
+	}
+	return out, nil
+}
+
+func (r *DB) Get(ctx context.Context, id uuid.UUID) (modeltag.Tag, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	t, err := scanTag(r.sql.QueryRowContext(ctx, sqlTextForGet, id, modeltenant.Scope(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modeltag.Tag{}, myerror.ErrorTagNotFound
+		}
+		return modeltag.Tag{}, fmt.Errorf("get tag: %w", err)
+	}
+	return t, nil
+}
+
+// List returns the tags of a user, or of everybody when userID is nil.
+func (r *DB) List(ctx context.Context, userID *uuid.UUID) ([]modeltag.Tag, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForList, userID, modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modeltag.Tag
+	for rows.Next() {
+		t, err := scanTag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list tags scan: %w", err)
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list tags rows: %w", err)
+	}
+	return out, nil
+}
+
+// Rename changes the name of a tag; the subscriptions keep it.
+func (r *DB) Rename(ctx context.Context, id uuid.UUID, name string) (modeltag.Tag, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return modeltag.Tag{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var taken bool
+	if err := tx.QueryRowContext(ctx, sqlTextForTaken, id, name).Scan(&taken); err != nil {
+		return modeltag.Tag{}, fmt.Errorf("check tag name: %w", err)
+	}
+	if taken {
+		return modeltag.Tag{}, fmt.Errorf("%w: tag %q", myerror.ErrorConflict, name)
+	}
+
+	out, err := scanTag(tx.QueryRowContext(ctx, sqlTextForRename, id, name, modeltenant.Scope(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modeltag.Tag{}, myerror.ErrorTagNotFound
+		}
+		return modeltag.Tag{}, fmt.Errorf("rename tag: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return modeltag.Tag{}, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
+
+// Delete removes the tag from the catalog and from every subscription.
+func (r *DB) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.sql.ExecContext(ctx, sqlTextForDelete, id, modeltenant.Scope(ctx))
+	if err != nil {
+		return fmt.Errorf("delete tag: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete tag rows: %w", err)
+	}
+	if n == 0 {
+		return myerror.ErrorTagNotFound
+	}
+	return nil
+}
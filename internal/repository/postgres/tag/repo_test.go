@@ -0,0 +1,69 @@
+package tag
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	modeltag "test_task/internal/domain/models/tag"
+	modeltenant "test_task/internal/domain/models/tenant"
+	"test_task/internal/repository/postgres/tenancy"
+	myerror "test_task/pkg/global_errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var tagRowColumns = []string{"id", "user_id", "name", "created_at", "updated_at"}
+
+func TestRepo_Create_DuplicateNameConflicts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	userID := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForCreate)).
+		WithArgs(userID, "Work", modeltenant.Default).
+		WillReturnRows(sqlmock.NewRows(tagRowColumns))
+
+	_, err = repo.Create(context.Background(), modeltag.Tag{UserID: userID, Name: "Work"})
+	if !errors.Is(err, myerror.ErrorConflict) {
+		t.Fatalf("want ErrorConflict, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Rename_TakenNameConflicts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(tenancy.SQLTextForSetTenant)).
+		WithArgs(modeltenant.Default).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForTaken)).
+		WithArgs(id, "music").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	_, err = repo.Rename(context.Background(), id, "music")
+	if !errors.Is(err, myerror.ErrorConflict) {
+		t.Fatalf("want ErrorConflict, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package tenancy
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	modeltenant "test_task/internal/domain/models/tenant"
+)
+
+// SQLTextForSetTenant scopes the row-level security policies of the rest of
+// the transaction to one tenant.
+const SQLTextForSetTenant = `SELECT set_config('app.tenant_id', $1, true)`
+
+// BeginTx starts a transaction restricted by the row-level security policies
+// to the tenant of ctx, on top of the tenant filter of the queries. The
+// connections of ConnectDB scope every statement the same way; this keeps
+// transactions scoped on any other connection. Jobs working through every
+// tenant rely on the BYPASSRLS role they connect as.
+func BeginTx(ctx context.Context, db *sql.DB) (*sql.Tx, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if id := modeltenant.Scope(ctx); id != nil {
+		if _, err := tx.ExecContext(ctx, SQLTextForSetTenant, *id); err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("set tenant: %w", err)
+		}
+	}
+	return tx, nil
+}
+
+// Querier runs statements on a database or in a transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// WithTx makes the repositories reading through Conn run in tx while ctx is
+// used, so that the checks guarding a write see its locks and share its
+// connection.
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFrom returns the transaction stored by WithTx.
+func TxFrom(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return tx, ok
+}
+
+// Conn returns the transaction stored in ctx by WithTx, or db.
+func Conn(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := TxFrom(ctx); ok {
+		return tx
+	}
+	return db
+}
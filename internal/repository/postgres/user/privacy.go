@@ -0,0 +1,282 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	modelaudit "test_task/internal/domain/models/audit"
+	modelsub "test_task/internal/domain/models/subscription"
+	modeltenant "test_task/internal/domain/models/tenant"
+	modeluser "test_task/internal/domain/models/user"
+	"test_task/internal/repository/postgres/tenancy"
+	myerror "test_task/pkg/global_errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	sqlTextForExportSubs = `SELECT id, tenant_id, service_name, service_id, price, user_id, start_date, end_date, trial_until, trial_price, status, deleted_at, created_at, updated_at,
+	array_to_string(ARRAY(
+		SELECT t.name FROM subscription_tags st JOIN tags t ON t.id = st.tag_id
+		WHERE st.subscription_id = subscriptions.id
+		ORDER BY lower(t.name)
+	), E'\n') AS tags
+	FROM subscriptions
+	WHERE user_id = $1 AND ($2::text IS NULL OR tenant_id = $2)
+	ORDER BY created_at, id`
+	sqlTextForExportPauses = `SELECT p.subscription_id, p.from_date, p.to_date, p.created_at
+	FROM subscription_pauses p
+	JOIN subscriptions s ON s.tenant_id = p.tenant_id AND s.id = p.subscription_id
+	WHERE s.user_id = $1 AND ($2::text IS NULL OR s.tenant_id = $2)
+	ORDER BY p.subscription_id, p.from_date`
+	// sqlTextForExportPrices takes the prices from the snapshots of the audit
+	// log: the one a subscription was created with and every changed one.
+	sqlTextForExportPrices = `SELECT subscription_id, (after->>'price')::int, created_at
+	FROM subscription_audit
+	WHERE user_id = $1 AND ($2::text IS NULL OR tenant_id = $2)
+	AND operation IN ('create', 'update')
+	AND after->'price' IS NOT NULL
+	AND (before IS NULL OR before->'price' IS DISTINCT FROM after->'price')
+	ORDER BY subscription_id, id`
+	sqlTextForExportTags = `SELECT id, name, created_at
+	FROM tags
+	WHERE user_id = $1 AND ($2::text IS NULL OR tenant_id = $2)
+	ORDER BY lower(name)`
+	sqlTextForExportBudgets = `SELECT id, amount, service_name, tag_id, enforce, created_at
+	FROM budgets
+	WHERE user_id = $1 AND ($2::text IS NULL OR tenant_id = $2)
+	ORDER BY created_at, id`
+	sqlTextForExportAudit = `SELECT id, subscription_id, operation, actor, before, after, created_at
+	FROM subscription_audit
+	WHERE user_id = $1 AND ($2::text IS NULL OR tenant_id = $2)
+	ORDER BY id`
+	sqlTextForExportNotifications = `SELECT id, subscription_id, kind, channel, subject, body, status, created_at, sent_at
+	FROM notifications
+	WHERE user_id = $1 AND ($2::text IS NULL OR tenant_id = $2)
+	ORDER BY id`
+
+	// sqlTextForAllowAuditErasure lets the rest of the transaction
+	// pseudonymize audit entries, see forbid_audit_mutation.
+	sqlTextForAllowAuditErasure = `SELECT set_config('app.audit_erasure', 'on', true)`
+	// Events of both subscriptions and budgets carry the user in their data.
+	sqlTextForEraseOutbox = `DELETE FROM outbox
+	WHERE tenant_id = $2 AND payload->'data'->>'user_id' = $1::text`
+	sqlTextForEraseDeliveries = `DELETE FROM webhook_deliveries
+	WHERE tenant_id = $2 AND payload->'data'->>'user_id' = $1::text`
+	sqlTextForEraseNotifications = `DELETE FROM notifications WHERE user_id = $1 AND tenant_id = $2`
+	// Pauses and tag links go with the subscriptions, the rollup triggers
+	// drop the monthly_spend rows.
+	sqlTextForEraseSubs     = `DELETE FROM subscriptions WHERE user_id = $1 AND tenant_id = $2`
+	sqlTextForEraseWebhooks = `DELETE FROM webhooks WHERE user_id = $1 AND tenant_id = $2`
+	sqlTextForEraseAPIKeys  = `DELETE FROM api_keys WHERE user_id = $1 AND tenant_id = $2`
+	sqlTextForEraseAudit    = `UPDATE subscription_audit
+	SET user_id = $3, before = NULL, after = NULL
+	WHERE user_id = $1 AND tenant_id = $2`
+	sqlTextForEraseProfile = `DELETE FROM users WHERE id = $1 AND tenant_id = $2`
+	sqlTextForTombstone    = `INSERT INTO subscription_audit(subscription_id, user_id, operation, actor, request_id, after, tenant_id)
+	VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7)`
+)
+
+// Export collects everything held about the user in one transaction.
+func (r *DB) Export(ctx context.Context, id uuid.UUID) (modeluser.Export, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return modeluser.Export{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	scope := modeltenant.Scope(ctx)
+	out := modeluser.Export{UserID: id, ExportedAt: time.Now().UTC()}
+
+	profile, err := scanUser(tx.QueryRowContext(ctx, sqlTextForGet, id, scope))
+	switch {
+	case err == nil:
+		out.Profile = &profile
+	case !errors.Is(err, sql.ErrNoRows):
+		return modeluser.Export{}, fmt.Errorf("export profile: %w", err)
+	}
+
+	if out.Subscriptions, err = exportRows(ctx, tx, "subscriptions", sqlTextForExportSubs, id, scope, scanExportSub); err != nil {
+		return modeluser.Export{}, err
+	}
+	if out.Pauses, err = exportRows(ctx, tx, "pauses", sqlTextForExportPauses, id, scope, func(row scanner) (modeluser.ExportPause, error) {
+		var p modeluser.ExportPause
+		return p, row.Scan(&p.SubscriptionID, &p.From, &p.To, &p.CreatedAt)
+	}); err != nil {
+		return modeluser.Export{}, err
+	}
+	if out.PriceHistory, err = exportRows(ctx, tx, "prices", sqlTextForExportPrices, id, scope, func(row scanner) (modeluser.PriceChange, error) {
+		var p modeluser.PriceChange
+		return p, row.Scan(&p.SubscriptionID, &p.Price, &p.ChangedAt)
+	}); err != nil {
+		return modeluser.Export{}, err
+	}
+	if out.Tags, err = exportRows(ctx, tx, "tags", sqlTextForExportTags, id, scope, func(row scanner) (modeluser.ExportTag, error) {
+		var t modeluser.ExportTag
+		return t, row.Scan(&t.ID, &t.Name, &t.CreatedAt)
+	}); err != nil {
+		return modeluser.Export{}, err
+	}
+	if out.Budgets, err = exportRows(ctx, tx, "budgets", sqlTextForExportBudgets, id, scope, func(row scanner) (modeluser.ExportBudget, error) {
+		var b modeluser.ExportBudget
+		return b, row.Scan(&b.ID, &b.Amount, &b.ServiceName, &b.TagID, &b.Enforce, &b.CreatedAt)
+	}); err != nil {
+		return modeluser.Export{}, err
+	}
+	if out.Audit, err = exportRows(ctx, tx, "audit", sqlTextForExportAudit, id, scope, func(row scanner) (modeluser.ExportAuditEntry, error) {
+		var e modeluser.ExportAuditEntry
+		var before, after []byte
+		err := row.Scan(&e.ID, &e.SubscriptionID, &e.Operation, &e.Actor, &before, &after, &e.CreatedAt)
+		e.Before, e.After = before, after
+		return e, err
+	}); err != nil {
+		return modeluser.Export{}, err
+	}
+	if out.Notifications, err = exportRows(ctx, tx, "notifications", sqlTextForExportNotifications, id, scope, func(row scanner) (modeluser.ExportNotification, error) {
+		var n modeluser.ExportNotification
+		return n, row.Scan(&n.ID, &n.SubscriptionID, &n.Kind, &n.Channel, &n.Subject, &n.Body, &n.Status, &n.CreatedAt, &n.SentAt)
+	}); err != nil {
+		return modeluser.Export{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return modeluser.Export{}, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
+
+// exportRows runs one query of Export. It returns an empty slice rather than
+// nil, so that empty sections render as [].
+func exportRows[T any](ctx context.Context, tx *sql.Tx, what, query string, id uuid.UUID, scope *string, scan func(scanner) (T, error)) ([]T, error) {
+	rows, err := tx.QueryContext(ctx, query, id, scope)
+	if err != nil {
+		return nil, fmt.Errorf("export %s: %w", what, err)
+	}
+	defer rows.Close()
+
+	out := []T{}
+	for rows.Next() {
+		v, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("export %s scan: %w", what, err)
+		}
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("export %s rows: %w", what, err)
+	}
+	return out, nil
+}
+
+func scanExportSub(row scanner) (modelsub.Subscription, error) {
+	var s modelsub.Subscription
+	var tags string
+	err := row.Scan(
+		&s.ID,
+		&s.TenantID,
+		&s.ServiceName,
+		&s.ServiceID,
+		&s.Price,
+		&s.UserID,
+		&s.StartDate,
+		&s.EndDate,
+		&s.TrialUntil,
+		&s.TrialPrice,
+		&s.Status,
+		&s.DeletedAt,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+		&tags,
+	)
+	s.Tags = []string{}
+	if tags != "" {
+		s.Tags = strings.Split(tags, "\n")
+	}
+	return s, err
+}
+
+// Erase removes everything held about the user in the tenant of ctx in one
+// transaction: the profile, subscriptions, tags, budgets, notifications,
+// webhooks and API keys bound to the user, and the events about them. Audit
+// entries are kept under a random pseudonym without their snapshots. A
+// tombstone entry records the erasure. It fails with ErrorUserNotFound when
+// nothing of the user was found.
+func (r *DB) Erase(ctx context.Context, id uuid.UUID) (modeluser.Erasure, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return modeluser.Erasure{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, sqlTextForAllowAuditErasure); err != nil {
+		return modeluser.Erasure{}, fmt.Errorf("allow audit erasure: %w", err)
+	}
+
+	tenant := modeltenant.From(ctx)
+	pseudonym := uuid.New()
+	out := modeluser.Erasure{UserID: id}
+	steps := []struct {
+		what  string
+		query string
+		args  []any
+		count *int64
+	}{
+		{"events", sqlTextForEraseOutbox, []any{id, tenant}, &out.Events},
+		{"deliveries", sqlTextForEraseDeliveries, []any{id, tenant}, &out.Events},
+		{"notifications", sqlTextForEraseNotifications, []any{id, tenant}, &out.Notifications},
+		{"subscriptions", sqlTextForEraseSubs, []any{id, tenant}, &out.Subscriptions},
+		{"budgets", sqlTextForDeleteBudgets, []any{id, tenant}, &out.Budgets},
+		{"tags", sqlTextForDeleteTags, []any{id, tenant}, &out.Tags},
+		{"webhooks", sqlTextForEraseWebhooks, []any{id, tenant}, &out.Webhooks},
+		{"api keys", sqlTextForEraseAPIKeys, []any{id, tenant}, &out.APIKeys},
+		{"audit", sqlTextForEraseAudit, []any{id, tenant, pseudonym}, &out.Audit},
+		{"profile", sqlTextForEraseProfile, []any{id, tenant}, &out.Profile},
+	}
+	for _, s := range steps {
+		res, err := tx.ExecContext(ctx, s.query, s.args...)
+		if err != nil {
+			return modeluser.Erasure{}, fmt.Errorf("erase %s: %w", s.what, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return modeluser.Erasure{}, fmt.Errorf("erase %s: %w", s.what, err)
+		}
+		*s.count += n
+	}
+	if out.Empty() {
+		return modeluser.Erasure{}, myerror.ErrorUserNotFound
+	}
+
+	after, err := json.Marshal(out)
+	if err != nil {
+		return modeluser.Erasure{}, fmt.Errorf("marshal tombstone: %w", err)
+	}
+	requestID := sql.NullString{String: modelaudit.RequestIDFrom(ctx)}
+	requestID.Valid = requestID.String != ""
+	if _, err := tx.ExecContext(ctx, sqlTextForTombstone,
+		uuid.Nil,
+		id,
+		modelaudit.OperationErase,
+		modelaudit.ActorFrom(ctx),
+		requestID,
+		string(after),
+		tenant,
+	); err != nil {
+		return modeluser.Erasure{}, fmt.Errorf("write tombstone: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return modeluser.Erasure{}, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,127 @@
+package user
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+
+	modelaudit "test_task/internal/domain/models/audit"
+	modeltenant "test_task/internal/domain/models/tenant"
+	modeluser "test_task/internal/domain/models/user"
+	"test_task/internal/repository/postgres/tenancy"
+	myerror "test_task/pkg/global_errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+// tombstone matches the after snapshot of the erase entry.
+type tombstone struct {
+	want modeluser.Erasure
+}
+
+func (t tombstone) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	var got modeluser.Erasure
+	return json.Unmarshal([]byte(s), &got) == nil && got == t.want
+}
+
+// notUser matches the pseudonym that replaces user in the audit log.
+type notUser uuid.UUID
+
+func (n notUser) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	id, err := uuid.Parse(s)
+	return err == nil && id != uuid.Nil && id != uuid.UUID(n)
+}
+
+func expectErase(mock sqlmock.Sqlmock, id uuid.UUID, tenant string, counts map[string]int64) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(tenancy.SQLTextForSetTenant)).
+		WithArgs(tenant).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForAllowAuditErasure)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	for _, q := range []string{
+		sqlTextForEraseOutbox,
+		sqlTextForEraseDeliveries,
+		sqlTextForEraseNotifications,
+		sqlTextForEraseSubs,
+		sqlTextForDeleteBudgets,
+		sqlTextForDeleteTags,
+		sqlTextForEraseWebhooks,
+		sqlTextForEraseAPIKeys,
+	} {
+		mock.ExpectExec(regexp.QuoteMeta(q)).
+			WithArgs(id, tenant).
+			WillReturnResult(sqlmock.NewResult(0, counts[q]))
+	}
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForEraseAudit)).
+		WithArgs(id, tenant, notUser(id)).
+		WillReturnResult(sqlmock.NewResult(0, counts[sqlTextForEraseAudit]))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForEraseProfile)).
+		WithArgs(id, tenant).
+		WillReturnResult(sqlmock.NewResult(0, counts[sqlTextForEraseProfile]))
+}
+
+func TestRepo_Erase_WritesTombstone(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	id := uuid.New()
+	want := modeluser.Erasure{UserID: id, Profile: 1, Subscriptions: 2, Events: 3, Notifications: 4, Audit: 5}
+	expectErase(mock, id, "acme", map[string]int64{
+		sqlTextForEraseOutbox:        2,
+		sqlTextForEraseDeliveries:    1,
+		sqlTextForEraseNotifications: 4,
+		sqlTextForEraseSubs:          2,
+		sqlTextForEraseAudit:         5,
+		sqlTextForEraseProfile:       1,
+	})
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForTombstone)).
+		WithArgs(uuid.Nil, id, modelaudit.OperationErase, "anonymous", sqlmock.AnyArg(), tombstone{want}, "acme").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	got, err := New(db).Erase(modeltenant.WithTenant(context.Background(), "acme"), id)
+	if err != nil {
+		t.Fatalf("Erase: %v", err)
+	}
+	if got != want {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Erase_NothingHeld(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	id := uuid.New()
+	expectErase(mock, id, modeltenant.Default, nil)
+	mock.ExpectRollback()
+
+	if _, err := New(db).Erase(context.Background(), id); !errors.Is(err, myerror.ErrorNotFound) {
+		t.Fatalf("want ErrorNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
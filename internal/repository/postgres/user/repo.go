@@ -0,0 +1,270 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	modeltenant "test_task/internal/domain/models/tenant"
+	modeluser "test_task/internal/domain/models/user"
+	reposub "test_task/internal/repository/postgres/subscription"
+	"test_task/internal/repository/postgres/tenancy"
+	myerror "test_task/pkg/global_errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	userColumns = `id, tenant_id, display_name, email, timezone, default_currency, anonymized_at, created_at, updated_at`
+
+	// sqlTextForCreate returns no row when the id or the e-mail is taken.
+	sqlTextForCreate = `INSERT INTO users(id, display_name, email, timezone, default_currency, tenant_id)
+	VALUES (coalesce($1, gen_random_uuid()), $2, $3, $4, $5, $6)
+	ON CONFLICT DO NOTHING
+	RETURNING ` + userColumns
+	sqlTextForGet = `SELECT ` + userColumns + `
+	FROM users
+	WHERE id = $1 AND ($2::text IS NULL OR tenant_id = $2)`
+	sqlTextForList = `SELECT ` + userColumns + `
+	FROM users
+	WHERE ($1::text IS NULL OR lower(email) = lower($1))
+	AND ($4::text IS NULL OR tenant_id = $4)
+	ORDER BY created_at, id
+	LIMIT $2 OFFSET $3`
+	sqlTextForEmailTaken = `SELECT EXISTS (
+	SELECT 1 FROM users
+	WHERE tenant_id = (SELECT tenant_id FROM users WHERE id = $1)
+	AND lower(email) = lower($2)
+	AND id <> $1
+	)`
+	sqlTextForUpdate = `UPDATE users
+	SET display_name = $2, email = $3, timezone = $4, default_currency = $5
+	WHERE id = $1 AND ($6::text IS NULL OR tenant_id = $6)
+	RETURNING ` + userColumns
+	sqlTextForAnonymize = `UPDATE users
+	SET display_name = $2, email = NULL, timezone = $3, anonymized_at = coalesce(anonymized_at, now())
+	WHERE id = $1 AND ($4::text IS NULL OR tenant_id = $4)
+	RETURNING ` + userColumns
+	// sqlTextForLock holds the user being deleted; creating a subscription
+	// for them waits for the delete, see the subscription repository.
+	sqlTextForLock = `SELECT tenant_id FROM users
+	WHERE id = $1 AND ($2::text IS NULL OR tenant_id = $2)
+	FOR UPDATE`
+	sqlTextForCountSubs = `SELECT count(*) FROM subscriptions
+	WHERE user_id = $1 AND tenant_id = $2 AND deleted_at IS NULL`
+	sqlTextForDelete        = `DELETE FROM users WHERE id = $1 AND tenant_id = $2`
+	sqlTextForDeleteBudgets = `DELETE FROM budgets WHERE user_id = $1 AND tenant_id = $2`
+	sqlTextForDeleteTags    = `DELETE FROM tags WHERE user_id = $1 AND tenant_id = $2`
+)
+
+type DB struct {
+	sql *sql.DB
+}
+
+func New(sql *sql.DB) *DB {
+	return &DB{
+		sql: sql,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row scanner) (modeluser.User, error) {
+	var u modeluser.User
+	err := row.Scan(
+		&u.ID,
+		&u.TenantID,
+		&u.DisplayName,
+		&u.Email,
+		&u.Timezone,
+		&u.DefaultCurrency,
+		&u.AnonymizedAt,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+	)
+	return u, err
+}
+
+// Create registers the user under u.ID, or a new id when it is nil. It fails
+// with ErrorConflict when the id or the e-mail is taken.
+func (r *DB) Create(ctx context.Context, u modeluser.User) (modeluser.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var id *uuid.UUID
+	if u.ID != uuid.Nil {
+		id = &u.ID
+	}
+	out, err := scanUser(r.sql.QueryRowContext(ctx, sqlTextForCreate,
+		id,
+		u.DisplayName,
+		u.Email,
+		u.Timezone,
+		u.DefaultCurrency,
+		modeltenant.From(ctx),
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modeluser.User{}, fmt.Errorf("%w: user id or email is already registered", myerror.ErrorConflict)
+		}
+		return modeluser.User{}, fmt.Errorf("create user: %w", err)
+	}
+	return out, nil
+}
+
+func (r *DB) Get(ctx context.Context, id uuid.UUID) (modeluser.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	u, err := scanUser(r.sql.QueryRowContext(ctx, sqlTextForGet, id, modeltenant.Scope(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modeluser.User{}, myerror.ErrorUserNotFound
+		}
+		return modeluser.User{}, fmt.Errorf("get user: %w", err)
+	}
+	return u, nil
+}
+
+func (r *DB) List(ctx context.Context, f modeluser.Filter) ([]modeluser.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if f.Limit <= 0 || f.Limit > 200 {
+		f.Limit = 50
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForList, f.Email, f.Limit, f.Offset, modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modeluser.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list users scan: %w", err)
+		}
+		out = append(out, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list users rows: %w", err)
+	}
+	return out, nil
+}
+
+// Update replaces the profile. It fails with ErrorConflict when another user
+// of the tenant has the e-mail.
+func (r *DB) Update(ctx context.Context, u modeluser.User) (modeluser.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return modeluser.User{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if u.Email != nil {
+		var taken bool
+		if err := tx.QueryRowContext(ctx, sqlTextForEmailTaken, u.ID, *u.Email).Scan(&taken); err != nil {
+			return modeluser.User{}, fmt.Errorf("check user email: %w", err)
+		}
+		if taken {
+			return modeluser.User{}, fmt.Errorf("%w: email %q is already registered", myerror.ErrorConflict, *u.Email)
+		}
+	}
+
+	out, err := scanUser(tx.QueryRowContext(ctx, sqlTextForUpdate,
+		u.ID,
+		u.DisplayName,
+		u.Email,
+		u.Timezone,
+		u.DefaultCurrency,
+		modeltenant.Scope(ctx),
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modeluser.User{}, myerror.ErrorUserNotFound
+		}
+		return modeluser.User{}, fmt.Errorf("update user: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return modeluser.User{}, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
+
+// Anonymize erases the profile of the user and keeps the row, so that their
+// subscriptions still reference a registered user.
+func (r *DB) Anonymize(ctx context.Context, id uuid.UUID) (modeluser.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	u, err := scanUser(r.sql.QueryRowContext(ctx, sqlTextForAnonymize,
+		id, modeluser.AnonymizedName, modeluser.DefaultTimezone, modeltenant.Scope(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modeluser.User{}, myerror.ErrorUserNotFound
+		}
+		return modeluser.User{}, fmt.Errorf("anonymize user: %w", err)
+	}
+	return u, nil
+}
+
+// Delete removes the user together with their tags and budgets in one
+// transaction. With modeluser.DeleteRefuse it fails with ErrorConflict while
+// the user has live subscriptions, with modeluser.DeleteCascade it
+// soft-deletes them first.
+func (r *DB) Delete(ctx context.Context, id uuid.UUID, mode string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var tenant string
+	if err := tx.QueryRowContext(ctx, sqlTextForLock, id, modeltenant.Scope(ctx)).Scan(&tenant); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return myerror.ErrorUserNotFound
+		}
+		return fmt.Errorf("lock user: %w", err)
+	}
+	switch mode {
+	case modeluser.DeleteRefuse:
+		var total int
+		if err := tx.QueryRowContext(ctx, sqlTextForCountSubs, id, tenant).Scan(&total); err != nil {
+			return fmt.Errorf("count user subscriptions: %w", err)
+		}
+		if total > 0 {
+			return fmt.Errorf("%w: user has %d subscription(s)", myerror.ErrorConflict, total)
+		}
+	case modeluser.DeleteCascade:
+		if _, err := reposub.DeleteByUser(ctx, tx, id, tenant); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, sqlTextForDelete, id, tenant); err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, sqlTextForDeleteBudgets, id, tenant); err != nil {
+		return fmt.Errorf("delete user budgets: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, sqlTextForDeleteTags, id, tenant); err != nil {
+		return fmt.Errorf("delete user tags: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	modeltenant "test_task/internal/domain/models/tenant"
+	modeluser "test_task/internal/domain/models/user"
+	"test_task/internal/repository/postgres/tenancy"
+	myerror "test_task/pkg/global_errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var userRowColumns = []string{"id", "tenant_id", "display_name", "email", "timezone", "default_currency", "anonymized_at", "created_at", "updated_at"}
+
+func TestRepo_Create_TakenConflicts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	id := uuid.New()
+	email := "ann@example.com"
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForCreate)).
+		WithArgs(&id, "Ann", &email, "UTC", "RUB", "acme").
+		WillReturnRows(sqlmock.NewRows(userRowColumns))
+
+	_, err = New(db).Create(modeltenant.WithTenant(context.Background(), "acme"), modeluser.User{
+		ID: id, DisplayName: "Ann", Email: &email, Timezone: "UTC", DefaultCurrency: "RUB",
+	})
+	if !errors.Is(err, myerror.ErrorConflict) {
+		t.Fatalf("want ErrorConflict, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Delete_RemovesTagsAndBudgets(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	id := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(tenancy.SQLTextForSetTenant)).
+		WithArgs("acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLock)).
+		WithArgs(id, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id"}).AddRow("acme"))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForCountSubs)).
+		WithArgs(id, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForDelete)).
+		WithArgs(id, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForDeleteBudgets)).
+		WithArgs(id, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForDeleteTags)).
+		WithArgs(id, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	if err := New(db).Delete(modeltenant.WithTenant(context.Background(), "acme"), id, modeluser.DeleteRefuse); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Delete_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	id := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(tenancy.SQLTextForSetTenant)).
+		WithArgs(modeltenant.Default).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLock)).
+		WithArgs(id, modeltenant.Default).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id"}))
+	mock.ExpectRollback()
+
+	if err := New(db).Delete(context.Background(), id, modeluser.DeleteCascade); !errors.Is(err, myerror.ErrorNotFound) {
+		t.Fatalf("want ErrorNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_Delete_RefuseWithSubscriptions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	id := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(tenancy.SQLTextForSetTenant)).
+		WithArgs("acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForLock)).
+		WithArgs(id, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id"}).AddRow("acme"))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForCountSubs)).
+		WithArgs(id, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectRollback()
+
+	if err := New(db).Delete(modeltenant.WithTenant(context.Background(), "acme"), id, modeluser.DeleteRefuse); !errors.Is(err, myerror.ErrorConflict) {
+		t.Fatalf("want ErrorConflict, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
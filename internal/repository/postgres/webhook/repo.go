@@ -0,0 +1,425 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	modelevent "test_task/internal/domain/models/event"
+	modeltenant "test_task/internal/domain/models/tenant"
+	modelwebhook "test_task/internal/domain/models/webhook"
+	"test_task/internal/repository/postgres/tenancy"
+	myerror "test_task/pkg/global_errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const webhookColumns = `id, tenant_id, url, secret, array_to_string(event_types, ','), user_id, failure_count, disabled_at, created_at, updated_at`
+
+const deliveryColumns = `id, tenant_id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, delivered_at, created_at`
+
+const (
+	sqlTextForCreate = `INSERT INTO webhooks(url, secret, event_types, user_id, tenant_id)
+	VALUES ($1, $2, string_to_array($3, ','), $4, $5)
+	RETURNING ` + webhookColumns
+	sqlTextForGet = `SELECT ` + webhookColumns + `
+	FROM webhooks
+	WHERE id = $1 AND ($2::text IS NULL OR tenant_id = $2)`
+	sqlTextForList = `SELECT ` + webhookColumns + `
+	FROM webhooks
+	WHERE ($1::uuid IS NULL OR user_id = $1)
+	AND ($2::text IS NULL OR tenant_id = $2)
+	ORDER BY created_at DESC`
+	// sqlTextForUpdate re-enabling a disabled webhook also clears its failure streak.
+	sqlTextForUpdate = `UPDATE webhooks
+	SET url = $2,
+		event_types = string_to_array($3, ','),
+		failure_count = CASE WHEN $4 AND disabled_at IS NOT NULL THEN 0 ELSE failure_count END,
+		disabled_at = CASE WHEN $4 THEN NULL ELSE coalesce(disabled_at, now()) END
+	WHERE id = $1 AND ($5::text IS NULL OR tenant_id = $5)
+	RETURNING ` + webhookColumns
+	sqlTextForDelete = `DELETE FROM webhooks WHERE id = $1 AND ($2::text IS NULL OR tenant_id = $2)`
+
+	// sqlTextForEnqueue only matches the webhooks of the tenant of the event.
+	sqlTextForEnqueue = `INSERT INTO webhook_deliveries(webhook_id, event_id, event_type, payload, tenant_id)
+	SELECT w.id, $1, $2, $3::jsonb, w.tenant_id
+	FROM webhooks w
+	WHERE w.tenant_id = $5
+		AND w.disabled_at IS NULL
+		AND (cardinality(w.event_types) = 0 OR $2 = ANY(w.event_types))
+		AND (w.user_id IS NULL OR w.user_id = $4)
+	ON CONFLICT (webhook_id, event_id) DO NOTHING`
+
+	sqlTextForListDeliveries = `SELECT ` + deliveryColumns + `
+	FROM webhook_deliveries
+	WHERE webhook_id = $1 AND ($2::text IS NULL OR status = $2)
+	AND ($5::text IS NULL OR tenant_id = $5)
+	ORDER BY id DESC
+	LIMIT $3 OFFSET $4`
+	sqlTextForGetDelivery = `SELECT ` + deliveryColumns + `
+	FROM webhook_deliveries
+	WHERE id = $1 AND webhook_id = $2 AND ($3::text IS NULL OR tenant_id = $3)`
+	sqlTextForListAttempts = `SELECT id, delivery_id, status_code, error, duration_ms, created_at
+	FROM webhook_delivery_attempts
+	WHERE delivery_id = $1 AND ($2::text IS NULL OR tenant_id = $2)
+	ORDER BY id`
+	// sqlTextForRedeliver gives the delivery a fresh retry budget; earlier
+	// attempts stay in the attempt log.
+	sqlTextForRedeliver = `UPDATE webhook_deliveries
+	SET status = 'pending', attempts = 0, next_attempt_at = now(), locked_until = NULL
+	WHERE id = $1 AND webhook_id = $2 AND ($3::text IS NULL OR tenant_id = $3)
+	RETURNING ` + deliveryColumns
+
+	sqlTextForClaim = `UPDATE webhook_deliveries d
+	SET locked_until = now() + make_interval(secs => $2)
+	FROM webhooks w
+	WHERE w.id = d.webhook_id AND d.id IN (
+		SELECT p.id
+		FROM webhook_deliveries p
+		JOIN webhooks pw ON pw.id = p.webhook_id
+		WHERE p.status = 'pending'
+			AND p.next_attempt_at <= now()
+			AND (p.locked_until IS NULL OR p.locked_until < now())
+			AND pw.disabled_at IS NULL
+			AND ($3::text IS NULL OR p.tenant_id = $3)
+		ORDER BY p.next_attempt_at, p.id
+		LIMIT $1
+		FOR UPDATE OF p SKIP LOCKED
+	)
+	RETURNING d.id, d.tenant_id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at,
+		d.last_status_code, d.last_error, d.delivered_at, d.created_at, w.url, w.secret`
+	sqlTextForInsertAttempt = `INSERT INTO webhook_delivery_attempts(delivery_id, status_code, error, duration_ms, tenant_id)
+	VALUES ($1, $2, $3, $4, $5)`
+	sqlTextForUpdateDelivery = `UPDATE webhook_deliveries
+	SET status = $2,
+		attempts = attempts + 1,
+		next_attempt_at = $3,
+		locked_until = NULL,
+		last_status_code = $4,
+		last_error = $5,
+		delivered_at = CASE WHEN $2 = 'succeeded' THEN now() END
+	WHERE id = $1 AND tenant_id = $6`
+	sqlTextForWebhookSucceeded = `UPDATE webhooks
+	SET failure_count = 0
+	WHERE id = $1 AND tenant_id = $2 AND failure_count <> 0`
+	sqlTextForWebhookFailed = `UPDATE webhooks
+	SET failure_count = failure_count + 1,
+		disabled_at = CASE WHEN failure_count + 1 >= $2 THEN coalesce(disabled_at, now()) ELSE disabled_at END
+	WHERE id = $1 AND tenant_id = $3
+	RETURNING disabled_at IS NOT NULL`
+)
+
+type DB struct {
+	sql *sql.DB
+}
+
+func New(sql *sql.DB) *DB {
+	return &DB{
+		sql: sql,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row scanner) (modelwebhook.Webhook, error) {
+	var w modelwebhook.Webhook
+	var types string
+	err := row.Scan(
+		&w.ID,
+		&w.TenantID,
+		&w.URL,
+		&w.Secret,
+		&types,
+		&w.UserID,
+		&w.FailureCount,
+		&w.DisabledAt,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	)
+	if err != nil {
+		return modelwebhook.Webhook{}, err
+	}
+	if types != "" {
+		w.EventTypes = strings.Split(types, ",")
+	}
+	return w, nil
+}
+
+func deliveryDest(d *modelwebhook.Delivery) []any {
+	return []any{
+		&d.ID,
+		&d.TenantID,
+		&d.WebhookID,
+		&d.EventID,
+		&d.EventType,
+		&d.Payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.LastStatusCode,
+		&d.LastError,
+		&d.DeliveredAt,
+		&d.CreatedAt,
+	}
+}
+
+func (r *DB) Create(ctx context.Context, w modelwebhook.Webhook) (modelwebhook.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	out, err := scanWebhook(r.sql.QueryRowContext(ctx, sqlTextForCreate,
+		w.URL,
+		w.Secret,
+		strings.Join(w.EventTypes, ","),
+		w.UserID,
+		modeltenant.From(ctx),
+	))
+	if err != nil {
+		return modelwebhook.Webhook{}, fmt.Errorf("create webhook: %w", err)
+	}
+	return out, nil
+}
+
+func (r *DB) Get(ctx context.Context, id uuid.UUID) (modelwebhook.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	w, err := scanWebhook(r.sql.QueryRowContext(ctx, sqlTextForGet, id, modeltenant.Scope(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelwebhook.Webhook{}, myerror.ErrorWebhookNotFound
+		}
+		return modelwebhook.Webhook{}, fmt.Errorf("get webhook: %w", err)
+	}
+	return w, nil
+}
+
+// List returns the webhooks of userID, or all of them when userID is nil.
+func (r *DB) List(ctx context.Context, userID *uuid.UUID) ([]modelwebhook.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForList, userID, modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelwebhook.Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list webhooks scan: %w", err)
+		}
+		out = append(out, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list webhooks rows: %w", err)
+	}
+	return out, nil
+}
+
+// Update changes the URL and filter. A nil DisabledAt enables the webhook.
+func (r *DB) Update(ctx context.Context, w modelwebhook.Webhook) (modelwebhook.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	out, err := scanWebhook(r.sql.QueryRowContext(ctx, sqlTextForUpdate,
+		w.ID,
+		w.URL,
+		strings.Join(w.EventTypes, ","),
+		w.DisabledAt == nil,
+		modeltenant.Scope(ctx),
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelwebhook.Webhook{}, myerror.ErrorWebhookNotFound
+		}
+		return modelwebhook.Webhook{}, fmt.Errorf("update webhook: %w", err)
+	}
+	return out, nil
+}
+
+func (r *DB) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tag, err := r.sql.ExecContext(ctx, sqlTextForDelete, id, modeltenant.Scope(ctx))
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	rows, err := tag.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if rows == 0 {
+		return myerror.ErrorWebhookNotFound
+	}
+	return nil
+}
+
+// Enqueue creates a pending delivery of ev for every matching enabled
+// webhook of the tenant of ctx. Enqueuing the same event twice is a no-op.
+func (r *DB) Enqueue(ctx context.Context, ev modelevent.Event, payload []byte, userID *uuid.UUID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tag, err := r.sql.ExecContext(ctx, sqlTextForEnqueue, ev.ID, ev.Type, string(payload), userID, modeltenant.From(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("enqueue webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected()
+}
+
+func (r *DB) ListDeliveries(ctx context.Context, webhookID uuid.UUID, status *string, limit, offset int) ([]modelwebhook.Delivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForListDeliveries, webhookID, status, limit, offset, modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelwebhook.Delivery
+	for rows.Next() {
+		var d modelwebhook.Delivery
+		if err := rows.Scan(deliveryDest(&d)...); err != nil {
+			return nil, fmt.Errorf("list webhook deliveries scan: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list webhook deliveries rows: %w", err)
+	}
+	return out, nil
+}
+
+// GetDelivery returns a delivery of the webhook with all its attempts.
+func (r *DB) GetDelivery(ctx context.Context, webhookID uuid.UUID, id int64) (modelwebhook.Delivery, []modelwebhook.Attempt, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var d modelwebhook.Delivery
+	if err := r.sql.QueryRowContext(ctx, sqlTextForGetDelivery, id, webhookID, modeltenant.Scope(ctx)).Scan(deliveryDest(&d)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelwebhook.Delivery{}, nil, myerror.ErrorDeliveryNotFound
+		}
+		return modelwebhook.Delivery{}, nil, fmt.Errorf("get webhook delivery: %w", err)
+	}
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForListAttempts, id, modeltenant.Scope(ctx))
+	if err != nil {
+		return modelwebhook.Delivery{}, nil, fmt.Errorf("list delivery attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []modelwebhook.Attempt
+	for rows.Next() {
+		var a modelwebhook.Attempt
+		var ms int64
+		if err := rows.Scan(&a.ID, &a.DeliveryID, &a.StatusCode, &a.Error, &ms, &a.CreatedAt); err != nil {
+			return modelwebhook.Delivery{}, nil, fmt.Errorf("list delivery attempts scan: %w", err)
+		}
+		a.Duration = time.Duration(ms) * time.Millisecond
+		attempts = append(attempts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return modelwebhook.Delivery{}, nil, fmt.Errorf("list delivery attempts rows: %w", err)
+	}
+	return d, attempts, nil
+}
+
+func (r *DB) Redeliver(ctx context.Context, webhookID uuid.UUID, id int64) (modelwebhook.Delivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var d modelwebhook.Delivery
+	if err := r.sql.QueryRowContext(ctx, sqlTextForRedeliver, id, webhookID, modeltenant.Scope(ctx)).Scan(deliveryDest(&d)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return modelwebhook.Delivery{}, myerror.ErrorDeliveryNotFound
+		}
+		return modelwebhook.Delivery{}, fmt.Errorf("redeliver webhook delivery: %w", err)
+	}
+	return d, nil
+}
+
+// Claim leases up to limit due deliveries of enabled webhooks.
+func (r *DB) Claim(ctx context.Context, limit int, lease time.Duration) ([]modelwebhook.PendingDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.sql.QueryContext(ctx, sqlTextForClaim, limit, lease.Seconds(), modeltenant.Scope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var out []modelwebhook.PendingDelivery
+	for rows.Next() {
+		var p modelwebhook.PendingDelivery
+		dest := append(deliveryDest(&p.Delivery), &p.URL, &p.Secret)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("claim webhook deliveries scan: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries rows: %w", err)
+	}
+	return out, nil
+}
+
+// RecordAttempt stores the attempt, moves the delivery to status and updates
+// the failure streak of the webhook, disabling it once the streak reaches
+// disableAfter. It reports whether the webhook is disabled.
+func (r *DB) RecordAttempt(ctx context.Context, d modelwebhook.PendingDelivery, a modelwebhook.Attempt, status string, nextAttempt time.Time, disableAfter int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := tenancy.BeginTx(ctx, r.sql)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, sqlTextForInsertAttempt,
+		d.ID,
+		a.StatusCode,
+		a.Error,
+		a.Duration.Milliseconds(),
+		d.TenantID,
+	); err != nil {
+		return false, fmt.Errorf("insert delivery attempt: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, sqlTextForUpdateDelivery, d.ID, status, nextAttempt, a.StatusCode, a.Error, d.TenantID); err != nil {
+		return false, fmt.Errorf("update delivery: %w", err)
+	}
+
+	var disabled bool
+	if status == modelwebhook.DeliverySucceeded {
+		if _, err := tx.ExecContext(ctx, sqlTextForWebhookSucceeded, d.WebhookID, d.TenantID); err != nil {
+			return false, fmt.Errorf("reset webhook failures: %w", err)
+		}
+	} else {
+		if err := tx.QueryRowContext(ctx, sqlTextForWebhookFailed, d.WebhookID, disableAfter, d.TenantID).Scan(&disabled); err != nil {
+			return false, fmt.Errorf("count webhook failure: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit: %w", err)
+	}
+	return disabled, nil
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	modelevent "test_task/internal/domain/models/event"
+	modeltenant "test_task/internal/domain/models/tenant"
+	modelwebhook "test_task/internal/domain/models/webhook"
+	"test_task/internal/repository/postgres/tenancy"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestRepo_Enqueue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	userID := uuid.New()
+	ev := modelevent.Event{ID: "ev-1", Type: modelevent.TypeSubscriptionCreated}
+
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForEnqueue)).
+		WithArgs("ev-1", modelevent.TypeSubscriptionCreated, `{"id":"ev-1"}`, &userID, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	n, err := repo.Enqueue(modeltenant.WithTenant(context.Background(), "acme"), ev, []byte(`{"id":"ev-1"}`), &userID)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("want 2 deliveries, got %d", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRepo_RecordAttempt_FailureDisables(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	webhookID := uuid.New()
+	next := time.Now().Add(time.Minute)
+	code := 500
+	msg := "receiver answered 500 Internal Server Error"
+
+	d := modelwebhook.PendingDelivery{Delivery: modelwebhook.Delivery{ID: 9, TenantID: "acme", WebhookID: webhookID}}
+	a := modelwebhook.Attempt{StatusCode: &code, Error: &msg, Duration: 120 * time.Millisecond}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(tenancy.SQLTextForSetTenant)).
+		WithArgs("acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForInsertAttempt)).
+		WithArgs(int64(9), &code, &msg, int64(120), "acme").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlTextForUpdateDelivery)).
+		WithArgs(int64(9), modelwebhook.DeliveryPending, next, &code, &msg, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlTextForWebhookFailed)).
+		WithArgs(webhookID, 20, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"disabled"}).AddRow(true))
+	mock.ExpectCommit()
+
+	disabled, err := repo.RecordAttempt(modeltenant.WithTenant(context.Background(), "acme"), d, a, modelwebhook.DeliveryPending, next, 20)
+	if err != nil {
+		t.Fatalf("RecordAttempt: %v", err)
+	}
+	if !disabled {
+		t.Fatalf("webhook must be reported as disabled")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
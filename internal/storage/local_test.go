@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	myerror "test_task/pkg/global_errors"
+)
+
+func TestLocal_PutOpenDelete(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocal(t.TempDir())
+
+	if err := s.Put(ctx, "services/1/logo", []byte("first")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s.Put(ctx, "services/1/logo", []byte("second")); err != nil {
+		t.Fatalf("Put overwrite: %v", err)
+	}
+
+	f, err := s.Open(ctx, "services/1/logo")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil || string(data) != "second" {
+		t.Fatalf("want second, got %q (%v)", data, err)
+	}
+
+	if err := s.Delete(ctx, "services/1/logo"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete(ctx, "services/1/logo"); err != nil {
+		t.Fatalf("Delete missing: %v", err)
+	}
+	if _, err := s.Open(ctx, "services/1/logo"); !errors.Is(err, myerror.ErrorNotFound) {
+		t.Fatalf("want ErrorNotFound, got %v", err)
+	}
+}
+
+func TestLocal_RejectsEscapingKeys(t *testing.T) {
+	s := NewLocal(t.TempDir())
+	for _, key := range []string{"../logo", "/etc/passwd", ""} {
+		if err := s.Put(context.Background(), key, []byte("x")); err == nil {
+			t.Fatalf("key %q must be rejected", key)
+		}
+	}
+}
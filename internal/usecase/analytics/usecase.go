@@ -0,0 +1,109 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	modelanalytics "test_task/internal/domain/models/analytics"
+	myerrors "test_task/pkg/global_errors"
+)
+
+// MaxMonths bounds the period an analytics query covers.
+const MaxMonths = 120
+
+// defaultMonths is the length of the period ending in the current month used
+// when no start is given.
+const defaultMonths = 12
+
+type RepoI interface {
+	MRR(ctx context.Context, from, to time.Time) ([]modelanalytics.MRRMonth, error)
+	Churn(ctx context.Context, from, to time.Time) ([]modelanalytics.ServiceChurn, error)
+	Cohorts(ctx context.Context, from, to time.Time) ([]modelanalytics.CohortMonth, error)
+}
+
+type Usecase struct {
+	repo RepoI
+	now  func() time.Time
+}
+
+func New(repo RepoI) *Usecase {
+	return &Usecase{repo: repo, now: time.Now}
+}
+
+// period fills in a zero to with the current month and a zero from with the
+// month defaultMonths-1 before to, and checks the bounds.
+func (u *Usecase) period(from, to time.Time) (time.Time, time.Time, error) {
+	if to.IsZero() {
+		now := u.now().UTC()
+		to = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, -(defaultMonths - 1), 0)
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be >= from", myerrors.ErrorValidation)
+	}
+	if monthsBetween(from, to) >= MaxMonths {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: the period must not exceed %d months", myerrors.ErrorValidation, MaxMonths)
+	}
+	return from, to, nil
+}
+
+// MRR returns the recurring revenue of every month from from to to and how it
+// moved since the month before.
+func (u *Usecase) MRR(ctx context.Context, from, to time.Time) ([]modelanalytics.MRRMonth, error) {
+	from, to, err := u.period(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return u.repo.MRR(ctx, from, to)
+}
+
+// Churn returns, per service, the subscriptions billed between from and to
+// and how many of them ended before to.
+func (u *Usecase) Churn(ctx context.Context, from, to time.Time) ([]modelanalytics.ServiceChurn, error) {
+	from, to, err := u.period(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return u.repo.Churn(ctx, from, to)
+}
+
+// Cohorts returns a cohort for every month from from to to in which
+// subscriptions started, with their retention up to to.
+func (u *Usecase) Cohorts(ctx context.Context, from, to time.Time) ([]modelanalytics.Cohort, error) {
+	from, to, err := u.period(from, to)
+	if err != nil {
+		return nil, err
+	}
+	cells, err := u.repo.Cohorts(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return buildCohorts(cells, to), nil
+}
+
+// buildCohorts groups the cells, ordered by cohort, into cohorts retained up
+// to to; months without a cell have no active subscription left.
+func buildCohorts(cells []modelanalytics.CohortMonth, to time.Time) []modelanalytics.Cohort {
+	var out []modelanalytics.Cohort
+	for _, c := range cells {
+		if len(out) == 0 || !out[len(out)-1].Month.Equal(c.Cohort) {
+			out = append(out, modelanalytics.Cohort{
+				Month:    c.Cohort,
+				Retained: make([]int64, monthsBetween(c.Cohort, to)+1),
+			})
+		}
+		cohort := &out[len(out)-1]
+		if k := monthsBetween(c.Cohort, c.Month); k >= 0 && k < len(cohort.Retained) {
+			cohort.Retained[k] = c.Active
+		}
+	}
+	return out
+}
+
+// monthsBetween is the number of months from from to to.
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
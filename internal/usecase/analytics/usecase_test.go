@@ -0,0 +1,290 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	modelanalytics "test_task/internal/domain/models/analytics"
+	modelsub "test_task/internal/domain/models/subscription"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+func month(y int, m time.Month) time.Time {
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// The functions below compute the analytics in memory with the definitions
+// the SQL in the analytics repository implements, so the math can be checked
+// without a database.
+
+// lastBilled is the last month s is billed in: its end date or the month it
+// was deleted in, nil while open.
+func lastBilled(s modelsub.Subscription) *time.Time {
+	last := s.EndDate
+	if s.DeletedAt != nil {
+		d := s.DeletedAt.UTC()
+		deleted := month(d.Year(), d.Month())
+		if last == nil || deleted.Before(*last) {
+			last = &deleted
+		}
+	}
+	return last
+}
+
+func active(s modelsub.Subscription, m time.Time) bool {
+	last := lastBilled(s)
+	return !m.Before(s.StartDate) && (last == nil || !m.After(*last))
+}
+
+func charge(s modelsub.Subscription, pauses []modelsub.Pause, m time.Time) (int64, bool) {
+	if !active(s, m) {
+		return 0, false
+	}
+	for _, p := range pauses {
+		if p.SubscriptionID == s.ID && !m.Before(p.From) && (p.To == nil || !m.After(*p.To)) {
+			return 0, false
+		}
+	}
+	return int64(s.PriceFor(m)), true
+}
+
+func userMRR(subs []modelsub.Subscription, pauses []modelsub.Pause, m time.Time) map[uuid.UUID]int64 {
+	out := map[uuid.UUID]int64{}
+	for _, s := range subs {
+		if amount, ok := charge(s, pauses, m); ok {
+			out[s.UserID] += amount
+		}
+	}
+	return out
+}
+
+func memMRR(subs []modelsub.Subscription, pauses []modelsub.Pause, from, to time.Time) []modelanalytics.MRRMonth {
+	var out []modelanalytics.MRRMonth
+	for m := from; !m.After(to); m = m.AddDate(0, 1, 0) {
+		cur, prev := userMRR(subs, pauses, m), userMRR(subs, pauses, m.AddDate(0, -1, 0))
+		row := modelanalytics.MRRMonth{Month: m}
+		for user, c := range cur {
+			row.MRR += c
+			p := prev[user]
+			switch {
+			case p == 0:
+				row.New += c
+			case c > p:
+				row.Expansion += c - p
+			case c > 0 && c < p:
+				row.Contraction += p - c
+			}
+		}
+		for user, p := range prev {
+			if cur[user] == 0 {
+				row.Churned += p
+			}
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+func memChurn(subs []modelsub.Subscription, from, to time.Time) []modelanalytics.ServiceChurn {
+	byService := map[string]*modelanalytics.ServiceChurn{}
+	for _, s := range subs {
+		first := s.StartDate
+		if first.Before(from) {
+			first = from
+		}
+		last := lastBilled(s)
+		if s.StartDate.After(to) || (last != nil && last.Before(first)) {
+			continue
+		}
+		c, ok := byService[s.ServiceName]
+		if !ok {
+			c = &modelanalytics.ServiceChurn{ServiceName: s.ServiceName}
+			byService[s.ServiceName] = c
+		}
+		c.Subscribers++
+		if last != nil && last.Before(to) {
+			c.Churned++
+		}
+	}
+	out := make([]modelanalytics.ServiceChurn, 0, len(byService))
+	for _, c := range byService {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ServiceName < out[j].ServiceName })
+	return out
+}
+
+// memCohortCells returns what the cohorts query does: only the months with
+// active subscriptions.
+func memCohortCells(subs []modelsub.Subscription, from, to time.Time) []modelanalytics.CohortMonth {
+	var out []modelanalytics.CohortMonth
+	for c := from; !c.After(to); c = c.AddDate(0, 1, 0) {
+		for m := c; !m.After(to); m = m.AddDate(0, 1, 0) {
+			var n int64
+			for _, s := range subs {
+				if s.StartDate.Equal(c) && active(s, m) {
+					n++
+				}
+			}
+			if n > 0 {
+				out = append(out, modelanalytics.CohortMonth{Cohort: c, Month: m, Active: n})
+			}
+		}
+	}
+	return out
+}
+
+type fakeRepo struct {
+	subs     []modelsub.Subscription
+	pauses   []modelsub.Pause
+	from, to time.Time
+}
+
+func (r *fakeRepo) MRR(_ context.Context, from, to time.Time) ([]modelanalytics.MRRMonth, error) {
+	r.from, r.to = from, to
+	return memMRR(r.subs, r.pauses, from, to), nil
+}
+
+func (r *fakeRepo) Churn(_ context.Context, from, to time.Time) ([]modelanalytics.ServiceChurn, error) {
+	r.from, r.to = from, to
+	return memChurn(r.subs, from, to), nil
+}
+
+func (r *fakeRepo) Cohorts(_ context.Context, from, to time.Time) ([]modelanalytics.CohortMonth, error) {
+	r.from, r.to = from, to
+	return memCohortCells(r.subs, from, to), nil
+}
+
+// fixture is three users over January to April 2025:
+//
+//	A: Netflix 500 from January, Spotify 200 in March only
+//	B: Netflix 500 in January and February, Spotify 250 from March, deleted in March
+//	C: Spotify 300 from February with a 100 trial month, paused from April
+func fixture() *fakeRepo {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	feb, mar := month(2025, time.February), month(2025, time.March)
+	trialPrice := 100
+	deleted := time.Date(2025, time.March, 15, 12, 0, 0, 0, time.UTC)
+	subs := []modelsub.Subscription{
+		{ID: uuid.New(), UserID: a, ServiceName: "Netflix", Price: 500, StartDate: month(2025, time.January)},
+		{ID: uuid.New(), UserID: a, ServiceName: "Spotify", Price: 200, StartDate: mar, EndDate: &mar},
+		{ID: uuid.New(), UserID: b, ServiceName: "Netflix", Price: 500, StartDate: month(2025, time.January), EndDate: &feb},
+		{ID: uuid.New(), UserID: c, ServiceName: "Spotify", Price: 300, StartDate: feb, TrialUntil: &feb, TrialPrice: &trialPrice},
+		{ID: uuid.New(), UserID: b, ServiceName: "Spotify", Price: 250, StartDate: mar, DeletedAt: &deleted},
+	}
+	pauses := []modelsub.Pause{{SubscriptionID: subs[3].ID, From: month(2025, time.April)}}
+	return &fakeRepo{subs: subs, pauses: pauses}
+}
+
+func TestMRR_Movements(t *testing.T) {
+	repo := fixture()
+	u := New(repo)
+
+	got, err := u.MRR(context.Background(), month(2025, time.February), month(2025, time.April))
+	if err != nil {
+		t.Fatalf("MRR error: %v", err)
+	}
+	want := []modelanalytics.MRRMonth{
+		{Month: month(2025, time.February), MRR: 1100, New: 100},
+		{Month: month(2025, time.March), MRR: 1250, Expansion: 400, Contraction: 250},
+		{Month: month(2025, time.April), MRR: 500, Contraction: 200, Churned: 550},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+
+	// Every month moves the MRR of the month before by its net change.
+	prev := memMRR(repo.subs, repo.pauses, month(2025, time.January), month(2025, time.January))[0].MRR
+	for _, m := range got {
+		if m.MRR-prev != m.NetChange() {
+			t.Fatalf("%s: mrr moved by %d, net change is %d", m.Month, m.MRR-prev, m.NetChange())
+		}
+		prev = m.MRR
+	}
+}
+
+func TestChurn_PerService(t *testing.T) {
+	u := New(fixture())
+
+	got, err := u.Churn(context.Background(), month(2025, time.February), month(2025, time.April))
+	if err != nil {
+		t.Fatalf("Churn error: %v", err)
+	}
+	// The paused Spotify subscription has not churned, the deleted one has.
+	want := []modelanalytics.ServiceChurn{
+		{ServiceName: "Netflix", Subscribers: 2, Churned: 1},
+		{ServiceName: "Spotify", Subscribers: 3, Churned: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestCohorts_FillsMonthsWithoutCells(t *testing.T) {
+	repo := fixture()
+	u := New(repo)
+	from, to := month(2025, time.February), month(2025, time.April)
+
+	got, err := u.Cohorts(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("Cohorts error: %v", err)
+	}
+	want := []modelanalytics.Cohort{
+		{Month: month(2025, time.February), Retained: []int64{1, 1, 1}},
+		{Month: month(2025, time.March), Retained: []int64{2, 0}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+
+	// Every retained count matches the subscriptions counted one by one.
+	for _, c := range got {
+		for k, n := range c.Retained {
+			m := c.Month.AddDate(0, k, 0)
+			var active int64
+			for _, s := range repo.subs {
+				if s.StartDate.Equal(c.Month) && (lastBilled(s) == nil || !m.After(*lastBilled(s))) {
+					active++
+				}
+			}
+			if n != active {
+				t.Fatalf("cohort %s month %d: want %d, got %d", c.Month, k, active, n)
+			}
+		}
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	repo := &fakeRepo{}
+	u := New(repo)
+	u.now = func() time.Time { return time.Date(2025, time.June, 18, 10, 0, 0, 0, time.UTC) }
+
+	if _, err := u.MRR(context.Background(), time.Time{}, time.Time{}); err != nil {
+		t.Fatalf("MRR error: %v", err)
+	}
+	if !repo.from.Equal(month(2024, time.July)) || !repo.to.Equal(month(2025, time.June)) {
+		t.Fatalf("default period: got %v - %v", repo.from, repo.to)
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+	}{
+		{"to before from", month(2025, time.March), month(2025, time.February)},
+		{"too long", month(2015, time.January), month(2025, time.January)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := u.Churn(context.Background(), tt.from, tt.to)
+			if !errors.Is(err, myerrors.ErrorValidation) {
+				t.Fatalf("want validation error, got %v", err)
+			}
+		})
+	}
+}
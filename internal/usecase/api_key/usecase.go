@@ -0,0 +1,144 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	modelkey "test_task/internal/domain/models/api_key"
+	modelauth "test_task/internal/domain/models/auth"
+	authmid "test_task/internal/middleware/auth_middleware"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Scheme is the Authorization scheme handled by the usecase.
+	Scheme = "ApiKey"
+
+	keyPrefix    = "sk_"
+	prefixLength = len(keyPrefix) + 8
+)
+
+type RepoI interface {
+	Create(ctx context.Context, k modelkey.APIKey) (modelkey.APIKey, error)
+	List(ctx context.Context) ([]modelkey.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (modelkey.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID) error
+}
+
+type Usecase struct {
+	repo          RepoI
+	bootstrapHash string
+	now           func() time.Time
+}
+
+// New creates the API key usecase. bootstrapKey, when not empty, is accepted
+// as an admin key so that the first real keys can be created.
+func New(repo RepoI, bootstrapKey string) *Usecase {
+	u := &Usecase{
+		repo: repo,
+		now:  time.Now,
+	}
+	if bootstrapKey != "" {
+		u.bootstrapHash = HashKey(bootstrapKey)
+	}
+	return u
+}
+
+// HashKey returns the stored representation of a plaintext key. Keys carry
+// 256 bits of randomness, so a fast hash is enough.
+func HashKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate api key: %w", err)
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Create stores a new key and returns it with its plaintext value, which is
+// not kept anywhere.
+func (u *Usecase) Create(ctx context.Context, k modelkey.APIKey) (modelkey.APIKey, string, error) {
+	for _, s := range k.Scopes {
+		if !slices.Contains(modelauth.KnownScopes, s) {
+			return modelkey.APIKey{}, "", fmt.Errorf("%w: unknown scope %q", myerrors.ErrorValidation, s)
+		}
+	}
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(u.now()) {
+		return modelkey.APIKey{}, "", fmt.Errorf("%w: expires_at must be in the future", myerrors.ErrorValidation)
+	}
+
+	plaintext, err := generateKey()
+	if err != nil {
+		return modelkey.APIKey{}, "", err
+	}
+	k.Prefix = plaintext[:prefixLength]
+	k.KeyHash = HashKey(plaintext)
+
+	created, err := u.repo.Create(ctx, k)
+	if err != nil {
+		return modelkey.APIKey{}, "", err
+	}
+	return created, plaintext, nil
+}
+
+func (u *Usecase) List(ctx context.Context) ([]modelkey.APIKey, error) {
+	return u.repo.List(ctx)
+}
+
+func (u *Usecase) Revoke(ctx context.Context, id uuid.UUID) error {
+	return u.repo.Revoke(ctx, id)
+}
+
+// Authenticate implements authmid.Authenticator for the ApiKey scheme.
+func (u *Usecase) Authenticate(ctx context.Context, credentials string) (modelauth.Principal, error) {
+	if credentials == "" {
+		return modelauth.Principal{}, authmid.ErrInvalidCredentials
+	}
+	hash := HashKey(credentials)
+
+	if u.bootstrapHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(u.bootstrapHash)) == 1 {
+		return modelauth.Principal{
+			Subject: "apikey:bootstrap",
+			Method:  Scheme,
+			Scopes:  []string{modelauth.ScopeAdmin},
+		}, nil
+	}
+
+	k, err := u.repo.GetByHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, myerrors.ErrorNotFound) {
+			return modelauth.Principal{}, authmid.ErrInvalidCredentials
+		}
+		return modelauth.Principal{}, err
+	}
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(u.now()) {
+		return modelauth.Principal{}, authmid.ErrInvalidCredentials
+	}
+
+	if err := u.repo.TouchLastUsed(ctx, k.ID); err != nil {
+		return modelauth.Principal{}, err
+	}
+
+	return modelauth.Principal{
+		Subject:  "apikey:" + k.ID.String(),
+		Method:   Scheme,
+		UserID:   k.UserID,
+		TenantID: k.TenantID,
+		Scopes:   k.Scopes,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	modelkey "test_task/internal/domain/models/api_key"
+	modelauth "test_task/internal/domain/models/auth"
+	authmid "test_task/internal/middleware/auth_middleware"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+// keyRepo keeps keys by hash and, like the SQL, does not find revoked ones.
+type keyRepo struct {
+	RepoI
+	keys    map[string]modelkey.APIKey
+	touched []uuid.UUID
+	err     error
+}
+
+func (r *keyRepo) GetByHash(_ context.Context, hash string) (modelkey.APIKey, error) {
+	if r.err != nil {
+		return modelkey.APIKey{}, r.err
+	}
+	k, ok := r.keys[hash]
+	if !ok || k.RevokedAt != nil {
+		return modelkey.APIKey{}, myerrors.ErrorNotFound
+	}
+	return k, nil
+}
+
+func (r *keyRepo) TouchLastUsed(_ context.Context, id uuid.UUID) error {
+	r.touched = append(r.touched, id)
+	return nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	now := time.Date(2025, time.June, 18, 10, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	user := uuid.New()
+	valid := modelkey.APIKey{ID: uuid.New(), TenantID: "acme", UserID: &user, ExpiresAt: &future,
+		Scopes: []string{modelauth.ScopeSubscriptionsRead}}
+	expired := modelkey.APIKey{ID: uuid.New(), ExpiresAt: &past}
+	revoked := modelkey.APIKey{ID: uuid.New(), RevokedAt: &past}
+
+	repo := &keyRepo{keys: map[string]modelkey.APIKey{
+		HashKey("sk_valid"):   valid,
+		HashKey("sk_expired"): expired,
+		HashKey("sk_revoked"): revoked,
+	}}
+	u := New(repo, "sk_bootstrap")
+	u.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, key := range []string{"", "sk_unknown", "sk_expired", "sk_revoked"} {
+		if _, err := u.Authenticate(ctx, key); !errors.Is(err, authmid.ErrInvalidCredentials) {
+			t.Fatalf("%q: want ErrInvalidCredentials, got %v", key, err)
+		}
+	}
+	if len(repo.touched) != 0 {
+		t.Fatalf("refused keys must not be touched, got %v", repo.touched)
+	}
+
+	p, err := u.Authenticate(ctx, "sk_valid")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if p.Subject != "apikey:"+valid.ID.String() || p.Method != Scheme || p.TenantID != "acme" ||
+		p.UserID == nil || *p.UserID != user || !p.HasScope(modelauth.ScopeSubscriptionsRead) || p.HasScope(modelauth.ScopeAdmin) {
+		t.Fatalf("unexpected principal %+v", p)
+	}
+	if len(repo.touched) != 1 || repo.touched[0] != valid.ID {
+		t.Fatalf("want the key touched once, got %v", repo.touched)
+	}
+
+	p, err = u.Authenticate(ctx, "sk_bootstrap")
+	if err != nil {
+		t.Fatalf("Authenticate bootstrap: %v", err)
+	}
+	if p.Subject != "apikey:bootstrap" || p.TenantID != "" || !p.HasScope(modelauth.ScopeAdmin) {
+		t.Fatalf("unexpected bootstrap principal %+v", p)
+	}
+	if len(repo.touched) != 1 {
+		t.Fatalf("the bootstrap key is not stored and must not be touched")
+	}
+}
+
+func TestAuthenticate_NoBootstrapKey(t *testing.T) {
+	u := New(&keyRepo{keys: map[string]modelkey.APIKey{}}, "")
+	// An empty bootstrap key must not make empty or unknown keys admins.
+	for _, key := range []string{"", "sk_bootstrap"} {
+		if _, err := u.Authenticate(context.Background(), key); !errors.Is(err, authmid.ErrInvalidCredentials) {
+			t.Fatalf("%q: want ErrInvalidCredentials, got %v", key, err)
+		}
+	}
+}
+
+func TestAuthenticate_RepoError(t *testing.T) {
+	boom := errors.New("connection refused")
+	u := New(&keyRepo{err: boom}, "")
+	if _, err := u.Authenticate(context.Background(), "sk_valid"); !errors.Is(err, boom) {
+		t.Fatalf("lookup errors must not read as invalid credentials, got %v", err)
+	}
+}
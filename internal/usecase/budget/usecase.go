@@ -0,0 +1,327 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	modelbudget "test_task/internal/domain/models/budget"
+	modelevent "test_task/internal/domain/models/event"
+	monthyear "test_task/internal/domain/models/month_year"
+	modelsub "test_task/internal/domain/models/subscription"
+	modeltag "test_task/internal/domain/models/tag"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+type RepoI interface {
+	Create(ctx context.Context, b modelbudget.Budget) (modelbudget.Budget, error)
+	Get(ctx context.Context, id uuid.UUID) (modelbudget.Budget, error)
+	List(ctx context.Context, userID *uuid.UUID) ([]modelbudget.Budget, error)
+	Update(ctx context.Context, b modelbudget.Budget) (modelbudget.Budget, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	WriteAlert(ctx context.Context, budgetID uuid.UUID, ev modelevent.Event) error
+}
+
+// checkHorizon is the least number of months Check evaluates from the first
+// month a subscription is billed in.
+const checkHorizon = 12
+
+// SummaryI sums the charges of the subscriptions matching a filter, with the
+// same month overlap rules as the summary endpoint. SummaryByMonth counts
+// deleted subscriptions up to month started only.
+type SummaryI interface {
+	Summary(ctx context.Context, f modelsub.SummaryFilter) (int64, error)
+	SummaryByMonth(ctx context.Context, f modelsub.SummaryFilter, started time.Time) ([]modelsub.MonthTotal, error)
+	ListPauses(ctx context.Context, id uuid.UUID) ([]modelsub.Pause, error)
+	BilledYearly(ctx context.Context, serviceID uuid.UUID) (bool, error)
+}
+
+// TagsI looks up the tags budgets are scoped to.
+type TagsI interface {
+	Get(ctx context.Context, id uuid.UUID) (modeltag.Tag, error)
+}
+
+type Usecase struct {
+	log     *slog.Logger
+	repo    RepoI
+	summary SummaryI
+	tags    TagsI
+	now     func() time.Time
+}
+
+func New(log *slog.Logger, repo RepoI, summary SummaryI, tags TagsI) *Usecase {
+	return &Usecase{
+		log:     log,
+		repo:    repo,
+		summary: summary,
+		tags:    tags,
+		now:     time.Now,
+	}
+}
+
+// normalize checks the limits of b and that its tag belongs to its user.
+func (u *Usecase) normalize(ctx context.Context, b *modelbudget.Budget) error {
+	if b.Amount < 0 {
+		return fmt.Errorf("%w: amount must be >= 0", myerrors.ErrorValidation)
+	}
+	if b.ServiceName != nil {
+		name := strings.TrimSpace(*b.ServiceName)
+		b.ServiceName = nil
+		if name != "" {
+			b.ServiceName = &name
+		}
+	}
+	if b.ServiceName != nil && b.TagID != nil {
+		return fmt.Errorf("%w: a budget is either per service or per tag", myerrors.ErrorValidation)
+	}
+	if b.TagID != nil {
+		t, err := u.tags.Get(ctx, *b.TagID)
+		if err != nil && !errors.Is(err, myerrors.ErrorNotFound) {
+			return err
+		}
+		if err != nil || t.UserID != b.UserID {
+			return fmt.Errorf("%w: unknown tag_id %s", myerrors.ErrorValidation, b.TagID)
+		}
+	}
+	return nil
+}
+
+func (u *Usecase) Create(ctx context.Context, b modelbudget.Budget) (modelbudget.Budget, error) {
+	if err := u.normalize(ctx, &b); err != nil {
+		return modelbudget.Budget{}, err
+	}
+	return u.repo.Create(ctx, b)
+}
+
+func (u *Usecase) Get(ctx context.Context, id uuid.UUID) (modelbudget.Budget, error) {
+	return u.repo.Get(ctx, id)
+}
+
+func (u *Usecase) List(ctx context.Context, userID *uuid.UUID) ([]modelbudget.Budget, error) {
+	return u.repo.List(ctx, userID)
+}
+
+// Update replaces the amount, scope and enforce flag of the budget b.ID.
+func (u *Usecase) Update(ctx context.Context, b modelbudget.Budget) (modelbudget.Budget, error) {
+	current, err := u.repo.Get(ctx, b.ID)
+	if err != nil {
+		return modelbudget.Budget{}, err
+	}
+	b.UserID = current.UserID
+	if err := u.normalize(ctx, &b); err != nil {
+		return modelbudget.Budget{}, err
+	}
+	return u.repo.Update(ctx, b)
+}
+
+func (u *Usecase) Delete(ctx context.Context, id uuid.UUID) error {
+	return u.repo.Delete(ctx, id)
+}
+
+// Status reports the spend against the budget in month, the current month
+// when zero.
+func (u *Usecase) Status(ctx context.Context, id uuid.UUID, month time.Time) (modelbudget.Status, error) {
+	b, err := u.repo.Get(ctx, id)
+	if err != nil {
+		return modelbudget.Status{}, err
+	}
+	if month.IsZero() {
+		month = u.currentMonth()
+	}
+	spent, projected, err := u.spend(ctx, b, month)
+	if err != nil {
+		return modelbudget.Status{}, err
+	}
+	return modelbudget.Status{Budget: b, Month: month, Spent: spent, Projected: projected}, nil
+}
+
+func (u *Usecase) currentMonth() time.Time {
+	now := u.now().UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// spend sums the charges in month of the subscriptions the budget covers.
+// Subscriptions are charged on the first day of the month, so a month that
+// has started is spent in full, soft-deleted subscriptions included as in
+// the summary. Later months are only projected and leave deleted ones out.
+func (u *Usecase) spend(ctx context.Context, b modelbudget.Budget, month time.Time) (spent, projected int64, err error) {
+	f := filter(b, month, month)
+	started := !month.After(u.currentMonth())
+	f.ExcludeDeleted = !started
+
+	total, err := u.summary.Summary(ctx, f)
+	if err != nil {
+		return 0, 0, err
+	}
+	if started {
+		return total, total, nil
+	}
+	return 0, total, nil
+}
+
+// filter selects the subscriptions the budget counts from month from to to.
+func filter(b modelbudget.Budget, from, to time.Time) modelsub.SummaryFilter {
+	f := modelsub.SummaryFilter{
+		From:        from,
+		To:          to,
+		UserID:      &b.UserID,
+		ServiceName: b.ServiceName,
+	}
+	if b.Tag != nil {
+		f.Tags = []string{*b.Tag}
+	}
+	return f
+}
+
+// Check returns the budgets of the user that changing a subscription from
+// before (nil for a new one) to after pushes over their amount, with the
+// first month each one is crossed in. It evaluates the months after is
+// billed in from the current month on, see checkMonths, with one query per
+// budget. It must run in the guard of the write, so that the writes of the
+// user are serialized until the change is committed.
+func (u *Usecase) Check(ctx context.Context, before *modelsub.Subscription, after modelsub.Subscription) ([]modelbudget.Overspend, error) {
+	months := u.checkMonths(after)
+	if len(months) == 0 {
+		return nil, nil
+	}
+	budgets, err := u.repo.List(ctx, &after.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// Pauses belong to the subscription and are kept by the change.
+	var pauses []modelsub.Pause
+	if before != nil {
+		if pauses, err = u.summary.ListPauses(ctx, before.ID); err != nil {
+			return nil, err
+		}
+	}
+	beforeYearly, err := u.billedYearly(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	afterYearly, err := u.billedYearly(ctx, &after)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []modelbudget.Overspend
+	for _, b := range budgets {
+		if !covers(b, &after) && !covers(b, before) {
+			continue
+		}
+		totals, err := u.summary.SummaryByMonth(ctx, filter(b, months[0], months[len(months)-1]), u.currentMonth())
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range totals {
+			month := t.Month.UTC()
+			next := t.Total - charge(b, before, beforeYearly, pauses, month) + charge(b, &after, afterYearly, pauses, month)
+			if t.Total <= int64(b.Amount) && next > int64(b.Amount) {
+				out = append(out, modelbudget.Overspend{Budget: b, Month: month, Projected: next})
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// Alert queues a budget.exceeded.v1 event for every overspend found by Check
+// once the change to s is written. Failures are logged: the change itself
+// has already been made.
+func (u *Usecase) Alert(ctx context.Context, s modelsub.Subscription, over []modelbudget.Overspend) {
+	for _, o := range over {
+		ev, err := modelevent.New(modelevent.TypeBudgetExceeded, o.Budget.ID.String(), modelbudget.Alert{
+			BudgetID:       o.Budget.ID,
+			UserID:         o.Budget.UserID,
+			SubscriptionID: s.ID,
+			Month:          monthyear.FormatMonthYear(o.Month),
+			Amount:         o.Budget.Amount,
+			Projected:      o.Projected,
+			ServiceName:    o.Budget.ServiceName,
+			TagID:          o.Budget.TagID,
+		})
+		if err == nil {
+			err = u.repo.WriteAlert(ctx, o.Budget.ID, ev)
+		}
+		if err != nil {
+			u.log.Error("budget alert failed",
+				slog.String("budget_id", o.Budget.ID.String()),
+				slog.String("subscription_id", s.ID.String()),
+				slog.Any("err", err))
+		}
+	}
+}
+
+// checkMonths returns the months s is billed in from the current month on:
+// checkHorizon months from the first one, and at least through the first
+// month at the full price after its trial, up to its end date.
+func (u *Usecase) checkMonths(s modelsub.Subscription) []time.Time {
+	first := u.currentMonth()
+	if s.StartDate.After(first) {
+		first = s.StartDate
+	}
+	last := first.AddDate(0, checkHorizon-1, 0)
+	if s.TrialUntil != nil && !s.TrialUntil.AddDate(0, 1, 0).Before(last) {
+		last = s.TrialUntil.AddDate(0, 1, 0)
+	}
+	if s.EndDate != nil && s.EndDate.Before(last) {
+		last = *s.EndDate
+	}
+
+	var months []time.Time
+	for m := first; !m.After(last); m = m.AddDate(0, 1, 0) {
+		months = append(months, m)
+	}
+	return months
+}
+
+// covers reports whether the budget counts the subscription.
+func covers(b modelbudget.Budget, s *modelsub.Subscription) bool {
+	if s == nil {
+		return false
+	}
+	if b.ServiceName != nil && *b.ServiceName != s.ServiceName {
+		return false
+	}
+	if b.Tag != nil {
+		for _, t := range s.Tags {
+			if strings.EqualFold(t, *b.Tag) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// billedYearly reports whether s is billed yearly by its catalog service,
+// which the summary charges once a year.
+func (u *Usecase) billedYearly(ctx context.Context, s *modelsub.Subscription) (bool, error) {
+	if s == nil || s.ServiceID == nil {
+		return false, nil
+	}
+	return u.summary.BilledYearly(ctx, *s.ServiceID)
+}
+
+// charge is what s adds to the budget in month, as the summary charges it;
+// the months covered by one of its pauses, an open one included, are not
+// billed.
+func charge(b modelbudget.Budget, s *modelsub.Subscription, yearly bool, pauses []modelsub.Pause, month time.Time) int64 {
+	if !covers(b, s) {
+		return 0
+	}
+	for _, p := range pauses {
+		if p.Covers(month) {
+			return 0
+		}
+	}
+	if month.Before(s.StartDate) || (s.EndDate != nil && month.After(*s.EndDate)) {
+		return 0
+	}
+	return int64(s.ChargeFor(month, yearly))
+}
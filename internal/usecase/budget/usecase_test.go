@@ -0,0 +1,262 @@
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	modelbudget "test_task/internal/domain/models/budget"
+	modelevent "test_task/internal/domain/models/event"
+	modelsub "test_task/internal/domain/models/subscription"
+	modeltag "test_task/internal/domain/models/tag"
+	logmid "test_task/internal/middleware/loger_middleware"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+type fakeRepo struct {
+	RepoI
+	budgets []modelbudget.Budget
+	alerts  []modelevent.Event
+}
+
+func (r *fakeRepo) Get(_ context.Context, id uuid.UUID) (modelbudget.Budget, error) {
+	for _, b := range r.budgets {
+		if b.ID == id {
+			return b, nil
+		}
+	}
+	return modelbudget.Budget{}, myerrors.ErrorNotFound
+}
+
+func (r *fakeRepo) List(context.Context, *uuid.UUID) ([]modelbudget.Budget, error) {
+	return r.budgets, nil
+}
+
+func (r *fakeRepo) Create(_ context.Context, b modelbudget.Budget) (modelbudget.Budget, error) {
+	return b, nil
+}
+
+func (r *fakeRepo) WriteAlert(_ context.Context, _ uuid.UUID, ev modelevent.Event) error {
+	r.alerts = append(r.alerts, ev)
+	return nil
+}
+
+// fakeSummary returns a fixed total per month and records the filters it
+// was asked for.
+type fakeSummary struct {
+	total   int64
+	pauses  []modelsub.Pause
+	filters []modelsub.SummaryFilter
+	yearly  map[uuid.UUID]bool
+}
+
+func (s *fakeSummary) Summary(_ context.Context, f modelsub.SummaryFilter) (int64, error) {
+	s.filters = append(s.filters, f)
+	return s.total, nil
+}
+
+func (s *fakeSummary) SummaryByMonth(_ context.Context, f modelsub.SummaryFilter, _ time.Time) ([]modelsub.MonthTotal, error) {
+	s.filters = append(s.filters, f)
+	var out []modelsub.MonthTotal
+	for m := f.From; !m.After(f.To); m = m.AddDate(0, 1, 0) {
+		out = append(out, modelsub.MonthTotal{Month: m, Total: s.total})
+	}
+	return out, nil
+}
+
+func (s *fakeSummary) ListPauses(context.Context, uuid.UUID) ([]modelsub.Pause, error) {
+	return s.pauses, nil
+}
+
+func (s *fakeSummary) BilledYearly(_ context.Context, serviceID uuid.UUID) (bool, error) {
+	return s.yearly[serviceID], nil
+}
+
+type fakeTags map[uuid.UUID]modeltag.Tag
+
+func (t fakeTags) Get(_ context.Context, id uuid.UUID) (modeltag.Tag, error) {
+	tag, ok := t[id]
+	if !ok {
+		return modeltag.Tag{}, myerrors.ErrorNotFound
+	}
+	return tag, nil
+}
+
+func month(y int, m time.Month) time.Time {
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func newUsecase(repo *fakeRepo, summary *fakeSummary) *Usecase {
+	u := New(logmid.NewLogger("error"), repo, summary, fakeTags{})
+	u.now = func() time.Time { return time.Date(2025, time.July, 20, 9, 0, 0, 0, time.UTC) }
+	return u
+}
+
+func TestStatus_SpentAndProjected(t *testing.T) {
+	b := modelbudget.Budget{ID: uuid.New(), UserID: uuid.New(), Amount: 1000}
+	summary := &fakeSummary{total: 1200}
+	u := newUsecase(&fakeRepo{budgets: []modelbudget.Budget{b}}, summary)
+
+	st, err := u.Status(context.Background(), b.ID, time.Time{})
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if !st.Month.Equal(month(2025, time.July)) || st.Spent != 1200 || st.Projected != 1200 || st.Remaining() != -200 || !st.Over() {
+		t.Fatalf("unexpected current month status: %+v", st)
+	}
+	if f := summary.filters[0]; f.ExcludeDeleted || !f.From.Equal(st.Month) || !f.To.Equal(st.Month) {
+		t.Fatalf("started month must count deleted subscriptions: %+v", f)
+	}
+
+	st, err = u.Status(context.Background(), b.ID, month(2025, time.September))
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if st.Spent != 0 || st.Projected != 1200 || st.Remaining() != 1000 {
+		t.Fatalf("unexpected future month status: %+v", st)
+	}
+	if !summary.filters[1].ExcludeDeleted {
+		t.Fatalf("future month must leave deleted subscriptions out")
+	}
+}
+
+func TestCheck_AlertsOnlyWhenCrossing(t *testing.T) {
+	userID := uuid.New()
+	netflix := "Netflix"
+	work := "Work"
+	all := modelbudget.Budget{ID: uuid.New(), UserID: userID, Amount: 1000}
+	service := modelbudget.Budget{ID: uuid.New(), UserID: userID, Amount: 500, ServiceName: &netflix, Enforce: true}
+	tagged := modelbudget.Budget{ID: uuid.New(), UserID: userID, Amount: 100, Tag: &work}
+	small := modelbudget.Budget{ID: uuid.New(), UserID: userID, Amount: 800}
+	repo := &fakeRepo{budgets: []modelbudget.Budget{all, service, tagged, small}}
+	u := newUsecase(repo, &fakeSummary{total: 900})
+
+	s := modelsub.Subscription{ID: uuid.New(), UserID: userID, ServiceName: "Spotify", Price: 200, StartDate: month(2025, time.January)}
+	over, err := u.Check(context.Background(), nil, s)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	// all goes 900 -> 1100; service and tagged do not cover the untagged
+	// Spotify subscription; small is already over at 900 and must not alert
+	// again.
+	if len(over) != 1 || over[0].Budget.ID != all.ID || over[0].Projected != 1100 || !over[0].Month.Equal(month(2025, time.July)) {
+		t.Fatalf("unexpected overspends: %+v", over)
+	}
+
+	u.Alert(context.Background(), s, over)
+	if len(repo.alerts) != 1 || repo.alerts[0].Type != modelevent.TypeBudgetExceeded || repo.alerts[0].Subject != all.ID.String() {
+		t.Fatalf("unexpected alerts: %+v", repo.alerts)
+	}
+	var data modelbudget.Alert
+	if err := json.Unmarshal(repo.alerts[0].Data, &data); err != nil {
+		t.Fatalf("decode alert: %v", err)
+	}
+	if data.UserID != userID || data.SubscriptionID != s.ID || data.Month != "07-2025" || data.Projected != 1100 {
+		t.Fatalf("unexpected alert data: %+v", data)
+	}
+
+	// Lowering the price of a subscription the budget already counts does
+	// not overspend.
+	before := s
+	before.Price = 300
+	if over, err = u.Check(context.Background(), &before, s); err != nil || len(over) != 0 {
+		t.Fatalf("want no overspend, got %+v (%v)", over, err)
+	}
+}
+
+func TestCheck_MonthsAfterTrial(t *testing.T) {
+	userID := uuid.New()
+	all := modelbudget.Budget{ID: uuid.New(), UserID: userID, Amount: 1000}
+	summary := &fakeSummary{total: 900}
+	u := newUsecase(&fakeRepo{budgets: []modelbudget.Budget{all}}, summary)
+
+	// Free until August 2026, 13 months from now: the first full-price
+	// month is past the horizon and must still be checked.
+	trialUntil := month(2026, time.August)
+	s := modelsub.Subscription{ID: uuid.New(), UserID: userID, ServiceName: "Spotify", Price: 200,
+		StartDate: month(2025, time.July), TrialUntil: &trialUntil}
+	over, err := u.Check(context.Background(), nil, s)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(over) != 1 || !over[0].Month.Equal(month(2026, time.September)) || over[0].Projected != 1100 {
+		t.Fatalf("want the overspend in the first paid month, got %+v", over)
+	}
+	if n := len(summary.filters); n != 1 || !summary.filters[0].From.Equal(month(2025, time.July)) || !summary.filters[0].To.Equal(month(2026, time.September)) {
+		t.Fatalf("want 15 months checked in one query, got %+v", summary.filters)
+	}
+
+	// An end date before the trial ends leaves nothing to overspend.
+	end := month(2026, time.January)
+	s.EndDate = &end
+	if over, err = u.Check(context.Background(), nil, s); err != nil || len(over) != 0 {
+		t.Fatalf("want no overspend, got %+v, %v", over, err)
+	}
+}
+
+func TestCreate_TagOfAnotherUser(t *testing.T) {
+	tagID := uuid.New()
+	u := newUsecase(&fakeRepo{}, &fakeSummary{})
+	u.tags = fakeTags{tagID: {ID: tagID, UserID: uuid.New(), Name: "Work"}}
+
+	_, err := u.Create(context.Background(), modelbudget.Budget{UserID: uuid.New(), Amount: 100, TagID: &tagID})
+	if !errors.Is(err, myerrors.ErrorValidation) {
+		t.Fatalf("want ErrorValidation, got %v", err)
+	}
+}
+
+func TestCheck_ScheduledPause(t *testing.T) {
+	userID := uuid.New()
+	all := modelbudget.Budget{ID: uuid.New(), UserID: userID, Amount: 1000}
+	summary := &fakeSummary{total: 900}
+	u := newUsecase(&fakeRepo{budgets: []modelbudget.Budget{all}}, summary)
+
+	// Active now, paused from July through December: raising the price only
+	// counts from January on.
+	december := month(2025, time.December)
+	before := modelsub.Subscription{ID: uuid.New(), UserID: userID, ServiceName: "Spotify", Price: 100,
+		StartDate: month(2025, time.January), Status: modelsub.StatusActive}
+	after := before
+	after.Price = 300
+	summary.pauses = []modelsub.Pause{{SubscriptionID: before.ID, From: month(2025, time.July), To: &december}}
+	over, err := u.Check(context.Background(), &before, after)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(over) != 1 || !over[0].Month.Equal(month(2026, time.January)) || over[0].Projected != 1100 {
+		t.Fatalf("want the overspend once the pause ends, got %+v", over)
+	}
+
+	// An open pause is assumed to last.
+	summary.pauses[0].To = nil
+	if over, err = u.Check(context.Background(), &before, after); err != nil || len(over) != 0 {
+		t.Fatalf("want no overspend, got %+v (%v)", over, err)
+	}
+}
+
+func TestCheck_BilledYearly(t *testing.T) {
+	userID, serviceID := uuid.New(), uuid.New()
+	all := modelbudget.Budget{ID: uuid.New(), UserID: userID, Amount: 1000}
+	summary := &fakeSummary{total: 900, yearly: map[uuid.UUID]bool{serviceID: true}}
+	u := newUsecase(&fakeRepo{budgets: []modelbudget.Budget{all}}, summary)
+
+	// 50 a month fits, but billed yearly the 600 of September do not.
+	s := modelsub.Subscription{ID: uuid.New(), UserID: userID, ServiceName: "Spotify", Price: 50,
+		StartDate: month(2025, time.September)}
+	over, err := u.Check(context.Background(), nil, s)
+	if err != nil || len(over) != 0 {
+		t.Fatalf("billed monthly: want no overspend, got %+v (%v)", over, err)
+	}
+	s.ServiceID = &serviceID
+	over, err = u.Check(context.Background(), nil, s)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(over) != 1 || !over[0].Month.Equal(month(2025, time.September)) || over[0].Projected != 1500 {
+		t.Fatalf("want the overspend in the billed month, got %+v", over)
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	modelservice "test_task/internal/domain/models/service"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+// logoMaxDimension bounds the decoded image, so that a small file cannot
+// expand into a huge bitmap.
+const logoMaxDimension = 4096
+
+// logoTypes are the sniffed content types accepted for logos.
+var logoTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+}
+
+type StorageI interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func logoKey(id uuid.UUID, etag string) string {
+	return "services/" + id.String() + "/logo-" + etag
+}
+
+func thumbnailKey(id uuid.UUID, etag string) string {
+	return "services/" + id.String() + "/logo-" + etag + "-thumb.png"
+}
+
+// SetLogo validates and stores an uploaded logo with its thumbnail and
+// replaces the previous logo of the service.
+func (u *Usecase) SetLogo(ctx context.Context, id uuid.UUID, data []byte) (modelservice.Service, error) {
+	if u.logos == nil {
+		return modelservice.Service{}, fmt.Errorf("%w: logo storage is not configured", myerrors.ErrorValidation)
+	}
+	if int64(len(data)) > u.logoMaxBytes {
+		return modelservice.Service{}, fmt.Errorf("%w: logo must be at most %d bytes", myerrors.ErrorTooLarge, u.logoMaxBytes)
+	}
+	contentType := http.DetectContentType(data)
+	if !logoTypes[contentType] {
+		return modelservice.Service{}, fmt.Errorf("%w: logo must be PNG, JPEG or GIF, got %s", myerrors.ErrorUnsupportedMedia, contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return modelservice.Service{}, fmt.Errorf("%w: logo is not a valid image", myerrors.ErrorValidation)
+	}
+	if cfg.Width > logoMaxDimension || cfg.Height > logoMaxDimension {
+		return modelservice.Service{}, fmt.Errorf("%w: logo must be at most %dx%d pixels", myerrors.ErrorValidation, logoMaxDimension, logoMaxDimension)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return modelservice.Service{}, fmt.Errorf("%w: logo is not a valid image", myerrors.ErrorValidation)
+	}
+	var thumb bytes.Buffer
+	if err := png.Encode(&thumb, thumbnail(img, u.thumbnailSize)); err != nil {
+		return modelservice.Service{}, fmt.Errorf("encode thumbnail: %w", err)
+	}
+
+	before, err := u.repo.Get(ctx, id)
+	if err != nil {
+		return modelservice.Service{}, err
+	}
+
+	sum := sha256.Sum256(data)
+	etag := hex.EncodeToString(sum[:16])
+	if err := u.logos.Put(ctx, logoKey(id, etag), data); err != nil {
+		return modelservice.Service{}, fmt.Errorf("store logo: %w", err)
+	}
+	if err := u.logos.Put(ctx, thumbnailKey(id, etag), thumb.Bytes()); err != nil {
+		return modelservice.Service{}, fmt.Errorf("store thumbnail: %w", err)
+	}
+
+	updated, err := u.repo.SetLogo(ctx, id, modelservice.Logo{ContentType: contentType, ETag: etag})
+	if err != nil {
+		return modelservice.Service{}, err
+	}
+	if before.Logo != nil && before.Logo.ETag != etag {
+		u.dropLogo(ctx, id, before.Logo.ETag)
+	}
+	return updated, nil
+}
+
+// Logo opens the logo of a service, or its thumbnail. The caller closes
+// the body.
+func (u *Usecase) Logo(ctx context.Context, id uuid.UUID, thumb bool) (modelservice.LogoFile, error) {
+	if u.logos == nil {
+		return modelservice.LogoFile{}, myerrors.ErrorLogoNotFound
+	}
+	s, err := u.repo.Get(ctx, id)
+	if err != nil {
+		return modelservice.LogoFile{}, err
+	}
+	if s.Logo == nil {
+		return modelservice.LogoFile{}, myerrors.ErrorLogoNotFound
+	}
+
+	f := modelservice.LogoFile{
+		ContentType: s.Logo.ContentType,
+		ETag:        s.Logo.ETag,
+		ModTime:     s.Logo.UpdatedAt,
+	}
+	key := logoKey(id, s.Logo.ETag)
+	if thumb {
+		f.ContentType = "image/png"
+		f.ETag += "-thumb"
+		key = thumbnailKey(id, s.Logo.ETag)
+	}
+	f.Body, err = u.logos.Open(ctx, key)
+	if err != nil {
+		return modelservice.LogoFile{}, err
+	}
+	return f, nil
+}
+
+// dropLogo removes the files of a replaced or deleted logo. Failures only
+// leave unreferenced files behind, so they are ignored.
+func (u *Usecase) dropLogo(ctx context.Context, id uuid.UUID, etag string) {
+	_ = u.logos.Delete(ctx, logoKey(id, etag))
+	_ = u.logos.Delete(ctx, thumbnailKey(id, etag))
+}
+
+// thumbnail scales src down to fit in a size x size square, averaging the
+// source pixels each thumbnail pixel covers. Smaller images keep their size.
+func thumbnail(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, max(1, h*size/w)
+		} else {
+			tw, th = max(1, w*size/h), size
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	modelservice "test_task/internal/domain/models/service"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+type RepoI interface {
+	Create(ctx context.Context, s modelservice.Service) (modelservice.Service, error)
+	Get(ctx context.Context, id uuid.UUID) (modelservice.Service, error)
+	Resolve(ctx context.Context, key string) (modelservice.Service, error)
+	List(ctx context.Context, f modelservice.Filter) ([]modelservice.Service, error)
+	Update(ctx context.Context, s modelservice.Service) (modelservice.Service, error)
+	SetLogo(ctx context.Context, id uuid.UUID, logo modelservice.Logo) (modelservice.Service, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type Usecase struct {
+	repo          RepoI
+	logos         StorageI
+	logoMaxBytes  int64
+	thumbnailSize int
+}
+
+// Option customises the usecase built by New.
+type Option func(*Usecase)
+
+// WithLogos enables logo uploads kept in store. Uploads are limited to
+// maxBytes and get a thumbnail fitting in thumbnailSize pixels.
+func WithLogos(store StorageI, maxBytes int64, thumbnailSize int) Option {
+	return func(u *Usecase) {
+		u.logos = store
+		u.logoMaxBytes = maxBytes
+		u.thumbnailSize = thumbnailSize
+	}
+}
+
+func New(repo RepoI, opts ...Option) *Usecase {
+	u := &Usecase{repo: repo}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
+
+// normalize trims the fields of s in place and validates them.
+func normalize(s *modelservice.Service) error {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" {
+		return fmt.Errorf("%w: name is required", myerrors.ErrorValidation)
+	}
+
+	aliases := make([]string, 0, len(s.Aliases))
+	for _, a := range s.Aliases {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+		if strings.ContainsAny(a, "\r\n") {
+			return fmt.Errorf("%w: aliases must be single line", myerrors.ErrorValidation)
+		}
+		if !slices.Contains(aliases, a) {
+			aliases = append(aliases, a)
+		}
+	}
+	s.Aliases = aliases
+
+	if s.BillingPeriod == "" {
+		s.BillingPeriod = modelservice.PeriodMonth
+	}
+	if !slices.Contains(modelservice.Periods, s.BillingPeriod) {
+		return fmt.Errorf("%w: billing_period must be one of: %s", myerrors.ErrorValidation, strings.Join(modelservice.Periods, ", "))
+	}
+	if s.DefaultPrice != nil && *s.DefaultPrice < 0 {
+		return fmt.Errorf("%w: default_price must be >= 0", myerrors.ErrorValidation)
+	}
+	if s.Category != nil {
+		if c := strings.TrimSpace(*s.Category); c != "" {
+			s.Category = &c
+		} else {
+			s.Category = nil
+		}
+	}
+	if s.LogoURL != nil {
+		u, err := url.Parse(*s.LogoURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: logo_url must be an absolute http or https URL", myerrors.ErrorValidation)
+		}
+	}
+	return nil
+}
+
+func (u *Usecase) Create(ctx context.Context, s modelservice.Service) (modelservice.Service, error) {
+	if err := normalize(&s); err != nil {
+		return modelservice.Service{}, err
+	}
+	return u.repo.Create(ctx, s)
+}
+
+func (u *Usecase) Get(ctx context.Context, id uuid.UUID) (modelservice.Service, error) {
+	return u.repo.Get(ctx, id)
+}
+
+// Resolve finds the service called name or having it as an alias, ignoring
+// case and extra whitespace.
+func (u *Usecase) Resolve(ctx context.Context, name string) (modelservice.Service, error) {
+	key := modelservice.Normalize(name)
+	if key == "" {
+		return modelservice.Service{}, myerrors.ErrorServiceNotFound
+	}
+	return u.repo.Resolve(ctx, key)
+}
+
+func (u *Usecase) List(ctx context.Context, f modelservice.Filter) ([]modelservice.Service, error) {
+	return u.repo.List(ctx, f)
+}
+
+func (u *Usecase) Update(ctx context.Context, s modelservice.Service) (modelservice.Service, error) {
+	if err := normalize(&s); err != nil {
+		return modelservice.Service{}, err
+	}
+	return u.repo.Update(ctx, s)
+}
+
+func (u *Usecase) Delete(ctx context.Context, id uuid.UUID) error {
+	s, err := u.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := u.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if s.Logo != nil && u.logos != nil {
+		u.dropLogo(ctx, id, s.Logo.ETag)
+	}
+	return nil
+}
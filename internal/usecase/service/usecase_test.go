@@ -0,0 +1,133 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+
+	modelservice "test_task/internal/domain/models/service"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+func TestNormalize(t *testing.T) {
+	price := -1
+	ftp := "ftp://cdn.example.com/logo.png"
+	category := "  video "
+
+	s := modelservice.Service{Name: " Netflix ", Aliases: []string{"netflix.com", " ", "netflix.com", " Нетфликс"}, Category: &category}
+	if err := normalize(&s); err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if s.Name != "Netflix" || len(s.Aliases) != 2 || s.Aliases[1] != "Нетфликс" {
+		t.Fatalf("unexpected service: %+v", s)
+	}
+	if s.BillingPeriod != modelservice.PeriodMonth || *s.Category != "video" {
+		t.Fatalf("unexpected defaults: %+v", s)
+	}
+
+	invalid := []modelservice.Service{
+		{Name: " "},
+		{Name: "Netflix", Aliases: []string{"a\nb"}},
+		{Name: "Netflix", BillingPeriod: "week"},
+		{Name: "Netflix", DefaultPrice: &price},
+		{Name: "Netflix", LogoURL: &ftp},
+	}
+	for _, s := range invalid {
+		if err := normalize(&s); !errors.Is(err, myerrors.ErrorValidation) {
+			t.Fatalf("want ErrorValidation for %+v, got %v", s, err)
+		}
+	}
+}
+
+type memStorage map[string][]byte
+
+func (m memStorage) Put(_ context.Context, key string, data []byte) error {
+	m[key] = data
+	return nil
+}
+
+func (m memStorage) Open(_ context.Context, key string) (io.ReadSeekCloser, error) {
+	data, ok := m[key]
+	if !ok {
+		return nil, myerrors.ErrorNotFound
+	}
+	return nopCloser{bytes.NewReader(data)}, nil
+}
+
+func (m memStorage) Delete(_ context.Context, key string) error {
+	delete(m, key)
+	return nil
+}
+
+type nopCloser struct{ *bytes.Reader }
+
+func (nopCloser) Close() error { return nil }
+
+type logoRepo struct {
+	RepoI
+	s modelservice.Service
+}
+
+func (r *logoRepo) Get(context.Context, uuid.UUID) (modelservice.Service, error) {
+	return r.s, nil
+}
+
+func (r *logoRepo) SetLogo(_ context.Context, _ uuid.UUID, logo modelservice.Logo) (modelservice.Service, error) {
+	r.s.Logo = &logo
+	return r.s, nil
+}
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSetLogo(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+	store := memStorage{}
+	repo := &logoRepo{s: modelservice.Service{ID: id, Name: "Netflix"}}
+	u := New(repo, WithLogos(store, 64<<10, 32))
+
+	if _, err := u.SetLogo(ctx, id, []byte("<svg></svg>")); !errors.Is(err, myerrors.ErrorUnsupportedMedia) {
+		t.Fatalf("want ErrorUnsupportedMedia, got %v", err)
+	}
+	if _, err := u.SetLogo(ctx, id, make([]byte, 64<<10+1)); !errors.Is(err, myerrors.ErrorTooLarge) {
+		t.Fatalf("want ErrorTooLarge, got %v", err)
+	}
+
+	first, err := u.SetLogo(ctx, id, encodePNG(t, 200, 100))
+	if err != nil {
+		t.Fatalf("SetLogo: %v", err)
+	}
+	thumb, err := u.Logo(ctx, id, true)
+	if err != nil {
+		t.Fatalf("Logo: %v", err)
+	}
+	cfg, err := png.DecodeConfig(thumb.Body)
+	if err != nil || cfg.Width != 32 || cfg.Height != 16 {
+		t.Fatalf("want 32x16 thumbnail, got %+v (%v)", cfg, err)
+	}
+
+	second, err := u.SetLogo(ctx, id, encodePNG(t, 10, 10))
+	if err != nil {
+		t.Fatalf("SetLogo replace: %v", err)
+	}
+	if second.Logo.ETag == first.Logo.ETag || len(store) != 2 {
+		t.Fatalf("previous logo must be replaced, got %d stored files", len(store))
+	}
+}
@@ -0,0 +1,94 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	monthyear "test_task/internal/domain/models/month_year"
+	modelsub "test_task/internal/domain/models/subscription"
+	modeltenant "test_task/internal/domain/models/tenant"
+
+	"github.com/google/uuid"
+)
+
+// SummaryCacheI keeps summary totals by tenant and filter. Entries are
+// grouped by the tenant and user of the filter, allUsers for filters over
+// every user of the tenant, so the writes of a user can drop the totals they
+// change. Set only stores a total while the group is at the generation read
+// before the total was loaded. cache.LRU is the in-process implementation.
+type SummaryCacheI interface {
+	Get(ctx context.Context, key string) (int64, bool)
+	Generation(ctx context.Context, group string) uint64
+	Set(ctx context.Context, group, key string, total int64, gen uint64)
+	Invalidate(ctx context.Context, group string)
+	Clear(ctx context.Context)
+}
+
+// allUsers groups the cached totals of filters without a user.
+const allUsers = ""
+
+// summaryGroup names the group of the totals of user in tenant.
+func summaryGroup(tenant, user string) string {
+	return tenant + "/" + user
+}
+
+// WithSummaryCache serves Summary from c. Creating, updating, deleting,
+// restoring, pausing, resuming or tagging a subscription, and renaming or
+// deleting a tag through InvalidateUser, drop the totals of its user and
+// those over every user; purging, including the retention job sharing c,
+// drops everything.
+func WithSummaryCache(c SummaryCacheI) Option {
+	return func(u *Usecase) {
+		u.cache = c
+	}
+}
+
+// summaryKey identifies a normalized filter in tenant. Tags are matched
+// case-insensitively and in any order, and with a single tag any and all
+// agree.
+func summaryKey(tenant string, f modelsub.SummaryFilter) (group, key string) {
+	user := allUsers
+	if f.UserID != nil {
+		user = f.UserID.String()
+	}
+	group = summaryGroup(tenant, user)
+	service := "-"
+	if f.ServiceName != nil {
+		service = "=" + *f.ServiceName
+	}
+	if f.ServiceID != nil {
+		service += "#" + f.ServiceID.String()
+	}
+	tags := make([]string, len(f.Tags))
+	for i, t := range f.Tags {
+		tags[i] = strings.ToLower(t)
+	}
+	sort.Strings(tags)
+	all := f.TagsAll && len(tags) > 1
+
+	key = fmt.Sprintf("%s|%s|%s|%q|%q|%t|%t",
+		monthyear.FormatMonthYear(f.From), monthyear.FormatMonthYear(f.To), group,
+		service, strings.Join(tags, "\n"), all, f.ExcludeDeleted)
+	return group, key
+}
+
+// invalidate drops the cached totals the subscriptions of users in the
+// tenant of ctx count in.
+func (u *Usecase) invalidate(ctx context.Context, users ...uuid.UUID) {
+	if u.cache == nil {
+		return
+	}
+	tenant := modeltenant.From(ctx)
+	for _, id := range users {
+		u.cache.Invalidate(ctx, summaryGroup(tenant, id.String()))
+	}
+	u.cache.Invalidate(ctx, summaryGroup(tenant, allUsers))
+}
+
+// InvalidateUser drops the cached totals of a user whose subscriptions were
+// changed outside the usecase, such as by the erasure of the user.
+func (u *Usecase) InvalidateUser(ctx context.Context, userID uuid.UUID) {
+	u.invalidate(ctx, userID)
+}
@@ -0,0 +1,177 @@
+package subscription
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"test_task/internal/cache"
+	modelsub "test_task/internal/domain/models/subscription"
+	modeltenant "test_task/internal/domain/models/tenant"
+
+	"github.com/google/uuid"
+)
+
+type summaryRepo struct {
+	RepoI
+	subs    map[uuid.UUID]modelsub.Subscription
+	queries int
+	// during runs while a summary is read.
+	during func()
+}
+
+func (r *summaryRepo) Summary(context.Context, modelsub.SummaryFilter) (int64, error) {
+	r.queries++
+	if r.during != nil {
+		r.during()
+	}
+	return int64(100 * r.queries), nil
+}
+
+func (r *summaryRepo) Create(_ context.Context, s modelsub.Subscription, _ modelsub.Guard) (modelsub.Subscription, error) {
+	s.ID = uuid.New()
+	r.subs[s.ID] = s
+	return s, nil
+}
+
+func (r *summaryRepo) GetSub(_ context.Context, id uuid.UUID) (modelsub.Subscription, error) {
+	return r.subs[id], nil
+}
+
+func (r *summaryRepo) Delete(context.Context, uuid.UUID) error { return nil }
+
+func TestSummary_Cache(t *testing.T) {
+	repo := &summaryRepo{subs: map[uuid.UUID]modelsub.Subscription{}}
+	c := cache.NewLRU(100, time.Minute)
+	u := New(repo, WithSummaryCache(c))
+	u.now = func() time.Time { return time.Date(2025, time.June, 18, 10, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	alice, bob := uuid.New(), uuid.New()
+	filter := func(user *uuid.UUID, tags ...string) modelsub.SummaryFilter {
+		return modelsub.SummaryFilter{From: month(2025, time.January), To: month(2025, time.December), UserID: user, Tags: tags}
+	}
+	sum := func(f modelsub.SummaryFilter) int64 {
+		t.Helper()
+		total, err := u.Summary(ctx, f)
+		if err != nil {
+			t.Fatalf("Summary error: %v", err)
+		}
+		return total
+	}
+
+	a := sum(filter(&alice, "Work", "home"))
+	b := sum(filter(&bob))
+	all := sum(filter(nil))
+	if repo.queries != 3 {
+		t.Fatalf("want 3 queries, got %d", repo.queries)
+	}
+	// Tags are matched regardless of case and order.
+	if got := sum(filter(&alice, "home", "work")); got != a || repo.queries != 3 {
+		t.Fatalf("want cached %d, got %d after %d queries", a, got, repo.queries)
+	}
+
+	s, err := u.Create(ctx, modelsub.Subscription{ServiceName: "Netflix", Price: 500, UserID: alice, StartDate: month(2025, time.June)})
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if got := sum(filter(&bob)); got != b {
+		t.Fatalf("other users stay cached: want %d, got %d", b, got)
+	}
+	if got := sum(filter(nil)); got == all {
+		t.Fatalf("totals over every user must be dropped, got cached %d", got)
+	}
+	if got := sum(filter(&alice, "work", "home")); got == a {
+		t.Fatalf("totals of the user must be dropped, got cached %d", got)
+	}
+
+	a = sum(filter(&alice, "work", "home"))
+	if err := u.Delete(ctx, s.ID); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if got := sum(filter(&alice, "work", "home")); got == a {
+		t.Fatalf("delete must drop the totals of the user, got cached %d", got)
+	}
+
+	st := c.Stats()
+	if st.Hits != 3 || st.Misses != 6 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestSummary_CacheKeepsTenantsApart(t *testing.T) {
+	repo := &summaryRepo{subs: map[uuid.UUID]modelsub.Subscription{}}
+	u := New(repo, WithSummaryCache(cache.NewLRU(100, time.Minute)))
+	u.now = func() time.Time { return time.Date(2025, time.June, 18, 10, 0, 0, 0, time.UTC) }
+	acme := modeltenant.WithTenant(context.Background(), "acme")
+	globex := modeltenant.WithTenant(context.Background(), "globex")
+
+	user := uuid.New()
+	f := modelsub.SummaryFilter{From: month(2025, time.January), To: month(2025, time.December), UserID: &user}
+	a, err := u.Summary(acme, f)
+	if err != nil {
+		t.Fatalf("Summary error: %v", err)
+	}
+	g, err := u.Summary(globex, f)
+	if err != nil {
+		t.Fatalf("Summary error: %v", err)
+	}
+	if a == g || repo.queries != 2 {
+		t.Fatalf("totals must not be shared across tenants: %d and %d after %d queries", a, g, repo.queries)
+	}
+
+	if _, err := u.Create(globex, modelsub.Subscription{ServiceName: "Netflix", Price: 500, UserID: user, StartDate: month(2025, time.June)}); err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if got, _ := u.Summary(acme, f); got != a {
+		t.Fatalf("writes in another tenant must keep the totals: want %d, got %d", a, got)
+	}
+	if got, _ := u.Summary(globex, f); got == g {
+		t.Fatalf("writes must drop the totals of their tenant, got cached %d", got)
+	}
+}
+
+func (r *summaryRepo) Purge(context.Context, time.Time) (int64, error) { return 1, nil }
+
+func TestSummary_PurgeClearsCache(t *testing.T) {
+	repo := &summaryRepo{subs: map[uuid.UUID]modelsub.Subscription{}}
+	u := New(repo, WithSummaryCache(cache.NewLRU(100, time.Minute)))
+	ctx := modeltenant.WithTenant(context.Background(), "acme")
+
+	f := modelsub.SummaryFilter{From: month(2025, time.January), To: month(2025, time.December)}
+	before, err := u.Summary(ctx, f)
+	if err != nil {
+		t.Fatalf("Summary error: %v", err)
+	}
+	if _, err := u.Purge(modeltenant.WithAllTenants(context.Background()), time.Now()); err != nil {
+		t.Fatalf("Purge error: %v", err)
+	}
+	if got, _ := u.Summary(ctx, f); got == before {
+		t.Fatalf("purge must drop the totals of every tenant, got cached %d", got)
+	}
+}
+
+func TestSummary_WriteDuringReadIsNotCached(t *testing.T) {
+	repo := &summaryRepo{subs: map[uuid.UUID]modelsub.Subscription{}}
+	u := New(repo, WithSummaryCache(cache.NewLRU(100, time.Minute)))
+	u.now = func() time.Time { return time.Date(2025, time.June, 18, 10, 0, 0, 0, time.UTC) }
+	ctx := modeltenant.WithTenant(context.Background(), "acme")
+
+	user := uuid.New()
+	f := modelsub.SummaryFilter{From: month(2025, time.January), To: month(2025, time.December), UserID: &user}
+	// The subscription is created after the total was read but before it
+	// is cached, so the total misses it.
+	repo.during = func() {
+		repo.during = nil
+		if _, err := u.Create(ctx, modelsub.Subscription{ServiceName: "Netflix", Price: 500, UserID: user, StartDate: month(2025, time.June)}); err != nil {
+			t.Fatalf("Create error: %v", err)
+		}
+	}
+	stale, err := u.Summary(ctx, f)
+	if err != nil {
+		t.Fatalf("Summary error: %v", err)
+	}
+	if got, _ := u.Summary(ctx, f); got == stale || repo.queries != 2 {
+		t.Fatalf("a total read across a write must not be cached, got %d after %d queries", got, repo.queries)
+	}
+}
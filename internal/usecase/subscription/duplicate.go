@@ -0,0 +1,113 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	modelservice "test_task/internal/domain/models/service"
+	modelsub "test_task/internal/domain/models/subscription"
+	myerror "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+// Duplicates returns every pair of subscriptions of the user to the same
+// service whose billed months overlap.
+func (u *Usecase) Duplicates(ctx context.Context, userID uuid.UUID) ([]modelsub.Overlap, error) {
+	subs, err := u.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return findOverlaps(subs), nil
+}
+
+// CreateChecked creates s after looking for existing subscriptions of the
+// user it overlaps with, as Duplicates would report them. With
+// DuplicateReject an overlap fails with ErrorConflict, with DuplicateWarn
+// the subscription is created and the overlapping ones are returned, and
+// DuplicateAllow skips the lookup like Create. The lookup runs in the
+// transaction of the write under the write lock of the user, so that two
+// overlapping subscriptions created at once cannot both pass it.
+func (u *Usecase) CreateChecked(ctx context.Context, s modelsub.Subscription, policy string) (modelsub.Subscription, []modelsub.Subscription, error) {
+	if !slices.Contains(modelsub.DuplicatePolicies, policy) {
+		return modelsub.Subscription{}, nil, fmt.Errorf("%w: on_duplicate must be one of %s",
+			myerror.ErrorValidation, strings.Join(modelsub.DuplicatePolicies, ", "))
+	}
+	if policy == modelsub.DuplicateAllow {
+		created, err := u.Create(ctx, s)
+		return created, nil, err
+	}
+
+	// Names are compared in their canonical catalog form, which create has
+	// linked s to by the time check runs.
+	var dups []modelsub.Subscription
+	created, err := u.create(ctx, s, func(ctx context.Context, s modelsub.Subscription) error {
+		existing, err := u.repo.ListByUser(ctx, s.UserID)
+		if err != nil {
+			return err
+		}
+		dups = nil
+		for _, e := range existing {
+			if _, ok := overlap(e, s); ok {
+				dups = append(dups, e)
+			}
+		}
+		if len(dups) > 0 && policy == modelsub.DuplicateReject {
+			return fmt.Errorf("%w: subscription to %s overlaps %d existing one(s)",
+				myerror.ErrorConflict, s.ServiceName, len(dups))
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, myerror.ErrorConflict) {
+			return modelsub.Subscription{}, dups, err
+		}
+		return modelsub.Subscription{}, nil, err
+	}
+	return created, dups, nil
+}
+
+// findOverlaps pairs the subscriptions to the same service with overlapping
+// months, in the order of subs.
+func findOverlaps(subs []modelsub.Subscription) []modelsub.Overlap {
+	var out []modelsub.Overlap
+	for i := range subs {
+		for j := i + 1; j < len(subs); j++ {
+			if o, ok := overlap(subs[i], subs[j]); ok {
+				out = append(out, o)
+			}
+		}
+	}
+	return out
+}
+
+// overlap reports the months a and b are both billed for when they are
+// subscriptions of the same user to the same service. Services are matched
+// by normalized name, which is also the catalog name of linked ones.
+func overlap(a, b modelsub.Subscription) (modelsub.Overlap, bool) {
+	if a.UserID != b.UserID || modelservice.Normalize(a.ServiceName) != modelservice.Normalize(b.ServiceName) {
+		return modelsub.Overlap{}, false
+	}
+
+	from := a.StartDate
+	if b.StartDate.After(from) {
+		from = b.StartDate
+	}
+	var to *time.Time
+	switch {
+	case a.EndDate == nil:
+		to = b.EndDate
+	case b.EndDate == nil || a.EndDate.Before(*b.EndDate):
+		to = a.EndDate
+	default:
+		to = b.EndDate
+	}
+	if to != nil && to.Before(from) {
+		return modelsub.Overlap{}, false
+	}
+	return modelsub.Overlap{First: a, Second: b, From: from, To: to}, true
+}
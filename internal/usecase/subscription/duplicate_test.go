@@ -0,0 +1,93 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	modelsub "test_task/internal/domain/models/subscription"
+	myerror "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+type userRepo struct {
+	RepoI
+	subs    []modelsub.Subscription
+	created *modelsub.Subscription
+}
+
+func (r *userRepo) ListByUser(_ context.Context, userID uuid.UUID) ([]modelsub.Subscription, error) {
+	var out []modelsub.Subscription
+	for _, s := range r.subs {
+		if s.UserID == userID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (r *userRepo) Create(ctx context.Context, s modelsub.Subscription, guard modelsub.Guard) (modelsub.Subscription, error) {
+	if guard != nil {
+		if err := guard(ctx, nil); err != nil {
+			return modelsub.Subscription{}, err
+		}
+	}
+	s.ID = uuid.New()
+	r.created = &s
+	return s, nil
+}
+
+func TestDuplicates_OverlappingMonths(t *testing.T) {
+	userID := uuid.New()
+	march, june := month(2025, time.March), month(2025, time.June)
+
+	family := modelsub.Subscription{ID: uuid.New(), UserID: userID, ServiceName: "Netflix", StartDate: month(2025, time.January), EndDate: &june}
+	personal := modelsub.Subscription{ID: uuid.New(), UserID: userID, ServiceName: " netflix ", StartDate: march}
+	later := modelsub.Subscription{ID: uuid.New(), UserID: userID, ServiceName: "NETFLIX", StartDate: month(2025, time.July)}
+	other := modelsub.Subscription{ID: uuid.New(), UserID: userID, ServiceName: "Spotify", StartDate: march}
+
+	u := New(&userRepo{subs: []modelsub.Subscription{family, personal, later, other}})
+	got, err := u.Duplicates(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("Duplicates: %v", err)
+	}
+	// family ends before later starts; personal is open-ended like later.
+	if len(got) != 2 {
+		t.Fatalf("want 2 overlaps, got %+v", got)
+	}
+	if got[0].First.ID != family.ID || got[0].Second.ID != personal.ID || !got[0].From.Equal(march) || got[0].To == nil || !got[0].To.Equal(june) {
+		t.Fatalf("unexpected first overlap: %+v", got[0])
+	}
+	if got[1].First.ID != personal.ID || got[1].Second.ID != later.ID || !got[1].From.Equal(later.StartDate) || got[1].To != nil {
+		t.Fatalf("unexpected second overlap: %+v", got[1])
+	}
+}
+
+func TestCreateChecked_Policies(t *testing.T) {
+	userID := uuid.New()
+	existing := modelsub.Subscription{ID: uuid.New(), UserID: userID, ServiceName: "Netflix", Price: 700, StartDate: month(2025, time.January)}
+	s := modelsub.Subscription{UserID: userID, ServiceName: "netflix", Price: 300, StartDate: month(2025, time.July)}
+
+	repo := &userRepo{subs: []modelsub.Subscription{existing}}
+	u := New(repo)
+	u.now = func() time.Time { return month(2025, time.July) }
+
+	_, dups, err := u.CreateChecked(context.Background(), s, modelsub.DuplicateReject)
+	if !errors.Is(err, myerror.ErrorConflict) || len(dups) != 1 || dups[0].ID != existing.ID {
+		t.Fatalf("reject: want ErrorConflict with the duplicate, got %v %+v", err, dups)
+	}
+	if repo.created != nil {
+		t.Fatalf("reject must not create the subscription")
+	}
+
+	created, dups, err := u.CreateChecked(context.Background(), s, modelsub.DuplicateWarn)
+	if err != nil || len(dups) != 1 || created.ID == uuid.Nil {
+		t.Fatalf("warn: want created subscription and the duplicate, got %+v %+v %v", created, dups, err)
+	}
+
+	if _, _, err := u.CreateChecked(context.Background(), s, "ignore"); !errors.Is(err, myerror.ErrorValidation) {
+		t.Fatalf("unknown policy: want ErrorValidation, got %v", err)
+	}
+}
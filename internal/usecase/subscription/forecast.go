@@ -0,0 +1,44 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	modelsub "test_task/internal/domain/models/subscription"
+	myerror "test_task/pkg/global_errors"
+)
+
+// MaxForecastMonths bounds how far Forecast looks ahead.
+const MaxForecastMonths = 60
+
+// Forecast projects the monthly spend of the subscriptions matching f for
+// the given number of months from the current one; f.From and f.To are set
+// from the window. Open-ended subscriptions are billed through the whole
+// window, ended ones until their end_date and trial months at the trial
+// price. Services of the catalog billed yearly are charged twelve months at
+// once, every twelve months from the first month after the trial, as in the
+// summary. An open pause is assumed to last and deleted subscriptions are
+// left out.
+func (u *Usecase) Forecast(ctx context.Context, f modelsub.SummaryFilter, months int) ([]modelsub.MonthTotal, error) {
+	if months < 1 || months > MaxForecastMonths {
+		return nil, fmt.Errorf("%w: months must be between 1 and %d", myerror.ErrorValidation, MaxForecastMonths)
+	}
+	now := u.now().UTC()
+	f.From = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	f.To = f.From.AddDate(0, months-1, 0)
+	f.ExcludeDeleted = true
+	f.ServiceName, f.ServiceID = u.serviceFilter(ctx, f.ServiceName, f.ServiceID)
+	f.Tags = filterTags(f.Tags)
+
+	totals, err := u.repo.Forecast(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	var cumulative int64
+	for i := range totals {
+		cumulative += totals[i].Total
+		totals[i].Cumulative = cumulative
+	}
+	return totals, nil
+}
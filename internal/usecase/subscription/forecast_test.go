@@ -0,0 +1,51 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	modelsub "test_task/internal/domain/models/subscription"
+	myerror "test_task/pkg/global_errors"
+)
+
+type monthRepo struct {
+	RepoI
+	filter modelsub.SummaryFilter
+}
+
+func (r *monthRepo) Forecast(_ context.Context, f modelsub.SummaryFilter) ([]modelsub.MonthTotal, error) {
+	r.filter = f
+	var out []modelsub.MonthTotal
+	for m, price := f.From, int64(100); !m.After(f.To); m, price = m.AddDate(0, 1, 0), price+100 {
+		out = append(out, modelsub.MonthTotal{Month: m, Total: price})
+	}
+	return out, nil
+}
+
+func TestForecast_WindowAndCumulative(t *testing.T) {
+	repo := &monthRepo{}
+	u := New(repo)
+	u.now = func() time.Time { return time.Date(2025, time.November, 20, 9, 0, 0, 0, time.UTC) }
+
+	got, err := u.Forecast(context.Background(), modelsub.SummaryFilter{Tags: []string{"work", " Work "}}, 3)
+	if err != nil {
+		t.Fatalf("Forecast: %v", err)
+	}
+	if !repo.filter.From.Equal(month(2025, time.November)) || !repo.filter.To.Equal(month(2026, time.January)) || !repo.filter.ExcludeDeleted {
+		t.Fatalf("unexpected filter: %+v", repo.filter)
+	}
+	if len(repo.filter.Tags) != 1 {
+		t.Fatalf("tags must be deduplicated, got %q", repo.filter.Tags)
+	}
+	if len(got) != 3 || got[0].Cumulative != 100 || got[1].Cumulative != 300 || got[2].Cumulative != 600 {
+		t.Fatalf("unexpected cumulative totals: %+v", got)
+	}
+
+	for _, months := range []int{0, MaxForecastMonths + 1} {
+		if _, err := u.Forecast(context.Background(), modelsub.SummaryFilter{}, months); !errors.Is(err, myerror.ErrorValidation) {
+			t.Fatalf("months=%d: want ErrorValidation, got %v", months, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package subscription
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	modelsub "test_task/internal/domain/models/subscription"
+	myerror "test_task/pkg/global_errors"
+)
+
+// transitions lists the statuses reachable from each status. Expired is
+// reachable from everywhere because it follows from the dates alone.
+var transitions = map[string][]string{
+	modelsub.StatusTrial:     {modelsub.StatusActive, modelsub.StatusPaused, modelsub.StatusCancelled, modelsub.StatusExpired},
+	modelsub.StatusActive:    {modelsub.StatusPaused, modelsub.StatusCancelled, modelsub.StatusExpired},
+	modelsub.StatusPaused:    {modelsub.StatusActive, modelsub.StatusCancelled, modelsub.StatusExpired},
+	modelsub.StatusCancelled: {modelsub.StatusActive, modelsub.StatusExpired},
+	modelsub.StatusExpired:   {modelsub.StatusActive},
+}
+
+// CanTransition reports whether a subscription may move from one status to
+// another. Staying in the same status is always allowed.
+func CanTransition(from, to string) bool {
+	return from == to || slices.Contains(transitions[from], to)
+}
+
+func monthOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// nextStatus resolves the status s should be stored with. before is nil on
+// create. An empty s.Status means the caller did not ask for a status and it
+// is derived from the dates: a running trial starts as trial and turns active
+// once over, setting end_date cancels, dropping or moving it past today
+// reactivates, and an end_date in a past month expires. Pausing and resuming
+// have their own calls because they also record the pause period.
+func nextStatus(before *modelsub.Subscription, s modelsub.Subscription, now time.Time) (string, error) {
+	current := monthOf(now)
+	isEnded := s.EndDate != nil && s.EndDate.Before(current)
+
+	to := s.Status
+	if to == "" {
+		to = deriveStatus(before, s, current)
+	} else if !slices.Contains(modelsub.Statuses, to) {
+		return "", fmt.Errorf("%w: unknown status %q", myerror.ErrorValidation, to)
+	}
+
+	switch {
+	case to == modelsub.StatusTrial && !s.InTrial(current):
+		return "", fmt.Errorf("%w: trial subscription requires trial_until not in the past", myerror.ErrorValidation)
+	case to == modelsub.StatusCancelled && s.EndDate == nil:
+		return "", fmt.Errorf("%w: cancelled subscription requires end_date", myerror.ErrorValidation)
+	case to == modelsub.StatusExpired && !isEnded:
+		return "", fmt.Errorf("%w: subscription is not past end_date", myerror.ErrorInvalidTransition)
+	case to != modelsub.StatusExpired && isEnded:
+		return "", fmt.Errorf("%w: subscription past end_date can only be expired", myerror.ErrorInvalidTransition)
+	}
+
+	wasPaused := before != nil && before.Status == modelsub.StatusPaused
+	switch {
+	case to == modelsub.StatusPaused && !wasPaused:
+		return "", fmt.Errorf("%w: use pause to pause a subscription", myerror.ErrorInvalidTransition)
+	case wasPaused && to == modelsub.StatusActive:
+		return "", fmt.Errorf("%w: use resume to resume a subscription", myerror.ErrorInvalidTransition)
+	}
+
+	if before != nil && !CanTransition(before.Status, to) {
+		return "", fmt.Errorf("%w: %s -> %s", myerror.ErrorInvalidTransition, before.Status, to)
+	}
+	return to, nil
+}
+
+func deriveStatus(before *modelsub.Subscription, s modelsub.Subscription, current time.Time) string {
+	if s.EndDate != nil && s.EndDate.Before(current) {
+		return modelsub.StatusExpired
+	}
+	if before == nil {
+		if s.InTrial(current) {
+			return modelsub.StatusTrial
+		}
+		return modelsub.StatusActive
+	}
+
+	switch before.Status {
+	case modelsub.StatusActive, modelsub.StatusTrial, modelsub.StatusPaused:
+		if before.EndDate == nil && s.EndDate != nil {
+			return modelsub.StatusCancelled
+		}
+		if before.Status == modelsub.StatusTrial && !s.InTrial(current) {
+			return modelsub.StatusActive
+		}
+	case modelsub.StatusCancelled:
+		if s.EndDate == nil {
+			return modelsub.StatusActive
+		}
+	case modelsub.StatusExpired:
+		return modelsub.StatusActive
+	}
+	return before.Status
+}
@@ -2,50 +2,640 @@ package subscription
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	modelaudit "test_task/internal/domain/models/audit"
+	modelbudget "test_task/internal/domain/models/budget"
+	monthyear "test_task/internal/domain/models/month_year"
+	modelservice "test_task/internal/domain/models/service"
 	modelsub "test_task/internal/domain/models/subscription"
+	modeltag "test_task/internal/domain/models/tag"
+	modeltenant "test_task/internal/domain/models/tenant"
+	modeluser "test_task/internal/domain/models/user"
+	myerror "test_task/pkg/global_errors"
 
 	"github.com/google/uuid"
 )
 
 type RepoI interface {
-	Create(ctx context.Context, s modelsub.Subscription) (modelsub.Subscription, error)
+	Create(ctx context.Context, s modelsub.Subscription, guard modelsub.Guard) (modelsub.Subscription, error)
 	GetSub(ctx context.Context, id uuid.UUID) (modelsub.Subscription, error)
-	UpdateSub(ctx context.Context, id uuid.UUID, s modelsub.Subscription) (modelsub.Subscription, error)
+	UpdateSub(ctx context.Context, id uuid.UUID, s modelsub.Subscription, guard modelsub.Guard) (modelsub.Subscription, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	Restore(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (modelsub.Subscription, error)
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
 	List(ctx context.Context, f modelsub.ListFilter) ([]modelsub.Subscription, int, error)
 	Summary(ctx context.Context, f modelsub.SummaryFilter) (int64, error)
+	AuditLog(ctx context.Context, f modelaudit.Filter) ([]modelaudit.Entry, error)
+	ListActive(ctx context.Context, f modelsub.UpcomingFilter) ([]modelsub.Subscription, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]modelsub.Subscription, error)
+	ListEnded(ctx context.Context, cutoff time.Time, limit int) ([]modelsub.Subscription, error)
+	ListTrialsEnded(ctx context.Context, cutoff time.Time, limit int) ([]modelsub.Subscription, error)
+	ListPausesStarted(ctx context.Context, cutoff time.Time, limit int) ([]modelsub.Subscription, error)
+	ListPausesEnded(ctx context.Context, cutoff time.Time, limit int) ([]modelsub.Subscription, error)
+	SetStatus(ctx context.Context, id uuid.UUID, from, to string) (modelsub.Subscription, error)
+	Pause(ctx context.Context, id uuid.UUID, status string, from time.Time, next string) (modelsub.Subscription, error)
+	Resume(ctx context.Context, id uuid.UUID, status string, from time.Time, next string) (modelsub.Subscription, error)
+	ListPauses(ctx context.Context, id uuid.UUID) ([]modelsub.Pause, error)
+	SetTags(ctx context.Context, id uuid.UUID, names []string, guard modelsub.Guard) (modelsub.Subscription, error)
+	SummaryByTag(ctx context.Context, f modelsub.SummaryFilter) ([]modelsub.TagTotal, error)
+	Forecast(ctx context.Context, f modelsub.SummaryFilter) ([]modelsub.MonthTotal, error)
+}
+
+// maxTags bounds the tags of one subscription.
+const maxTags = 20
+
+// statusBatch bounds how many subscriptions ExpireEnded, EndTrials and
+// ApplyPauses load at once.
+const statusBatch = 100
+
+// CatalogI looks up services of the catalog subscriptions link to.
+type CatalogI interface {
+	Get(ctx context.Context, id uuid.UUID) (modelservice.Service, error)
+	Resolve(ctx context.Context, name string) (modelservice.Service, error)
+}
+
+// BudgetsI finds the budgets a subscription change overspends and alerts
+// about them once the change is written. The check runs in the guard of the
+// write, under the write lock of the user.
+type BudgetsI interface {
+	List(ctx context.Context, userID *uuid.UUID) ([]modelbudget.Budget, error)
+	Check(ctx context.Context, before *modelsub.Subscription, after modelsub.Subscription) ([]modelbudget.Overspend, error)
+	Alert(ctx context.Context, s modelsub.Subscription, over []modelbudget.Overspend)
+}
+
+// UsersI looks up registered users.
+type UsersI interface {
+	Get(ctx context.Context, id uuid.UUID) (modeluser.User, error)
 }
 
 type Usecase struct {
-	repo RepoI
+	repo    RepoI
+	catalog CatalogI
+	budgets BudgetsI
+	users   UsersI
+	cache   SummaryCacheI
+	now     func() time.Time
+}
+
+// Option customises the usecase built by New.
+type Option func(*Usecase)
+
+// WithCatalog links subscriptions to the service catalog: names are resolved
+// through the catalog aliases and missing prices default to catalog prices.
+func WithCatalog(c CatalogI) Option {
+	return func(u *Usecase) {
+		u.catalog = c
+	}
+}
+
+// WithBudgets checks subscription changes against the user's budgets:
+// creating a subscription that overspends an enforced budget fails with
+// ErrorBudgetExceeded, other overspends are alerted.
+func WithBudgets(b BudgetsI) Option {
+	return func(u *Usecase) {
+		u.budgets = b
+	}
+}
+
+// WithUsers requires subscriptions to belong to registered users: creating a
+// subscription for an unknown user_id or moving one to it fails with
+// ErrorValidation. Subscriptions written before stay as they are.
+func WithUsers(users UsersI) Option {
+	return func(u *Usecase) {
+		u.users = users
+	}
 }
 
-func New(repo RepoI) *Usecase {
-	return &Usecase{
+func New(repo RepoI, opts ...Option) *Usecase {
+	u := &Usecase{
 		repo: repo,
+		now:  time.Now,
+	}
+	for _, opt := range opts {
+		opt(u)
 	}
+	return u
+}
+
+// Now returns the current time of the usecase clock, so that callers
+// computing relative dates agree with the usecase.
+func (u *Usecase) Now() time.Time {
+	return u.now()
+}
+
+// linkService resolves the catalog service of s. A known service_id or a
+// name matching a catalog name or alias sets both the link and the canonical
+// name; other names are kept as free text.
+func (u *Usecase) linkService(ctx context.Context, s *modelsub.Subscription) error {
+	var svc *modelservice.Service
+	switch {
+	case u.catalog == nil:
+		if s.ServiceID != nil {
+			return fmt.Errorf("%w: service catalog is not available", myerror.ErrorValidation)
+		}
+	case s.ServiceID != nil:
+		found, err := u.catalog.Get(ctx, *s.ServiceID)
+		if errors.Is(err, myerror.ErrorNotFound) {
+			return fmt.Errorf("%w: unknown service_id %s", myerror.ErrorValidation, s.ServiceID)
+		}
+		if err != nil {
+			return err
+		}
+		svc = &found
+	default:
+		found, err := u.catalog.Resolve(ctx, s.ServiceName)
+		if err != nil && !errors.Is(err, myerror.ErrorNotFound) {
+			return err
+		}
+		if err == nil {
+			svc = &found
+		}
+	}
+
+	if svc != nil {
+		s.ServiceID = &svc.ID
+		s.ServiceName = svc.Name
+	}
+	if s.PriceFromCatalog {
+		price, ok := 0, false
+		if svc != nil {
+			price, ok = svc.MonthlyPrice()
+		}
+		if !ok {
+			return fmt.Errorf("%w: price is required for services without a catalog price", myerror.ErrorValidation)
+		}
+		s.Price = price
+	}
+	return nil
+}
+
+// checkUser makes sure userID is registered when users are required.
+func (u *Usecase) checkUser(ctx context.Context, userID uuid.UUID) error {
+	if u.users == nil {
+		return nil
+	}
+	_, err := u.users.Get(ctx, userID)
+	if errors.Is(err, myerror.ErrorNotFound) {
+		return fmt.Errorf("%w: user %s is not registered", myerror.ErrorValidation, userID)
+	}
+	return err
+}
+
+// serviceFilter narrows a service name filter to the catalog service it
+// names, so that the subscriptions linked to the service match whichever
+// name they were written with. Names the catalog does not know, or that
+// name another service than id, stay a free-text filter.
+func (u *Usecase) serviceFilter(ctx context.Context, name *string, id *uuid.UUID) (*string, *uuid.UUID) {
+	if name == nil || u.catalog == nil {
+		return name, id
+	}
+	svc, err := u.catalog.Resolve(ctx, *name)
+	if err != nil || (id != nil && *id != svc.ID) {
+		return name, id
+	}
+	return nil, &svc.ID
 }
 
 func (u *Usecase) Create(ctx context.Context, s modelsub.Subscription) (modelsub.Subscription, error) {
-	return u.repo.Create(ctx, s)
+	return u.create(ctx, s, nil)
+}
+
+// create links s to the catalog, derives its status and writes it. check,
+// when given, runs in the transaction of the write under the write lock of
+// the user, with s as it is written, before the budgets are checked.
+func (u *Usecase) create(ctx context.Context, s modelsub.Subscription, check func(ctx context.Context, s modelsub.Subscription) error) (modelsub.Subscription, error) {
+	if err := u.checkUser(ctx, s.UserID); err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if err := u.linkService(ctx, &s); err != nil {
+		return modelsub.Subscription{}, err
+	}
+	status, err := nextStatus(nil, s, u.now())
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	s.Status = status
+
+	var over []modelbudget.Overspend
+	guard, err := u.budgetGuard(ctx, s.UserID, true, func(*modelsub.Subscription) modelsub.Subscription { return s }, &over)
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if check != nil {
+		budgets := guard
+		guard = func(ctx context.Context, before *modelsub.Subscription) error {
+			if err := check(ctx, s); err != nil {
+				return err
+			}
+			if budgets != nil {
+				return budgets(ctx, before)
+			}
+			return nil
+		}
+	}
+	created, err := u.repo.Create(ctx, s, guard)
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	u.invalidate(ctx, created.UserID)
+	u.alertBudgets(ctx, created, over)
+	return created, nil
+}
+
+// budgetGuard checks the budgets of the user in the transaction of a write
+// and stores what the write overspends in over; after derives the written
+// subscription from the locked row. With enforce an overspent enforced
+// budget refuses the write. Users without budgets get no guard, so that
+// their writes take no lock.
+func (u *Usecase) budgetGuard(ctx context.Context, userID uuid.UUID, enforce bool, after func(before *modelsub.Subscription) modelsub.Subscription, over *[]modelbudget.Overspend) (modelsub.Guard, error) {
+	if u.budgets == nil {
+		return nil, nil
+	}
+	budgets, err := u.budgets.List(ctx, &userID)
+	if err != nil || len(budgets) == 0 {
+		return nil, err
+	}
+	return func(ctx context.Context, before *modelsub.Subscription) error {
+		found, err := u.budgets.Check(ctx, before, after(before))
+		if err != nil {
+			return err
+		}
+		for _, o := range found {
+			if enforce && o.Budget.Enforce {
+				return fmt.Errorf("%w: %s would bring the spend to %d of %d",
+					myerror.ErrorBudgetExceeded, monthyear.FormatMonthYear(o.Month), o.Projected, o.Budget.Amount)
+			}
+		}
+		*over = found
+		return nil
+	}, nil
+}
+
+func (u *Usecase) alertBudgets(ctx context.Context, s modelsub.Subscription, over []modelbudget.Overspend) {
+	if len(over) > 0 {
+		u.budgets.Alert(ctx, s, over)
+	}
 }
 
 func (u *Usecase) GetSub(ctx context.Context, id uuid.UUID) (modelsub.Subscription, error) {
 	return u.repo.GetSub(ctx, id)
 }
 
+// UpdateSub replaces the subscription. The status is checked against the
+// state machine; see nextStatus for how it is derived when s.Status is empty.
 func (u *Usecase) UpdateSub(ctx context.Context, id uuid.UUID, s modelsub.Subscription) (modelsub.Subscription, error) {
-	return u.repo.UpdateSub(ctx, id, s)
+	before, err := u.repo.GetSub(ctx, id)
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if s.UserID != before.UserID {
+		if err := u.checkUser(ctx, s.UserID); err != nil {
+			return modelsub.Subscription{}, err
+		}
+	}
+	if err := u.linkService(ctx, &s); err != nil {
+		return modelsub.Subscription{}, err
+	}
+	status, err := nextStatus(&before, s, u.now())
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	s.Status = status
+
+	// Tags are not part of the update and stay as they are.
+	s.Tags = before.Tags
+	var over []modelbudget.Overspend
+	guard, err := u.budgetGuard(ctx, s.UserID, false, func(locked *modelsub.Subscription) modelsub.Subscription {
+		after := s
+		after.Tags = locked.Tags
+		return after
+	}, &over)
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	updated, err := u.repo.UpdateSub(ctx, id, s, guard)
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	u.invalidate(ctx, before.UserID, updated.UserID)
+	u.alertBudgets(ctx, updated, over)
+	return updated, nil
 }
 
 func (u *Usecase) Delete(ctx context.Context, id uuid.UUID) error {
-	return u.repo.Delete(ctx, id)
+	if u.cache == nil {
+		return u.repo.Delete(ctx, id)
+	}
+	// The owner is only known before the subscription is deleted.
+	s, err := u.repo.GetSub(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := u.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	u.invalidate(ctx, s.UserID)
+	return nil
+}
+
+func (u *Usecase) Restore(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (modelsub.Subscription, error) {
+	restored, err := u.repo.Restore(ctx, id, userID)
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	u.invalidate(ctx, restored.UserID)
+	return restored, nil
+}
+
+func (u *Usecase) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	n, err := u.repo.Purge(ctx, deletedBefore)
+	if err == nil && n > 0 && u.cache != nil {
+		u.cache.Clear(ctx)
+	}
+	return n, err
+}
+
+// Pause stops billing from month from (the current month when zero) until
+// the subscription is resumed. A pause from a later month keeps the status
+// until then; ApplyPauses pauses the subscription when the month comes.
+func (u *Usecase) Pause(ctx context.Context, id uuid.UUID, from time.Time) (modelsub.Subscription, error) {
+	s, err := u.repo.GetSub(ctx, id)
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if s.Status == modelsub.StatusPaused || !CanTransition(s.Status, modelsub.StatusPaused) {
+		return modelsub.Subscription{}, fmt.Errorf("%w: %s subscription cannot be paused", myerror.ErrorInvalidTransition, s.Status)
+	}
+
+	from, err = u.pauseMonth(from)
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	if from.Before(s.StartDate) {
+		return modelsub.Subscription{}, fmt.Errorf("%w: pause must start on or after start_date", myerror.ErrorValidation)
+	}
+	if s.EndDate != nil && from.After(*s.EndDate) {
+		return modelsub.Subscription{}, fmt.Errorf("%w: pause must start on or before end_date", myerror.ErrorValidation)
+	}
+	next := modelsub.StatusPaused
+	if from.After(monthOf(u.now())) {
+		next = s.Status
+	}
+	paused, err := u.repo.Pause(ctx, id, s.Status, from, next)
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	u.invalidate(ctx, paused.UserID)
+	return paused, nil
+}
+
+// Resume ends the open pause; month from (the current month when zero) is
+// billed again. A paused subscription stays paused until then, when
+// ApplyPauses resumes it; a pause that has not started yet is shortened or,
+// resumed before it starts, dropped.
+func (u *Usecase) Resume(ctx context.Context, id uuid.UUID, from time.Time) (modelsub.Subscription, error) {
+	s, err := u.repo.GetSub(ctx, id)
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+
+	from, err = u.pauseMonth(from)
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	next := s.Status
+	if s.Status == modelsub.StatusPaused && !from.After(monthOf(u.now())) {
+		next = modelsub.StatusActive
+	}
+	resumed, err := u.repo.Resume(ctx, id, s.Status, from, next)
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	u.invalidate(ctx, resumed.UserID)
+	return resumed, nil
+}
+
+func (u *Usecase) Pauses(ctx context.Context, id uuid.UUID) ([]modelsub.Pause, error) {
+	return u.repo.ListPauses(ctx, id)
+}
+
+// pauseMonth defaults a pause or resume month to the current one and rejects
+// months in the past, which would rewrite what was already billed.
+func (u *Usecase) pauseMonth(m time.Time) (time.Time, error) {
+	now := u.now().UTC()
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if m.IsZero() {
+		return current, nil
+	}
+	if m.Before(current) {
+		return time.Time{}, fmt.Errorf("%w: month must not be in the past", myerror.ErrorValidation)
+	}
+	return m, nil
+}
+
+// ExpireEnded moves every subscription past its end_date to expired and
+// returns how many were changed.
+func (u *Usecase) ExpireEnded(ctx context.Context) (int, error) {
+	return u.moveAll(ctx, u.repo.ListEnded, modelsub.StatusExpired)
+}
+
+// EndTrials moves every subscription whose trial is over from trial to
+// active and returns how many were changed.
+func (u *Usecase) EndTrials(ctx context.Context) (int, error) {
+	return u.moveAll(ctx, u.repo.ListTrialsEnded, modelsub.StatusActive)
+}
+
+// ApplyPauses pauses the subscriptions whose pause has started and resumes
+// the ones whose pause is over, and returns how many were changed.
+func (u *Usecase) ApplyPauses(ctx context.Context) (int, error) {
+	started, err := u.moveAll(ctx, u.repo.ListPausesStarted, modelsub.StatusPaused)
+	if err != nil {
+		return started, err
+	}
+	ended, err := u.moveAll(ctx, u.repo.ListPausesEnded, modelsub.StatusActive)
+	return started + ended, err
+}
+
+// moveAll sets status to on every subscription returned by list for the
+// current month, each in its own tenant. Subscriptions changed concurrently
+// are skipped and picked up on the next run.
+func (u *Usecase) moveAll(
+	ctx context.Context,
+	list func(ctx context.Context, cutoff time.Time, limit int) ([]modelsub.Subscription, error),
+	to string,
+) (int, error) {
+	cutoff := monthOf(u.now())
+
+	moved := 0
+	for {
+		subs, err := list(ctx, cutoff, statusBatch)
+		if err != nil {
+			return moved, err
+		}
+
+		changed := 0
+		for _, s := range subs {
+			_, err := u.repo.SetStatus(modeltenant.WithTenant(ctx, s.TenantID), s.ID, s.Status, to)
+			if errors.Is(err, myerror.ErrorInvalidTransition) || errors.Is(err, myerror.ErrorNotFound) {
+				continue
+			}
+			if err != nil {
+				return moved, fmt.Errorf("set %s status %s: %w", s.ID, to, err)
+			}
+			changed++
+		}
+		moved += changed
+
+		if len(subs) < statusBatch || changed == 0 {
+			return moved, nil
+		}
+	}
+}
+
+// TrialsEnding returns the first paid charge of every running trial that
+// falls within the given window from now.
+func (u *Usecase) TrialsEnding(ctx context.Context, f modelsub.UpcomingFilter, within time.Duration) ([]modelsub.Renewal, error) {
+	renewals, err := u.Upcoming(ctx, f, within)
+	if err != nil {
+		return nil, err
+	}
+
+	out := renewals[:0]
+	for _, rn := range renewals {
+		s := rn.Subscription
+		if s.TrialUntil != nil && rn.ChargeDate.Equal(s.TrialUntil.AddDate(0, 1, 0)) {
+			out = append(out, rn)
+		}
+	}
+	return out, nil
 }
 
 func (u *Usecase) List(ctx context.Context, f modelsub.ListFilter) ([]modelsub.Subscription, int, error) {
+	f.ServiceName, f.ServiceID = u.serviceFilter(ctx, f.ServiceName, f.ServiceID)
+	f.Tags = filterTags(f.Tags)
 	return u.repo.List(ctx, f)
 }
 
 func (u *Usecase) Summary(ctx context.Context, f modelsub.SummaryFilter) (int64, error) {
-	return u.repo.Summary(ctx, f)
+	f.ServiceName, f.ServiceID = u.serviceFilter(ctx, f.ServiceName, f.ServiceID)
+	f.Tags = filterTags(f.Tags)
+	if u.cache == nil {
+		return u.repo.Summary(ctx, f)
+	}
+
+	group, key := summaryKey(modeltenant.From(ctx), f)
+	if total, ok := u.cache.Get(ctx, key); ok {
+		return total, nil
+	}
+	// A write committed while the total is read invalidates the group, and
+	// the total it may have missed is not cached.
+	gen := u.cache.Generation(ctx, group)
+	total, err := u.repo.Summary(ctx, f)
+	if err != nil {
+		return 0, err
+	}
+	u.cache.Set(ctx, group, key, total, gen)
+	return total, nil
+}
+
+// SummaryByTag splits the summary by tag. Subscriptions with several tags
+// count towards each of them, so the totals may add up to more than Summary.
+func (u *Usecase) SummaryByTag(ctx context.Context, f modelsub.SummaryFilter) ([]modelsub.TagTotal, error) {
+	f.ServiceName, f.ServiceID = u.serviceFilter(ctx, f.ServiceName, f.ServiceID)
+	f.Tags = filterTags(f.Tags)
+	return u.repo.SummaryByTag(ctx, f)
+}
+
+// SetTags replaces the tags of a subscription. Tags the user does not have
+// yet are created.
+func (u *Usecase) SetTags(ctx context.Context, id uuid.UUID, names []string) (modelsub.Subscription, error) {
+	tags := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, n := range names {
+		name, err := modeltag.ParseName(n)
+		if err != nil {
+			return modelsub.Subscription{}, fmt.Errorf("%w: %s", myerror.ErrorValidation, err)
+		}
+		if key := strings.ToLower(name); !seen[key] {
+			seen[key] = true
+			tags = append(tags, name)
+		}
+	}
+	if len(tags) > maxTags {
+		return modelsub.Subscription{}, fmt.Errorf("%w: at most %d tags per subscription", myerror.ErrorValidation, maxTags)
+	}
+
+	var over []modelbudget.Overspend
+	var guard modelsub.Guard
+	if u.budgets != nil {
+		before, err := u.repo.GetSub(ctx, id)
+		if err != nil {
+			return modelsub.Subscription{}, err
+		}
+		guard, err = u.budgetGuard(ctx, before.UserID, false, func(locked *modelsub.Subscription) modelsub.Subscription {
+			after := *locked
+			after.Tags = tags
+			return after
+		}, &over)
+		if err != nil {
+			return modelsub.Subscription{}, err
+		}
+	}
+	updated, err := u.repo.SetTags(ctx, id, tags, guard)
+	if err != nil {
+		return modelsub.Subscription{}, err
+	}
+	u.invalidate(ctx, updated.UserID)
+	u.alertBudgets(ctx, updated, over)
+	return updated, nil
+}
+
+// filterTags drops blank and repeated names from a tag filter.
+func filterTags(names []string) []string {
+	var out []string
+	seen := make(map[string]bool, len(names))
+	for _, n := range names {
+		n = strings.Join(strings.Fields(n), " ")
+		if key := strings.ToLower(n); n != "" && !seen[key] {
+			seen[key] = true
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func (u *Usecase) AuditLog(ctx context.Context, f modelaudit.Filter) ([]modelaudit.Entry, error) {
+	return u.repo.AuditLog(ctx, f)
+}
+
+// Upcoming returns the next charge of every active subscription matching f
+// that falls within the given window from now, earliest first. f.From and
+// f.To are set from the window.
+func (u *Usecase) Upcoming(ctx context.Context, f modelsub.UpcomingFilter, within time.Duration) ([]modelsub.Renewal, error) {
+	f.From = u.now().UTC()
+	f.To = f.From.Add(within)
+	f.ServiceName, f.ServiceID = u.serviceFilter(ctx, f.ServiceName, f.ServiceID)
+
+	subs, err := u.repo.ListActive(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]modelsub.Renewal, 0, len(subs))
+	for _, s := range subs {
+		next, ok := s.NextCharge(f.From)
+		if !ok || next.After(f.To) {
+			continue
+		}
+		out = append(out, modelsub.Renewal{Subscription: s, ChargeDate: next, Amount: s.PriceFor(next)})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].ChargeDate.Before(out[j].ChargeDate)
+	})
+	return out, nil
 }
@@ -0,0 +1,556 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	modelbudget "test_task/internal/domain/models/budget"
+	modelservice "test_task/internal/domain/models/service"
+	modelsub "test_task/internal/domain/models/subscription"
+	modeluser "test_task/internal/domain/models/user"
+	myerror "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+type fakeRepo struct {
+	RepoI
+	active []modelsub.Subscription
+	filter modelsub.UpcomingFilter
+}
+
+func (r *fakeRepo) ListActive(_ context.Context, f modelsub.UpcomingFilter) ([]modelsub.Subscription, error) {
+	r.filter = f
+	return r.active, nil
+}
+
+func month(y int, m time.Month) time.Time {
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestUpcoming_NextChargeDates(t *testing.T) {
+	now := time.Date(2025, time.July, 20, 9, 0, 0, 0, time.UTC)
+	endedJuly := month(2025, time.July)
+
+	running := modelsub.Subscription{ID: uuid.New(), ServiceName: "Netflix", StartDate: month(2025, time.January)}
+	future := modelsub.Subscription{ID: uuid.New(), ServiceName: "Spotify", StartDate: month(2025, time.September)}
+	ending := modelsub.Subscription{ID: uuid.New(), ServiceName: "Kinopoisk", StartDate: month(2025, time.January), EndDate: &endedJuly}
+	later := modelsub.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", StartDate: month(2025, time.December)}
+
+	repo := &fakeRepo{active: []modelsub.Subscription{future, ending, running, later}}
+	u := New(repo)
+	u.now = func() time.Time { return now }
+
+	got, err := u.Upcoming(context.Background(), modelsub.UpcomingFilter{}, 60*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Upcoming: %v", err)
+	}
+	if !repo.filter.From.Equal(now) || !repo.filter.To.Equal(now.Add(60*24*time.Hour)) {
+		t.Fatalf("unexpected window %v..%v", repo.filter.From, repo.filter.To)
+	}
+
+	want := []struct {
+		id   uuid.UUID
+		date time.Time
+	}{
+		{running.ID, month(2025, time.August)},
+		{future.ID, month(2025, time.September)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %d renewals, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Subscription.ID != w.id || !got[i].ChargeDate.Equal(w.date) {
+			t.Fatalf("renewal %d: want %s on %v, got %s on %v", i, w.id, w.date, got[i].Subscription.ID, got[i].ChargeDate)
+		}
+	}
+}
+
+func TestNextCharge_FirstOfMonthIsToday(t *testing.T) {
+	s := modelsub.Subscription{StartDate: month(2025, time.January)}
+
+	got, ok := s.NextCharge(month(2025, time.July))
+	if !ok || !got.Equal(month(2025, time.July)) {
+		t.Fatalf("want charge today, got %v %v", got, ok)
+	}
+}
+
+type statusRepo struct {
+	RepoI
+	current modelsub.Subscription
+	saved   modelsub.Subscription
+	ended   []modelsub.Subscription
+	set     map[uuid.UUID]string
+}
+
+func (r *statusRepo) GetSub(context.Context, uuid.UUID) (modelsub.Subscription, error) {
+	return r.current, nil
+}
+
+func (r *statusRepo) UpdateSub(_ context.Context, _ uuid.UUID, s modelsub.Subscription, _ modelsub.Guard) (modelsub.Subscription, error) {
+	r.saved = s
+	return s, nil
+}
+
+func (r *statusRepo) ListEnded(context.Context, time.Time, int) ([]modelsub.Subscription, error) {
+	out := r.ended
+	r.ended = nil
+	return out, nil
+}
+
+func (r *statusRepo) SetStatus(_ context.Context, id uuid.UUID, from, to string) (modelsub.Subscription, error) {
+	if from == modelsub.StatusPaused {
+		return modelsub.Subscription{}, myerror.ErrorInvalidTransition
+	}
+	r.set[id] = to
+	return modelsub.Subscription{ID: id, Status: to}, nil
+}
+
+func TestUpdateSub_StatusMachine(t *testing.T) {
+	now := time.Date(2025, time.July, 20, 9, 0, 0, 0, time.UTC)
+	dec := month(2025, time.December)
+	may := month(2025, time.May)
+
+	cases := []struct {
+		name    string
+		current modelsub.Subscription
+		next    modelsub.Subscription
+		want    string
+		wantErr error
+	}{
+		{"end date cancels", modelsub.Subscription{Status: modelsub.StatusActive}, modelsub.Subscription{EndDate: &dec}, modelsub.StatusCancelled, nil},
+		{"keeps paused", modelsub.Subscription{Status: modelsub.StatusPaused}, modelsub.Subscription{}, modelsub.StatusPaused, nil},
+		{"dropping end date reactivates", modelsub.Subscription{Status: modelsub.StatusCancelled, EndDate: &dec}, modelsub.Subscription{}, modelsub.StatusActive, nil},
+		{"past end date expires", modelsub.Subscription{Status: modelsub.StatusActive}, modelsub.Subscription{EndDate: &may}, modelsub.StatusExpired, nil},
+		{"pause needs pause call", modelsub.Subscription{Status: modelsub.StatusActive}, modelsub.Subscription{Status: modelsub.StatusPaused}, "", myerror.ErrorInvalidTransition},
+		{"resume needs resume call", modelsub.Subscription{Status: modelsub.StatusPaused}, modelsub.Subscription{Status: modelsub.StatusActive}, "", myerror.ErrorInvalidTransition},
+		{"cancel while paused", modelsub.Subscription{Status: modelsub.StatusPaused}, modelsub.Subscription{EndDate: &dec}, modelsub.StatusCancelled, nil},
+		{"expired cannot pause", modelsub.Subscription{Status: modelsub.StatusExpired, EndDate: &may}, modelsub.Subscription{Status: modelsub.StatusPaused}, "", myerror.ErrorInvalidTransition},
+		{"cancelled cannot go to trial", modelsub.Subscription{Status: modelsub.StatusCancelled, EndDate: &dec}, modelsub.Subscription{EndDate: &dec, TrialUntil: &dec, Status: modelsub.StatusTrial}, "", myerror.ErrorInvalidTransition},
+		{"trial over turns active", modelsub.Subscription{Status: modelsub.StatusTrial, TrialUntil: &may}, modelsub.Subscription{TrialUntil: &may}, modelsub.StatusActive, nil},
+		{"trial needs running trial", modelsub.Subscription{Status: modelsub.StatusActive}, modelsub.Subscription{TrialUntil: &may, Status: modelsub.StatusTrial}, "", myerror.ErrorValidation},
+		{"ended must stay expired", modelsub.Subscription{Status: modelsub.StatusExpired, EndDate: &may}, modelsub.Subscription{EndDate: &may, Status: modelsub.StatusActive}, "", myerror.ErrorInvalidTransition},
+		{"cancel needs end date", modelsub.Subscription{Status: modelsub.StatusActive}, modelsub.Subscription{Status: modelsub.StatusCancelled}, "", myerror.ErrorValidation},
+		{"unknown status", modelsub.Subscription{Status: modelsub.StatusActive}, modelsub.Subscription{Status: "frozen"}, "", myerror.ErrorValidation},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &statusRepo{current: tc.current}
+			u := New(repo)
+			u.now = func() time.Time { return now }
+
+			got, err := u.UpdateSub(context.Background(), uuid.New(), tc.next)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("want %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateSub: %v", err)
+			}
+			if got.Status != tc.want || repo.saved.Status != tc.want {
+				t.Fatalf("want status %s, got %s", tc.want, got.Status)
+			}
+		})
+	}
+}
+
+func TestExpireEnded_SkipsChangedSubscriptions(t *testing.T) {
+	active := modelsub.Subscription{ID: uuid.New(), Status: modelsub.StatusActive}
+	paused := modelsub.Subscription{ID: uuid.New(), Status: modelsub.StatusPaused}
+
+	repo := &statusRepo{ended: []modelsub.Subscription{active, paused}, set: map[uuid.UUID]string{}}
+	u := New(repo)
+
+	n, err := u.ExpireEnded(context.Background())
+	if err != nil {
+		t.Fatalf("ExpireEnded: %v", err)
+	}
+	if n != 1 || repo.set[active.ID] != modelsub.StatusExpired {
+		t.Fatalf("want only %s expired, got %d %v", active.ID, n, repo.set)
+	}
+}
+
+type pauseRepo struct {
+	RepoI
+	current modelsub.Subscription
+	open    bool
+	from    time.Time
+	next    string
+}
+
+func (r *pauseRepo) GetSub(context.Context, uuid.UUID) (modelsub.Subscription, error) {
+	return r.current, nil
+}
+
+func (r *pauseRepo) Pause(_ context.Context, _ uuid.UUID, status string, from time.Time, next string) (modelsub.Subscription, error) {
+	if status != r.current.Status {
+		return modelsub.Subscription{}, myerror.ErrorInvalidTransition
+	}
+	r.from, r.next = from, next
+	return modelsub.Subscription{Status: next}, nil
+}
+
+func (r *pauseRepo) Resume(_ context.Context, _ uuid.UUID, status string, from time.Time, next string) (modelsub.Subscription, error) {
+	if status != r.current.Status || !r.open {
+		return modelsub.Subscription{}, myerror.ErrorInvalidTransition
+	}
+	r.from, r.next = from, next
+	return modelsub.Subscription{Status: next}, nil
+}
+
+func TestPause_Validation(t *testing.T) {
+	now := time.Date(2025, time.July, 20, 9, 0, 0, 0, time.UTC)
+
+	repo := &pauseRepo{current: modelsub.Subscription{Status: modelsub.StatusActive, StartDate: month(2025, time.January)}}
+	u := New(repo)
+	u.now = func() time.Time { return now }
+
+	if _, err := u.Pause(context.Background(), uuid.New(), time.Time{}); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if !repo.from.Equal(month(2025, time.July)) {
+		t.Fatalf("pause must default to the current month, got %v", repo.from)
+	}
+
+	if _, err := u.Pause(context.Background(), uuid.New(), month(2025, time.June)); !errors.Is(err, myerror.ErrorValidation) {
+		t.Fatalf("past month: want ErrorValidation, got %v", err)
+	}
+
+	repo.current.Status = modelsub.StatusPaused
+	if _, err := u.Pause(context.Background(), uuid.New(), time.Time{}); !errors.Is(err, myerror.ErrorInvalidTransition) {
+		t.Fatalf("already paused: want ErrorInvalidTransition, got %v", err)
+	}
+
+	repo.current.Status = modelsub.StatusCancelled
+	if _, err := u.Resume(context.Background(), uuid.New(), time.Time{}); !errors.Is(err, myerror.ErrorInvalidTransition) {
+		t.Fatalf("not paused: want ErrorInvalidTransition, got %v", err)
+	}
+}
+
+func TestPauseResume_LaterMonthKeepsStatus(t *testing.T) {
+	now := time.Date(2025, time.July, 20, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		status string
+		resume bool
+		from   time.Time
+		want   string
+	}{
+		{"pause now", modelsub.StatusActive, false, month(2025, time.July), modelsub.StatusPaused},
+		{"pause later", modelsub.StatusTrial, false, month(2025, time.September), modelsub.StatusTrial},
+		{"resume now", modelsub.StatusPaused, true, time.Time{}, modelsub.StatusActive},
+		{"resume later", modelsub.StatusPaused, true, month(2025, time.October), modelsub.StatusPaused},
+		{"resume before the pause starts", modelsub.StatusActive, true, month(2025, time.August), modelsub.StatusActive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &pauseRepo{current: modelsub.Subscription{Status: tt.status, StartDate: month(2025, time.January)}, open: tt.resume}
+			u := New(repo)
+			u.now = func() time.Time { return now }
+
+			var got modelsub.Subscription
+			var err error
+			if tt.resume {
+				got, err = u.Resume(context.Background(), uuid.New(), tt.from)
+			} else {
+				got, err = u.Pause(context.Background(), uuid.New(), tt.from)
+			}
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+			if got.Status != tt.want || repo.next != tt.want {
+				t.Fatalf("want status %s, got %s", tt.want, got.Status)
+			}
+		})
+	}
+}
+
+type applyPausesRepo struct {
+	RepoI
+	started, ended []modelsub.Subscription
+	set            map[uuid.UUID]string
+}
+
+func (r *applyPausesRepo) ListPausesStarted(context.Context, time.Time, int) ([]modelsub.Subscription, error) {
+	out := r.started
+	r.started = nil
+	return out, nil
+}
+
+func (r *applyPausesRepo) ListPausesEnded(context.Context, time.Time, int) ([]modelsub.Subscription, error) {
+	out := r.ended
+	r.ended = nil
+	return out, nil
+}
+
+func (r *applyPausesRepo) SetStatus(_ context.Context, id uuid.UUID, _, to string) (modelsub.Subscription, error) {
+	r.set[id] = to
+	return modelsub.Subscription{ID: id, Status: to}, nil
+}
+
+func TestApplyPauses(t *testing.T) {
+	starting := modelsub.Subscription{ID: uuid.New(), Status: modelsub.StatusActive}
+	ending := modelsub.Subscription{ID: uuid.New(), Status: modelsub.StatusPaused}
+
+	repo := &applyPausesRepo{started: []modelsub.Subscription{starting}, ended: []modelsub.Subscription{ending}, set: map[uuid.UUID]string{}}
+	n, err := New(repo).ApplyPauses(context.Background())
+	if err != nil {
+		t.Fatalf("ApplyPauses: %v", err)
+	}
+	if n != 2 || repo.set[starting.ID] != modelsub.StatusPaused || repo.set[ending.ID] != modelsub.StatusActive {
+		t.Fatalf("want one paused and one resumed, got %d %v", n, repo.set)
+	}
+}
+
+func TestTrialsEnding_FirstPaidCharge(t *testing.T) {
+	now := time.Date(2025, time.July, 28, 9, 0, 0, 0, time.UTC)
+	july := month(2025, time.July)
+	sept := month(2025, time.September)
+	price := 99
+
+	ending := modelsub.Subscription{ID: uuid.New(), Price: 300, StartDate: month(2025, time.June), TrialUntil: &july, TrialPrice: &price}
+	running := modelsub.Subscription{ID: uuid.New(), Price: 400, StartDate: month(2025, time.June), TrialUntil: &sept}
+	regular := modelsub.Subscription{ID: uuid.New(), Price: 500, StartDate: month(2025, time.January)}
+
+	u := New(&fakeRepo{active: []modelsub.Subscription{ending, running, regular}})
+	u.now = func() time.Time { return now }
+
+	renewals, err := u.Upcoming(context.Background(), modelsub.UpcomingFilter{}, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Upcoming: %v", err)
+	}
+	amounts := map[uuid.UUID]int{}
+	for _, rn := range renewals {
+		amounts[rn.Subscription.ID] = rn.Amount
+	}
+	if amounts[ending.ID] != 300 || amounts[running.ID] != 0 || amounts[regular.ID] != 500 {
+		t.Fatalf("unexpected amounts %v", amounts)
+	}
+
+	got, err := u.TrialsEnding(context.Background(), modelsub.UpcomingFilter{}, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("TrialsEnding: %v", err)
+	}
+	if len(got) != 1 || got[0].Subscription.ID != ending.ID || !got[0].ChargeDate.Equal(month(2025, time.August)) {
+		t.Fatalf("want only %s ending on 2025-08-01, got %+v", ending.ID, got)
+	}
+}
+
+type createRepo struct {
+	RepoI
+	created modelsub.Subscription
+	guarded int
+}
+
+func (r *createRepo) Create(ctx context.Context, s modelsub.Subscription, guard modelsub.Guard) (modelsub.Subscription, error) {
+	if guard != nil {
+		r.guarded++
+		if err := guard(ctx, nil); err != nil {
+			return modelsub.Subscription{}, err
+		}
+	}
+	r.created = s
+	return s, nil
+}
+
+type fakeCatalog map[string]modelservice.Service
+
+func (c fakeCatalog) Get(_ context.Context, id uuid.UUID) (modelservice.Service, error) {
+	for _, s := range c {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return modelservice.Service{}, myerror.ErrorNotFound
+}
+
+func (c fakeCatalog) Resolve(_ context.Context, name string) (modelservice.Service, error) {
+	s, ok := c[modelservice.Normalize(name)]
+	if !ok {
+		return modelservice.Service{}, myerror.ErrorNotFound
+	}
+	return s, nil
+}
+
+func TestCreate_LinksCatalogService(t *testing.T) {
+	yearly := 3000
+	netflix := modelservice.Service{ID: uuid.New(), Name: "Netflix", BillingPeriod: modelservice.PeriodMonth}
+	plus := modelservice.Service{ID: uuid.New(), Name: "Yandex Plus", DefaultPrice: &yearly, BillingPeriod: modelservice.PeriodYear}
+	catalog := fakeCatalog{"яндекс плюс": plus, "yandex plus": plus, "netflix": netflix}
+	start := month(2025, time.July)
+	unknownID := uuid.New()
+
+	tests := []struct {
+		name      string
+		in        modelsub.Subscription
+		wantName  string
+		wantID    *uuid.UUID
+		wantPrice int
+		wantErr   error
+	}{
+		{"alias resolves with catalog price", modelsub.Subscription{ServiceName: "  ЯНДЕКС   плюс", PriceFromCatalog: true}, "Yandex Plus", &plus.ID, 250, nil},
+		{"explicit price wins", modelsub.Subscription{ServiceName: "yandex plus", Price: 199}, "Yandex Plus", &plus.ID, 199, nil},
+		{"service_id sets the name", modelsub.Subscription{ServiceID: &netflix.ID, Price: 700}, "Netflix", &netflix.ID, 700, nil},
+		{"unknown name stays free text", modelsub.Subscription{ServiceName: "Kinopoisk", Price: 300}, "Kinopoisk", nil, 300, nil},
+		{"no catalog price", modelsub.Subscription{ServiceName: "Netflix", PriceFromCatalog: true}, "", nil, 0, myerror.ErrorValidation},
+		{"unknown service_id", modelsub.Subscription{ServiceID: &unknownID, Price: 100}, "", nil, 0, myerror.ErrorValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &createRepo{}
+			u := New(repo, WithCatalog(catalog))
+			u.now = func() time.Time { return start }
+
+			tt.in.StartDate = start
+			_, err := u.Create(context.Background(), tt.in)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("want %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			got := repo.created
+			if got.ServiceName != tt.wantName || got.Price != tt.wantPrice {
+				t.Fatalf("want %s/%d, got %s/%d", tt.wantName, tt.wantPrice, got.ServiceName, got.Price)
+			}
+			if (got.ServiceID == nil) != (tt.wantID == nil) || (got.ServiceID != nil && *got.ServiceID != *tt.wantID) {
+				t.Fatalf("want service_id %v, got %v", tt.wantID, got.ServiceID)
+			}
+		})
+	}
+}
+
+type tagsRepo struct {
+	RepoI
+	names []string
+}
+
+func (r *tagsRepo) SetTags(_ context.Context, id uuid.UUID, names []string, _ modelsub.Guard) (modelsub.Subscription, error) {
+	r.names = names
+	return modelsub.Subscription{ID: id, Tags: names}, nil
+}
+
+func TestSetTags_NormalizesNames(t *testing.T) {
+	repo := &tagsRepo{}
+	u := New(repo)
+
+	if _, err := u.SetTags(context.Background(), uuid.New(), []string{"  Work ", "work", "Home  office"}); err != nil {
+		t.Fatalf("SetTags: %v", err)
+	}
+	if len(repo.names) != 2 || repo.names[0] != "Work" || repo.names[1] != "Home office" {
+		t.Fatalf("want [Work Home office], got %q", repo.names)
+	}
+
+	tooMany := make([]string, maxTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag-%d", i)
+	}
+	for _, names := range [][]string{{" "}, {"a,b"}, tooMany} {
+		if _, err := u.SetTags(context.Background(), uuid.New(), names); !errors.Is(err, myerror.ErrorValidation) {
+			t.Fatalf("%q: want ErrorValidation, got %v", names, err)
+		}
+	}
+}
+
+type fakeBudgets struct {
+	budgets []modelbudget.Budget
+	over    []modelbudget.Overspend
+	alerted []modelbudget.Overspend
+}
+
+func (b *fakeBudgets) List(context.Context, *uuid.UUID) ([]modelbudget.Budget, error) {
+	return b.budgets, nil
+}
+
+func (b *fakeBudgets) Check(context.Context, *modelsub.Subscription, modelsub.Subscription) ([]modelbudget.Overspend, error) {
+	return b.over, nil
+}
+
+func (b *fakeBudgets) Alert(_ context.Context, _ modelsub.Subscription, over []modelbudget.Overspend) {
+	b.alerted = append(b.alerted, over...)
+}
+
+func TestCreate_Budgets(t *testing.T) {
+	start := month(2025, time.July)
+	alertOnly := modelbudget.Overspend{Budget: modelbudget.Budget{ID: uuid.New(), Amount: 1000}, Month: start, Projected: 1100}
+	enforced := modelbudget.Overspend{Budget: modelbudget.Budget{ID: uuid.New(), Amount: 500, Enforce: true}, Month: start, Projected: 1100}
+
+	// Without budgets the write is not guarded and takes no lock.
+	repo := &createRepo{}
+	u := New(repo, WithBudgets(&fakeBudgets{}))
+	u.now = func() time.Time { return start }
+	if _, err := u.Create(context.Background(), modelsub.Subscription{ServiceName: "Netflix", Price: 700, StartDate: start}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if repo.guarded != 0 {
+		t.Fatalf("a user without budgets must not be guarded")
+	}
+
+	repo = &createRepo{}
+	budgets := &fakeBudgets{budgets: []modelbudget.Budget{alertOnly.Budget}, over: []modelbudget.Overspend{alertOnly}}
+	u = New(repo, WithBudgets(budgets))
+	u.now = func() time.Time { return start }
+
+	if _, err := u.Create(context.Background(), modelsub.Subscription{ServiceName: "Netflix", Price: 700, StartDate: start}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if repo.guarded != 1 {
+		t.Fatalf("the budget check must guard the write")
+	}
+	if len(budgets.alerted) != 1 || budgets.alerted[0].Budget.ID != alertOnly.Budget.ID {
+		t.Fatalf("want an alert for the overspent budget, got %+v", budgets.alerted)
+	}
+
+	repo = &createRepo{}
+	budgets = &fakeBudgets{budgets: []modelbudget.Budget{alertOnly.Budget, enforced.Budget}, over: []modelbudget.Overspend{alertOnly, enforced}}
+	u = New(repo, WithBudgets(budgets))
+	u.now = func() time.Time { return start }
+
+	_, err := u.Create(context.Background(), modelsub.Subscription{ServiceName: "Netflix", Price: 700, StartDate: start})
+	if !errors.Is(err, myerror.ErrorBudgetExceeded) {
+		t.Fatalf("want ErrorBudgetExceeded, got %v", err)
+	}
+	if repo.created.ServiceName != "" || len(budgets.alerted) != 0 {
+		t.Fatalf("rejected subscription must not be created nor alerted")
+	}
+}
+
+type fakeUsers map[uuid.UUID]bool
+
+func (f fakeUsers) Get(_ context.Context, id uuid.UUID) (modeluser.User, error) {
+	if !f[id] {
+		return modeluser.User{}, myerror.ErrorNotFound
+	}
+	return modeluser.User{ID: id}, nil
+}
+
+func TestCreate_RequiresRegisteredUser(t *testing.T) {
+	registered := uuid.New()
+	repo := &createRepo{}
+	u := New(repo, WithUsers(fakeUsers{registered: true}))
+	u.now = func() time.Time { return time.Date(2025, time.July, 10, 0, 0, 0, 0, time.UTC) }
+
+	s := modelsub.Subscription{ServiceName: "Netflix", Price: 700, StartDate: month(2025, time.July)}
+	s.UserID = uuid.New()
+	if _, err := u.Create(context.Background(), s); !errors.Is(err, myerror.ErrorValidation) {
+		t.Fatalf("unregistered user: want ErrorValidation, got %v", err)
+	}
+
+	s.UserID = registered
+	if _, err := u.Create(context.Background(), s); err != nil {
+		t.Fatalf("registered user: %v", err)
+	}
+	if repo.created.UserID != registered {
+		t.Fatalf("want subscription of %s, got %+v", registered, repo.created)
+	}
+}
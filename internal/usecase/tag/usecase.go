@@ -0,0 +1,76 @@
+package tag
+
+import (
+	"context"
+	"fmt"
+
+	modeltag "test_task/internal/domain/models/tag"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+type RepoI interface {
+	Create(ctx context.Context, t modeltag.Tag) (modeltag.Tag, error)
+	Get(ctx context.Context, id uuid.UUID) (modeltag.Tag, error)
+	List(ctx context.Context, userID *uuid.UUID) ([]modeltag.Tag, error)
+	Rename(ctx context.Context, id uuid.UUID, name string) (modeltag.Tag, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// SubscriptionsI drops the cached summary totals of a user whose tag was
+// renamed or deleted, since tag filters match by name.
+type SubscriptionsI interface {
+	InvalidateUser(ctx context.Context, userID uuid.UUID)
+}
+
+type Usecase struct {
+	repo RepoI
+	subs SubscriptionsI
+}
+
+func New(repo RepoI, subs SubscriptionsI) *Usecase {
+	return &Usecase{repo: repo, subs: subs}
+}
+
+func (u *Usecase) Create(ctx context.Context, t modeltag.Tag) (modeltag.Tag, error) {
+	name, err := modeltag.ParseName(t.Name)
+	if err != nil {
+		return modeltag.Tag{}, fmt.Errorf("%w: %s", myerrors.ErrorValidation, err)
+	}
+	t.Name = name
+	return u.repo.Create(ctx, t)
+}
+
+func (u *Usecase) Get(ctx context.Context, id uuid.UUID) (modeltag.Tag, error) {
+	return u.repo.Get(ctx, id)
+}
+
+func (u *Usecase) List(ctx context.Context, userID *uuid.UUID) ([]modeltag.Tag, error) {
+	return u.repo.List(ctx, userID)
+}
+
+func (u *Usecase) Rename(ctx context.Context, id uuid.UUID, name string) (modeltag.Tag, error) {
+	name, err := modeltag.ParseName(name)
+	if err != nil {
+		return modeltag.Tag{}, fmt.Errorf("%w: %s", myerrors.ErrorValidation, err)
+	}
+	t, err := u.repo.Rename(ctx, id, name)
+	if err != nil {
+		return modeltag.Tag{}, err
+	}
+	u.subs.InvalidateUser(ctx, t.UserID)
+	return t, nil
+}
+
+func (u *Usecase) Delete(ctx context.Context, id uuid.UUID) error {
+	t, err := u.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := u.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	u.subs.InvalidateUser(ctx, t.UserID)
+	return nil
+}
@@ -0,0 +1,72 @@
+package tag
+
+import (
+	"context"
+	"testing"
+
+	modeltag "test_task/internal/domain/models/tag"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+type memRepo struct {
+	RepoI
+	tags map[uuid.UUID]modeltag.Tag
+}
+
+func (r *memRepo) Get(_ context.Context, id uuid.UUID) (modeltag.Tag, error) {
+	t, ok := r.tags[id]
+	if !ok {
+		return modeltag.Tag{}, myerrors.ErrorNotFound
+	}
+	return t, nil
+}
+
+func (r *memRepo) Rename(_ context.Context, id uuid.UUID, name string) (modeltag.Tag, error) {
+	t, ok := r.tags[id]
+	if !ok {
+		return modeltag.Tag{}, myerrors.ErrorNotFound
+	}
+	t.Name = name
+	r.tags[id] = t
+	return t, nil
+}
+
+func (r *memRepo) Delete(_ context.Context, id uuid.UUID) error {
+	delete(r.tags, id)
+	return nil
+}
+
+type memSubs struct {
+	invalidated []uuid.UUID
+}
+
+func (s *memSubs) InvalidateUser(_ context.Context, userID uuid.UUID) {
+	s.invalidated = append(s.invalidated, userID)
+}
+
+func TestRenameDelete_InvalidateSummaries(t *testing.T) {
+	user := uuid.New()
+	tag := modeltag.Tag{ID: uuid.New(), UserID: user, Name: "work"}
+	subs := &memSubs{}
+	u := New(&memRepo{tags: map[uuid.UUID]modeltag.Tag{tag.ID: tag}}, subs)
+	ctx := context.Background()
+
+	if _, err := u.Rename(ctx, tag.ID, "job"); err != nil {
+		t.Fatalf("Rename error: %v", err)
+	}
+	if err := u.Delete(ctx, tag.ID); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if len(subs.invalidated) != 2 || subs.invalidated[0] != user || subs.invalidated[1] != user {
+		t.Fatalf("want the totals of %s dropped twice, got %v", user, subs.invalidated)
+	}
+
+	if err := u.Delete(ctx, tag.ID); err == nil {
+		t.Fatalf("want not found for a deleted tag")
+	}
+	if len(subs.invalidated) != 2 {
+		t.Fatalf("failed deletes must not drop totals, got %v", subs.invalidated)
+	}
+}
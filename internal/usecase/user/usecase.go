@@ -0,0 +1,111 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	modeluser "test_task/internal/domain/models/user"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+type RepoI interface {
+	Create(ctx context.Context, u modeluser.User) (modeluser.User, error)
+	Get(ctx context.Context, id uuid.UUID) (modeluser.User, error)
+	List(ctx context.Context, f modeluser.Filter) ([]modeluser.User, error)
+	Update(ctx context.Context, u modeluser.User) (modeluser.User, error)
+	Anonymize(ctx context.Context, id uuid.UUID) (modeluser.User, error)
+	Delete(ctx context.Context, id uuid.UUID, mode string) error
+	Export(ctx context.Context, id uuid.UUID) (modeluser.Export, error)
+	Erase(ctx context.Context, id uuid.UUID) (modeluser.Erasure, error)
+}
+
+// SubscriptionsI drops the cached summary totals of a user after the
+// repository deleted or erased their subscriptions.
+type SubscriptionsI interface {
+	InvalidateUser(ctx context.Context, userID uuid.UUID)
+}
+
+type Usecase struct {
+	repo RepoI
+	subs SubscriptionsI
+}
+
+func New(repo RepoI, subs SubscriptionsI) *Usecase {
+	return &Usecase{repo: repo, subs: subs}
+}
+
+func (u *Usecase) Create(ctx context.Context, usr modeluser.User) (modeluser.User, error) {
+	if err := usr.Normalize(); err != nil {
+		return modeluser.User{}, fmt.Errorf("%w: %s", myerrors.ErrorValidation, err)
+	}
+	return u.repo.Create(ctx, usr)
+}
+
+func (u *Usecase) Get(ctx context.Context, id uuid.UUID) (modeluser.User, error) {
+	return u.repo.Get(ctx, id)
+}
+
+func (u *Usecase) List(ctx context.Context, f modeluser.Filter) ([]modeluser.User, error) {
+	return u.repo.List(ctx, f)
+}
+
+func (u *Usecase) Update(ctx context.Context, usr modeluser.User) (modeluser.User, error) {
+	if err := usr.Normalize(); err != nil {
+		return modeluser.User{}, fmt.Errorf("%w: %s", myerrors.ErrorValidation, err)
+	}
+	return u.repo.Update(ctx, usr)
+}
+
+// Delete removes the user according to mode, see the modeluser.Delete*
+// constants. Anonymizing returns the erased profile, the other modes return
+// nil. Soft-deleted subscriptions do not block DeleteRefuse; the retention
+// job purges them. A failed cascade leaves the user and the subscriptions in
+// place, so it can be retried.
+func (u *Usecase) Delete(ctx context.Context, id uuid.UUID, mode string) (*modeluser.User, error) {
+	if !slices.Contains(modeluser.DeleteModes, mode) {
+		return nil, fmt.Errorf("%w: mode must be one of %s", myerrors.ErrorValidation, strings.Join(modeluser.DeleteModes, ", "))
+	}
+
+	if mode == modeluser.DeleteAnonymize {
+		anonymized, err := u.repo.Anonymize(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &anonymized, nil
+	}
+	if err := u.repo.Delete(ctx, id, mode); err != nil {
+		return nil, err
+	}
+	if mode == modeluser.DeleteCascade {
+		u.subs.InvalidateUser(ctx, id)
+	}
+	return nil, nil
+}
+
+// Export returns everything held about the user, registered or not. It fails
+// with ErrorUserNotFound when there is nothing.
+func (u *Usecase) Export(ctx context.Context, id uuid.UUID) (modeluser.Export, error) {
+	e, err := u.repo.Export(ctx, id)
+	if err != nil {
+		return modeluser.Export{}, err
+	}
+	if e.Empty() {
+		return modeluser.Export{}, myerrors.ErrorUserNotFound
+	}
+	return e, nil
+}
+
+// Erase removes or pseudonymizes everything held about the user, registered
+// or not, see the Erase method of the repository.
+func (u *Usecase) Erase(ctx context.Context, id uuid.UUID) (modeluser.Erasure, error) {
+	e, err := u.repo.Erase(ctx, id)
+	if err != nil {
+		return modeluser.Erasure{}, err
+	}
+	u.subs.InvalidateUser(ctx, id)
+	return e, nil
+}
@@ -0,0 +1,212 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	modeluser "test_task/internal/domain/models/user"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+type memRepo struct {
+	users map[uuid.UUID]modeluser.User
+	// subs are the live subscriptions of the users.
+	subs map[uuid.UUID]bool
+}
+
+func (r *memRepo) Create(_ context.Context, u modeluser.User) (modeluser.User, error) {
+	r.users[u.ID] = u
+	return u, nil
+}
+
+func (r *memRepo) Get(_ context.Context, id uuid.UUID) (modeluser.User, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return modeluser.User{}, myerrors.ErrorNotFound
+	}
+	return u, nil
+}
+
+func (r *memRepo) List(context.Context, modeluser.Filter) ([]modeluser.User, error) { return nil, nil }
+
+func (r *memRepo) Update(_ context.Context, u modeluser.User) (modeluser.User, error) {
+	r.users[u.ID] = u
+	return u, nil
+}
+
+func (r *memRepo) Anonymize(_ context.Context, id uuid.UUID) (modeluser.User, error) {
+	u := r.users[id]
+	u.DisplayName, u.Email = modeluser.AnonymizedName, nil
+	r.users[id] = u
+	return u, nil
+}
+
+func (r *memRepo) Delete(_ context.Context, id uuid.UUID, mode string) error {
+	if _, ok := r.users[id]; !ok {
+		return myerrors.ErrorNotFound
+	}
+	if mode == modeluser.DeleteRefuse && len(r.subs) > 0 {
+		return myerrors.ErrorConflict
+	}
+	clear(r.subs)
+	delete(r.users, id)
+	return nil
+}
+
+func (r *memRepo) Export(_ context.Context, id uuid.UUID) (modeluser.Export, error) {
+	e := modeluser.Export{UserID: id}
+	if u, ok := r.users[id]; ok {
+		e.Profile = &u
+	}
+	return e, nil
+}
+
+func (r *memRepo) Erase(_ context.Context, id uuid.UUID) (modeluser.Erasure, error) {
+	if _, ok := r.users[id]; !ok {
+		return modeluser.Erasure{}, myerrors.ErrorNotFound
+	}
+	delete(r.users, id)
+	return modeluser.Erasure{UserID: id, Profile: 1}, nil
+}
+
+type memSubs struct {
+	invalidated []uuid.UUID
+}
+
+func (s *memSubs) InvalidateUser(_ context.Context, userID uuid.UUID) {
+	s.invalidated = append(s.invalidated, userID)
+}
+
+func newUsecase(subs int) (*Usecase, *memRepo, *memSubs, uuid.UUID) {
+	id := uuid.New()
+	email := "ann@example.com"
+	repo := &memRepo{users: map[uuid.UUID]modeluser.User{id: {ID: id, DisplayName: "Ann", Email: &email}}, subs: map[uuid.UUID]bool{}}
+	for range subs {
+		repo.subs[uuid.New()] = true
+	}
+	s := &memSubs{}
+	return New(repo, s), repo, s, id
+}
+
+func TestDelete_Modes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("refuse with subscriptions", func(t *testing.T) {
+		u, repo, _, id := newUsecase(2)
+		if _, err := u.Delete(ctx, id, modeluser.DeleteRefuse); !errors.Is(err, myerrors.ErrorConflict) {
+			t.Fatalf("want ErrorConflict, got %v", err)
+		}
+		if _, ok := repo.users[id]; !ok {
+			t.Fatalf("user must be kept")
+		}
+	})
+
+	t.Run("refuse without subscriptions", func(t *testing.T) {
+		u, repo, _, id := newUsecase(0)
+		if _, err := u.Delete(ctx, id, modeluser.DeleteRefuse); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, ok := repo.users[id]; ok {
+			t.Fatalf("user must be deleted")
+		}
+	})
+
+	t.Run("cascade", func(t *testing.T) {
+		u, repo, subs, id := newUsecase(5)
+		if _, err := u.Delete(ctx, id, modeluser.DeleteCascade); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if len(repo.subs) != 0 {
+			t.Fatalf("want every subscription deleted, %d left", len(repo.subs))
+		}
+		if len(subs.invalidated) != 1 || subs.invalidated[0] != id {
+			t.Fatalf("want the cached totals of %s dropped, got %v", id, subs.invalidated)
+		}
+		if _, ok := repo.users[id]; ok {
+			t.Fatalf("user must be deleted")
+		}
+	})
+
+	t.Run("anonymize", func(t *testing.T) {
+		u, repo, _, id := newUsecase(2)
+		got, err := u.Delete(ctx, id, modeluser.DeleteAnonymize)
+		if err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if got == nil || got.DisplayName != modeluser.AnonymizedName || got.Email != nil {
+			t.Fatalf("want anonymized profile, got %+v", got)
+		}
+		if len(repo.subs) != 2 {
+			t.Fatalf("subscriptions must be kept, %d left", len(repo.subs))
+		}
+	})
+
+	t.Run("unknown mode", func(t *testing.T) {
+		u, _, _, id := newUsecase(0)
+		if _, err := u.Delete(ctx, id, "purge"); !errors.Is(err, myerrors.ErrorValidation) {
+			t.Fatalf("want ErrorValidation, got %v", err)
+		}
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		u, _, _, _ := newUsecase(0)
+		if _, err := u.Delete(ctx, uuid.New(), modeluser.DeleteCascade); !errors.Is(err, myerrors.ErrorNotFound) {
+			t.Fatalf("want ErrorNotFound, got %v", err)
+		}
+	})
+}
+
+func TestCreate_Normalizes(t *testing.T) {
+	u, _, _, _ := newUsecase(0)
+	email := " bob@example.com "
+	got, err := u.Create(context.Background(), modeluser.User{ID: uuid.New(), DisplayName: "  Bob   Smith ", Email: &email, DefaultCurrency: "usd"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got.DisplayName != "Bob Smith" || *got.Email != "bob@example.com" || got.Timezone != "UTC" || got.DefaultCurrency != "USD" {
+		t.Fatalf("unexpected user %+v", got)
+	}
+
+	for _, bad := range []modeluser.User{
+		{DisplayName: ""},
+		{DisplayName: "Bob", Timezone: "Mars/Olympus"},
+		{DisplayName: "Bob", DefaultCurrency: "rubles"},
+		{DisplayName: "Bob", Email: ptr("not an email")},
+	} {
+		if _, err := u.Create(context.Background(), bad); !errors.Is(err, myerrors.ErrorValidation) {
+			t.Fatalf("%+v: want ErrorValidation, got %v", bad, err)
+		}
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func TestExport_NothingHeldIsNotFound(t *testing.T) {
+	u, _, _, id := newUsecase(0)
+	if _, err := u.Export(context.Background(), uuid.New()); !errors.Is(err, myerrors.ErrorNotFound) {
+		t.Fatalf("want ErrorNotFound, got %v", err)
+	}
+	e, err := u.Export(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if e.Profile == nil || e.Profile.DisplayName != "Ann" {
+		t.Fatalf("want the profile of Ann, got %+v", e.Profile)
+	}
+}
+
+func TestErase_DropsCachedTotals(t *testing.T) {
+	u, repo, subs, id := newUsecase(1)
+	if _, err := u.Erase(context.Background(), id); err != nil {
+		t.Fatalf("Erase: %v", err)
+	}
+	if _, ok := repo.users[id]; ok {
+		t.Fatal("user still registered")
+	}
+	if len(subs.invalidated) != 1 || subs.invalidated[0] != id {
+		t.Fatalf("want the totals of %s dropped, got %v", id, subs.invalidated)
+	}
+}
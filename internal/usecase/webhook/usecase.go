@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+
+	modelevent "test_task/internal/domain/models/event"
+	modelwebhook "test_task/internal/domain/models/webhook"
+	myerrors "test_task/pkg/global_errors"
+	"test_task/pkg/netguard"
+
+	"github.com/google/uuid"
+)
+
+const secretPrefix = "whsec_"
+
+type RepoI interface {
+	Create(ctx context.Context, w modelwebhook.Webhook) (modelwebhook.Webhook, error)
+	Get(ctx context.Context, id uuid.UUID) (modelwebhook.Webhook, error)
+	List(ctx context.Context, userID *uuid.UUID) ([]modelwebhook.Webhook, error)
+	Update(ctx context.Context, w modelwebhook.Webhook) (modelwebhook.Webhook, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	Enqueue(ctx context.Context, ev modelevent.Event, payload []byte, userID *uuid.UUID) (int64, error)
+	ListDeliveries(ctx context.Context, webhookID uuid.UUID, status *string, limit, offset int) ([]modelwebhook.Delivery, error)
+	GetDelivery(ctx context.Context, webhookID uuid.UUID, id int64) (modelwebhook.Delivery, []modelwebhook.Attempt, error)
+	Redeliver(ctx context.Context, webhookID uuid.UUID, id int64) (modelwebhook.Delivery, error)
+}
+
+type Usecase struct {
+	repo     RepoI
+	resolver netguard.Resolver
+	// allowPrivate lets webhooks point at private networks.
+	allowPrivate bool
+}
+
+type Option func(*Usecase)
+
+// WithResolver looks up webhook hosts with r instead of net.DefaultResolver.
+func WithResolver(r netguard.Resolver) Option {
+	return func(u *Usecase) {
+		u.resolver = r
+	}
+}
+
+// WithPrivateNetworks accepts URLs on loopback and private networks, for
+// deployments whose receivers run next to the service.
+func WithPrivateNetworks() Option {
+	return func(u *Usecase) {
+		u.allowPrivate = true
+	}
+}
+
+func New(repo RepoI, opts ...Option) *Usecase {
+	u := &Usecase{repo: repo, resolver: net.DefaultResolver}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// validate checks the webhook and that its URL points at a public address.
+// The deliverer checks the address again when it connects.
+func (u *Usecase) validate(ctx context.Context, w modelwebhook.Webhook) error {
+	target, err := url.Parse(w.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", myerrors.ErrorValidation)
+	}
+	for _, t := range w.EventTypes {
+		if !slices.Contains(modelevent.Types, t) {
+			return fmt.Errorf("%w: unknown event type %q", myerrors.ErrorValidation, t)
+		}
+	}
+	if u.allowPrivate {
+		return nil
+	}
+	if err := netguard.CheckHost(ctx, u.resolver, target.Hostname()); err != nil {
+		if errors.Is(err, netguard.ErrNotPublic) {
+			return fmt.Errorf("%w: url must point at a public address", myerrors.ErrorValidation)
+		}
+		return fmt.Errorf("%w: url host cannot be resolved", myerrors.ErrorValidation)
+	}
+	return nil
+}
+
+// Create registers a webhook with a generated signing secret, which is only
+// returned here.
+func (u *Usecase) Create(ctx context.Context, w modelwebhook.Webhook) (modelwebhook.Webhook, error) {
+	if err := u.validate(ctx, w); err != nil {
+		return modelwebhook.Webhook{}, err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return modelwebhook.Webhook{}, err
+	}
+	w.Secret = secret
+	return u.repo.Create(ctx, w)
+}
+
+func (u *Usecase) Get(ctx context.Context, id uuid.UUID) (modelwebhook.Webhook, error) {
+	return u.repo.Get(ctx, id)
+}
+
+func (u *Usecase) List(ctx context.Context, userID *uuid.UUID) ([]modelwebhook.Webhook, error) {
+	return u.repo.List(ctx, userID)
+}
+
+func (u *Usecase) Update(ctx context.Context, w modelwebhook.Webhook) (modelwebhook.Webhook, error) {
+	if err := u.validate(ctx, w); err != nil {
+		return modelwebhook.Webhook{}, err
+	}
+	return u.repo.Update(ctx, w)
+}
+
+func (u *Usecase) Delete(ctx context.Context, id uuid.UUID) error {
+	return u.repo.Delete(ctx, id)
+}
+
+func (u *Usecase) Deliveries(ctx context.Context, webhookID uuid.UUID, status *string, limit, offset int) ([]modelwebhook.Delivery, error) {
+	return u.repo.ListDeliveries(ctx, webhookID, status, limit, offset)
+}
+
+func (u *Usecase) Delivery(ctx context.Context, webhookID uuid.UUID, id int64) (modelwebhook.Delivery, []modelwebhook.Attempt, error) {
+	return u.repo.GetDelivery(ctx, webhookID, id)
+}
+
+func (u *Usecase) Redeliver(ctx context.Context, webhookID uuid.UUID, id int64) (modelwebhook.Delivery, error) {
+	return u.repo.Redeliver(ctx, webhookID, id)
+}
+
+// Publish fans an outbox event out to the matching webhooks. It is used as
+// an outbox publisher, so a failure here is retried by the dispatcher.
+func (u *Usecase) Publish(ctx context.Context, ev modelevent.Event) error {
+	var data struct {
+		UserID *uuid.UUID `json:"user_id"`
+	}
+	if err := json.Unmarshal(ev.Data, &data); err != nil {
+		return fmt.Errorf("decode event data: %w", err)
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	_, err = u.repo.Enqueue(ctx, ev, payload, data.UserID)
+	return err
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	modelevent "test_task/internal/domain/models/event"
+	modelwebhook "test_task/internal/domain/models/webhook"
+	myerrors "test_task/pkg/global_errors"
+
+	"github.com/google/uuid"
+)
+
+type fakeRepo struct {
+	RepoI
+	created  modelwebhook.Webhook
+	enqueued *uuid.UUID
+}
+
+func (r *fakeRepo) Create(_ context.Context, w modelwebhook.Webhook) (modelwebhook.Webhook, error) {
+	r.created = w
+	return w, nil
+}
+
+func (r *fakeRepo) Enqueue(_ context.Context, _ modelevent.Event, _ []byte, userID *uuid.UUID) (int64, error) {
+	r.enqueued = userID
+	return 1, nil
+}
+
+type staticResolver map[string][]netip.Addr
+
+func (r staticResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return ips, nil
+}
+
+var resolver = staticResolver{
+	"example.com":          {netip.MustParseAddr("93.184.216.34")},
+	"internal.example.com": {netip.MustParseAddr("10.0.0.7")},
+}
+
+func TestCreate_Validates(t *testing.T) {
+	repo := &fakeRepo{}
+	u := New(repo, WithResolver(resolver))
+
+	cases := []modelwebhook.Webhook{
+		{URL: "ftp://example.com/hook"},
+		{URL: "/relative"},
+		{URL: "https://example.com/hook", EventTypes: []string{"subscription.exploded.v1"}},
+		{URL: "http://169.254.169.254/latest/meta-data/"},
+		{URL: "http://127.0.0.1:8080/hook"},
+		{URL: "http://[::1]/hook"},
+		{URL: "https://internal.example.com/hook"},
+		{URL: "https://unknown.example.com/hook"},
+	}
+	for _, c := range cases {
+		if _, err := u.Create(context.Background(), c); !errors.Is(err, myerrors.ErrorValidation) {
+			t.Fatalf("%+v: want validation error, got %v", c, err)
+		}
+	}
+
+	w, err := u.Create(context.Background(), modelwebhook.Webhook{
+		URL:        "https://example.com/hook",
+		EventTypes: []string{modelevent.TypeSubscriptionCancelled},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(w.Secret) < 40 || repo.created.Secret != w.Secret {
+		t.Fatalf("secret must be generated, got %q", w.Secret)
+	}
+}
+
+func TestCreate_PrivateNetworksAllowed(t *testing.T) {
+	u := New(&fakeRepo{}, WithResolver(resolver), WithPrivateNetworks())
+	if _, err := u.Create(context.Background(), modelwebhook.Webhook{URL: "http://127.0.0.1:8080/hook"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+}
+
+func TestPublish_RoutesByUser(t *testing.T) {
+	repo := &fakeRepo{}
+	u := New(repo)
+	userID := uuid.New()
+
+	ev, err := modelevent.New(modelevent.TypeSubscriptionCreated, uuid.NewString(), map[string]any{"user_id": userID})
+	if err != nil {
+		t.Fatalf("event: %v", err)
+	}
+	if err := u.Publish(context.Background(), ev); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if repo.enqueued == nil || *repo.enqueued != userID {
+		t.Fatalf("want deliveries for %s, got %v", userID, repo.enqueued)
+	}
+}
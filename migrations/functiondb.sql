@@ -1,3 +1,34 @@
+DROP FUNCTION IF EXISTS tenant_visible(text) CASCADE;
+DROP TRIGGER IF EXISTS trg_users_set_updated_at ON users;
+DROP TABLE IF EXISTS users;
+DROP TRIGGER IF EXISTS trg_monthly_spend_sync_service ON services;
+DROP TRIGGER IF EXISTS trg_monthly_spend_sync_pause ON subscription_pauses;
+DROP TRIGGER IF EXISTS trg_monthly_spend_sync ON subscriptions;
+DROP FUNCTION IF EXISTS monthly_spend_sync_service();
+DROP FUNCTION IF EXISTS monthly_spend_sync_pause();
+DROP FUNCTION IF EXISTS monthly_spend_sync();
+DROP FUNCTION IF EXISTS monthly_spend_rebuild(date);
+DROP FUNCTION IF EXISTS monthly_spend_refresh(text, uuid, text);
+DROP FUNCTION IF EXISTS monthly_spend_rows(text, uuid, text, date);
+DROP TABLE IF EXISTS monthly_spend_meta;
+DROP TABLE IF EXISTS monthly_spend;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS webhook_delivery_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS subscription_audit;
+DROP TABLE IF EXISTS subscription_pauses;
+DROP TRIGGER IF EXISTS trg_budgets_set_updated_at ON budgets;
+DROP TABLE IF EXISTS budgets;
+DROP TABLE IF EXISTS subscription_tags;
+DROP TRIGGER IF EXISTS trg_tags_set_updated_at ON tags;
+DROP TABLE IF EXISTS tags;
+DROP FUNCTION IF EXISTS forbid_audit_mutation();
+DROP TABLE IF EXISTS api_keys;
 DROP TRIGGER IF EXISTS trg_set_updated_at ON subscriptions;
+DROP TRIGGER IF EXISTS trg_services_set_updated_at ON services;
 DROP FUNCTION IF EXISTS set_updated_at();
 DROP TABLE IF EXISTS subscriptions;
+DROP TABLE IF EXISTS service_aliases;
+DROP TABLE IF EXISTS services;
@@ -1,22 +1,74 @@
 CREATE EXTENSION IF NOT EXISTS pgcrypto;
 
+CREATE TABLE IF NOT EXISTS services (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id text NOT NULL DEFAULT 'default',
+    name text NOT NULL CHECK (length(name) > 0),
+    aliases text[] NOT NULL DEFAULT '{}',
+    category text NULL,
+    default_price integer NULL CHECK (default_price >= 0),
+    billing_period text NOT NULL DEFAULT 'month' CHECK (billing_period IN ('month', 'year')),
+    logo_url text NULL CHECK (logo_url ~ '^https?://'),
+    logo_content_type text NULL,
+    logo_etag text NULL,
+    logo_updated_at timestamptz NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (tenant_id, id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_services_category ON services(tenant_id, category);
+
+-- Normalized names and aliases of the services; the primary key keeps every
+-- spelling pointing at a single service of the tenant.
+CREATE TABLE IF NOT EXISTS service_aliases (
+    tenant_id text NOT NULL DEFAULT 'default',
+    alias text NOT NULL,
+    service_id uuid NOT NULL,
+    PRIMARY KEY (tenant_id, alias),
+    FOREIGN KEY (tenant_id, service_id) REFERENCES services(tenant_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_aliases_service ON service_aliases(service_id);
+
 CREATE TABLE IF NOT EXISTS subscriptions (
     id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id text NOT NULL DEFAULT 'default',
     service_name text NOT NULL CHECK (length(service_name) > 0),
+    service_id uuid NULL,
     price integer NOT NULL CHECK (price >= 0),
     user_id uuid NOT NULL,
     start_date date NOT NULL,
     end_date date NULL,
+    trial_until date NULL,
+    trial_price integer NULL CHECK (trial_price >= 0),
+    status text NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'trial', 'paused', 'cancelled', 'expired')),
+    deleted_at timestamptz NULL,
     created_at timestamptz NOT NULL DEFAULT now(),
     updated_at timestamptz NOT NULL DEFAULT now(),
     CONSTRAINT chk_start_day CHECK (date_part('day', start_date) = 1),
     CONSTRAINT chk_end_day CHECK (end_date IS NULL OR date_part('day', end_date) = 1),
-    CONSTRAINT chk_end_ge_start CHECK (end_date IS NULL OR end_date >= start_date)
+    CONSTRAINT chk_end_ge_start CHECK (end_date IS NULL OR end_date >= start_date),
+    CONSTRAINT chk_trial_day CHECK (trial_until IS NULL OR date_part('day', trial_until) = 1),
+    CONSTRAINT chk_trial_in_range CHECK (
+        trial_until IS NULL OR (trial_until >= start_date AND (end_date IS NULL OR trial_until <= end_date))
+    ),
+    CONSTRAINT chk_trial_price CHECK (trial_price IS NULL OR trial_until IS NOT NULL),
+    UNIQUE (tenant_id, id),
+    -- A subscription links only services of its own tenant; deleting the
+    -- service unlinks it and keeps the tenant.
+    FOREIGN KEY (tenant_id, service_id) REFERENCES services(tenant_id, id) ON DELETE SET NULL (service_id)
 );
 
-CREATE INDEX IF NOT EXISTS idx_subscriptions_user_id ON subscriptions(user_id);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_id ON subscriptions(tenant_id, user_id);
 CREATE INDEX IF NOT EXISTS idx_subscriptions_service_name ON subscriptions(service_name);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_service_id ON subscriptions(service_id) WHERE service_id IS NOT NULL;
 CREATE INDEX IF NOT EXISTS idx_subscriptions_dates ON subscriptions(start_date, end_date);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_deleted_at ON subscriptions(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_subscriptions_expiring ON subscriptions(end_date) WHERE status <> 'expired' AND end_date IS NOT NULL;
+-- Analytics look up the subscriptions still billed at the start of a period.
+CREATE INDEX IF NOT EXISTS idx_subscriptions_end_date ON subscriptions(end_date);
 
 CREATE OR REPLACE FUNCTION set_updated_at()
 RETURNS TRIGGER AS $$
@@ -32,3 +84,493 @@ CREATE TRIGGER trg_set_updated_at
 BEFORE UPDATE ON subscriptions
 FOR EACH ROW
 EXECUTE FUNCTION set_updated_at();
+
+DROP TRIGGER IF EXISTS trg_services_set_updated_at ON services;
+
+CREATE TRIGGER trg_services_set_updated_at
+BEFORE UPDATE ON services
+FOR EACH ROW
+EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id text NOT NULL DEFAULT 'default',
+    name text NOT NULL CHECK (length(name) > 0),
+    prefix text NOT NULL,
+    key_hash text NOT NULL UNIQUE,
+    scopes text[] NOT NULL DEFAULT '{}',
+    user_id uuid NULL,
+    expires_at timestamptz NULL,
+    last_used_at timestamptz NULL,
+    revoked_at timestamptz NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Months from from_date to to_date inclusive are not billed; to_date is NULL
+-- while the pause is open.
+CREATE TABLE IF NOT EXISTS subscription_pauses (
+    id bigserial PRIMARY KEY,
+    tenant_id text NOT NULL DEFAULT 'default',
+    subscription_id uuid NOT NULL,
+    from_date date NOT NULL,
+    to_date date NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    FOREIGN KEY (tenant_id, subscription_id) REFERENCES subscriptions(tenant_id, id) ON DELETE CASCADE,
+    CONSTRAINT chk_pause_from_day CHECK (date_part('day', from_date) = 1),
+    CONSTRAINT chk_pause_to_day CHECK (to_date IS NULL OR date_part('day', to_date) = 1),
+    CONSTRAINT chk_pause_to_ge_from CHECK (to_date IS NULL OR to_date >= from_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_pauses_subscription ON subscription_pauses(subscription_id, from_date);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_pauses_open ON subscription_pauses(subscription_id) WHERE to_date IS NULL;
+
+-- Tag names are unique per user of a tenant ignoring case.
+CREATE TABLE IF NOT EXISTS tags (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id text NOT NULL DEFAULT 'default',
+    user_id uuid NOT NULL,
+    name text NOT NULL CHECK (length(name) > 0),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (tenant_id, id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags(tenant_id, user_id, lower(name));
+
+DROP TRIGGER IF EXISTS trg_tags_set_updated_at ON tags;
+
+CREATE TRIGGER trg_tags_set_updated_at
+BEFORE UPDATE ON tags
+FOR EACH ROW
+EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE IF NOT EXISTS subscription_tags (
+    tenant_id text NOT NULL DEFAULT 'default',
+    subscription_id uuid NOT NULL,
+    tag_id uuid NOT NULL,
+    PRIMARY KEY (subscription_id, tag_id),
+    FOREIGN KEY (tenant_id, subscription_id) REFERENCES subscriptions(tenant_id, id) ON DELETE CASCADE,
+    FOREIGN KEY (tenant_id, tag_id) REFERENCES tags(tenant_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_tags_tag ON subscription_tags(tag_id);
+
+-- A budget covers every subscription of the user, or only those of one
+-- service or carrying one tag.
+CREATE TABLE IF NOT EXISTS budgets (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id text NOT NULL DEFAULT 'default',
+    user_id uuid NOT NULL,
+    amount integer NOT NULL CHECK (amount >= 0),
+    service_name text NULL CHECK (service_name IS NULL OR length(service_name) > 0),
+    tag_id uuid NULL,
+    enforce boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT chk_budget_scope CHECK (service_name IS NULL OR tag_id IS NULL),
+    FOREIGN KEY (tenant_id, tag_id) REFERENCES tags(tenant_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_budgets_user_id ON budgets(tenant_id, user_id);
+
+DROP TRIGGER IF EXISTS trg_budgets_set_updated_at ON budgets;
+
+CREATE TRIGGER trg_budgets_set_updated_at
+BEFORE UPDATE ON budgets
+FOR EACH ROW
+EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE IF NOT EXISTS subscription_audit (
+    id bigserial PRIMARY KEY,
+    tenant_id text NOT NULL DEFAULT 'default',
+    subscription_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    operation text NOT NULL CHECK (operation IN ('create', 'update', 'delete', 'restore', 'purge', 'erase')),
+    actor text NOT NULL,
+    request_id text NULL,
+    before jsonb NULL,
+    after jsonb NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_audit_subscription ON subscription_audit(subscription_id, id);
+CREATE INDEX IF NOT EXISTS idx_subscription_audit_user ON subscription_audit(tenant_id, user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_subscription_audit_created ON subscription_audit(tenant_id, created_at);
+
+-- The erasure of a user is the one exception: a transaction that sets
+-- app.audit_erasure may replace the user and drop the snapshots of an entry,
+-- but not change what happened, when and by whom, nor delete it. The erasure
+-- leaves an 'erase' entry with the nil subscription_id as its tombstone.
+CREATE OR REPLACE FUNCTION forbid_audit_mutation()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND current_setting('app.audit_erasure', true) = 'on'
+        AND (NEW.id, NEW.tenant_id, NEW.subscription_id, NEW.operation, NEW.actor, NEW.request_id, NEW.created_at)
+            IS NOT DISTINCT FROM (OLD.id, OLD.tenant_id, OLD.subscription_id, OLD.operation, OLD.actor, OLD.request_id, OLD.created_at)
+    THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'subscription_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_subscription_audit_append_only ON subscription_audit;
+
+CREATE TRIGGER trg_subscription_audit_append_only
+BEFORE UPDATE OR DELETE ON subscription_audit
+FOR EACH ROW
+EXECUTE FUNCTION forbid_audit_mutation();
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id bigserial PRIMARY KEY,
+    tenant_id text NOT NULL DEFAULT 'default',
+    aggregate_id uuid NOT NULL,
+    event_type text NOT NULL,
+    payload jsonb NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    locked_until timestamptz NULL,
+    last_error text NULL,
+    published_at timestamptz NULL,
+    dead_at timestamptz NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(aggregate_id, id) WHERE published_at IS NULL AND dead_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id text NOT NULL DEFAULT 'default',
+    url text NOT NULL CHECK (url ~ '^https?://'),
+    secret text NOT NULL,
+    event_types text[] NOT NULL DEFAULT '{}',
+    user_id uuid NULL,
+    failure_count integer NOT NULL DEFAULT 0,
+    disabled_at timestamptz NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (tenant_id, id)
+);
+
+DROP TRIGGER IF EXISTS trg_webhooks_set_updated_at ON webhooks;
+
+CREATE TRIGGER trg_webhooks_set_updated_at
+BEFORE UPDATE ON webhooks
+FOR EACH ROW
+EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial PRIMARY KEY,
+    tenant_id text NOT NULL DEFAULT 'default',
+    webhook_id uuid NOT NULL,
+    event_id text NOT NULL,
+    event_type text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    locked_until timestamptz NULL,
+    last_status_code integer NULL,
+    last_error text NULL,
+    delivered_at timestamptz NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (webhook_id, event_id),
+    UNIQUE (tenant_id, id),
+    FOREIGN KEY (tenant_id, webhook_id) REFERENCES webhooks(tenant_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id bigserial PRIMARY KEY,
+    tenant_id text NOT NULL DEFAULT 'default',
+    delivery_id bigint NOT NULL,
+    status_code integer NULL,
+    error text NULL,
+    duration_ms integer NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    FOREIGN KEY (tenant_id, delivery_id) REFERENCES webhook_deliveries(tenant_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts(delivery_id, id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id bigserial PRIMARY KEY,
+    tenant_id text NOT NULL DEFAULT 'default',
+    user_id uuid NOT NULL,
+    subscription_id uuid NULL,
+    kind text NOT NULL,
+    channel text NOT NULL,
+    subject text NOT NULL,
+    body text NOT NULL,
+    dedup_key text NOT NULL,
+    status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    error text NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    sent_at timestamptz NULL,
+    UNIQUE (tenant_id, dedup_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(tenant_id, user_id, created_at);
+
+-- monthly_spend pre-aggregates the months the summary counts per tenant,
+-- user, service and deleted flag, up to the horizon in monthly_spend_meta,
+-- which is shared by every tenant. It is
+-- filled by monthly_spend_rebuild (./main rollup rebuild) and kept in sync
+-- by the triggers below; without a meta row it is not used.
+CREATE TABLE IF NOT EXISTS monthly_spend (
+    tenant_id text NOT NULL DEFAULT 'default',
+    user_id uuid NOT NULL,
+    service_name text NOT NULL,
+    month date NOT NULL,
+    deleted boolean NOT NULL,
+    amount bigint NOT NULL,
+    count integer NOT NULL,
+    PRIMARY KEY (tenant_id, user_id, service_name, deleted, month)
+);
+
+CREATE INDEX IF NOT EXISTS idx_monthly_spend_month ON monthly_spend(tenant_id, month);
+
+CREATE TABLE IF NOT EXISTS monthly_spend_meta (
+    id boolean PRIMARY KEY DEFAULT true CHECK (id),
+    horizon date NOT NULL,
+    rebuilt_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- monthly_spend_rows bills subscriptions like the summary query: trial
+-- months at the trial price, paused months left out, services billed yearly
+-- twelve prices once a year from the first month after the trial, open-ended
+-- ones up to p_horizon. NULL p_tenant, p_user or p_service means every
+-- tenant, user or service.
+CREATE OR REPLACE FUNCTION monthly_spend_rows(p_tenant text, p_user uuid, p_service text, p_horizon date)
+RETURNS TABLE (tenant_id text, user_id uuid, service_name text, month date, deleted boolean, amount bigint, count integer)
+LANGUAGE sql STABLE AS $$
+    SELECT s.tenant_id, s.user_id, s.service_name, m::date, s.deleted_at IS NOT NULL,
+        SUM(CASE WHEN s.trial_until IS NOT NULL AND m::date <= s.trial_until
+            THEN COALESCE(s.trial_price, 0)
+        WHEN NOT EXISTS (
+            SELECT 1 FROM services sv WHERE sv.id = s.service_id AND sv.billing_period = 'year'
+        ) THEN s.price
+        WHEN ((date_part('year', m) - date_part('year', y.anchor)) * 12
+            + date_part('month', m) - date_part('month', y.anchor))::int % 12 = 0
+            THEN s.price * 12
+        ELSE 0
+        END)::bigint,
+        count(*)::integer
+    FROM subscriptions s
+    CROSS JOIN LATERAL generate_series(s.start_date, LEAST(COALESCE(s.end_date, p_horizon), p_horizon), interval '1 month') AS m
+    CROSS JOIN LATERAL (
+        SELECT COALESCE((s.trial_until + interval '1 month')::date, s.start_date) AS anchor
+    ) y
+    WHERE (p_tenant IS NULL OR s.tenant_id = p_tenant)
+        AND (p_user IS NULL OR s.user_id = p_user)
+        AND (p_service IS NULL OR s.service_name = p_service)
+        AND NOT EXISTS (
+            SELECT 1 FROM subscription_pauses p
+            WHERE p.subscription_id = s.id
+            AND m::date >= p.from_date
+            AND (p.to_date IS NULL OR m::date <= p.to_date)
+        )
+    GROUP BY s.tenant_id, s.user_id, s.service_name, m::date, s.deleted_at IS NOT NULL
+$$;
+
+-- monthly_spend_refresh recomputes the rows of one user and service of a
+-- tenant. The lock waits for a running rebuild so the new horizon is used.
+CREATE OR REPLACE FUNCTION monthly_spend_refresh(p_tenant text, p_user uuid, p_service text)
+RETURNS void AS $$
+DECLARE
+    v_horizon date;
+BEGIN
+    LOCK TABLE monthly_spend IN ROW EXCLUSIVE MODE;
+    SELECT horizon INTO v_horizon FROM monthly_spend_meta;
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+    PERFORM pg_advisory_xact_lock(hashtext('monthly_spend:' || p_tenant || ':' || p_user::text || ':' || p_service));
+
+    DELETE FROM monthly_spend ms
+    WHERE ms.tenant_id = p_tenant AND ms.user_id = p_user AND ms.service_name = p_service;
+    INSERT INTO monthly_spend (tenant_id, user_id, service_name, month, deleted, amount, count)
+    SELECT r.tenant_id, r.user_id, r.service_name, r.month, r.deleted, r.amount, r.count
+    FROM monthly_spend_rows(p_tenant, p_user, p_service, v_horizon) r;
+END;
+$$ LANGUAGE plpgsql;
+
+-- monthly_spend_rebuild refills the rollup up to p_horizon and returns the
+-- number of rows. Readers keep seeing the previous rows until it commits.
+CREATE OR REPLACE FUNCTION monthly_spend_rebuild(p_horizon date)
+RETURNS bigint AS $$
+DECLARE
+    v_rows bigint;
+BEGIN
+    LOCK TABLE monthly_spend IN EXCLUSIVE MODE;
+    DELETE FROM monthly_spend;
+    INSERT INTO monthly_spend (tenant_id, user_id, service_name, month, deleted, amount, count)
+    SELECT r.tenant_id, r.user_id, r.service_name, r.month, r.deleted, r.amount, r.count
+    FROM monthly_spend_rows(NULL, NULL, NULL, p_horizon) r;
+    GET DIAGNOSTICS v_rows = ROW_COUNT;
+
+    INSERT INTO monthly_spend_meta (id, horizon, rebuilt_at) VALUES (true, p_horizon, now())
+    ON CONFLICT (id) DO UPDATE SET horizon = EXCLUDED.horizon, rebuilt_at = EXCLUDED.rebuilt_at;
+    RETURN v_rows;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION monthly_spend_sync()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        PERFORM monthly_spend_refresh(NEW.tenant_id, NEW.user_id, NEW.service_name);
+        RETURN NULL;
+    END IF;
+    PERFORM monthly_spend_refresh(OLD.tenant_id, OLD.user_id, OLD.service_name);
+    IF TG_OP = 'UPDATE' AND (NEW.tenant_id, NEW.user_id, NEW.service_name) IS DISTINCT FROM (OLD.tenant_id, OLD.user_id, OLD.service_name) THEN
+        PERFORM monthly_spend_refresh(NEW.tenant_id, NEW.user_id, NEW.service_name);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION monthly_spend_sync_pause()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_id uuid;
+    v_sub record;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        v_id := OLD.subscription_id;
+    ELSE
+        v_id := NEW.subscription_id;
+    END IF;
+    SELECT s.tenant_id, s.user_id, s.service_name INTO v_sub
+    FROM subscriptions s
+    WHERE s.id = v_id;
+    IF FOUND THEN
+        PERFORM monthly_spend_refresh(v_sub.tenant_id, v_sub.user_id, v_sub.service_name);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_monthly_spend_sync ON subscriptions;
+
+CREATE TRIGGER trg_monthly_spend_sync
+AFTER INSERT OR DELETE OR UPDATE OF tenant_id, user_id, service_name, service_id, price, start_date, end_date, trial_until, trial_price, deleted_at ON subscriptions
+FOR EACH ROW
+EXECUTE FUNCTION monthly_spend_sync();
+
+DROP TRIGGER IF EXISTS trg_monthly_spend_sync_pause ON subscription_pauses;
+
+CREATE TRIGGER trg_monthly_spend_sync_pause
+AFTER INSERT OR UPDATE OR DELETE ON subscription_pauses
+FOR EACH ROW
+EXECUTE FUNCTION monthly_spend_sync_pause();
+
+-- monthly_spend_sync_service recomputes the rows of the subscriptions to a
+-- service whose billing period changed.
+CREATE OR REPLACE FUNCTION monthly_spend_sync_service()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_sub record;
+BEGIN
+    FOR v_sub IN
+        SELECT DISTINCT s.tenant_id, s.user_id, s.service_name
+        FROM subscriptions s
+        WHERE s.service_id = NEW.id
+    LOOP
+        PERFORM monthly_spend_refresh(v_sub.tenant_id, v_sub.user_id, v_sub.service_name);
+    END LOOP;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_monthly_spend_sync_service ON services;
+
+CREATE TRIGGER trg_monthly_spend_sync_service
+AFTER UPDATE OF billing_period ON services
+FOR EACH ROW
+WHEN (OLD.billing_period IS DISTINCT FROM NEW.billing_period)
+EXECUTE FUNCTION monthly_spend_sync_service();
+
+-- Registered users. Subscriptions reference users by user_id without a
+-- database constraint by default: the service checks it when
+-- users.require_registered is set, so deployments that never register users
+-- keep working. The delete locks the user row and creating a subscription
+-- waits for it. migrations/users_fk.sh adds the foreign key when
+-- USERS_FOREIGN_KEY=true.
+CREATE TABLE IF NOT EXISTS users (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id text NOT NULL DEFAULT 'default',
+    display_name text NOT NULL,
+    email text NULL,
+    timezone text NOT NULL DEFAULT 'UTC',
+    default_currency text NOT NULL DEFAULT 'RUB' CHECK (default_currency ~ '^[A-Z]{3}$'),
+    anonymized_at timestamptz NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (tenant_id, id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(tenant_id, lower(email)) WHERE email IS NOT NULL;
+
+DROP TRIGGER IF EXISTS trg_users_set_updated_at ON users;
+
+CREATE TRIGGER trg_users_set_updated_at
+BEFORE UPDATE ON users
+FOR EACH ROW
+EXECUTE FUNCTION set_updated_at();
+
+-- Backfill of the catalog links, safe to rerun: every service name and
+-- alias is a lookup key, subscriptions written with a key before the service
+-- was in the catalog are linked to it, and linked subscriptions carry the
+-- current name of their service. Keys are normalized like
+-- modelservice.Normalize; a spelling taken by another service is skipped.
+INSERT INTO service_aliases (tenant_id, alias, service_id)
+SELECT s.tenant_id, lower(regexp_replace(btrim(k.name), '\s+', ' ', 'g')), s.id
+FROM services s
+CROSS JOIN LATERAL unnest(array_prepend(s.name, s.aliases)) AS k(name)
+WHERE btrim(k.name) <> ''
+ON CONFLICT (tenant_id, alias) DO NOTHING;
+
+UPDATE subscriptions sub
+SET service_id = a.service_id
+FROM service_aliases a
+WHERE sub.service_id IS NULL
+    AND a.tenant_id = sub.tenant_id
+    AND a.alias = lower(regexp_replace(btrim(sub.service_name), '\s+', ' ', 'g'));
+
+UPDATE subscriptions sub
+SET service_name = s.name
+FROM services s
+WHERE s.tenant_id = sub.tenant_id
+    AND s.id = sub.service_id
+    AND sub.service_name <> s.name;
+
+-- Row-level security backs the tenant filter of the queries: a session
+-- sees and writes only the rows of the tenant in app.tenant_id, which the
+-- application sets for every statement. Sessions without it see nothing.
+-- The background jobs, the rollup rebuild and the key lookup work across
+-- tenants as a BYPASSRLS role (roles.sh), migrations as the owner superuser.
+CREATE OR REPLACE FUNCTION tenant_visible(p_tenant text)
+RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT COALESCE(current_setting('app.tenant_id', true) = p_tenant, false)
+$$;
+
+DO $$
+DECLARE
+    t text;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'services', 'service_aliases', 'subscriptions', 'api_keys', 'subscription_pauses',
+        'tags', 'subscription_tags', 'budgets', 'subscription_audit', 'outbox', 'webhooks',
+        'webhook_deliveries', 'webhook_delivery_attempts', 'notifications', 'monthly_spend', 'users'
+    ] LOOP
+        EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', t);
+        EXECUTE format('ALTER TABLE %I FORCE ROW LEVEL SECURITY', t);
+        EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', t);
+        EXECUTE format('CREATE POLICY tenant_isolation ON %I USING (tenant_visible(tenant_id)) WITH CHECK (tenant_visible(tenant_id))', t);
+    END LOOP;
+END;
+$$;
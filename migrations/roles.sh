@@ -0,0 +1,25 @@
+#!/bin/sh
+# Creates the roles the application connects as, after initdb.sql created the
+# schema as the owner superuser. The API role is subject to the row-level
+# security, the jobs role bypasses it to work across tenants.
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" \
+	-v app_user="${POSTGRES_APP_USER:-subscriptions_app}" \
+	-v app_password="${POSTGRES_APP_PASSWORD:-subscriptions_app}" \
+	-v jobs_user="${POSTGRES_JOBS_USER:-subscriptions_jobs}" \
+	-v jobs_password="${POSTGRES_JOBS_PASSWORD:-subscriptions_jobs}" <<'EOSQL'
+SELECT format('CREATE ROLE %I LOGIN PASSWORD %L NOSUPERUSER NOBYPASSRLS', :'app_user', :'app_password')
+WHERE NOT EXISTS (SELECT FROM pg_roles WHERE rolname = :'app_user') \gexec
+
+SELECT format('CREATE ROLE %I LOGIN PASSWORD %L NOSUPERUSER BYPASSRLS', :'jobs_user', :'jobs_password')
+WHERE NOT EXISTS (SELECT FROM pg_roles WHERE rolname = :'jobs_user') \gexec
+
+GRANT USAGE ON SCHEMA public TO :"app_user", :"jobs_user";
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO :"app_user", :"jobs_user";
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO :"app_user", :"jobs_user";
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+	GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO :"app_user", :"jobs_user";
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+	GRANT USAGE, SELECT ON SEQUENCES TO :"app_user", :"jobs_user";
+EOSQL
@@ -0,0 +1,25 @@
+#!/bin/sh
+# Adds the foreign key from subscriptions.user_id to users when
+# USERS_FOREIGN_KEY=true, after initdb.sql created both tables. It is meant
+# for deployments that run with users.require_registered. The key is NOT
+# VALID, so subscriptions written before for unregistered users stay, while
+# new and changed rows must name a registered user. Deleting a user then
+# removes their subscriptions, soft-deleted ones included, at once instead of
+# leaving them to the retention job. Safe to rerun.
+set -e
+
+if [ "${USERS_FOREIGN_KEY:-false}" != "true" ]; then
+	exit 0
+fi
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<'EOSQL'
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT FROM pg_constraint WHERE conname = 'fk_subscriptions_user') THEN
+        ALTER TABLE subscriptions
+            ADD CONSTRAINT fk_subscriptions_user
+            FOREIGN KEY (tenant_id, user_id) REFERENCES users(tenant_id, id) ON DELETE CASCADE
+            NOT VALID;
+    END IF;
+END $$;
+EOSQL
@@ -0,0 +1,18 @@
+package backoff
+
+import "time"
+
+// Exponential returns base * 2^(attempt-1), capped at max.
+func Exponential(attempt int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= max || d <= 0 {
+			return max
+		}
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
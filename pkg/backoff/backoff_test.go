@@ -0,0 +1,24 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponential(t *testing.T) {
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{10, time.Minute},
+		{100, time.Minute},
+	}
+	for _, c := range cases {
+		if got := Exponential(c.attempt, time.Second, time.Minute); got != c.want {
+			t.Fatalf("attempt %d: want %v, got %v", c.attempt, c.want, got)
+		}
+	}
+}
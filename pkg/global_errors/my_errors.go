@@ -1,7 +1,38 @@
 package globalerrors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrorNotFound is wrapped by the not found error of every entity, so
+	// errors.Is matches any of them.
+	ErrorNotFound   = errors.New("not found")
+	ErrorValidation = errors.New("validation failed")
+	// ErrorInvalidTransition rejects a status change the state machine forbids.
+	ErrorInvalidTransition = errors.New("invalid status transition")
+	// ErrorConflict reports a unique value that is already taken.
+	ErrorConflict = errors.New("already exists")
+	// ErrorUnsupportedMedia rejects an upload of a type the endpoint does not
+	// accept.
+	ErrorUnsupportedMedia = errors.New("unsupported media type")
+	// ErrorTooLarge rejects an upload over the configured size.
+	ErrorTooLarge = errors.New("too large")
+	// ErrorBudgetExceeded rejects a change that would overspend an enforced
+	// budget.
+	ErrorBudgetExceeded = errors.New("budget exceeded")
+)
 
 var (
-	ErrorNotFound = errors.New("subscription not found")
+	ErrorSubscriptionNotFound = fmt.Errorf("subscription %w", ErrorNotFound)
+	ErrorUserNotFound         = fmt.Errorf("user %w", ErrorNotFound)
+	ErrorTagNotFound          = fmt.Errorf("tag %w", ErrorNotFound)
+	ErrorServiceNotFound      = fmt.Errorf("service %w", ErrorNotFound)
+	ErrorLogoNotFound         = fmt.Errorf("logo %w", ErrorNotFound)
+	ErrorBudgetNotFound       = fmt.Errorf("budget %w", ErrorNotFound)
+	ErrorWebhookNotFound      = fmt.Errorf("webhook %w", ErrorNotFound)
+	ErrorDeliveryNotFound     = fmt.Errorf("delivery %w", ErrorNotFound)
+	ErrorAPIKeyNotFound       = fmt.Errorf("api key %w", ErrorNotFound)
+	ErrorObjectNotFound       = fmt.Errorf("object %w", ErrorNotFound)
 )
@@ -0,0 +1,92 @@
+// Package netguard keeps requests to caller-supplied URLs away from the
+// private networks of the deployment: loopback, RFC 1918, link-local (and
+// with it the cloud metadata endpoint 169.254.169.254) and other addresses
+// that are not publicly routable.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrNotPublic is returned for addresses that are not publicly routable.
+var ErrNotPublic = errors.New("address is not publicly routable")
+
+// reserved lists the special-purpose ranges netip does not classify.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// IsPublic reports whether ip is publicly routable.
+func IsPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range reserved {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolver looks up the addresses of a host; *net.Resolver implements it.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// CheckHost fails with ErrNotPublic unless every address host resolves to
+// is public. It catches bad URLs early; Control still has to guard the
+// connection, since the answer can change by the time it is made.
+func CheckHost(ctx context.Context, r Resolver, host string) error {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !IsPublic(ip) {
+			return fmt.Errorf("%s: %w", host, ErrNotPublic)
+		}
+		return nil
+	}
+	ips, err := r.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	for _, ip := range ips {
+		if !IsPublic(ip) {
+			return fmt.Errorf("%s resolves to %s: %w", host, ip, ErrNotPublic)
+		}
+	}
+	return nil
+}
+
+// Control is a net.Dialer Control function refusing to connect to addresses
+// that are not public. It sees the resolved address, so DNS rebinding cannot
+// get around it.
+func Control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !IsPublic(ip) {
+		return fmt.Errorf("dial %s: %w", address, ErrNotPublic)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":          true,
+		"2606:2800:220:1::":      true,
+		"127.0.0.1":              false,
+		"10.1.2.3":               false,
+		"172.16.0.1":             false,
+		"192.168.1.1":            false,
+		"169.254.169.254":        false,
+		"100.64.0.1":             false,
+		"0.0.0.0":                false,
+		"255.255.255.255":        false,
+		"224.0.0.1":              false,
+		"::1":                    false,
+		"fd00::1":                false,
+		"fe80::1":                false,
+		"::ffff:127.0.0.1":       false,
+		"::ffff:169.254.169.254": false,
+	}
+	for addr, want := range cases {
+		if got := IsPublic(netip.MustParseAddr(addr)); got != want {
+			t.Fatalf("%s: want %t, got %t", addr, want, got)
+		}
+	}
+}
+
+type staticResolver map[string][]netip.Addr
+
+func (r staticResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	return r[host], nil
+}
+
+func TestCheckHost(t *testing.T) {
+	r := staticResolver{
+		"hooks.example.com":  {netip.MustParseAddr("93.184.216.34")},
+		"rebind.example.com": {netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("10.0.0.1")},
+	}
+	if err := CheckHost(context.Background(), r, "hooks.example.com"); err != nil {
+		t.Fatalf("public host: %v", err)
+	}
+	for _, host := range []string{"rebind.example.com", "169.254.169.254", "::1"} {
+		if err := CheckHost(context.Background(), r, host); !errors.Is(err, ErrNotPublic) {
+			t.Fatalf("%s: want ErrNotPublic, got %v", host, err)
+		}
+	}
+}
+
+func TestControl(t *testing.T) {
+	if err := Control("tcp4", "127.0.0.1:8080", nil); !errors.Is(err, ErrNotPublic) {
+		t.Fatalf("loopback: want ErrNotPublic, got %v", err)
+	}
+	if err := Control("tcp6", "[2606:2800:220:1::]:443", nil); err != nil {
+		t.Fatalf("public: %v", err)
+	}
+}